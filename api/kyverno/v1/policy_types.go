@@ -52,8 +52,9 @@ type Spec struct {
 	FailurePolicy *FailurePolicyType `json:"failurePolicy,omitempty" yaml:"failurePolicy,omitempty"`
 
 	// ValidationFailureAction controls if a validation policy rule failure should disallow
-	// the admission review request (enforce), or allow (audit) the admission review request
-	// and report an error in a policy report. Optional. The default value is "audit".
+	// the admission review request (enforce), or allow the admission review request and report
+	// an error in a policy report (audit), or also return the messages of the failed rules as
+	// admission warnings (warn). Optional. The default value is "audit".
 	// +optional
 	ValidationFailureAction string `json:"validationFailureAction,omitempty" yaml:"validationFailureAction,omitempty"`
 
@@ -63,6 +64,13 @@ type Spec struct {
 	// +optional
 	Background *bool `json:"background,omitempty" yaml:"background,omitempty"`
 
+	// FailFast stops the evaluation of the remaining validate rules after the first validate rule that fails.
+	// Rules are evaluated in the declared order. Mutate and generate rules are not affected and are always
+	// applied, a failed validate rule only skips the validate rules declared after it.
+	// Optional. The default value is "false".
+	// +optional
+	FailFast *bool `json:"failFast,omitempty" yaml:"failFast,omitempty"`
+
 	// SchemaValidation skips policy validation checks.
 	// Optional. The default value is set to "true", it must be set to "false" to disable the validation checks.
 	// +optional
@@ -168,6 +176,11 @@ type ContextEntry struct {
 	// APICall defines an HTTP request to the Kubernetes API server. The JSON
 	// data retrieved is stored in the context.
 	APICall *APICall `json:"apiCall,omitempty" yaml:"apiCall,omitempty"`
+
+	// ResourceQuota fetches the ResourceQuotas of a namespace. The context
+	// entry contains the hard limit, the used and the remaining quantity
+	// of each quota resource, and exists is false if no quota is found.
+	ResourceQuota *ResourceQuotaReference `json:"resourceQuota,omitempty" yaml:"resourceQuota,omitempty"`
 }
 
 // ConfigMapReference refers to a ConfigMap
@@ -180,6 +193,21 @@ type ConfigMapReference struct {
 	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 }
 
+// ResourceQuotaReference refers to the ResourceQuotas of a namespace
+type ResourceQuotaReference struct {
+
+	// Name is the ResourceQuota name. If empty, all the ResourceQuotas of
+	// the namespace are fetched and the remaining quantity of a resource
+	// is the lowest of the quotas.
+	// +optional
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Namespace is the ResourceQuota namespace. Defaults to the namespace
+	// of the resource.
+	// +optional
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
 // APICall defines an HTTP request to the Kubernetes API server. The JSON
 // data retrieved is stored in the context. An APICall contains a URLPath
 // used to perform the HTTP GET request and an optional JMESPath used to
@@ -341,17 +369,23 @@ type UserInfo struct {
 
 // ResourceDescription contains criteria used to match resources.
 type ResourceDescription struct {
-	// Kinds is a list of resource kinds.
+	// Kinds is a list of resource kinds. An entry may list several comma separated kinds,
+	// e.g. "Deployment,StatefulSet,DaemonSet", and "*" matches all kinds.
+	// The kinds that do not exist in the cluster never match and are not registered in the webhooks.
 	// +optional
 	Kinds []string `json:"kinds,omitempty" yaml:"kinds,omitempty"`
 
-	// Name is the name of the resource. The name supports wildcard characters
-	// "*" (matches zero or many characters) and "?" (at least one character).
+	// Name is the name of the resource. The name is a glob pattern that matches the whole name,
+	// "*" matches zero or many characters, "?" exactly one character and "[a-z]" one character of a class,
+	// e.g. "kube-*" matches "kube-proxy" but not "my-kube-config". A name without glob characters is compared as is.
+	// The generateName of the resource is matched if its name is empty, e.g. for the Pods created by a controller.
 	// +optional
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 
-	// Names are the names of the resources. Each name supports wildcard characters
-	// "*" (matches zero or many characters) and "?" (at least one character).
+	// Names are the names of the resources. Each name is a glob pattern that matches the whole name,
+	// "*" matches zero or many characters, "?" exactly one character and "[a-z]" one character of a class,
+	// e.g. "kube-*" matches "kube-proxy" but not "my-kube-config". A name without glob characters is compared as is.
+	// The generateName of the resource is matched if its name is empty, e.g. for the Pods created by a controller.
 	// NOTE: "Name" is being deprecated in favor of "Names".
 	// +optional
 	Names []string `json:"names,omitempty" yaml:"names,omitempty"`
@@ -381,6 +415,14 @@ type ResourceDescription struct {
 	// does not match an empty label set.
 	// +optional
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty" yaml:"namespaceSelector,omitempty"`
+
+	// AnnotationSelector is a selector evaluated against the resource annotations.
+	// An annotation listed in `matchLabels` must be present with the same value, keys
+	// and values support the wildcard characters `*` and `?` as in Annotations.
+	// `matchExpressions` support the `In`, `NotIn`, `Exists` and `DoesNotExist` operators.
+	// Unlike label selectors, annotation values are not restricted to the label value syntax.
+	// +optional
+	AnnotationSelector *metav1.LabelSelector `json:"annotationSelector,omitempty" yaml:"annotationSelector,omitempty"`
 }
 
 // Mutation defines how resource are modified.
@@ -403,6 +445,8 @@ type Mutation struct {
 	// PatchStrategicMerge is a strategic merge patch used to modify resources.
 	// See https://kubernetes.io/docs/tasks/manage-kubernetes-objects/update-api-object-kubectl-patch/
 	// and https://kubectl.docs.kubernetes.io/references/kustomize/patchesstrategicmerge/.
+	// A list of patches is applied in sequence, each patch to the resource patched by the
+	// previous ones, and the rule fails without patching the resource if a patch fails.
 	// +kubebuilder:validation:XPreserveUnknownFields
 	// +optional
 	PatchStrategicMerge apiextensions.JSON `json:"patchStrategicMerge,omitempty" yaml:"patchStrategicMerge,omitempty"`
@@ -484,6 +528,16 @@ type Validation struct {
 	// +optional
 	AnyPattern apiextensions.JSON `json:"anyPattern,omitempty" yaml:"anyPattern,omitempty"`
 
+	// RejectUnknownFields fails the validation when the resource contains a field that is not
+	// declared in the pattern, or in the matching pattern of anyPattern. The apiVersion, kind and
+	// status fields of the resource, and the metadata fields at any level, e.g. of a pod template,
+	// are always allowed. Anchored fields are allowed, except for negation anchors, and the elements
+	// of an array are checked against the first element of the array in the pattern. A field with a
+	// value pattern allows any nested field. The autogen rules only check the pod template of the
+	// controllers.
+	// +optional
+	RejectUnknownFields bool `json:"rejectUnknownFields,omitempty" yaml:"rejectUnknownFields,omitempty"`
+
 	// Deny defines conditions used to pass or fail a validation rule.
 	// +optional
 	Deny *Deny `json:"deny,omitempty" yaml:"deny,omitempty"`
@@ -588,6 +642,14 @@ type Generation struct {
 	// +optional
 	Synchronize bool `json:"synchronize,omitempty" yaml:"synchronize,omitempty"`
 
+	// OwnerRef sets the trigger resource as the owner of the generated resource, so that the
+	// generated resource is garbage collected when the trigger is deleted. The owner is not set
+	// if the trigger is namespaced and the generated resource is cluster-scoped or in another
+	// namespace, as Kubernetes does not allow such owner references.
+	// Optional. Defaults to "false" if not specified.
+	// +optional
+	OwnerRef bool `json:"ownerRef,omitempty" yaml:"ownerRef,omitempty"`
+
 	// Data provides the resource declaration used to populate each generated resource.
 	// At most one of Data or Clone must be specified. If neither are provided, the generated
 	// resource will be created with default data only.
@@ -620,6 +682,45 @@ type CloneFrom struct {
 type PolicyStatus struct {
 	// Ready indicates if the policy is ready to serve the admission request
 	Ready bool `json:"ready" yaml:"ready"`
+
+	// LastMutation contains the patches and the rule errors of the last admission request
+	// mutated by the policy, to help debugging mutate rules.
+	// +optional
+	LastMutation *MutationStatus `json:"lastMutation,omitempty" yaml:"lastMutation,omitempty"`
+}
+
+// MutationStatus contains information about a mutation applied by a policy.
+type MutationStatus struct {
+	// Resource is the mutated resource.
+	// +optional
+	Resource ResourceSpec `json:"resource,omitempty" yaml:"resource,omitempty"`
+
+	// Patches is the JSON patch generated by the policy. Patches that do not fit
+	// in the status size limit are omitted.
+	// +optional
+	Patches string `json:"patches,omitempty" yaml:"patches,omitempty"`
+
+	// Truncated is true if some patches or rule errors are omitted.
+	// +optional
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+
+	// RuleErrors contains the rules that failed to mutate the resource.
+	// +optional
+	RuleErrors []RuleErrorStatus `json:"ruleErrors,omitempty" yaml:"ruleErrors,omitempty"`
+
+	// Time is the time of the mutation.
+	// +optional
+	Time metav1.Time `json:"time,omitempty" yaml:"time,omitempty"`
+}
+
+// RuleErrorStatus contains the error of a rule.
+type RuleErrorStatus struct {
+	// Rule is the rule name.
+	Rule string `json:"rule" yaml:"rule"`
+
+	// Message is the error message.
+	// +optional
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
 }
 
 // ResourceSpec contains information to identify a resource.
@@ -82,6 +82,15 @@ func (p *ClusterPolicy) BackgroundProcessingEnabled() bool {
 	return *p.Spec.Background
 }
 
+// FailFastEnabled checks if failFast is set to true
+func (p *ClusterPolicy) FailFastEnabled() bool {
+	if p.Spec.FailFast == nil {
+		return false
+	}
+
+	return *p.Spec.FailFast
+}
+
 // HasMutate checks for mutate rule
 func (r Rule) HasMutate() bool {
 	return !reflect.DeepEqual(r.Mutation, Mutation{})
@@ -112,7 +121,7 @@ func (r Rule) MatchKinds() []string {
 		matchKinds = append(matchKinds, value.ResourceDescription.Kinds...)
 	}
 
-	return matchKinds
+	return ExpandKinds(matchKinds)
 }
 
 // ExcludeKinds returns a slice of all kinds to exclude
@@ -124,7 +133,39 @@ func (r Rule) ExcludeKinds() []string {
 	for _, value := range r.ExcludeResources.Any {
 		excludeKinds = append(excludeKinds, value.ResourceDescription.Kinds...)
 	}
-	return excludeKinds
+	return ExpandKinds(excludeKinds)
+}
+
+// ExpandKinds splits the comma separated kinds, e.g. "Deployment,StatefulSet,DaemonSet",
+// and returns the distinct kinds in their order of appearance
+func ExpandKinds(kinds []string) []string {
+	expanded := make([]string, 0, len(kinds))
+	seen := make(map[string]bool, len(kinds))
+	for _, value := range kinds {
+		for _, kind := range strings.Split(value, ",") {
+			kind = strings.TrimSpace(kind)
+			if kind == "" || seen[kind] {
+				continue
+			}
+			seen[kind] = true
+			expanded = append(expanded, kind)
+		}
+	}
+	return expanded
+}
+
+// StrategicMergePatches returns the strategic merge patches of the mutation in the order they are applied,
+// the patchStrategicMerge is either a single patch or a list of patches
+func (in *Mutation) StrategicMergePatches() []interface{} {
+	if in.PatchStrategicMerge == nil {
+		return nil
+	}
+
+	if patches, ok := in.PatchStrategicMerge.([]interface{}); ok {
+		return patches
+	}
+
+	return []interface{}{in.PatchStrategicMerge}
 }
 
 // DeserializeAnyPattern deserialize apiextensions.JSON to []interface{}
@@ -20,16 +20,16 @@ import (
 	coord "k8s.io/api/coordination/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	rest "k8s.io/client-go/rest"
-	clientcmd "k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var (
-	kubeconfig string
-	setupLog   = log.Log.WithName("setup")
+	kubeconfig           string
+	clientRateLimitQPS   float64
+	clientRateLimitBurst int
+	setupLog             = log.Log.WithName("setup")
 )
 
 const (
@@ -46,16 +46,23 @@ func main() {
 	log.SetLogger(klogr.New())
 	// arguments
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	flag.Float64Var(&clientRateLimitQPS, "clientRateLimitQPS", config.ClientRateLimitQPS, "Maximum queries per second of the clients to the API server.")
+	flag.IntVar(&clientRateLimitBurst, "clientRateLimitBurst", config.ClientRateLimitBurst, "Maximum burst of queries of the clients to the API server, above clientRateLimitQPS.")
 	if err := flag.Set("v", "2"); err != nil {
 		klog.Fatalf("failed to set log level: %v", err)
 	}
 
 	flag.Parse()
 
+	if clientRateLimitQPS <= 0 || clientRateLimitBurst <= 0 {
+		setupLog.Error(fmt.Errorf("qps %v, burst %d", clientRateLimitQPS, clientRateLimitBurst), "invalid value for flags clientRateLimitQPS and clientRateLimitBurst, must be positive")
+		os.Exit(1)
+	}
+
 	// os signal handler
 	stopCh := signal.SetupSignalHandler()
 	// create client config
-	clientConfig, err := createClientConfig(kubeconfig)
+	clientConfig, err := config.CreateClientConfig(kubeconfig, clientRateLimitQPS, clientRateLimitBurst, log.Log)
 	if err != nil {
 		setupLog.Error(err, "Failed to build kubeconfig")
 		os.Exit(1)
@@ -69,12 +76,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	pclientConfig, err := config.CreateClientConfig(kubeconfig, log.Log)
-	if err != nil {
-		setupLog.Error(err, "Failed to build client config")
-		os.Exit(1)
-	}
-	pclient, err := kyvernoclient.NewForConfig(pclientConfig)
+	pclient, err := kyvernoclient.NewForConfig(clientConfig)
 	if err != nil {
 		setupLog.Error(err, "Failed to create client")
 		os.Exit(1)
@@ -182,17 +184,6 @@ func executeRequest(client *client.Client, pclient *kyvernoclient.Clientset, req
 	return nil
 }
 
-func createClientConfig(kubeconfig string) (*rest.Config, error) {
-	logger := log.Log
-	if kubeconfig == "" {
-		logger.Info("Using in-cluster configuration")
-		return rest.InClusterConfig()
-	}
-
-	logger.Info(fmt.Sprintf("Using configuration from '%s'", kubeconfig))
-	return clientcmd.BuildConfigFromFlags("", kubeconfig)
-}
-
 type request struct {
 	kind string
 	name string
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
@@ -34,6 +36,7 @@ import (
 	"github.com/kyverno/kyverno/pkg/policy"
 	"github.com/kyverno/kyverno/pkg/policycache"
 	"github.com/kyverno/kyverno/pkg/policyreport"
+	"github.com/kyverno/kyverno/pkg/policystatus"
 	"github.com/kyverno/kyverno/pkg/resourcecache"
 	"github.com/kyverno/kyverno/pkg/signal"
 	ktls "github.com/kyverno/kyverno/pkg/tls"
@@ -52,6 +55,9 @@ var (
 	filterK8sResources           string
 	kubeconfig                   string
 	serverIP                     string
+	serverPathPrefix             string
+	caFile                       string
+	caConfigMap                  string
 	excludeGroupRole             string
 	excludeUsername              string
 	profilePort                  string
@@ -61,6 +67,22 @@ var (
 	profile                      bool
 	disableMetricsExport         bool
 	autoUpdateWebhooks           bool
+	enableMutation               bool
+	enableValidation             bool
+	webhookExcludeLabels         string
+	webhookExcludeNamespaces     string
+	webhookMutateOperations      string
+	webhookValidateOperations    string
+	dryRun                       bool
+	webhookReinvocationPolicy    string
+	webhookMatchPolicy           string
+	webhookUpdateDebounce        time.Duration
+	engineTimeout                time.Duration
+	slowRuleThreshold            time.Duration
+	excludeKyvernoServiceAccount bool
+	debugEndpoint                bool
+	clientRateLimitQPS           float64
+	clientRateLimitBurst         int
 	policyControllerResyncPeriod time.Duration
 	imagePullSecrets             string
 	imageSignatureRepository     string
@@ -80,6 +102,9 @@ func main() {
 	flag.IntVar(&genWorkers, "genWorkers", 10, "Workers for generate controller")
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&serverIP, "serverIP", "", "IP address where Kyverno controller runs. Only required if out-of-cluster.")
+	flag.StringVar(&serverPathPrefix, "serverPathPrefix", "", "Path prefix of the webhook URLs registered with serverIP, e.g. /kyverno when an ingress strips the prefix before routing to Kyverno. Must start with /.")
+	flag.StringVar(&caConfigMap, "caConfigMap", "", "ConfigMap holding the CA bundle set on the webhook configurations in debug mode, in the format namespace/name[:key]. The key defaults to ca.crt. Used after caFile and before the CA secret and the kubeconfig. Requires serverIP.")
+	flag.StringVar(&caFile, "caFile", "", "Path to the CA bundle set on the webhook configurations. Takes precedence over the CA secret and the kubeconfig.")
 	flag.BoolVar(&profile, "profile", false, "Set this flag to 'true', to enable profiling.")
 	// deprecated
 	flag.StringVar(&profilePort, "profile-port", "6060", "Enable profiling at given port, defaults to 6060. Deprecated and will be removed in 1.6.0. ")
@@ -96,6 +121,22 @@ func main() {
 	flag.StringVar(&imagePullSecrets, "imagePullSecrets", "", "Secret resource names for image registry access credentials.")
 	flag.StringVar(&imageSignatureRepository, "imageSignatureRepository", "", "Alternate repository for image signatures. Can be overridden per rule via `verifyImages.Repository`.")
 	flag.BoolVar(&autoUpdateWebhooks, "autoUpdateWebhooks", true, "Set this flag to 'false' to disable auto-configuration of the webhook.")
+	flag.BoolVar(&enableMutation, "enable-mutation", true, "Set this flag to 'false' to not register the resource mutating webhook, e.g. when Kyverno is only used for validation.")
+	flag.BoolVar(&enableValidation, "enable-validation", true, "Set this flag to 'false' to not register the resource validating webhook, e.g. when Kyverno is only used for mutation.")
+	flag.StringVar(&webhookExcludeLabels, "webhookExcludeLabels", labels.FormatLabels(config.KyvernoAppLabels), "Labels in format key1=value1,key2=value2 of the objects excluded from the resource webhooks. Set to an empty string to intercept all objects.")
+	flag.StringVar(&webhookExcludeNamespaces, "webhookExcludeNamespaces", config.KyvernoNamespace, "Comma separated list of namespaces excluded from the resource webhooks. Set to an empty string to intercept all namespaces.")
+	flag.StringVar(&webhookMutateOperations, "webhookMutateOperations", "", "Comma separated admission operations sent to the resource mutating webhook, e.g. CREATE,UPDATE,DELETE. Defaults to CREATE,UPDATE. The policies may add operations with the kyverno.io/operations annotation.")
+	flag.StringVar(&webhookValidateOperations, "webhookValidateOperations", "", "Comma separated admission operations sent to the resource validating webhook, e.g. CREATE,UPDATE. Defaults to CREATE,UPDATE,DELETE,CONNECT. The policies may add operations with the kyverno.io/operations annotation.")
+	flag.StringVar(&webhookReinvocationPolicy, "webhookReinvocationPolicy", string(config.WebhookReinvocationPolicy), "Reinvocation policy of the resource mutating webhook, Never or IfNeeded. IfNeeded calls Kyverno again if another webhook modified the resource after Kyverno mutated it.")
+	flag.StringVar(&webhookMatchPolicy, "webhookMatchPolicy", string(config.WebhookMatchPolicy), "Match policy of the webhooks, Exact or Equivalent. Exact lets requests made through another API version of a resource bypass the policies matching that resource.")
+	flag.DurationVar(&webhookUpdateDebounce, "webhookUpdateDebounce", config.WebhookUpdateDebounce, "Time the policy changes are collected before the resource webhook configurations are updated, e.g., 500ms, 2s. Set to 0 to update the webhooks for each change.")
+	flag.DurationVar(&engineTimeout, "engineTimeout", webhooks.DefaultEngineTimeout, "Deadline of the policy evaluation of an admission request, e.g., 500ms, 8s. On timeout the request is denied, unless all the policies of the request have the failurePolicy Ignore. Should be lower than webhookTimeout. Set to 0 to disable the deadline.")
+	flag.DurationVar(&slowRuleThreshold, "slowRuleThreshold", 0, "Processing time above which a mutate or validate rule of an admission request is logged as slow, e.g., 100ms, 1s. Set to 0 to disable the logging. The processing times of the rules are exposed by the kyverno_policy_execution_duration_seconds metric.")
+	flag.BoolVar(&excludeKyvernoServiceAccount, "excludeKyvernoServiceAccount", config.ExcludeKyvernoServiceAccount, "Set this flag to 'false' to apply the validate rules to the requests of the Kyverno service account, set by the KYVERNO_SERVICEACCOUNT_NAME environment variable. By default the resources generated or mutated by Kyverno are not validated, to avoid a policy blocking or recursively triggering Kyverno itself.")
+	flag.BoolVar(&debugEndpoint, "debugEndpoint", false, "Set this flag to 'true' to serve the registered webhook configurations, the policy cache and the CA fingerprint as JSON at /debug, to the requests from localhost, e.g. through kubectl port-forward.")
+	flag.Float64Var(&clientRateLimitQPS, "clientRateLimitQPS", config.ClientRateLimitQPS, "Maximum queries per second of the clients to the API server, e.g. during background scans and generate reconciliation.")
+	flag.IntVar(&clientRateLimitBurst, "clientRateLimitBurst", config.ClientRateLimitBurst, "Maximum burst of queries of the clients to the API server, above clientRateLimitQPS.")
+	flag.BoolVar(&dryRun, "dryRun", false, "Set this flag to 'true' to print the webhook configurations as YAML and exit without registering them.")
 
 	if err := flag.Set("v", "2"); err != nil {
 		setupLog.Error(err, "failed to set log level")
@@ -104,10 +145,43 @@ func main() {
 
 	flag.Parse()
 
+	if err := webhookconfig.ValidateWebhookTimeout(int64(webhookTimeout)); err != nil {
+		setupLog.Error(err, "invalid value for flag webhookTimeout")
+		os.Exit(1)
+	}
+
+	if engineTimeout >= time.Duration(webhookTimeout)*time.Second {
+		setupLog.Info("the engineTimeout is not lower than the webhookTimeout, the API server may stop waiting for the admission response first", "engineTimeout", engineTimeout, "webhookTimeout", webhookTimeout)
+	}
+
+	if err := webhookconfig.ValidateReinvocationPolicy(webhookReinvocationPolicy); err != nil {
+		setupLog.Error(err, "invalid value for flag webhookReinvocationPolicy")
+		os.Exit(1)
+	}
+	config.WebhookReinvocationPolicy = admregapi.ReinvocationPolicyType(webhookReinvocationPolicy)
+
+	if err := webhookconfig.ValidateMatchPolicy(webhookMatchPolicy); err != nil {
+		setupLog.Error(err, "invalid value for flag webhookMatchPolicy")
+		os.Exit(1)
+	}
+	config.WebhookMatchPolicy = admregapi.MatchPolicyType(webhookMatchPolicy)
+
+	if webhookUpdateDebounce < 0 {
+		setupLog.Error(fmt.Errorf("negative duration %s", webhookUpdateDebounce), "invalid value for flag webhookUpdateDebounce")
+		os.Exit(1)
+	}
+	config.WebhookUpdateDebounce = webhookUpdateDebounce
+	config.ExcludeKyvernoServiceAccount = excludeKyvernoServiceAccount
+
+	if clientRateLimitQPS <= 0 || clientRateLimitBurst <= 0 {
+		setupLog.Error(fmt.Errorf("qps %v, burst %d", clientRateLimitQPS, clientRateLimitBurst), "invalid value for flags clientRateLimitQPS and clientRateLimitBurst, must be positive")
+		os.Exit(1)
+	}
+
 	version.PrintVersionInfo(log.Log)
 	cleanUp := make(chan struct{})
 	stopCh := signal.SetupSignalHandler()
-	clientConfig, err := config.CreateClientConfig(kubeconfig, log.Log)
+	clientConfig, err := config.CreateClientConfig(kubeconfig, clientRateLimitQPS, clientRateLimitBurst, log.Log)
 	if err != nil {
 		setupLog.Error(err, "Failed to build kubeconfig")
 		os.Exit(1)
@@ -226,8 +300,60 @@ func main() {
 		os.Exit(1)
 	}
 
+	webhookExclusions, err := webhookconfig.ParseWebhookExclusions(webhookExcludeLabels, webhookExcludeNamespaces)
+	if err != nil {
+		setupLog.Error(err, "failed to parse webhook exclusions")
+		os.Exit(1)
+	}
+
+	webhookOperations, err := webhookconfig.ParseWebhookOperations(webhookMutateOperations, webhookValidateOperations)
+	if err != nil {
+		setupLog.Error(err, "failed to parse webhook operations")
+		os.Exit(1)
+	}
+
+	// the webhooks are registered with serverIP and the serving certificate is issued for its host
+	serverIP, err = webhookconfig.NormalizeServerIP(serverIP)
+	if err != nil {
+		setupLog.Error(err, "invalid value for flag serverIP")
+		os.Exit(1)
+	}
+
+	caConfigMapRef, err := webhookconfig.ParseCAConfigMapRef(caConfigMap)
+	if err != nil {
+		setupLog.Error(err, "invalid value for flag caConfigMap")
+		os.Exit(1)
+	}
+
+	metricsConfigData, err := config.NewMetricsConfigData(
+		kubeClient,
+		log.Log.WithName("MetricsConfigData"),
+	)
+	if err != nil {
+		setupLog.Error(err, "failed to fetch metrics config")
+		os.Exit(1)
+	}
+
+	if !disableMetricsExport {
+		promConfig, err = metrics.NewPromConfig(metricsConfigData, log.Log.WithName("MetricsConfig"))
+		if err != nil {
+			setupLog.Error(err, "failed to setup Prometheus metric configuration")
+			os.Exit(1)
+		}
+		metricsServerMux = http.NewServeMux()
+		metricsServerMux.Handle("/metrics", promhttp.HandlerFor(promConfig.MetricsRegistry, promhttp.HandlerOpts{Timeout: 10 * time.Second}))
+		metricsAddr := ":" + metricsPort
+		go func() {
+			setupLog.Info("enabling metrics service", "address", metricsAddr)
+			if err := http.ListenAndServe(metricsAddr, metricsServerMux); err != nil {
+				setupLog.Error(err, "failed to enable metrics service", "address", metricsAddr)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	debug := serverIP != ""
-	webhookCfg := webhookconfig.NewRegister(
+	webhookCfg, err := webhookconfig.NewRegister(
 		clientConfig,
 		client,
 		pclient,
@@ -235,11 +361,26 @@ func main() {
 		pInformer.Kyverno().V1().ClusterPolicies(),
 		pInformer.Kyverno().V1().Policies(),
 		serverIP,
+		serverPathPrefix,
+		caFile,
+		caConfigMapRef,
+		config.KyvernoServiceName,
+		config.KyvernoNamespace,
+		config.KyvernoDeploymentName,
 		int32(webhookTimeout),
 		debug,
 		autoUpdateWebhooks,
+		enableMutation,
+		enableValidation,
+		webhookExclusions,
+		webhookOperations,
+		promConfig,
 		stopCh,
 		log.Log)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize webhook registration")
+		os.Exit(1)
+	}
 
 	webhookMonitor, err := webhookconfig.NewMonitor(kubeClient, log.Log.WithName("WebhookMonitor"))
 	if err != nil {
@@ -262,33 +403,6 @@ func main() {
 		log.Log.WithName("ConfigData"),
 	)
 
-	metricsConfigData, err := config.NewMetricsConfigData(
-		kubeClient,
-		log.Log.WithName("MetricsConfigData"),
-	)
-	if err != nil {
-		setupLog.Error(err, "failed to fetch metrics config")
-		os.Exit(1)
-	}
-
-	if !disableMetricsExport {
-		promConfig, err = metrics.NewPromConfig(metricsConfigData, log.Log.WithName("MetricsConfig"))
-		if err != nil {
-			setupLog.Error(err, "failed to setup Prometheus metric configuration")
-			os.Exit(1)
-		}
-		metricsServerMux = http.NewServeMux()
-		metricsServerMux.Handle("/metrics", promhttp.HandlerFor(promConfig.MetricsRegistry, promhttp.HandlerOpts{Timeout: 10 * time.Second}))
-		metricsAddr := ":" + metricsPort
-		go func() {
-			setupLog.Info("enabling metrics service", "address", metricsAddr)
-			if err := http.ListenAndServe(metricsAddr, metricsServerMux); err != nil {
-				setupLog.Error(err, "failed to enable metrics service", "address", metricsAddr)
-				os.Exit(1)
-			}
-		}()
-	}
-
 	// POLICY CONTROLLER
 	// - reconciliation policy and policy violation
 	// - process policy on existing resources
@@ -375,10 +489,13 @@ func main() {
 	)
 
 	certRenewer := ktls.NewCertRenewer(client, clientConfig, ktls.CertRenewalInterval, ktls.CertValidityDuration, serverIP, log.Log.WithName("CertRenewer"))
+	certCache := ktls.NewCertificateCache()
 	certManager, err := webhookconfig.NewCertManager(
 		kubeInformer.Core().V1().Secrets(),
 		kubeClient,
 		certRenewer,
+		certCache,
+		promConfig,
 		log.Log.WithName("CertManager"),
 		stopCh,
 	)
@@ -388,7 +505,30 @@ func main() {
 		os.Exit(1)
 	}
 
-	registerWrapperRetry := common.RetryFunc(time.Second, 30*time.Second, webhookCfg.Register, setupLog)
+	if dryRun {
+		if err := webhookCfg.ValidateWebhookConfigurations(config.KyvernoNamespace, configData.GetInitConfigMapName()); err != nil {
+			setupLog.Error(err, "invalid format of the Kyverno init ConfigMap, please correct the format of 'data.webhooks'")
+			os.Exit(1)
+		}
+
+		if err := webhookCfg.RegisterDryRun(os.Stdout); err != nil {
+			setupLog.Error(err, "failed to print webhook configurations")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// leader election and webhook registration context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel the context on shutdown signals
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	registerWrapperRetry := common.RetryFunc(time.Second, 30*time.Second, func() error { return webhookCfg.Register(ctx) }, setupLog)
 	registerWebhookConfigurations := func() {
 		certManager.InitTLSPemPair()
 
@@ -406,18 +546,9 @@ func main() {
 			os.Exit(1)
 		}
 		webhookCfg.UpdateWebhookChan <- true
+		go webhookCfg.WatchRegistration(stopCh)
 	}
 
-	// leader election context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// cancel leader election context on shutdown signals
-	go func() {
-		<-stopCh
-		cancel()
-	}()
-
 	// webhookconfigurations are registered by the leader only
 	webhookRegisterLeader, err := leaderelection.New("webhook-register", config.KyvernoNamespace, kubeClient, registerWebhookConfigurations, nil, log.Log.WithName("webhookRegister/LeaderElection"))
 	if err != nil {
@@ -437,6 +568,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the served certificate is refreshed by the cert manager when the TLS pair secret is rotated
+	if err := certCache.Update(tlsPair); err != nil {
+		setupLog.Error(err, "Failed to load TLS key/certificate pair")
+		os.Exit(1)
+	}
+
+	// the last mutation of each policy is recorded in its status by the leader
+	statusUpdater := policystatus.NewUpdater(pclient, log.Log.WithName("PolicyStatusUpdater"))
+
 	// WEBHOOK
 	// - https server to provide endpoints called based on rules defined in Mutating & Validation webhook configuration
 	// - reports the results based on the response from the policy engine:
@@ -446,7 +586,7 @@ func main() {
 	server, err := webhooks.NewWebhookServer(
 		pclient,
 		client,
-		tlsPair,
+		certCache,
 		pInformer.Kyverno().V1().GenerateRequests(),
 		pInformer.Kyverno().V1().ClusterPolicies(),
 		kubeInformer.Rbac().V1().RoleBindings(),
@@ -468,6 +608,10 @@ func main() {
 		rCache,
 		grc,
 		promConfig,
+		statusUpdater,
+		engineTimeout,
+		slowRuleThreshold,
+		debugEndpoint,
 	)
 
 	if err != nil {
@@ -483,6 +627,7 @@ func main() {
 		go prgen.Run(1, stopCh)
 		go grc.Run(genWorkers, stopCh)
 		go grcc.Run(1, stopCh)
+		go statusUpdater.Run(stopCh)
 	}
 
 	kubeClientLeaderElection, err := utils.NewKubeClient(clientConfig)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode"
 
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -26,6 +27,8 @@ const (
 	Enforce = "enforce"
 	// Audit indicates not to block the request on failure, but report failiures as policy violations
 	Audit = "audit"
+	// Warn indicates not to block the request on failure, but report failures and return them as admission warnings
+	Warn = "warn"
 )
 
 // Policy Reporting Types
@@ -90,6 +93,71 @@ func GetKindFromGVK(str string) (apiVersion string, kind string) {
 	return splitString[0] + "/" + splitString[1], splitString[2]
 }
 
+// subresourceKind is a subresource whose admission requests are sent with an object of its own kind,
+// the other subresources, e.g. pods/status, are sent with the object of the parent kind
+type subresourceKind struct {
+	kind        string
+	subresource string
+	resource    string
+	objectKind  string
+}
+
+// subresourceKinds lists the subresources of the core v1 API sent with an object of their own kind
+var subresourceKinds = []subresourceKind{
+	{kind: "Node", subresource: "proxy", resource: "nodes/proxy", objectKind: "NodeProxyOptions"},
+	{kind: "Pod", subresource: "attach", resource: "pods/attach", objectKind: "PodAttachOptions"},
+	{kind: "Pod", subresource: "binding", resource: "pods/binding", objectKind: "Binding"},
+	{kind: "Pod", subresource: "eviction", resource: "pods/eviction", objectKind: "Eviction"},
+	{kind: "Pod", subresource: "exec", resource: "pods/exec", objectKind: "PodExecOptions"},
+	{kind: "Pod", subresource: "portforward", resource: "pods/portforward", objectKind: "PodPortForwardOptions"},
+	{kind: "Pod", subresource: "proxy", resource: "pods/proxy", objectKind: "PodProxyOptions"},
+	{kind: "Service", subresource: "proxy", resource: "services/proxy", objectKind: "ServiceProxyOptions"},
+}
+
+// SplitSubresource splits a kind in the Kind/subresource format, e.g. Pod/exec, into the kind and the subresource.
+// The subresource is empty for the other formats, the version of the version/Kind format starts with a lowercase letter.
+func SplitSubresource(gvk string) (kind string, subresource string) {
+	split := strings.Split(gvk, "/")
+	if len(split) != 2 || split[0] == "" || split[1] == "" || !unicode.IsUpper(rune(split[0][0])) {
+		return gvk, ""
+	}
+	return split[0], split[1]
+}
+
+// GetSubresourceKind returns the kind of the object sent in the admission requests of the subresource,
+// e.g. PodExecOptions for the exec subresource of Pod, or the parent kind for subresources such as status
+func GetSubresourceKind(kind, subresource string) string {
+	if subresource == "scale" {
+		return "Scale"
+	}
+	for _, s := range subresourceKinds {
+		if s.kind == kind && s.subresource == subresource {
+			return s.objectKind
+		}
+	}
+	return kind
+}
+
+// GetSubresourceOfKind returns the core v1 subresource, e.g. pods/exec, whose admission requests are sent
+// with an object of the kind, e.g. PodExecOptions
+func GetSubresourceOfKind(kind string) (resource string, ok bool) {
+	for _, s := range subresourceKinds {
+		if s.objectKind == kind {
+			return s.resource, true
+		}
+	}
+	return "", false
+}
+
+// RequestSubresource returns the subresource of an admission request with its parent resource, e.g. pods/exec,
+// or an empty string for the requests of the resource itself
+func RequestSubresource(resource, subresource string) string {
+	if subresource == "" {
+		return ""
+	}
+	return resource + "/" + subresource
+}
+
 func VariableToJSON(key, value string) []byte {
 	var subString string
 	splitBySlash := strings.Split(key, "\"")
@@ -2,8 +2,10 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/go-logr/logr"
+	admregapi "k8s.io/api/admissionregistration/v1"
 	rest "k8s.io/client-go/rest"
 	clientcmd "k8s.io/client-go/tools/clientcmd"
 )
@@ -75,6 +77,49 @@ var (
 	//KyvernoServiceName is the Kyverno service name
 	KyvernoServiceName = getKyvernoServiceName()
 
+	// KyvernoServiceAccountName is the name of the Kyverno service account
+	KyvernoServiceAccountName = getKyvernoServiceAccountName()
+
+	// ExcludeKyvernoServiceAccount excludes the requests of the Kyverno service account from the validate rules,
+	// so that the resources generated or mutated by Kyverno do not trigger its own validate policies
+	ExcludeKyvernoServiceAccount = true
+
+	// KyvernoAppLabels are the labels set on the Kyverno resources
+	KyvernoAppLabels = map[string]string{"app.kubernetes.io/name": "kyverno"}
+
+	// WebhookReinvocationPolicy is the reinvocation policy of the resource mutating webhook.
+	// With IfNeeded the API server calls Kyverno once more if a later webhook modified the resource,
+	// so that mutate policies apply to the changes of other webhooks. The API server re-invokes
+	// a webhook at most once, and a reinvocation with a resource Kyverno already mutated produces no patch.
+	WebhookReinvocationPolicy = admregapi.IfNeededReinvocationPolicy
+
+	// WebhookMatchPolicy is the match policy of the webhooks.
+	// With Equivalent the API server sends a request made through another API version of a matched resource,
+	// e.g. a Deployment created via extensions/v1beta1 for a rule on apps/v1 deployments, converted to the
+	// matched version. With Exact such a request does not match the webhook rules, and a policy can be
+	// bypassed by using another apiVersion of the same resource.
+	WebhookMatchPolicy = admregapi.Equivalent
+
+	// WebhookUpdateDebounce is the time the policy changes are collected before the resource webhook
+	// configurations are updated, so that applying many policies at once results in a single update
+	WebhookUpdateDebounce = time.Second
+
+	// ClientRateLimitQPS is the default maximum QPS of the clients to the API server
+	ClientRateLimitQPS = 20.0
+
+	// ClientRateLimitBurst is the default maximum burst of the clients to the API server
+	ClientRateLimitBurst = 50
+
+	// MutatingWebhookSideEffects is the side effect class of the mutating webhooks
+	MutatingWebhookSideEffects = admregapi.SideEffectClassNoneOnDryRun
+
+	// ValidatingWebhookSideEffects is the side effect class of the validating webhooks
+	ValidatingWebhookSideEffects = admregapi.SideEffectClassNone
+
+	// WebhookAdmissionReviewVersions are the AdmissionReview versions accepted by the webhooks,
+	// the API server sends the first version of the list it supports
+	WebhookAdmissionReviewVersions = []string{"v1beta1", "v1"}
+
 	//MutatingWebhookServicePath is the path for mutation webhook
 	MutatingWebhookServicePath = "/mutate"
 
@@ -90,16 +135,44 @@ var (
 	//VerifyMutatingWebhookServicePath is the path for verify webhook(used to veryfing if admission control is enabled and active)
 	VerifyMutatingWebhookServicePath = "/verifymutate"
 
+	// ConversionWebhookServicePath is the path for the conversion webhook of the policy CRDs
+	ConversionWebhookServicePath = "/convert"
+
+	// ConversionReviewVersions are the ConversionReview versions accepted by the conversion webhook
+	ConversionReviewVersions = []string{"v1"}
+
+	// PolicyCRDNames are the names of the CRDs converted by the conversion webhook
+	PolicyCRDNames = []string{"clusterpolicies.kyverno.io", "policies.kyverno.io"}
+
 	// LivenessServicePath is the path for check liveness health
 	LivenessServicePath = "/health/liveness"
 
 	// ReadinessServicePath is the path for check readness health
 	ReadinessServicePath = "/health/readiness"
+
+	// ReadyzServicePath is the path to check if the webhooks are registered and the serving certificate is valid
+	ReadyzServicePath = "/readyz"
+
+	// DebugServicePath is the path of the debug endpoint serving the webhook registration and the policy cache
+	DebugServicePath = "/debug"
 )
 
-//CreateClientConfig creates client config
-func CreateClientConfig(kubeconfig string, log logr.Logger) (*rest.Config, error) {
+//CreateClientConfig creates client config, the clients built from the config are
+//rate limited to qps queries per second with the given burst
+func CreateClientConfig(kubeconfig string, qps float64, burst int, log logr.Logger) (*rest.Config, error) {
 	logger := log.WithName("CreateClientConfig")
+	clientConfig, err := createClientConfig(kubeconfig, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig.QPS = float32(qps)
+	clientConfig.Burst = burst
+	logger.V(2).Info("Client rate limit", "qps", qps, "burst", burst)
+	return clientConfig, nil
+}
+
+func createClientConfig(kubeconfig string, logger logr.Logger) (*rest.Config, error) {
 	if kubeconfig == "" {
 		logger.Info("Using in-cluster configuration")
 		return rest.InClusterConfig()
@@ -126,6 +199,20 @@ func getKyvernoServiceName() string {
 	return webhookServiceName
 }
 
+// getKyvernoServiceAccountName - setting default KyvernoServiceAccountName
+func getKyvernoServiceAccountName() string {
+	name := os.Getenv("KYVERNO_SERVICEACCOUNT_NAME")
+	if name == "" {
+		name = "kyverno-service-account"
+	}
+	return name
+}
+
+// KyvernoUsername returns the username of the Kyverno service account in the admission requests
+func KyvernoUsername() string {
+	return "system:serviceaccount:" + KyvernoNamespace + ":" + KyvernoServiceAccountName
+}
+
 // getKyvernoDeploymentName - setting default KyvernoServiceName
 func getKyvernoDeploymentName() string {
 	name := os.Getenv("KYVERNO_DEPLOYMENT")
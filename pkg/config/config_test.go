@@ -0,0 +1,83 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test
+`
+
+func Test_CreateClientConfig_RateLimit(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NilError(t, ioutil.WriteFile(kubeconfig, []byte(testKubeconfig), 0600))
+
+	clientConfig, err := CreateClientConfig(kubeconfig, 15, 30, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	assert.Equal(t, clientConfig.QPS, float32(15))
+	assert.Equal(t, clientConfig.Burst, 30)
+
+	// the clients built from the config are rate limited
+	kclient, err := kubernetes.NewForConfig(clientConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, kclient.CoreV1().RESTClient().GetRateLimiter().QPS(), float32(15))
+}
+
+func Test_CreateClientConfig_DefaultRateLimit(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NilError(t, ioutil.WriteFile(kubeconfig, []byte(testKubeconfig), 0600))
+
+	clientConfig, err := CreateClientConfig(kubeconfig, ClientRateLimitQPS, ClientRateLimitBurst, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	assert.Equal(t, clientConfig.QPS, float32(20))
+	assert.Equal(t, clientConfig.Burst, 50)
+}
+
+func Test_ConfigData_Enforcement(t *testing.T) {
+	cd := &ConfigData{log: logr.DiscardLogger{}}
+	assert.Assert(t, !cd.IsEnforcementPaused())
+
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": EnforcementPaused}})
+	assert.Assert(t, cd.IsEnforcementPaused())
+
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": EnforcementActive}})
+	assert.Assert(t, !cd.IsEnforcementPaused())
+
+	// the enforcement is resumed when the key is removed or invalid
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": EnforcementPaused}})
+	cd.load(v1.ConfigMap{Data: map[string]string{"generateSuccessEvents": "false"}})
+	assert.Assert(t, !cd.IsEnforcementPaused())
+
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": EnforcementPaused}})
+	cd.load(v1.ConfigMap{})
+	assert.Assert(t, !cd.IsEnforcementPaused())
+
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": "off"}})
+	assert.Assert(t, !cd.IsEnforcementPaused())
+
+	// and when the ConfigMap is deleted
+	cd.load(v1.ConfigMap{Data: map[string]string{"enforcement": EnforcementPaused}})
+	cd.unload(v1.ConfigMap{})
+	assert.Assert(t, !cd.IsEnforcementPaused())
+}
@@ -22,6 +22,15 @@ import (
 // this configmap stores the resources that are to be filtered
 const cmNameEnv string = "INIT_CONFIG"
 
+const (
+	// EnforcementActive is the default value of the enforcement key of the ConfigMap, the policies are applied
+	EnforcementActive = "active"
+
+	// EnforcementPaused pauses the enforcement, the admission requests are allowed without applying the policies
+	// while the webhooks stay registered, so that the enforcement resumes as soon as the key is changed
+	EnforcementPaused = "paused"
+)
+
 var defaultExcludeGroupRole []string = []string{"system:serviceaccounts:kube-system", "system:nodes", "system:kube-scheduler"}
 
 type WebhookConfig struct {
@@ -39,6 +48,7 @@ type ConfigData struct {
 	restrictDevelopmentUsername []string
 	webhooks                    []WebhookConfig
 	generateSuccessEvents       bool
+	enforcementPaused           bool
 	cmSycned                    cache.InformerSynced
 	reconcilePolicyReport       chan<- bool
 	updateWebhookConfigurations chan<- bool
@@ -92,6 +102,13 @@ func (cd *ConfigData) GetGenerateSuccessEvents() bool {
 	return cd.generateSuccessEvents
 }
 
+// IsEnforcementPaused returns true if the enforcement is paused, the admission requests are then allowed without applying the policies
+func (cd *ConfigData) IsEnforcementPaused() bool {
+	cd.mux.RLock()
+	defer cd.mux.RUnlock()
+	return cd.enforcementPaused
+}
+
 // FilterNamespaces filters exclude namespace
 func (cd *ConfigData) FilterNamespaces(namespaces []string) []string {
 	var results []string
@@ -122,6 +139,7 @@ type Interface interface {
 	GetExcludeGroupRole() []string
 	GetExcludeUsername() []string
 	GetGenerateSuccessEvents() bool
+	IsEnforcementPaused() bool
 	RestrictDevelopmentUsername() []string
 	FilterNamespaces(namespaces []string) []string
 	GetWebhooks() []WebhookConfig
@@ -232,14 +250,19 @@ func (cd *ConfigData) deleteCM(obj interface{}) {
 
 func (cd *ConfigData) load(cm v1.ConfigMap) (reconcilePolicyReport, updateWebhook bool) {
 	logger := cd.log.WithValues("name", cm.Name, "namespace", cm.Namespace)
+
+	cd.mux.Lock()
+	defer cd.mux.Unlock()
+
+	// the enforcement is loaded first so that it can be paused or resumed
+	// regardless of the other configuration parameters
+	cd.loadEnforcement(cm, logger)
+
 	if cm.Data == nil {
 		logger.V(4).Info("configuration: No data defined in ConfigMap")
 		return
 	}
 
-	cd.mux.Lock()
-	defer cd.mux.Unlock()
-
 	filters, ok := cm.Data["resourceFilters"]
 	if !ok {
 		logger.V(4).Info("configuration: No resourceFilters defined in ConfigMap")
@@ -320,6 +343,31 @@ func (cd *ConfigData) load(cm v1.ConfigMap) (reconcilePolicyReport, updateWebhoo
 	return
 }
 
+// loadEnforcement pauses or resumes the enforcement, it is resumed when the key is removed
+func (cd *ConfigData) loadEnforcement(cm v1.ConfigMap, logger logr.Logger) {
+	enforcementPaused := false
+	enforcement, ok := cm.Data["enforcement"]
+	if !ok {
+		logger.V(4).Info("configuration: No enforcement defined in ConfigMap")
+	} else if enforcement == EnforcementPaused {
+		enforcementPaused = true
+	} else if enforcement != EnforcementActive {
+		logger.Info("configuration: enforcement must be either active/paused, resuming the enforcement", "enforcement", enforcement)
+	}
+
+	if enforcementPaused == cd.enforcementPaused {
+		logger.V(4).Info("enforcement did not change")
+		return
+	}
+
+	if enforcementPaused {
+		logger.Info("enforcement paused, the admission requests are allowed without applying the policies")
+	} else {
+		logger.Info("enforcement resumed")
+	}
+	cd.enforcementPaused = enforcementPaused
+}
+
 func (cd *ConfigData) initFilters(filters string) {
 	logger := cd.log
 	// parse and load the configuration
@@ -359,6 +407,10 @@ func (cd *ConfigData) unload(cm v1.ConfigMap) {
 	cd.excludeGroupRole = append(cd.excludeGroupRole, defaultExcludeGroupRole...)
 	cd.excludeUsername = []string{}
 	cd.generateSuccessEvents = false
+	if cd.enforcementPaused {
+		logger.Info("enforcement resumed")
+	}
+	cd.enforcementPaused = false
 }
 
 type k8Resource struct {
@@ -0,0 +1,142 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// HubVersion is the API version the policies are converted through, every served
+// version converts to and from the hub version
+const HubVersion = "kyverno.io/v1"
+
+// ConvertFunc converts the object in place, the API version is set by the converter
+type ConvertFunc func(obj *unstructured.Unstructured) error
+
+type versionConverter struct {
+	toHub   ConvertFunc
+	fromHub ConvertFunc
+}
+
+// Converter converts Policy and ClusterPolicy objects between the served API versions.
+// A conversion from a version to another goes through the hub version, so that each version
+// only converts to and from the hub. The conversions must be lossless: the fields that do
+// not exist in a version are kept, e.g. in an annotation, to be restored by the reverse conversion.
+type Converter struct {
+	mutex    sync.RWMutex
+	versions map[string]versionConverter
+}
+
+// NewConverter returns a converter of the policy API versions
+func NewConverter() *Converter {
+	c := &Converter{versions: make(map[string]versionConverter)}
+	c.Register(HubVersion, nil, nil)
+	return c
+}
+
+// Register adds the API version with its conversions to and from the hub version.
+// A nil conversion only changes the API version of the object.
+func (c *Converter) Register(apiVersion string, toHub, fromHub ConvertFunc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.versions[apiVersion] = versionConverter{toHub: toHub, fromHub: fromHub}
+}
+
+// Versions returns the sorted API versions of the converter
+func (c *Converter) Versions() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	versions := make([]string, 0, len(c.versions))
+	for v := range c.versions {
+		versions = append(versions, v)
+	}
+
+	sort.Strings(versions)
+	return versions
+}
+
+// Convert returns a copy of the object converted to the API version
+func (c *Converter) Convert(obj *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	from := obj.GetAPIVersion()
+	src, ok := c.versions[from]
+	if !ok {
+		return nil, fmt.Errorf("unsupported API version %s of %s %s", from, obj.GetKind(), obj.GetName())
+	}
+
+	dst, ok := c.versions[apiVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported API version %s", apiVersion)
+	}
+
+	converted := obj.DeepCopy()
+	if from == apiVersion {
+		return converted, nil
+	}
+
+	if src.toHub != nil {
+		if err := src.toHub(converted); err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s from %s to %s: %v", obj.GetKind(), obj.GetName(), from, HubVersion, err)
+		}
+	}
+	converted.SetAPIVersion(HubVersion)
+
+	if dst.fromHub != nil {
+		if err := dst.fromHub(converted); err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s from %s to %s: %v", obj.GetKind(), obj.GetName(), HubVersion, apiVersion, err)
+		}
+	}
+	converted.SetAPIVersion(apiVersion)
+
+	return converted, nil
+}
+
+// Review converts the objects of a conversion request to the desired API version.
+// If any object cannot be converted the response fails and has no converted objects.
+func (c *Converter) Review(request *apiextv1.ConversionRequest) *apiextv1.ConversionResponse {
+	response := &apiextv1.ConversionResponse{UID: request.UID}
+
+	errs := make([]string, 0)
+	converted := make([]runtime.RawExtension, 0, len(request.Objects))
+	for i, object := range request.Objects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(object.Raw); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to decode object %d: %v", i, err))
+			continue
+		}
+
+		convertedObj, err := c.Convert(obj, request.DesiredAPIVersion)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		raw, err := json.Marshal(convertedObj)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to encode %s %s: %v", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+
+		converted = append(converted, runtime.RawExtension{Raw: raw})
+	}
+
+	if len(errs) > 0 {
+		response.Result = metav1.Status{Status: metav1.StatusFailure, Message: strings.Join(errs, ",")}
+		return response
+	}
+
+	response.ConvertedObjects = converted
+	response.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return response
+}
@@ -0,0 +1,173 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	testVersion          = "kyverno.io/v2beta1"
+	backgroundAnnotation = "conversion.kyverno.io/background"
+)
+
+// newTestConverter registers a version that renames spec.validationFailureAction to spec.failureAction
+// and has no spec.background, which is kept in an annotation to be restored
+func newTestConverter() *Converter {
+	c := NewConverter()
+	c.Register(testVersion,
+		func(obj *unstructured.Unstructured) error {
+			if action, found, _ := unstructured.NestedString(obj.Object, "spec", "failureAction"); found {
+				unstructured.RemoveNestedField(obj.Object, "spec", "failureAction")
+				if err := unstructured.SetNestedField(obj.Object, action, "spec", "validationFailureAction"); err != nil {
+					return err
+				}
+			}
+
+			annotations := obj.GetAnnotations()
+			if background, ok := annotations[backgroundAnnotation]; ok {
+				delete(annotations, backgroundAnnotation)
+				if len(annotations) == 0 {
+					annotations = nil
+				}
+				obj.SetAnnotations(annotations)
+				if err := unstructured.SetNestedField(obj.Object, background == "true", "spec", "background"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		func(obj *unstructured.Unstructured) error {
+			if action, found, _ := unstructured.NestedString(obj.Object, "spec", "validationFailureAction"); found {
+				unstructured.RemoveNestedField(obj.Object, "spec", "validationFailureAction")
+				if err := unstructured.SetNestedField(obj.Object, action, "spec", "failureAction"); err != nil {
+					return err
+				}
+			}
+
+			if background, found, _ := unstructured.NestedBool(obj.Object, "spec", "background"); found {
+				unstructured.RemoveNestedField(obj.Object, "spec", "background")
+				annotations := obj.GetAnnotations()
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[backgroundAnnotation] = "false"
+				if background {
+					annotations[backgroundAnnotation] = "true"
+				}
+				obj.SetAnnotations(annotations)
+			}
+			return nil
+		},
+	)
+	return c
+}
+
+func newTestPolicy(t *testing.T, raw string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	assert.NilError(t, obj.UnmarshalJSON([]byte(raw)))
+	return obj
+}
+
+func testPolicies(t *testing.T, c *Converter) []*unstructured.Unstructured {
+	hub := []*unstructured.Unstructured{
+		newTestPolicy(t, `{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"require-labels","annotations":{"policies.kyverno.io/title":"Require Labels"}},"spec":{"validationFailureAction":"enforce","background":false,"rules":[{"name":"check-team","match":{"resources":{"kinds":["Pod"]}},"validate":{"message":"label team is required","pattern":{"metadata":{"labels":{"team":"?*"}}}}}]}}`),
+		newTestPolicy(t, `{"apiVersion":"kyverno.io/v1","kind":"Policy","metadata":{"name":"add-labels","namespace":"team-a"},"spec":{"rules":[{"name":"add-team","match":{"resources":{"kinds":["Pod"]}},"mutate":{"patchStrategicMerge":{"metadata":{"labels":{"team":"a"}}}}}]},"status":{"ready":true}}`),
+	}
+
+	// the policies in each version
+	var policies []*unstructured.Unstructured
+	for _, version := range c.Versions() {
+		for _, obj := range hub {
+			converted, err := c.Convert(obj, version)
+			assert.NilError(t, err)
+			policies = append(policies, converted)
+		}
+	}
+	return policies
+}
+
+func Test_Convert_RoundTrip(t *testing.T) {
+	converters := map[string]*Converter{
+		"served versions":   NewConverter(),
+		"with test version": newTestConverter(),
+	}
+
+	for name, c := range converters {
+		t.Run(name, func(t *testing.T) {
+			for _, obj := range testPolicies(t, c) {
+				for _, version := range c.Versions() {
+					converted, err := c.Convert(obj, version)
+					assert.NilError(t, err)
+					assert.Equal(t, converted.GetAPIVersion(), version)
+
+					restored, err := c.Convert(converted, obj.GetAPIVersion())
+					assert.NilError(t, err)
+					assert.DeepEqual(t, restored.Object, obj.Object)
+				}
+			}
+		})
+	}
+}
+
+func Test_Convert_ThroughHub(t *testing.T) {
+	c := newTestConverter()
+	obj := newTestPolicy(t, `{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"require-labels"},"spec":{"validationFailureAction":"enforce","background":false}}`)
+
+	converted, err := c.Convert(obj, testVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, converted.Object["spec"], map[string]interface{}{"failureAction": "enforce"})
+	assert.DeepEqual(t, converted.GetAnnotations(), map[string]string{backgroundAnnotation: "false"})
+
+	// the source object is not changed
+	assert.Equal(t, obj.GetAPIVersion(), HubVersion)
+	assert.DeepEqual(t, obj.Object["spec"], map[string]interface{}{"validationFailureAction": "enforce", "background": false})
+}
+
+func Test_Convert_UnsupportedVersion(t *testing.T) {
+	c := NewConverter()
+	obj := newTestPolicy(t, `{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"require-labels"}}`)
+
+	_, err := c.Convert(obj, "kyverno.io/v3")
+	assert.ErrorContains(t, err, "unsupported API version kyverno.io/v3")
+
+	obj.SetAPIVersion("kyverno.io/v0")
+	_, err = c.Convert(obj, HubVersion)
+	assert.ErrorContains(t, err, "unsupported API version kyverno.io/v0 of ClusterPolicy require-labels")
+}
+
+func Test_Review(t *testing.T) {
+	c := newTestConverter()
+	request := &apiextv1.ConversionRequest{
+		UID:               "1",
+		DesiredAPIVersion: testVersion,
+		Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"a"},"spec":{"validationFailureAction":"audit"}}`)},
+			{Raw: []byte(`{"apiVersion":"kyverno.io/v1","kind":"Policy","metadata":{"name":"b","namespace":"team-a"},"spec":{"validationFailureAction":"enforce"}}`)},
+		},
+	}
+
+	response := c.Review(request)
+	assert.Equal(t, string(response.UID), "1")
+	assert.Equal(t, response.Result.Status, metav1.StatusSuccess)
+	assert.Equal(t, len(response.ConvertedObjects), 2)
+
+	for i, expected := range []string{"audit", "enforce"} {
+		var converted map[string]interface{}
+		assert.NilError(t, json.Unmarshal(response.ConvertedObjects[i].Raw, &converted))
+		assert.Equal(t, converted["apiVersion"], testVersion)
+		assert.DeepEqual(t, converted["spec"], map[string]interface{}{"failureAction": expected})
+	}
+
+	request.Objects = append(request.Objects, runtime.RawExtension{Raw: []byte(`{"apiVersion":"kyverno.io/v0","kind":"Policy","metadata":{"name":"c"}}`)})
+	response = c.Review(request)
+	assert.Equal(t, response.Result.Status, metav1.StatusFailure)
+	assert.Assert(t, strings.Contains(response.Result.Message, "unsupported API version kyverno.io/v0"))
+	assert.Equal(t, len(response.ConvertedObjects), 0)
+}
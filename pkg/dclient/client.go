@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-logr/logr"
 	openapiv2 "github.com/googleapis/gnostic/openapiv2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -22,8 +23,13 @@ import (
 	csrtype "k8s.io/client-go/kubernetes/typed/certificates/v1beta1"
 	event "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
+// defaultListPageSize is the number of resources fetched per request by ListResources
+// when the list options do not set a limit
+const defaultListPageSize int64 = 500
+
 //Client enables interaction with k8 resource
 type Client struct {
 	client          dynamic.Interface
@@ -31,6 +37,9 @@ type Client struct {
 	clientConfig    *rest.Config
 	kclient         kubernetes.Interface
 	DiscoveryClient IDiscovery
+
+	// ctx is the context of the API requests, context.TODO() is used if it is nil
+	ctx context.Context
 }
 
 //NewClient creates new instance of client
@@ -52,8 +61,10 @@ func NewClient(config *rest.Config, resync time.Duration, stopCh <-chan struct{}
 	}
 
 	// Set discovery client
+	resources := newResourceCache()
 	discoveryClient := &ServerPreferredResources{
-		cachedClient: memory.NewMemCacheClient(kclient.Discovery()),
+		cachedClient: invalidatingDiscoveryCache{CachedDiscoveryInterface: memory.NewMemCacheClient(kclient.Discovery()), resources: resources},
+		resources:    resources,
 		log:          client.log,
 	}
 
@@ -68,6 +79,20 @@ func NewClient(config *rest.Config, resync time.Duration, stopCh <-chan struct{}
 	return &client, nil
 }
 
+// WithContext returns a copy of the client whose API requests are aborted when ctx is done
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := *c
+	client.ctx = ctx
+	return &client
+}
+
+func (c *Client) requestContext() context.Context {
+	if c.ctx == nil {
+		return context.TODO()
+	}
+	return c.ctx
+}
+
 //NewDynamicSharedInformerFactory returns a new instance of DynamicSharedInformerFactory
 func (c *Client) NewDynamicSharedInformerFactory(defaultResync time.Duration) dynamicinformer.DynamicSharedInformerFactory {
 	return dynamicinformer.NewDynamicSharedInformerFactory(c.client, defaultResync)
@@ -112,12 +137,73 @@ func (c *Client) getGroupVersionMapper(apiVersion string, kind string) schema.Gr
 
 // GetResource returns the resource in unstructured/json format
 func (c *Client) GetResource(apiVersion string, kind string, namespace string, name string, subresources ...string) (*unstructured.Unstructured, error) {
-	return c.getResourceInterface(apiVersion, kind, namespace).Get(context.TODO(), name, meta.GetOptions{}, subresources...)
+	return c.getResourceInterface(apiVersion, kind, namespace).Get(c.requestContext(), name, meta.GetOptions{}, subresources...)
+}
+
+// GetResourceByGVR returns the resource of the group version resource in unstructured/json format.
+// Use IsNotFound to distinguish a missing resource from other API errors.
+func (c *Client) GetResourceByGVR(gvr schema.GroupVersionResource, namespace string, name string) (*unstructured.Unstructured, error) {
+	if namespace != "" {
+		return c.client.Resource(gvr).Namespace(namespace).Get(c.requestContext(), name, meta.GetOptions{})
+	}
+
+	return c.client.Resource(gvr).Get(c.requestContext(), name, meta.GetOptions{})
+}
+
+// IsNotFound returns true if the error returned by the client indicates that the resource does not exist
+func IsNotFound(err error) bool {
+	return apierrors.IsNotFound(err)
+}
+
+// PatchResource patches the resource of the group version resource with the JSON patch, so that only
+// the patched fields are sent instead of the get-modify-update of the whole resource
+func (c *Client) PatchResource(gvr schema.GroupVersionResource, namespace string, name string, patch []byte) (*unstructured.Unstructured, error) {
+	if namespace != "" {
+		return c.client.Resource(gvr).Namespace(namespace).Patch(c.requestContext(), name, patchTypes.JSONPatchType, patch, meta.PatchOptions{})
+	}
+
+	return c.client.Resource(gvr).Patch(c.requestContext(), name, patchTypes.JSONPatchType, patch, meta.PatchOptions{})
+}
+
+// PatchResourceWithRetry patches the resource with the JSON patch built from its current state by buildPatch.
+// On conflict errors, e.g. when the patch tests or sets the resourceVersion, the resource is fetched again
+// and the patch rebuilt, with the client-go default backoff.
+func (c *Client) PatchResourceWithRetry(gvr schema.GroupVersionResource, namespace string, name string, buildPatch func(resource *unstructured.Unstructured) ([]byte, error)) (*unstructured.Unstructured, error) {
+	var patched *unstructured.Unstructured
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		resource, err := c.GetResourceByGVR(gvr, namespace, name)
+		if err != nil {
+			return err
+		}
+
+		patch, err := buildPatch(resource)
+		if err != nil {
+			return fmt.Errorf("failed to build the patch of %s %s/%s: %v", gvr.Resource, namespace, name, err)
+		}
+
+		patched, err = c.PatchResource(gvr, namespace, name, patch)
+		return err
+	})
+
+	return patched, err
 }
 
-//PatchResource patches the resource
-func (c *Client) PatchResource(apiVersion string, kind string, namespace string, name string, patch []byte) (*unstructured.Unstructured, error) {
-	return c.getResourceInterface(apiVersion, kind, namespace).Patch(context.TODO(), name, patchTypes.JSONPatchType, patch, meta.PatchOptions{})
+// ApplyResource applies the resource with server-side apply, so that the field manager only owns
+// the fields set in obj. If force is set, the conflicts with other field managers are overridden.
+func (c *Client) ApplyResource(gvr schema.GroupVersionResource, obj *unstructured.Unstructured, fieldManager string, force bool) error {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s/%s: %v", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	var resourceInterface dynamic.ResourceInterface = c.client.Resource(gvr)
+	if obj.GetNamespace() != "" {
+		resourceInterface = c.client.Resource(gvr).Namespace(obj.GetNamespace())
+	}
+
+	options := meta.PatchOptions{FieldManager: fieldManager, Force: &force}
+	_, err = resourceInterface.Patch(c.requestContext(), obj.GetName(), patchTypes.ApplyPatchType, data, options)
+	return err
 }
 
 // GetDynamicInterface fetches underlying dynamic interface
@@ -133,7 +219,45 @@ func (c *Client) ListResource(apiVersion string, kind string, namespace string,
 		options = meta.ListOptions{LabelSelector: meta.FormatLabelSelector(lselector)}
 	}
 
-	return c.getResourceInterface(apiVersion, kind, namespace).List(context.TODO(), options)
+	return c.getResourceInterface(apiVersion, kind, namespace).List(c.requestContext(), options)
+}
+
+// ListResources returns all resources of the group version resource in the namespace, or in all
+// namespaces if the namespace is empty. The resources are fetched in pages of opts.Limit items,
+// defaulting to defaultListPageSize, following the continue token until the last page.
+func (c *Client) ListResources(gvr schema.GroupVersionResource, namespace string, opts meta.ListOptions) (*unstructured.UnstructuredList, error) {
+	var resourceInterface dynamic.ResourceInterface = c.client.Resource(gvr)
+	if namespace != "" {
+		resourceInterface = c.client.Resource(gvr).Namespace(namespace)
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = defaultListPageSize
+	}
+	opts.Continue = ""
+
+	result := &unstructured.UnstructuredList{}
+	for {
+		page, err := resourceInterface.List(c.requestContext(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", gvr.String(), err)
+		}
+
+		if result.Object == nil {
+			result.Object = page.Object
+		}
+		result.Items = append(result.Items, page.Items...)
+
+		if page.GetContinue() == "" {
+			break
+		}
+
+		c.log.V(4).Info("fetching next page", "resource", gvr.String(), "namespace", namespace, "items", len(result.Items))
+		opts.Continue = page.GetContinue()
+	}
+
+	result.SetContinue("")
+	return result, nil
 }
 
 // DeleteResource deletes the specified resource
@@ -142,7 +266,7 @@ func (c *Client) DeleteResource(apiVersion string, kind string, namespace string
 	if dryRun {
 		options = meta.DeleteOptions{DryRun: []string{meta.DryRunAll}}
 	}
-	return c.getResourceInterface(apiVersion, kind, namespace).Delete(context.TODO(), name, options)
+	return c.getResourceInterface(apiVersion, kind, namespace).Delete(c.requestContext(), name, options)
 
 }
 
@@ -154,7 +278,7 @@ func (c *Client) CreateResource(apiVersion string, kind string, namespace string
 	}
 	// convert typed to unstructured obj
 	if unstructuredObj := convertToUnstructured(obj); unstructuredObj != nil {
-		return c.getResourceInterface(apiVersion, kind, namespace).Create(context.TODO(), unstructuredObj, options)
+		return c.getResourceInterface(apiVersion, kind, namespace).Create(c.requestContext(), unstructuredObj, options)
 	}
 	return nil, fmt.Errorf("unable to create resource ")
 }
@@ -167,7 +291,7 @@ func (c *Client) UpdateResource(apiVersion string, kind string, namespace string
 	}
 	// convert typed to unstructured obj
 	if unstructuredObj := convertToUnstructured(obj); unstructuredObj != nil {
-		return c.getResourceInterface(apiVersion, kind, namespace).Update(context.TODO(), unstructuredObj, options)
+		return c.getResourceInterface(apiVersion, kind, namespace).Update(c.requestContext(), unstructuredObj, options)
 	}
 	return nil, fmt.Errorf("unable to update resource ")
 }
@@ -180,7 +304,7 @@ func (c *Client) UpdateStatusResource(apiVersion string, kind string, namespace
 	}
 	// convert typed to unstructured obj
 	if unstructuredObj := convertToUnstructured(obj); unstructuredObj != nil {
-		return c.getResourceInterface(apiVersion, kind, namespace).UpdateStatus(context.TODO(), unstructuredObj, options)
+		return c.getResourceInterface(apiVersion, kind, namespace).UpdateStatus(c.requestContext(), unstructuredObj, options)
 	}
 	return nil, fmt.Errorf("unable to update resource ")
 }
@@ -211,7 +335,11 @@ func (c *Client) SetDiscovery(discoveryClient IDiscovery) {
 //ServerPreferredResources stores the cachedClient instance for discovery client
 type ServerPreferredResources struct {
 	cachedClient discovery.CachedDiscoveryInterface
-	log          logr.Logger
+
+	// resources caches the results of FindResource, it is flushed when cachedClient is invalidated
+	resources *resourceCache
+
+	log logr.Logger
 }
 
 // DiscoveryCache gets the discovery client cache
@@ -276,16 +404,23 @@ func (c ServerPreferredResources) GetServerVersion() (*version.Info, error) {
 }
 
 // FindResource finds an API resource that matches 'kind'. If the resource is not
-// found and the Cache is not fresh, the cache is invalidated and a retry is attempted
+// found and the Cache is not fresh, the cache is invalidated and a retry is attempted.
+// The resources found are cached until the discovery cache is invalidated.
 func (c ServerPreferredResources) FindResource(apiVersion string, kind string) (*meta.APIResource, schema.GroupVersionResource, error) {
+	if r, gvr, ok := c.resources.get(apiVersion, kind); ok {
+		return r, gvr, nil
+	}
+
 	r, gvr, err := c.findResource(apiVersion, kind)
 	if err == nil {
+		c.resources.set(apiVersion, kind, r, gvr)
 		return r, gvr, nil
 	}
 
 	if !c.cachedClient.Fresh() {
 		c.cachedClient.Invalidate()
 		if r, gvr, err = c.findResource(apiVersion, kind); err == nil {
+			c.resources.set(apiVersion, kind, r, gvr)
 			return r, gvr, nil
 		}
 	}
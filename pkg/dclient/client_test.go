@@ -2,12 +2,21 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/kyverno/kyverno/pkg/config"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // GetResource
@@ -126,3 +135,280 @@ func TestCSRInterface(t *testing.T) {
 		t.Errorf("Testing CSR interface not working: %s", err)
 	}
 }
+
+// pagedResourceClient serves the items in pages of opts.Limit items, with the index of the next item as continue token
+type pagedResourceClient struct {
+	dynamic.NamespaceableResourceInterface
+	items    []unstructured.Unstructured
+	requests []meta.ListOptions
+}
+
+func (c *pagedResourceClient) Namespace(string) dynamic.ResourceInterface {
+	return c
+}
+
+func (c *pagedResourceClient) List(_ context.Context, opts meta.ListOptions) (*unstructured.UnstructuredList, error) {
+	c.requests = append(c.requests, opts)
+
+	start := 0
+	if opts.Continue != "" {
+		var err error
+		if start, err = strconv.Atoi(opts.Continue); err != nil {
+			return nil, err
+		}
+	}
+
+	end := start + int(opts.Limit)
+	if end > len(c.items) {
+		end = len(c.items)
+	}
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "group/version", "kind": "TheKindList"}}
+	list.Items = append(list.Items, c.items[start:end]...)
+	if end < len(c.items) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	return list, nil
+}
+
+type pagedDynamicClient struct {
+	dynamic.Interface
+	resource *pagedResourceClient
+}
+
+func (c *pagedDynamicClient) Resource(schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return c.resource
+}
+
+func TestListResources_Pagination(t *testing.T) {
+	resource := &pagedResourceClient{}
+	for i := 0; i < 5; i++ {
+		resource.items = append(resource.items, *newUnstructured("group/version", "TheKind", "ns-foo", fmt.Sprintf("name-%d", i)))
+	}
+
+	client := &Client{client: &pagedDynamicClient{resource: resource}, log: logr.DiscardLogger{}}
+	gvr := schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}
+
+	list, err := client.ListResources(gvr, "", meta.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListResources not working: %s", err)
+	}
+	if len(resource.requests) != 3 {
+		t.Errorf("expected 3 list requests, got %d", len(resource.requests))
+	}
+	if len(list.Items) != 5 {
+		t.Errorf("expected 5 resources, got %d", len(list.Items))
+	}
+	for i, item := range list.Items {
+		if item.GetName() != fmt.Sprintf("name-%d", i) {
+			t.Errorf("expected resource %d to be name-%d, got %s", i, i, item.GetName())
+		}
+	}
+	if list.GetContinue() != "" {
+		t.Errorf("expected the continue token of the aggregated list to be empty, got %s", list.GetContinue())
+	}
+	if resource.requests[0].Continue != "" || resource.requests[1].Continue != "2" || resource.requests[2].Continue != "4" {
+		t.Errorf("unexpected continue tokens: %v", resource.requests)
+	}
+
+	// the default page size is used if the limit is not set
+	resource.requests = nil
+	if _, err := client.ListResources(gvr, "ns-foo", meta.ListOptions{}); err != nil {
+		t.Fatalf("ListResources not working: %s", err)
+	}
+	if len(resource.requests) != 1 || resource.requests[0].Limit != defaultListPageSize {
+		t.Errorf("expected a single request with limit %d, got %v", defaultListPageSize, resource.requests)
+	}
+}
+
+func TestListResources_SinglePage(t *testing.T) {
+	f := newFixture(t)
+	gvr := schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}
+
+	list, err := f.client.ListResources(gvr, "ns-foo", meta.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListResources not working: %s", err)
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected 3 resources, got %d", len(list.Items))
+	}
+}
+
+func TestGetResourceByGVR(t *testing.T) {
+	f := newFixture(t)
+	gvr := schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}
+
+	// found
+	obj, err := f.client.GetResourceByGVR(gvr, "ns-foo", "name-foo")
+	if err != nil {
+		t.Fatalf("GetResourceByGVR not working: %s", err)
+	}
+	if obj.GetName() != "name-foo" {
+		t.Errorf("expected name-foo, got %s", obj.GetName())
+	}
+
+	// not found
+	_, err = f.client.GetResourceByGVR(gvr, "ns-foo", "name-missing")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	// server error
+	f.client.client.(*fake.FakeDynamicClient).PrependReactor("get", "thekinds", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(fmt.Errorf("etcd unavailable"))
+	})
+
+	_, err = f.client.GetResourceByGVR(gvr, "ns-foo", "name-foo")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if IsNotFound(err) {
+		t.Errorf("expected a server error to not be reported as not found, got %v", err)
+	}
+}
+
+func TestPatchResource(t *testing.T) {
+	f := newFixture(t)
+	gvr := schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}
+
+	patch := []byte(`[{"op":"add","path":"/metadata/labels","value":{"app":"nginx"}}]`)
+	patched, err := f.client.PatchResource(gvr, "ns-foo", "name-foo", patch)
+	if err != nil {
+		t.Fatalf("PatchResource not working: %s", err)
+	}
+	if patched.GetLabels()["app"] != "nginx" {
+		t.Errorf("expected the patched resource to have the label app=nginx, got %v", patched.GetLabels())
+	}
+
+	obj, err := f.client.GetResourceByGVR(gvr, "ns-foo", "name-foo")
+	if err != nil {
+		t.Fatalf("GetResourceByGVR not working: %s", err)
+	}
+	if obj.GetLabels()["app"] != "nginx" {
+		t.Errorf("expected the stored resource to have the label app=nginx, got %v", obj.GetLabels())
+	}
+
+	// cluster-wide resource
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	if _, err := f.client.CreateResource("", "Namespace", "", newUnstructured("v1", "Namespace", "", "ns-bar"), false); err != nil {
+		t.Fatalf("CreateResource not working: %s", err)
+	}
+	if _, err := f.client.PatchResource(namespaces, "", "ns-bar", patch); err != nil {
+		t.Fatalf("PatchResource not working on a cluster-wide resource: %s", err)
+	}
+
+	if _, err := f.client.PatchResource(gvr, "ns-foo", "name-missing", patch); !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestPatchResourceWithRetry(t *testing.T) {
+	f := newFixture(t)
+	gvr := schema.GroupVersionResource{Group: "group", Version: "version", Resource: "thekinds"}
+
+	// the first patch conflicts with a concurrent update
+	conflicts := 1
+	f.client.client.(*fake.FakeDynamicClient).PrependReactor("patch", "thekinds", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			conflicts--
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "group", Resource: "thekinds"}, "name-foo", fmt.Errorf("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	builds := 0
+	patched, err := f.client.PatchResourceWithRetry(gvr, "ns-foo", "name-foo", func(resource *unstructured.Unstructured) ([]byte, error) {
+		builds++
+		return []byte(`[{"op":"add","path":"/metadata/labels","value":{"build":"` + strconv.Itoa(builds) + `"}}]`), nil
+	})
+	if err != nil {
+		t.Fatalf("PatchResourceWithRetry not working: %s", err)
+	}
+	if builds != 2 {
+		t.Errorf("expected the patch to be built twice, got %d", builds)
+	}
+	if patched.GetLabels()["build"] != "2" {
+		t.Errorf("expected the patch of the second attempt to be applied, got %v", patched.GetLabels())
+	}
+
+	// the other errors are not retried
+	builds = 0
+	_, err = f.client.PatchResourceWithRetry(gvr, "ns-foo", "name-foo", func(resource *unstructured.Unstructured) ([]byte, error) {
+		builds++
+		return nil, fmt.Errorf("invalid resource")
+	})
+	if err == nil || builds != 1 {
+		t.Errorf("expected a single failed attempt, got %d attempts and error %v", builds, err)
+	}
+
+	_, err = f.client.PatchResourceWithRetry(gvr, "ns-foo", "name-missing", func(resource *unstructured.Unstructured) ([]byte, error) {
+		return []byte(`[]`), nil
+	})
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+// patchRecorder records the patch requests sent to a resource
+type patchRecorder struct {
+	dynamic.NamespaceableResourceInterface
+	namespace string
+	name      string
+	patchType types.PatchType
+	data      []byte
+	options   meta.PatchOptions
+}
+
+func (r *patchRecorder) Namespace(namespace string) dynamic.ResourceInterface {
+	r.namespace = namespace
+	return r
+}
+
+func (r *patchRecorder) Patch(_ context.Context, name string, pt types.PatchType, data []byte, options meta.PatchOptions, _ ...string) (*unstructured.Unstructured, error) {
+	r.name = name
+	r.patchType = pt
+	r.data = data
+	r.options = options
+	return &unstructured.Unstructured{}, nil
+}
+
+type patchRecorderClient struct {
+	dynamic.Interface
+	recorder *patchRecorder
+}
+
+func (c *patchRecorderClient) Resource(schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return c.recorder
+}
+
+func TestApplyResource(t *testing.T) {
+	recorder := &patchRecorder{}
+	client := &Client{client: &patchRecorderClient{recorder: recorder}, log: logr.DiscardLogger{}}
+	gvr := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}
+	obj := newUnstructuredWithSpec("networking.k8s.io/v1", "NetworkPolicy", "ns-foo", "default-deny", map[string]interface{}{"podSelector": map[string]interface{}{}})
+
+	if err := client.ApplyResource(gvr, obj, "kyverno", true); err != nil {
+		t.Fatalf("ApplyResource not working: %s", err)
+	}
+
+	if recorder.patchType != types.ApplyPatchType {
+		t.Errorf("expected patch type %s, got %s", types.ApplyPatchType, recorder.patchType)
+	}
+	if recorder.options.FieldManager != "kyverno" {
+		t.Errorf("expected field manager kyverno, got %s", recorder.options.FieldManager)
+	}
+	if recorder.options.Force == nil || !*recorder.options.Force {
+		t.Errorf("expected force to be set")
+	}
+	if recorder.namespace != "ns-foo" || recorder.name != "default-deny" {
+		t.Errorf("expected ns-foo/default-deny to be patched, got %s/%s", recorder.namespace, recorder.name)
+	}
+
+	applied := &unstructured.Unstructured{}
+	if err := applied.UnmarshalJSON(recorder.data); err != nil {
+		t.Fatalf("failed to decode the applied resource: %s", err)
+	}
+	if applied.GetKind() != "NetworkPolicy" {
+		t.Errorf("expected the applied resource to be a NetworkPolicy, got %s", applied.GetKind())
+	}
+}
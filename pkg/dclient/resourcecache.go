@@ -0,0 +1,69 @@
+package client
+
+import (
+	"sync"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// resourceCache stores the API resources found by kind and apiVersion, so that repeated
+// lookups do not go through the discovery documents again.
+// It is flushed whenever the discovery cache is invalidated, e.g. when CRDs are synced.
+type resourceCache struct {
+	mutex     sync.RWMutex
+	resources map[string]cachedResource
+}
+
+type cachedResource struct {
+	resource meta.APIResource
+	gvr      schema.GroupVersionResource
+}
+
+func newResourceCache() *resourceCache {
+	return &resourceCache{resources: make(map[string]cachedResource)}
+}
+
+func resourceCacheKey(apiVersion, kind string) string {
+	return apiVersion + "|" + kind
+}
+
+func (c *resourceCache) get(apiVersion, kind string) (*meta.APIResource, schema.GroupVersionResource, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	r, ok := c.resources[resourceCacheKey(apiVersion, kind)]
+	if !ok {
+		return nil, schema.GroupVersionResource{}, false
+	}
+
+	resource := r.resource
+	return &resource, r.gvr, true
+}
+
+func (c *resourceCache) set(apiVersion, kind string, resource *meta.APIResource, gvr schema.GroupVersionResource) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resources[resourceCacheKey(apiVersion, kind)] = cachedResource{resource: *resource, gvr: gvr}
+}
+
+func (c *resourceCache) flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resources = make(map[string]cachedResource)
+}
+
+// invalidatingDiscoveryCache flushes the resource cache together with the discovery cache
+type invalidatingDiscoveryCache struct {
+	discovery.CachedDiscoveryInterface
+	resources *resourceCache
+}
+
+// Invalidate marks the discovery cache and the resource cache as stale
+func (c invalidatingDiscoveryCache) Invalidate() {
+	c.resources.flush()
+	c.CachedDiscoveryInterface.Invalidate()
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/assert"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// countingDiscoveryCache serves the API resources and counts the discovery requests
+type countingDiscoveryCache struct {
+	discovery.CachedDiscoveryInterface
+	resources []*meta.APIResourceList
+	requests  int
+}
+
+func (c *countingDiscoveryCache) ServerPreferredResources() ([]*meta.APIResourceList, error) {
+	c.requests++
+	return c.resources, nil
+}
+
+func (c *countingDiscoveryCache) ServerGroupsAndResources() ([]*meta.APIGroup, []*meta.APIResourceList, error) {
+	c.requests++
+	return nil, c.resources, nil
+}
+
+func (c *countingDiscoveryCache) Fresh() bool {
+	return true
+}
+
+func (c *countingDiscoveryCache) Invalidate() {}
+
+func newCachedServerPreferredResources(cached *countingDiscoveryCache) ServerPreferredResources {
+	resources := newResourceCache()
+	return ServerPreferredResources{
+		cachedClient: invalidatingDiscoveryCache{CachedDiscoveryInterface: cached, resources: resources},
+		resources:    resources,
+		log:          logr.DiscardLogger{},
+	}
+}
+
+func TestGetGVRFromKind_Cached(t *testing.T) {
+	cached := &countingDiscoveryCache{
+		resources: []*meta.APIResourceList{
+			{GroupVersion: "apps/v1", APIResources: []meta.APIResource{{Name: "deployments", SingularName: "deployment", Kind: "Deployment"}}},
+		},
+	}
+	c := newCachedServerPreferredResources(cached)
+
+	for i := 0; i < 3; i++ {
+		gvr, err := c.GetGVRFromKind("Deployment")
+		assert.NilError(t, err)
+		assert.Equal(t, gvr, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+	}
+	assert.Equal(t, cached.requests, 1)
+
+	gvr := c.GetGVRFromAPIVersionKind("apps/v1", "Deployment")
+	assert.Equal(t, gvr, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+	gvr = c.GetGVRFromAPIVersionKind("apps/v1", "Deployment")
+	assert.Equal(t, gvr, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+	assert.Equal(t, cached.requests, 2)
+}
+
+func TestGetGVRFromKind_InvalidateRefreshes(t *testing.T) {
+	cached := &countingDiscoveryCache{
+		resources: []*meta.APIResourceList{
+			{GroupVersion: "apps/v1", APIResources: []meta.APIResource{{Name: "deployments", SingularName: "deployment", Kind: "Deployment"}}},
+		},
+	}
+	c := newCachedServerPreferredResources(cached)
+
+	_, err := c.GetGVRFromKind("Deployment")
+	assert.NilError(t, err)
+	_, err = c.GetGVRFromKind("Widget")
+	assert.ErrorContains(t, err, "kind 'Widget' not found")
+
+	// a new CRD is installed, the CRD sync invalidates the discovery cache
+	cached.resources = append(cached.resources, &meta.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []meta.APIResource{{Name: "widgets", SingularName: "widget", Kind: "Widget"}},
+	})
+	c.DiscoveryCache().Invalidate()
+	requests := cached.requests
+
+	gvr, err := c.GetGVRFromKind("Widget")
+	assert.NilError(t, err)
+	assert.Equal(t, gvr, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"})
+
+	// the cached kinds are looked up again after the invalidation
+	_, err = c.GetGVRFromKind("Deployment")
+	assert.NilError(t, err)
+	assert.Equal(t, cached.requests, requests+2)
+}
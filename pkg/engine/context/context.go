@@ -275,6 +275,23 @@ func (ctx *Context) AddNamespace(namespace string) error {
 	return ctx.AddJSON(objRaw)
 }
 
+// AddNamespaceObject merges the namespace object json under namespace
+func (ctx *Context) AddNamespaceObject(data interface{}) error {
+	namespace := struct {
+		Namespace interface{} `json:"namespace"`
+	}{
+		Namespace: data,
+	}
+
+	objRaw, err := json.Marshal(namespace)
+	if err != nil {
+		ctx.log.Error(err, "failed to marshal the namespace")
+		return err
+	}
+
+	return ctx.AddJSON(objRaw)
+}
+
 func (ctx *Context) AddImageInfo(resource *unstructured.Unstructured) error {
 	initContainersImgs, containersImgs := extractImageInfo(resource, ctx.log)
 	if len(initContainersImgs) == 0 && len(containersImgs) == 0 {
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kyverno/kyverno/pkg/engine/response"
+)
+
+var (
+	// ErrRuleEvaluation is the error of a rule whose context, preconditions, variables or conditions cannot be evaluated
+	ErrRuleEvaluation = errors.New("rule evaluation failed")
+
+	// ErrPatchApply is the error of a mutate rule whose patches cannot be applied to the resource
+	ErrPatchApply = errors.New("failed to apply patches")
+
+	// ErrPatternMatch is the error of a validate rule whose pattern cannot be processed, e.g. an invalid pattern
+	ErrPatternMatch = errors.New("failed to process pattern")
+)
+
+// RuleError is the error of a rule that cannot be applied to a resource, it is set in the
+// rule response of the rules with the error status.
+// errors.Is reports if the error is one of ErrRuleEvaluation, ErrPatchApply or ErrPatternMatch.
+type RuleError struct {
+	// Type is one of ErrRuleEvaluation, ErrPatchApply or ErrPatternMatch
+	Type error
+
+	// Policy is the policy name, prefixed with the namespace for namespaced policies
+	Policy string
+
+	// Rule is the rule name
+	Rule string
+
+	// Resource is the resource key in the kind/namespace/name format
+	Resource string
+
+	// Err is the cause of the error
+	Err error
+}
+
+func (e *RuleError) Error() string {
+	if errors.Is(e.Err, e.Type) {
+		return fmt.Sprintf("policy %s, rule %s, resource %s: %v", e.Policy, e.Rule, e.Resource, e.Err)
+	}
+
+	return fmt.Sprintf("policy %s, rule %s, resource %s: %v: %v", e.Policy, e.Rule, e.Resource, e.Type, e.Err)
+}
+
+// Is returns true if target is the type of the error
+func (e *RuleError) Is(target error) bool {
+	return target == e.Type
+}
+
+// Unwrap returns the cause of the error
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// withRuleError sets the error of the rule response, the type is errType
+// unless err already wraps one of the error types
+func withRuleError(resp *response.RuleResponse, errType error, err error) *response.RuleResponse {
+	for _, t := range []error{ErrRuleEvaluation, ErrPatchApply, ErrPatternMatch} {
+		if errors.Is(err, t) {
+			errType = t
+			break
+		}
+	}
+
+	resp.Error = &RuleError{Type: errType, Rule: resp.Name, Err: err}
+	return resp
+}
+
+// setRuleErrorContext adds the policy and the resource of the engine response to the rule errors
+func setRuleErrorContext(resp *response.EngineResponse) {
+	policy := resp.PolicyResponse.Policy.Name
+	if resp.PolicyResponse.Policy.Namespace != "" {
+		policy = resp.PolicyResponse.Policy.Namespace + "/" + policy
+	}
+
+	for _, rule := range resp.PolicyResponse.Rules {
+		if ruleErr, ok := rule.Error.(*RuleError); ok {
+			ruleErr.Policy = policy
+			ruleErr.Resource = resp.PolicyResponse.Resource.GetKey()
+		}
+	}
+}
@@ -90,7 +90,7 @@ func ForceMutate(ctx context.EvalInterface, policy kyverno.ClusterPolicy, resour
 
 		if rule.Mutation.PatchStrategicMerge != nil {
 			var resp response.RuleResponse
-			resp, resource = mutate.ProcessStrategicMergePatch(rule.Name, rule.Mutation.PatchStrategicMerge, resource, logger.WithValues("rule", rule.Name))
+			resp, resource = mutate.ProcessStrategicMergePatches(rule.Name, rule.Mutation.StrategicMergePatches(), resource, logger.WithValues("rule", rule.Name))
 			if resp.Status != response.RuleStatusPass {
 				return unstructured.Unstructured{}, fmt.Errorf(resp.Message)
 			}
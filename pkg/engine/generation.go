@@ -47,6 +47,10 @@ func filterRules(policyContext *PolicyContext, startTime time.Time) *response.En
 	}
 
 	for _, rule := range policyContext.Policy.Spec.Rules {
+		if policyContext.Cancelled() {
+			break
+		}
+
 		if ruleResp := filterRule(rule, policyContext); ruleResp != nil {
 			resp.PolicyResponse.Rules = append(resp.PolicyResponse.Rules, *ruleResp)
 		}
@@ -75,10 +79,10 @@ func filterRule(rule kyverno.Rule, policyContext *PolicyContext) *response.RuleR
 	logger := log.Log.WithName("Generate").WithValues("policy", policy.Name,
 		"kind", newResource.GetKind(), "namespace", newResource.GetNamespace(), "name", newResource.GetName())
 
-	if err = MatchesResourceDescription(newResource, rule, admissionInfo, excludeGroupRole, namespaceLabels, ""); err != nil {
+	if err = MatchesResourceDescription(newResource, rule, admissionInfo, excludeGroupRole, namespaceLabels, "", policyContext.SubResource); err != nil {
 
 		// if the oldResource matched, return "false" to delete GR for it
-		if err = MatchesResourceDescription(oldResource, rule, admissionInfo, excludeGroupRole, namespaceLabels, ""); err == nil {
+		if err = MatchesResourceDescription(oldResource, rule, admissionInfo, excludeGroupRole, namespaceLabels, "", policyContext.SubResource); err == nil {
 			return &response.RuleResponse{
 				Name:   rule.Name,
 				Type:   "Generation",
@@ -45,6 +45,11 @@ func VerifyAndPatchImages(policyContext *PolicyContext) (resp *response.EngineRe
 			continue
 		}
 
+		if policyContext.Cancelled() {
+			logger.V(3).Info("skipping the remaining verifyImages rules, the evaluation of the request was cancelled")
+			break
+		}
+
 		if !matches(logger, rule, policyContext) {
 			continue
 		}
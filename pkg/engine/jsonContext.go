@@ -47,24 +47,26 @@ func LoadContext(logger logr.Logger, contextEntries []kyverno.ContextEntry, resC
 		}
 
 	} else {
-		// get GVR Cache for "configmaps"
-		// can get cache for other resources if the informers are enabled in resource cache
-		gvrC, ok := resCache.GetGVRCache("ConfigMap")
-		if !ok {
-			return errors.New("configmaps GVR Cache not found")
-		}
-
-		lister := gvrC.Lister()
-
 		for _, entry := range contextEntries {
 			if entry.ConfigMap != nil {
-				if err := loadConfigMap(logger, entry, lister, ctx.JSONContext); err != nil {
+				// get GVR Cache for "configmaps"
+				// can get cache for other resources if the informers are enabled in resource cache
+				gvrC, ok := resCache.GetGVRCache("ConfigMap")
+				if !ok {
+					return errors.New("configmaps GVR Cache not found")
+				}
+
+				if err := loadConfigMap(logger, entry, gvrC.Lister(), ctx.JSONContext); err != nil {
 					return err
 				}
 			} else if entry.APICall != nil {
 				if err := loadAPIData(logger, entry, ctx); err != nil {
 					return err
 				}
+			} else if entry.ResourceQuota != nil {
+				if err := loadResourceQuota(logger, entry, ctx); err != nil {
+					return err
+				}
 			}
 		}
 	}
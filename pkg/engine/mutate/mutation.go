@@ -18,6 +18,8 @@ type Handler interface {
 func CreateMutateHandler(ruleName string, mutate *kyverno.Mutation, patchedResource unstructured.Unstructured, context context.EvalInterface, logger logr.Logger, foreachIndex int) Handler {
 
 	switch {
+	case isPatchStrategicMergeList(mutate):
+		return newPatchStrategicMergeListHandler(ruleName, mutate, patchedResource, logger)
 	case isPatchStrategicMerge(mutate):
 		return newPatchStrategicMergeHandler(ruleName, mutate, patchedResource, context, logger)
 	case isPatchesJSON6902(mutate):
@@ -60,6 +62,27 @@ func (h patchStrategicMergeHandler) Handle() (response.RuleResponse, unstructure
 	return ProcessStrategicMergePatch(h.ruleName, h.mutation.PatchStrategicMerge, h.patchedResource, h.logger)
 }
 
+// patchStrategicMergeListHandler
+type patchStrategicMergeListHandler struct {
+	ruleName        string
+	mutation        *kyverno.Mutation
+	patchedResource unstructured.Unstructured
+	logger          logr.Logger
+}
+
+func newPatchStrategicMergeListHandler(ruleName string, mutate *kyverno.Mutation, patchedResource unstructured.Unstructured, logger logr.Logger) Handler {
+	return patchStrategicMergeListHandler{
+		ruleName:        ruleName,
+		mutation:        mutate,
+		patchedResource: patchedResource,
+		logger:          logger,
+	}
+}
+
+func (h patchStrategicMergeListHandler) Handle() (response.RuleResponse, unstructured.Unstructured) {
+	return ProcessStrategicMergePatches(h.ruleName, h.mutation.StrategicMergePatches(), h.patchedResource, h.logger)
+}
+
 type forEachHandler struct {
 	ruleName        string
 	mutation        *kyverno.Mutation
@@ -161,6 +184,11 @@ func isPatchStrategicMerge(mutate *kyverno.Mutation) bool {
 	return mutate.PatchStrategicMerge != nil
 }
 
+func isPatchStrategicMergeList(mutate *kyverno.Mutation) bool {
+	_, ok := mutate.PatchStrategicMerge.([]interface{})
+	return ok
+}
+
 func isForEach(mutate *kyverno.Mutation) bool {
 	return mutate.ForEachMutation != nil
 }
@@ -377,3 +377,99 @@ spec:
 		}
 	}
 }
+
+func TestProcessPatchJSON6902_TestOperation(t *testing.T) {
+	tests := []struct {
+		name           string
+		patches        string
+		expectedStatus response.RuleStatus
+		expectedLabels map[string]string
+		expectedPatch  int
+	}{
+		{
+			name: "add-remove-replace",
+			patches: `[
+  {"op": "test", "path": "/spec/template/metadata/labels/old-label", "value": "old-value"},
+  {"op": "add", "path": "/spec/template/metadata/labels/new-label", "value": "new-value"},
+  {"op": "remove", "path": "/spec/template/metadata/labels/old-label"},
+  {"op": "replace", "path": "/spec/replica", "value": 3}
+]`,
+			expectedStatus: response.RuleStatusPass,
+			expectedLabels: map[string]string{"new-label": "new-value"},
+			expectedPatch:  3,
+		},
+		{
+			name: "failing-test-operation",
+			patches: `[
+  {"op": "add", "path": "/spec/template/metadata/labels/new-label", "value": "new-value"},
+  {"op": "test", "path": "/spec/template/metadata/labels/old-label", "value": "other-value"},
+  {"op": "replace", "path": "/spec/replica", "value": 3}
+]`,
+			expectedStatus: response.RuleStatusFail,
+			expectedLabels: map[string]string{"old-label": "old-value"},
+		},
+	}
+
+	for _, test := range tests {
+		jsonResource, err := yaml.YAMLToJSON(inputBytes)
+		assert.Nil(t, err)
+		var resource unstructured.Unstructured
+		assert.Nil(t, resource.UnmarshalJSON(jsonResource))
+
+		resp, patchedResource := ProcessPatchJSON6902(test.name, []byte(test.patches), resource, log.Log)
+		assert.Equal(t, test.expectedStatus, resp.Status, test.name)
+		assert.Equal(t, test.expectedPatch, len(resp.Patches), test.name)
+
+		labels, _, err := unstructured.NestedStringMap(patchedResource.Object, "spec", "template", "metadata", "labels")
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedLabels, labels, test.name)
+
+		// the patches are applied atomically, a failing operation leaves the resource unchanged
+		replica, _, _ := unstructured.NestedInt64(patchedResource.Object, "spec", "replica")
+		if test.expectedStatus == response.RuleStatusFail {
+			assert.Equal(t, int64(2), replica, test.name)
+		} else {
+			assert.Equal(t, int64(3), replica, test.name)
+		}
+	}
+}
+
+func TestProcessPatchJSON6902_RemoveByIndex(t *testing.T) {
+	resource := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}, {"name": "sidecar", "image": "busybox:1.34"}]}}`)
+
+	testCases := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "existing index",
+			patch:    `[{"op": "remove", "path": "/spec/containers/1"}]`,
+			expected: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`,
+		},
+		{
+			name:     "last element",
+			patch:    `[{"op": "remove", "path": "/spec/containers/-1"}]`,
+			expected: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`,
+		},
+		{
+			name:     "absent field",
+			patch:    `[{"op": "remove", "path": "/spec/hostNetwork"}]`,
+			expected: string(resource),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var resourceUnstructured unstructured.Unstructured
+			assert.Nil(t, resourceUnstructured.UnmarshalJSON(resource))
+
+			resp, patchedResource := ProcessPatchJSON6902("remove-by-index", []byte(test.patch), resourceUnstructured, log.Log)
+			assert.Equal(t, response.RuleStatusPass, resp.Status)
+
+			patched, err := patchedResource.MarshalJSON()
+			assert.Nil(t, err)
+			assert.JSONEq(t, test.expected, string(patched))
+		})
+	}
+}
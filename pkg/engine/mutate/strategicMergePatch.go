@@ -15,7 +15,14 @@ import (
 	yaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
-// ProcessStrategicMergePatch ...
+// ProcessStrategicMergePatch applies the strategic merge patch, or the overlay, to the resource
+// and returns the JSON patches of the changes.
+//
+// A field is removed by setting it to null in the patch, e.g. {"spec": {"hostNetwork": null}}, which
+// generates a remove patch and does nothing if the field is absent. An element of a list of maps with
+// a merge key, e.g. the containers, is removed by its key with the "$patch": "delete" directive, e.g.
+// {"spec": {"containers": [{"name": "sidecar", "$patch": "delete"}]}}. Elements cannot be removed
+// by index or by value, use a patchesJson6902 remove operation instead.
 func ProcessStrategicMergePatch(ruleName string, overlay interface{}, resource unstructured.Unstructured, log logr.Logger) (resp response.RuleResponse, patchedResource unstructured.Unstructured) {
 	startTime := time.Now()
 	logger := log.WithName("ProcessStrategicMergePatch").WithValues("rule", ruleName)
@@ -82,6 +89,39 @@ func ProcessStrategicMergePatch(ruleName string, overlay interface{}, resource u
 	return resp, patchedResource
 }
 
+// ProcessStrategicMergePatches applies the strategic merge patches to the resource in sequence, each patch is applied
+// to the resource patched by the previous ones, and returns the JSON patches of all the patches in order.
+// If a patch fails the rule fails with the index of the patch, and the resource is not patched.
+func ProcessStrategicMergePatches(ruleName string, patches []interface{}, resource unstructured.Unstructured, log logr.Logger) (resp response.RuleResponse, patchedResource unstructured.Unstructured) {
+	startTime := time.Now()
+	logger := log.WithName("ProcessStrategicMergePatches").WithValues("rule", ruleName)
+	resp.Name = ruleName
+	resp.Type = utils.Mutation.String()
+
+	defer func() {
+		resp.RuleStats.ProcessingTime = time.Since(startTime)
+		resp.RuleStats.RuleExecutionTimestamp = startTime.Unix()
+		logger.V(4).Info("finished applying strategic merge patches", "processingTime", resp.RuleStats.ProcessingTime.String())
+	}()
+
+	patchedResource = resource
+	for i, patch := range patches {
+		patchResp, patchResource := ProcessStrategicMergePatch(ruleName, patch, patchedResource, log.WithValues("patchStrategicMerge", i))
+		if patchResp.Status != response.RuleStatusPass {
+			resp.Status = patchResp.Status
+			resp.Message = fmt.Sprintf("failed to apply patchStrategicMerge[%d]: %s", i, patchResp.Message)
+			return resp, resource
+		}
+
+		resp.Patches = append(resp.Patches, patchResp.Patches...)
+		patchedResource = patchResource
+	}
+
+	resp.Status = response.RuleStatusPass
+	resp.Message = fmt.Sprintf("successfully processed %d strategic merge patches", len(patches))
+	return resp, patchedResource
+}
+
 func strategicMergePatch(logger logr.Logger, base, overlay string) ([]byte, error) {
 	preprocessedYaml, err := preProcessStrategicMergePatch(logger, overlay, base)
 	if err != nil {
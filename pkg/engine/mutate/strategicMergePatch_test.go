@@ -2,9 +2,13 @@ package mutate
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/engine/utils"
 	assertnew "github.com/stretchr/testify/assert"
 	"gotest.tools/assert"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -262,3 +266,354 @@ func Test_PolicyDeserilize(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func Test_CreateMutateHandler_PatchStrategicMergeDeploymentResources(t *testing.T) {
+	rawMutation := []byte(`{
+    "patchStrategicMerge": {
+      "spec": {
+        "template": {
+          "spec": {
+            "containers": [
+              {
+                "name": "app",
+                "resources": {
+                  "limits": {"memory": "256Mi"},
+                  "requests": {"cpu": "100m", "memory": "128Mi"}
+                }
+              }
+            ]
+          }
+        }
+      }
+    }
+  }`)
+
+	rawResource := []byte(`{
+    "apiVersion": "apps/v1",
+    "kind": "Deployment",
+    "metadata": {"name": "web"},
+    "spec": {
+      "template": {
+        "spec": {
+          "containers": [
+            {"name": "sidecar", "image": "envoyproxy/envoy:v1.19.0"},
+            {"name": "app", "image": "nginx:1.21", "resources": {"limits": {"cpu": "500m"}}}
+          ]
+        }
+      }
+    }
+  }`)
+
+	expected := []byte(`{
+    "apiVersion": "apps/v1",
+    "kind": "Deployment",
+    "metadata": {"name": "web"},
+    "spec": {
+      "template": {
+        "spec": {
+          "containers": [
+            {"name": "sidecar", "image": "envoyproxy/envoy:v1.19.0"},
+            {
+              "name": "app",
+              "image": "nginx:1.21",
+              "resources": {
+                "limits": {"cpu": "500m", "memory": "256Mi"},
+                "requests": {"cpu": "100m", "memory": "128Mi"}
+              }
+            }
+          ]
+        }
+      }
+    }
+  }`)
+
+	var mutation kyvernov1.Mutation
+	assert.NilError(t, json.Unmarshal(rawMutation, &mutation))
+
+	var resource unstructured.Unstructured
+	assert.NilError(t, resource.UnmarshalJSON(rawResource))
+
+	// the containers are merged by name, not by position
+	handler := CreateMutateHandler("set-resources", &mutation, resource, context.NewContext(), log.Log, 0)
+	resp, patched := handler.Handle()
+	assert.Equal(t, resp.Status, response.RuleStatusPass, resp.Message)
+	assert.Assert(t, len(resp.Patches) > 0)
+
+	patchedBytes, err := patched.MarshalJSON()
+	assert.NilError(t, err)
+	areEqualJSONs(t, expected, patchedBytes)
+}
+
+func Test_StrategicMergePatch_ConditionalAnchor(t *testing.T) {
+	overlay := []byte(`{
+    "spec": {
+      "containers": [
+        {
+          "(image)": "*:latest",
+          "imagePullPolicy": "Always"
+        }
+      ]
+    }
+  }`)
+
+	testCases := []struct {
+		name        string
+		rawResource []byte
+		expected    []byte
+	}{
+		{
+			name: "matched",
+			rawResource: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "containers": [
+            {"name": "nginx", "image": "nginx:latest"},
+            {"name": "envoy", "image": "envoyproxy/envoy:v1.19.0"}
+          ]
+        }
+      }`),
+			expected: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "containers": [
+            {"name": "nginx", "image": "nginx:latest", "imagePullPolicy": "Always"},
+            {"name": "envoy", "image": "envoyproxy/envoy:v1.19.0"}
+          ]
+        }
+      }`),
+		},
+		{
+			name: "not matched",
+			rawResource: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "containers": [
+            {"name": "nginx", "image": "nginx:1.21"}
+          ]
+        }
+      }`),
+			expected: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "containers": [
+            {"name": "nginx", "image": "nginx:1.21"}
+          ]
+        }
+      }`),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := strategicMergePatch(log.Log, string(test.rawResource), string(overlay))
+			assert.NilError(t, err)
+			areEqualJSONs(t, test.expected, out)
+		})
+	}
+}
+
+func Test_StrategicMergePatch_GlobalAnchorPrecedence(t *testing.T) {
+	// the global anchor does not match, the conditional anchor of the containers is not evaluated
+	overlay := []byte(`{
+    "metadata": {
+      "labels": {
+        "<(tier)": "frontend"
+      }
+    },
+    "spec": {
+      "containers": [
+        {
+          "(image)": "*:latest",
+          "imagePullPolicy": "Always"
+        }
+      ]
+    }
+  }`)
+
+	resource := []byte(`{
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "metadata": {"name": "web", "labels": {"tier": "backend"}},
+    "spec": {
+      "containers": [
+        {"name": "nginx", "image": "nginx:latest"}
+      ]
+    }
+  }`)
+
+	out, err := strategicMergePatch(log.Log, string(resource), string(overlay))
+	assert.NilError(t, err)
+	areEqualJSONs(t, resource, out)
+}
+
+func Test_ProcessStrategicMergePatch_RemoveFields(t *testing.T) {
+	resource := []byte(`{
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "metadata": {"name": "web"},
+    "spec": {
+      "hostNetwork": true,
+      "containers": [
+        {"name": "nginx", "image": "nginx:1.21"},
+        {"name": "sidecar", "image": "busybox:1.34"}
+      ]
+    }
+  }`)
+
+	testCases := []struct {
+		name     string
+		overlay  []byte
+		expected []byte
+		patches  []string
+	}{
+		{
+			name:    "scalar field",
+			overlay: []byte(`{"spec": {"hostNetwork": null}}`),
+			expected: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "containers": [
+            {"name": "nginx", "image": "nginx:1.21"},
+            {"name": "sidecar", "image": "busybox:1.34"}
+          ]
+        }
+      }`),
+			patches: []string{`{"op":"remove","path":"/spec/hostNetwork"}`},
+		},
+		{
+			name:     "absent field",
+			overlay:  []byte(`{"spec": {"dnsPolicy": null}}`),
+			expected: resource,
+		},
+		{
+			name:    "list element by merge key",
+			overlay: []byte(`{"spec": {"containers": [{"name": "sidecar", "$patch": "delete"}]}}`),
+			expected: []byte(`{
+        "apiVersion": "v1",
+        "kind": "Pod",
+        "metadata": {"name": "web"},
+        "spec": {
+          "hostNetwork": true,
+          "containers": [
+            {"name": "nginx", "image": "nginx:1.21"}
+          ]
+        }
+      }`),
+			patches: []string{`{"op":"remove","path":"/spec/containers/1"}`},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var overlay interface{}
+			assert.NilError(t, json.Unmarshal(test.overlay, &overlay))
+
+			var resourceUnstructured unstructured.Unstructured
+			assert.NilError(t, resourceUnstructured.UnmarshalJSON(resource))
+
+			resp, patchedResource := ProcessStrategicMergePatch("remove-fields", overlay, resourceUnstructured, log.Log)
+			assert.Equal(t, resp.Status, response.RuleStatusPass)
+
+			patched, err := patchedResource.MarshalJSON()
+			assert.NilError(t, err)
+			areEqualJSONs(t, test.expected, patched)
+
+			var patches []string
+			for _, p := range resp.Patches {
+				patches = append(patches, string(p))
+			}
+			assert.DeepEqual(t, patches, test.patches)
+		})
+	}
+}
+
+func Test_CreateMutateHandler_SequentialStrategicMergePatches(t *testing.T) {
+	// the second patch matches the image set by the first one
+	rawMutation := []byte(`{
+    "patchStrategicMerge": [
+      {
+        "spec": {
+          "containers": [
+            {"(name)": "nginx", "image": "nginx:latest"}
+          ]
+        }
+      },
+      {
+        "spec": {
+          "containers": [
+            {"(image)": "*:latest", "imagePullPolicy": "Always"}
+          ]
+        }
+      }
+    ]
+  }`)
+
+	rawResource := []byte(`{
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "metadata": {"name": "web"},
+    "spec": {
+      "containers": [
+        {"name": "nginx", "image": "nginx:1.21"}
+      ]
+    }
+  }`)
+
+	expected := []byte(`{
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "metadata": {"name": "web"},
+    "spec": {
+      "containers": [
+        {"name": "nginx", "image": "nginx:latest", "imagePullPolicy": "Always"}
+      ]
+    }
+  }`)
+
+	var mutation kyvernov1.Mutation
+	assert.NilError(t, json.Unmarshal(rawMutation, &mutation))
+
+	var resource unstructured.Unstructured
+	assert.NilError(t, resource.UnmarshalJSON(rawResource))
+
+	handler := CreateMutateHandler("latest-image", &mutation, resource, context.NewContext(), log.Log, 0)
+	resp, patched := handler.Handle()
+	assert.Equal(t, resp.Status, response.RuleStatusPass, resp.Message)
+	assert.Equal(t, len(resp.Patches), 2)
+
+	patchedBytes, err := patched.MarshalJSON()
+	assert.NilError(t, err)
+	areEqualJSONs(t, expected, patchedBytes)
+
+	// the JSON patches of both strategic merge patches are combined in order
+	combined, err := utils.ApplyPatches(rawResource, resp.Patches)
+	assert.NilError(t, err)
+	areEqualJSONs(t, expected, combined)
+}
+
+func Test_ProcessStrategicMergePatches_Failure(t *testing.T) {
+	var resource unstructured.Unstructured
+	assert.NilError(t, resource.UnmarshalJSON([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}}`)))
+
+	patches := []interface{}{
+		map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"team": "platform"}}},
+		// cannot be marshaled
+		map[string]interface{}{"metadata": make(chan int)},
+	}
+
+	resp, patched := ProcessStrategicMergePatches("add-labels", patches, resource, log.Log)
+	assert.Equal(t, resp.Status, response.RuleStatusFail)
+	assert.Assert(t, strings.HasPrefix(resp.Message, "failed to apply patchStrategicMerge[1]: failed to process patchStrategicMerge"), resp.Message)
+	assert.Equal(t, len(resp.Patches), 0)
+	assert.DeepEqual(t, patched, resource)
+}
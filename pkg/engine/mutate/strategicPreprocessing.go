@@ -37,6 +37,12 @@ func NewGlobalConditionError(err error) error {
 // preProcessPattern - Dynamically preProcess the yaml
 // 1> For conditional anchor remove anchors from the pattern.
 // 2> For Adding anchors remove anchor tags.
+//
+// When a map has several anchors, they are evaluated in this order:
+// 1> Global anchors <(key): if one does not match, the whole patch is skipped.
+// 2> Conditional anchors (key): all of them must match. If one does not match, the element of
+//    a list of maps is left unchanged, and the whole patch is skipped for any other map.
+// 3> Adding anchors +(key): only applied once the conditions of the map have matched.
 
 // The whole yaml is structured as a pointer tree.
 // https://godoc.org/gopkg.in/yaml.v3#Node
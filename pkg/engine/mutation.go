@@ -15,7 +15,6 @@ import (
 	"github.com/kyverno/kyverno/pkg/engine/utils"
 	"github.com/kyverno/kyverno/pkg/engine/variables"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -36,7 +35,7 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 	ctx := policyContext.JSONContext
 
 	resCache := policyContext.ResourceCache
-	logger := log.Log.WithName("EngineMutate").WithValues("policy", policy.Name, "kind", patchedResource.GetKind(),
+	logger := policyContext.logger().WithName("EngineMutate").WithValues("policy", policy.Name, "kind", patchedResource.GetKind(),
 		"namespace", patchedResource.GetNamespace(), "name", patchedResource.GetName())
 
 	logger.V(4).Info("start policy processing", "startTime", startTime)
@@ -44,6 +43,11 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 	startMutateResultResponse(resp, policy, patchedResource)
 	defer endMutateResultResponse(logger, resp, startTime)
 
+	// the namespace is loaded before the checkpoint to be kept for the next policies of the request
+	if err := loadNamespace(logger, policyContext); err != nil {
+		logger.Error(err, "failed to load namespace in context")
+	}
+
 	policyContext.JSONContext.Checkpoint()
 	defer policyContext.JSONContext.Restore()
 
@@ -54,13 +58,18 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 			continue
 		}
 
+		if policyContext.Cancelled() {
+			logger.V(3).Info("skipping the remaining mutate rules, the evaluation of the request was cancelled")
+			break
+		}
+
 		logger := logger.WithValues("rule", rule.Name)
 		excludeResource := []string{}
 		if len(policyContext.ExcludeGroupRole) > 0 {
 			excludeResource = policyContext.ExcludeGroupRole
 		}
 
-		if err = MatchesResourceDescription(patchedResource, rule, policyContext.AdmissionInfo, excludeResource, policyContext.NamespaceLabels, policyContext.Policy.Namespace); err != nil {
+		if err = MatchesResourceDescription(patchedResource, rule, policyContext.AdmissionInfo, excludeResource, policyContext.NamespaceLabels, policyContext.Policy.Namespace, policyContext.SubResource); err != nil {
 			logger.V(4).Info("rule not matched", "reason", err.Error())
 			continue
 		}
@@ -88,6 +97,7 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 			continue
 		}
 
+		ruleStartTime := time.Now()
 		ruleCopy := rule.DeepCopy()
 		var ruleResp *response.RuleResponse
 		if rule.Mutation.ForEachMutation != nil {
@@ -98,7 +108,7 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 				if mutateResp.skip {
 					ruleResp = ruleResponse(&policy.Spec.Rules[i], utils.Mutation, err.Error(), response.RuleStatusSkip)
 				} else {
-					ruleResp = ruleResponse(&policy.Spec.Rules[i], utils.Mutation, err.Error(), response.RuleStatusError)
+					ruleResp = withRuleError(ruleResponse(&policy.Spec.Rules[i], utils.Mutation, err.Error(), response.RuleStatusError), ErrRuleEvaluation, err)
 				}
 			} else {
 				if mutateResp.message == "" {
@@ -112,6 +122,10 @@ func Mutate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 		}
 
 		if ruleResp != nil {
+			ruleResp.RuleStats.ProcessingTime = time.Since(ruleStartTime)
+			ruleResp.RuleStats.RuleExecutionTimestamp = ruleStartTime.Unix()
+			logSlowRule(logger, policyContext, ruleResp)
+
 			resp.PolicyResponse.Rules = append(resp.PolicyResponse.Rules, *ruleResp)
 			if ruleResp.Status == response.RuleStatusError {
 				incrementErrorCount(resp)
@@ -170,7 +184,7 @@ func mutateForEachResource(rule *kyverno.Rule, ctx *PolicyContext, resource unst
 			var skip = false
 			mutateResp, err := mutateResource(rule, ctx.JSONContext, patchedResource, logger, foreachIndex)
 			if err != nil && !skip {
-				return ruleResponse(rule, utils.Mutation, err.Error(), response.RuleStatusError), resource
+				return withRuleError(ruleResponse(rule, utils.Mutation, err.Error(), response.RuleStatusError), ErrRuleEvaluation, err), resource
 			}
 
 			patchedResource = mutateResp.patchedResource
@@ -240,6 +254,8 @@ func mutateResource(rule *kyverno.Rule, ctx *context.Context, resource unstructu
 		mutateResp.patches = resp.Patches
 		mutateResp.message = resp.Message
 		logger.V(4).Info("mutate rule applied successfully", "ruleName", rule.Name)
+	} else {
+		return mutateResp, fmt.Errorf("%w: %s", ErrPatchApply, resp.Message)
 	}
 
 	if err := ctx.AddResourceAsObject(patchedResource.Object); err != nil {
@@ -260,6 +276,9 @@ func startMutateResultResponse(resp *response.EngineResponse, policy kyverno.Clu
 	resp.PolicyResponse.Resource.Namespace = resource.GetNamespace()
 	resp.PolicyResponse.Resource.Kind = resource.GetKind()
 	resp.PolicyResponse.Resource.APIVersion = resource.GetAPIVersion()
+	if policy.Spec.FailurePolicy != nil {
+		resp.PolicyResponse.FailurePolicy = string(*policy.Spec.FailurePolicy)
+	}
 }
 
 func endMutateResultResponse(logger logr.Logger, resp *response.EngineResponse, startTime time.Time) {
@@ -269,5 +288,6 @@ func endMutateResultResponse(logger logr.Logger, resp *response.EngineResponse,
 
 	resp.PolicyResponse.ProcessingTime = time.Since(startTime)
 	resp.PolicyResponse.PolicyExecutionTimestamp = startTime.Unix()
+	setRuleErrorContext(resp)
 	logger.V(5).Info("finished processing policy", "processingTime", resp.PolicyResponse.ProcessingTime.String(), "mutationRulesApplied", resp.PolicyResponse.RulesAppliedCount)
 }
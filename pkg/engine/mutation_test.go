@@ -1,7 +1,9 @@
 package engine
 
 import (
+	gocontext "context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -12,7 +14,11 @@ import (
 	"github.com/kyverno/kyverno/pkg/engine/utils"
 	"github.com/kyverno/kyverno/pkg/kyverno/store"
 	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func Test_VariableSubstitutionOverlay(t *testing.T) {
@@ -769,3 +775,297 @@ func Test_foreach_element_mutation(t *testing.T) {
 		assert.Equal(t, securityContext["privileged"], false)
 	}
 }
+
+func Test_addIfAbsent_labels_annotations(t *testing.T) {
+	policyRaw := []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {
+    "name": "add-default-labels"
+  },
+  "spec": {
+    "rules": [
+      {
+        "name": "add-default-labels",
+        "match": {
+          "resources": {
+            "kinds": [
+              "Pod"
+            ]
+          }
+        },
+        "mutate": {
+          "patchStrategicMerge": {
+            "metadata": {
+              "labels": {
+                "+(environment)": "prod"
+              },
+              "annotations": {
+                "owner": "platform"
+              }
+            }
+          }
+        }
+      }
+    ]
+  }
+}`)
+
+	testCases := []struct {
+		name                string
+		metadata            string
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+		expectedReplace     bool
+	}{
+		{
+			name:                "absent",
+			metadata:            `{"name": "nginx"}`,
+			expectedLabels:      map[string]string{"environment": "prod"},
+			expectedAnnotations: map[string]string{"owner": "platform"},
+		},
+		{
+			name:                "present",
+			metadata:            `{"name": "nginx", "labels": {"environment": "dev", "app": "nginx"}, "annotations": {"owner": "platform"}}`,
+			expectedLabels:      map[string]string{"environment": "dev", "app": "nginx"},
+			expectedAnnotations: map[string]string{"owner": "platform"},
+		},
+		{
+			name:                "overwrite",
+			metadata:            `{"name": "nginx", "labels": {"app": "nginx"}, "annotations": {"owner": "team-a"}}`,
+			expectedLabels:      map[string]string{"environment": "prod", "app": "nginx"},
+			expectedAnnotations: map[string]string{"owner": "platform"},
+			expectedReplace:     true,
+		},
+	}
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": ` + tc.metadata + `, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`)
+			resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+			assert.NilError(t, err)
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddResource(resourceRaw))
+
+			er := Mutate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured})
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusPass)
+
+			assert.DeepEqual(t, er.PatchedResource.GetLabels(), tc.expectedLabels)
+			assert.DeepEqual(t, er.PatchedResource.GetAnnotations(), tc.expectedAnnotations)
+
+			var replaced bool
+			for _, patch := range er.PolicyResponse.Rules[0].Patches {
+				var op map[string]interface{}
+				assert.NilError(t, json.Unmarshal(patch, &op))
+				if op["op"] == "replace" {
+					replaced = true
+					assert.Equal(t, op["path"], "/metadata/annotations/owner")
+				}
+			}
+			assert.Equal(t, replaced, tc.expectedReplace)
+		})
+	}
+}
+
+func Test_Mutate_PatchApplyError(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "set-owner"},
+		"spec": {
+		  "failurePolicy": "Ignore",
+		  "rules": [
+			{
+			  "name": "set-owner-of-nginx",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "mutate": {
+				"patchesJson6902": "- op: test\n  path: /metadata/name\n  value: nginx\n- op: add\n  path: /metadata/labels/owner\n  value: web"
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "httpd", "namespace": "default", "labels": {"app": "httpd"}}, "spec": {"containers": [{"name": "httpd", "image": "httpd:2.4"}]}}`)
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+	resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddResource(resourceRaw))
+
+	er := Mutate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured})
+	assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusError)
+	assert.Equal(t, er.PolicyResponse.FailurePolicy, "Ignore")
+	assert.Assert(t, errors.Is(er.PolicyResponse.Rules[0].Error, ErrPatchApply))
+	assert.Assert(t, !errors.Is(er.PolicyResponse.Rules[0].Error, ErrRuleEvaluation))
+
+	ruleErr, ok := er.PolicyResponse.Rules[0].Error.(*RuleError)
+	assert.Assert(t, ok)
+	assert.Equal(t, ruleErr.Policy, "set-owner")
+	assert.Equal(t, ruleErr.Rule, "set-owner-of-nginx")
+	assert.Equal(t, ruleErr.Resource, "Pod/default/httpd")
+
+	// the resource is not changed by the failed rule
+	assert.DeepEqual(t, er.PatchedResource.GetLabels(), map[string]string{"app": "httpd"})
+}
+
+var namespaceLabelsPolicy = []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {
+    "name": "inject-sidecar"
+  },
+  "spec": {
+    "rules": [
+      {
+        "name": "inject-sidecar-annotation",
+        "match": {
+          "resources": {
+            "kinds": [
+              "Pod"
+            ]
+          }
+        },
+        "preconditions": [
+          {
+            "key": "{{ namespace.metadata.labels.\"istio-injection\" || '' }}",
+            "operator": "Equals",
+            "value": "enabled"
+          }
+        ],
+        "mutate": {
+          "patchStrategicMerge": {
+            "metadata": {
+              "annotations": {
+                "sidecar.istio.io/inject": "true"
+              }
+            }
+          }
+        }
+      }
+    ]
+  }
+}`)
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newNamespaceLister(t *testing.T, namespaces ...*corev1.Namespace) (listerv1.NamespaceLister, cache.Indexer) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, namespace := range namespaces {
+		assert.NilError(t, indexer.Add(namespace))
+	}
+
+	return listerv1.NewNamespaceLister(indexer), indexer
+}
+
+func newNamespacePolicyContext(t *testing.T, namespace string) *PolicyContext {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(namespaceLabelsPolicy, &policy))
+
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "` + namespace + `"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`)
+	resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddResource(resourceRaw))
+
+	return &PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured}
+}
+
+func Test_Mutate_NamespaceLabels(t *testing.T) {
+	testCases := []struct {
+		name                string
+		namespace           string
+		expectedStatus      response.RuleStatus
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "labeled namespace",
+			namespace:           "mesh",
+			expectedStatus:      response.RuleStatusPass,
+			expectedAnnotations: map[string]string{"sidecar.istio.io/inject": "true"},
+		},
+		{
+			name:           "disabled namespace",
+			namespace:      "legacy",
+			expectedStatus: response.RuleStatusSkip,
+		},
+		{
+			name:           "unlabeled namespace",
+			namespace:      "default",
+			expectedStatus: response.RuleStatusSkip,
+		},
+	}
+
+	lister, _ := newNamespaceLister(t,
+		newNamespace("mesh", map[string]string{"istio-injection": "enabled"}),
+		newNamespace("legacy", map[string]string{"istio-injection": "disabled"}),
+		newNamespace("default", map[string]string{"team": "platform"}),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policyContext := newNamespacePolicyContext(t, tc.namespace)
+			policyContext.NamespaceLister = lister
+
+			er := Mutate(policyContext)
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.expectedStatus)
+			assert.DeepEqual(t, er.PatchedResource.GetAnnotations(), tc.expectedAnnotations)
+		})
+	}
+}
+
+func Test_Mutate_NamespaceLabelsCachedPerRequest(t *testing.T) {
+	mesh := newNamespace("mesh", map[string]string{"istio-injection": "enabled"})
+	lister, indexer := newNamespaceLister(t, mesh)
+
+	policyContext := newNamespacePolicyContext(t, "mesh")
+	policyContext.NamespaceLister = lister
+
+	er := Mutate(policyContext)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusPass)
+
+	// the next policies of the request read the namespace from the context, not from the lister
+	assert.NilError(t, indexer.Delete(mesh))
+
+	er = Mutate(policyContext)
+	assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusPass)
+	assert.DeepEqual(t, er.PatchedResource.GetAnnotations(), map[string]string{"sidecar.istio.io/inject": "true"})
+}
+
+func Test_Mutate_NamespaceLabelsWithoutLister(t *testing.T) {
+	policyContext := newNamespacePolicyContext(t, "mesh")
+	policyContext.NamespaceLabels = map[string]string{"istio-injection": "enabled"}
+
+	er := Mutate(policyContext)
+	assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusPass)
+	assert.DeepEqual(t, er.PatchedResource.GetAnnotations(), map[string]string{"sidecar.istio.io/inject": "true"})
+}
+
+func Test_Mutate_CancelledRequest(t *testing.T) {
+	policyContext := newNamespacePolicyContext(t, "mesh")
+	policyContext.NamespaceLabels = map[string]string{"istio-injection": "enabled"}
+
+	requestContext, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	policyContext.RequestContext = requestContext
+
+	// the rules are skipped once the evaluation of the request is cancelled
+	er := Mutate(policyContext)
+	assert.Equal(t, len(er.PolicyResponse.Rules), 0)
+	assert.Assert(t, er.PatchedResource.GetAnnotations() == nil)
+}
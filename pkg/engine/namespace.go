@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// regexNamespaceVariable matches the variables that reference the namespace of the resource, e.g. {{namespace.metadata.labels}}
+var regexNamespaceVariable = regexp.MustCompile(`\{\{\s*namespace\.`)
+
+// loadNamespace adds the namespace of the resource under namespace in the JSON context,
+// if a rule of the policy references it.
+// The namespace is read from the namespace lister, or built from the namespace labels when
+// no lister is set, e.g. in the CLI. It is loaded once per JSON context, i.e. once per
+// admission request, and shared by all the policies applied to the request.
+func loadNamespace(logger logr.Logger, ctx *PolicyContext) error {
+	if ctx.JSONContext == nil || !referencesNamespace(ctx.Policy) {
+		return nil
+	}
+
+	resource := ctx.NewResource
+	if resource.Object == nil {
+		resource = ctx.OldResource
+	}
+
+	name := resource.GetNamespace()
+	if name == "" || resource.GetKind() == "Namespace" {
+		return nil
+	}
+
+	if loaded, err := ctx.JSONContext.Query("namespace.metadata.name"); err == nil && loaded == name {
+		logger.V(4).Info("namespace already loaded in context", "namespace", name)
+		return nil
+	}
+
+	namespace, err := getNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	logger.V(4).Info("loaded namespace in context", "namespace", name)
+	return ctx.JSONContext.AddNamespaceObject(namespace)
+}
+
+func getNamespace(ctx *PolicyContext, name string) (interface{}, error) {
+	if ctx.NamespaceLister == nil {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   name,
+				"labels": ctx.NamespaceLabels,
+			},
+		}, nil
+	}
+
+	namespace, err := ctx.NamespaceLister.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %v", name, err)
+	}
+
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(namespace)
+}
+
+// referencesNamespace returns true if a rule of the policy has a variable under namespace
+func referencesNamespace(policy kyverno.ClusterPolicy) bool {
+	rules, err := json.Marshal(policy.Spec.Rules)
+	if err != nil {
+		return false
+	}
+
+	return regexNamespaceVariable.Match(rules)
+}
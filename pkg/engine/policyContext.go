@@ -1,11 +1,17 @@
 package engine
 
 import (
+	gocontext "context"
+	"time"
+
+	"github.com/go-logr/logr"
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
 	client "github.com/kyverno/kyverno/pkg/dclient"
 	"github.com/kyverno/kyverno/pkg/engine/context"
 	"github.com/kyverno/kyverno/pkg/resourcecache"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // PolicyContext contains the contexts for engine to process
@@ -42,6 +48,31 @@ type PolicyContext struct {
 
 	// NamespaceLabels stores the label of namespace to be processed by namespace selector
 	NamespaceLabels map[string]string
+
+	// NamespaceLister gets the namespace of the resource for the namespace variables,
+	// the namespace is built from NamespaceLabels if it is nil
+	NamespaceLister listerv1.NamespaceLister
+
+	// SubResource is the subresource of the admission request with its parent resource, e.g. pods/exec
+	SubResource string
+
+	// SlowRuleThreshold is the processing time above which a mutate or validate rule is logged as slow,
+	// the slow rules are not logged if it is zero
+	SlowRuleThreshold time.Duration
+
+	// ExcludedUsername is the username whose requests are not validated, e.g. the Kyverno service account,
+	// all the requests are validated if it is empty
+	ExcludedUsername string
+
+	// RequestContext is cancelled when the evaluation of the admission request is abandoned, e.g. on the
+	// engine timeout of the webhook, the remaining rules are then skipped. It is never cancelled if nil.
+	RequestContext gocontext.Context
+
+	// Log is the logger of the mutate and validate rules, log.Log is used if it is nil
+	Log logr.Logger
+
+	// resourceQuotas caches the ResourceQuotas fetched for the resourceQuota context entries
+	resourceQuotas *resourceQuotaCache
 }
 
 func (pc *PolicyContext) Copy() *PolicyContext {
@@ -56,5 +87,34 @@ func (pc *PolicyContext) Copy() *PolicyContext {
 		ResourceCache:       pc.ResourceCache,
 		JSONContext:         pc.JSONContext,
 		NamespaceLabels:     pc.NamespaceLabels,
+		NamespaceLister:     pc.NamespaceLister,
+		SubResource:         pc.SubResource,
+		SlowRuleThreshold:   pc.SlowRuleThreshold,
+		ExcludedUsername:    pc.ExcludedUsername,
+		RequestContext:      pc.RequestContext,
+		Log:                 pc.Log,
+		resourceQuotas:      pc.resourceQuotaCache(),
+	}
+}
+
+// logger returns the logger of the context, or log.Log if it is not set
+func (pc *PolicyContext) logger() logr.Logger {
+	if pc.Log == nil {
+		return log.Log
+	}
+	return pc.Log
+}
+
+// Cancelled returns true if the evaluation of the admission request was abandoned
+func (pc *PolicyContext) Cancelled() bool {
+	return pc.RequestContext != nil && pc.RequestContext.Err() != nil
+}
+
+// resourceQuotaCache returns the ResourceQuota cache of the context, shared by its copies,
+// the context is used for a single admission request
+func (pc *PolicyContext) resourceQuotaCache() *resourceQuotaCache {
+	if pc.resourceQuotas == nil {
+		pc.resourceQuotas = &resourceQuotaCache{}
 	}
+	return pc.resourceQuotas
 }
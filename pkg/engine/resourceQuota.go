@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	client "github.com/kyverno/kyverno/pkg/dclient"
+	"github.com/kyverno/kyverno/pkg/engine/variables"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// resourceQuotaCache caches the ResourceQuotas by namespace, so that the quotas of a namespace
+// are listed once for all the rules of an admission request
+type resourceQuotaCache struct {
+	sync.Mutex
+	quotas map[string][]unstructured.Unstructured
+}
+
+func (c *resourceQuotaCache) list(kclient *client.Client, namespace string) ([]unstructured.Unstructured, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if quotas, ok := c.quotas[namespace]; ok {
+		return quotas, nil
+	}
+
+	l, err := kclient.ListResource("v1", "ResourceQuota", namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.quotas == nil {
+		c.quotas = make(map[string][]unstructured.Unstructured)
+	}
+	c.quotas[namespace] = l.Items
+	return l.Items, nil
+}
+
+func loadResourceQuota(logger logr.Logger, entry kyverno.ContextEntry, ctx *PolicyContext) error {
+	data, err := fetchResourceQuota(logger, entry, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve resource quota for context entry %s: %v", entry.Name, err)
+	}
+
+	if err := ctx.JSONContext.AddJSON(data); err != nil {
+		return fmt.Errorf("failed to add resource quota for context entry %s: %v", entry.Name, err)
+	}
+
+	return nil
+}
+
+func fetchResourceQuota(logger logr.Logger, entry kyverno.ContextEntry, ctx *PolicyContext) ([]byte, error) {
+	name, err := variables.SubstituteAll(logger, ctx.JSONContext, entry.ResourceQuota.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute variables in context %s resourceQuota.name %s: %v", entry.Name, entry.ResourceQuota.Name, err)
+	}
+
+	namespace, err := variables.SubstituteAll(logger, ctx.JSONContext, entry.ResourceQuota.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute variables in context %s resourceQuota.namespace %s: %v", entry.Name, entry.ResourceQuota.Namespace, err)
+	}
+
+	ns := fmt.Sprint(namespace)
+	if ns == "" {
+		ns = ctx.NewResource.GetNamespace()
+	}
+	if ns == "" {
+		ns = ctx.OldResource.GetNamespace()
+	}
+	if ns == "" {
+		return nil, fmt.Errorf("a namespace is required for the resourceQuota of a cluster-scoped resource")
+	}
+
+	if ctx.Client == nil {
+		return nil, fmt.Errorf("API client is not available")
+	}
+
+	quotas, err := ctx.resourceQuotaCache().list(ctx.Client, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the resource quotas of namespace %s: %v", ns, err)
+	}
+
+	if quotaName := fmt.Sprint(name); quotaName != "" {
+		var named []unstructured.Unstructured
+		for _, quota := range quotas {
+			if quota.GetName() == quotaName {
+				named = append(named, quota)
+			}
+		}
+		quotas = named
+	}
+
+	contextData, err := resourceQuotaContext(quotas)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(4).Info("loaded resource quota context entry", "name", entry.Name, "namespace", ns, "quotas", contextData["names"])
+	return json.Marshal(map[string]interface{}{entry.Name: contextData})
+}
+
+// resourceQuotaContext returns the context data of the quotas. For each quota resource, e.g. requests.storage,
+// it contains the hard limit and the used quantity of the quota with the lowest remaining quantity.
+// If there is no quota, exists is false and the quantities are empty, the resources are not limited.
+func resourceQuotaContext(quotas []unstructured.Unstructured) (map[string]interface{}, error) {
+	names := make([]interface{}, 0, len(quotas))
+	hard := make(map[string]interface{})
+	used := make(map[string]interface{})
+	remaining := make(map[string]interface{})
+	lowest := make(map[string]resource.Quantity)
+
+	for _, quota := range quotas {
+		names = append(names, quota.GetName())
+
+		// the status is set by the quota controller, the spec is used until it is synced
+		quotaHard, found, err := unstructured.NestedStringMap(quota.Object, "status", "hard")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the hard limits of resource quota %s: %v", quota.GetName(), err)
+		}
+		if !found {
+			if quotaHard, _, err = unstructured.NestedStringMap(quota.Object, "spec", "hard"); err != nil {
+				return nil, fmt.Errorf("failed to read the hard limits of resource quota %s: %v", quota.GetName(), err)
+			}
+		}
+
+		quotaUsed, _, err := unstructured.NestedStringMap(quota.Object, "status", "used")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the used quantities of resource quota %s: %v", quota.GetName(), err)
+		}
+
+		for res, h := range quotaHard {
+			hardQuantity, err := resource.ParseQuantity(h)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hard limit %s of %s in resource quota %s: %v", h, res, quota.GetName(), err)
+			}
+
+			usedQuantity := resource.Quantity{}
+			if u, ok := quotaUsed[res]; ok {
+				if usedQuantity, err = resource.ParseQuantity(u); err != nil {
+					return nil, fmt.Errorf("invalid used quantity %s of %s in resource quota %s: %v", u, res, quota.GetName(), err)
+				}
+			}
+
+			remainingQuantity := hardQuantity.DeepCopy()
+			remainingQuantity.Sub(usedQuantity)
+			if current, ok := lowest[res]; ok && current.Cmp(remainingQuantity) <= 0 {
+				continue
+			}
+
+			lowest[res] = remainingQuantity
+			hard[res] = hardQuantity.String()
+			used[res] = usedQuantity.String()
+			remaining[res] = remainingQuantity.String()
+		}
+	}
+
+	return map[string]interface{}{
+		"exists":    len(quotas) > 0,
+		"names":     names,
+		"hard":      hard,
+		"used":      used,
+		"remaining": remaining,
+	}, nil
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	client "github.com/kyverno/kyverno/pkg/dclient"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/engine/utils"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var pvcQuotaPolicy = []byte(`{
+	"apiVersion": "kyverno.io/v1",
+	"kind": "ClusterPolicy",
+	"metadata": {"name": "pvc-fits-quota"},
+	"spec": {
+		"validationFailureAction": "enforce",
+		"rules": [
+			{
+				"name": "pvc-fits-quota",
+				"match": {"resources": {"kinds": ["PersistentVolumeClaim"]}},
+				"context": [{"name": "quota", "resourceQuota": {}}],
+				"preconditions": {"all": [{"key": "{{ quota.exists }}", "operator": "Equals", "value": true}]},
+				"validate": {
+					"message": "the claim of {{ request.object.spec.resources.requests.storage }} exceeds the remaining storage quota",
+					"deny": {
+						"conditions": {
+							"any": [
+								{
+									"key": "{{ request.object.spec.resources.requests.storage }}",
+									"operator": "GreaterThan",
+									"value": "{{ quota.remaining.\"requests.storage\" }}"
+								}
+							]
+						}
+					}
+				}
+			}
+		]
+	}
+}`)
+
+func newResourceQuota(namespace, name string, spec, hard, used map[string]interface{}) *unstructured.Unstructured {
+	quota := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ResourceQuota",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       map[string]interface{}{"hard": spec},
+	}}
+	if hard != nil {
+		quota.Object["status"] = map[string]interface{}{"hard": hard, "used": used}
+	}
+	return quota
+}
+
+func newResourceQuotaClient(t *testing.T, objects ...runtime.Object) *client.Client {
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "resourcequotas"}: "ResourceQuotaList",
+	}
+
+	c, err := client.NewMockClient(runtime.NewScheme(), gvrToListKind, objects...)
+	assert.NilError(t, err)
+	c.SetDiscovery(client.NewFakeDiscoveryClient(nil))
+	return c
+}
+
+func newPVCPolicyContext(t *testing.T, c *client.Client, namespace, storage string) *PolicyContext {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(pvcQuotaPolicy, &policy))
+
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "data", "namespace": "` + namespace + `"},
+		"spec": {"accessModes": ["ReadWriteOnce"], "resources": {"requests": {"storage": "` + storage + `"}}}}`)
+	resource, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddResource(resourceRaw))
+	return &PolicyContext{Policy: policy, NewResource: *resource, JSONContext: ctx, Client: c}
+}
+
+func Test_Validate_ResourceQuota(t *testing.T) {
+	c := newResourceQuotaClient(t,
+		newResourceQuota("team-a", "storage",
+			map[string]interface{}{"requests.storage": "10Gi"},
+			map[string]interface{}{"requests.storage": "10Gi"},
+			map[string]interface{}{"requests.storage": "7Gi"}),
+		newResourceQuota("team-a", "compute",
+			map[string]interface{}{"requests.storage": "50Gi", "requests.cpu": "4"},
+			map[string]interface{}{"requests.storage": "50Gi", "requests.cpu": "4"},
+			map[string]interface{}{"requests.storage": "7Gi", "requests.cpu": "1"}),
+	)
+
+	testCases := []struct {
+		name      string
+		namespace string
+		storage   string
+		status    response.RuleStatus
+		message   string
+	}{
+		{name: "claim within the remaining quota", namespace: "team-a", storage: "2Gi", status: response.RuleStatusPass},
+		{name: "claim of the remaining quota", namespace: "team-a", storage: "3Gi", status: response.RuleStatusPass},
+		{name: "claim above the lowest remaining quota", namespace: "team-a", storage: "5Gi", status: response.RuleStatusFail,
+			message: "the claim of 5Gi exceeds the remaining storage quota"},
+		{name: "namespace without quota", namespace: "team-b", storage: "500Gi", status: response.RuleStatusSkip},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			er := Validate(newPVCPolicyContext(t, c, tc.namespace, tc.storage))
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status, er.PolicyResponse.Rules[0].Message)
+			if tc.message != "" {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Message, tc.message)
+			}
+		})
+	}
+}
+
+func Test_Validate_ResourceQuota_CachedPerRequest(t *testing.T) {
+	c := newResourceQuotaClient(t, newResourceQuota("team-a", "storage",
+		map[string]interface{}{"requests.storage": "10Gi"},
+		map[string]interface{}{"requests.storage": "10Gi"},
+		map[string]interface{}{"requests.storage": "7Gi"}))
+
+	policyContext := newPVCPolicyContext(t, c, "team-a", "5Gi")
+	er := Validate(policyContext)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusFail)
+
+	// the quotas listed for the request are used by the next policies of the request
+	assert.NilError(t, c.DeleteResource("v1", "ResourceQuota", "team-a", "storage", false))
+	er = Validate(policyContext)
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusFail)
+
+	er = Validate(newPVCPolicyContext(t, c, "team-a", "5Gi"))
+	assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusSkip)
+}
+
+func Test_resourceQuotaContext(t *testing.T) {
+	data, err := resourceQuotaContext(nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data, map[string]interface{}{
+		"exists":    false,
+		"names":     []interface{}{},
+		"hard":      map[string]interface{}{},
+		"used":      map[string]interface{}{},
+		"remaining": map[string]interface{}{},
+	})
+
+	data, err = resourceQuotaContext([]unstructured.Unstructured{
+		*newResourceQuota("team-a", "storage",
+			map[string]interface{}{"requests.storage": "10Gi", "persistentvolumeclaims": "5"},
+			map[string]interface{}{"requests.storage": "10Gi", "persistentvolumeclaims": "5"},
+			map[string]interface{}{"requests.storage": "8Gi", "persistentvolumeclaims": "1"}),
+		// not synced by the quota controller yet
+		*newResourceQuota("team-a", "claims", map[string]interface{}{"persistentvolumeclaims": "2"}, nil, nil),
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data, map[string]interface{}{
+		"exists":    true,
+		"names":     []interface{}{"storage", "claims"},
+		"hard":      map[string]interface{}{"requests.storage": "10Gi", "persistentvolumeclaims": "2"},
+		"used":      map[string]interface{}{"requests.storage": "8Gi", "persistentvolumeclaims": "0"},
+		"remaining": map[string]interface{}{"requests.storage": "2Gi", "persistentvolumeclaims": "2"},
+	})
+
+	_, err = resourceQuotaContext([]unstructured.Unstructured{
+		*newResourceQuota("team-a", "invalid", map[string]interface{}{"requests.storage": "ten"}, nil, nil),
+	})
+	assert.ErrorContains(t, err, "invalid hard limit ten of requests.storage in resource quota invalid")
+}
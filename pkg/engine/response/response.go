@@ -27,6 +27,8 @@ type PolicyResponse struct {
 	Rules []RuleResponse `json:"rules"`
 	// ValidationFailureAction: audit (default) or enforce
 	ValidationFailureAction string
+	// FailurePolicy: Fail (default) or Ignore
+	FailurePolicy string
 }
 
 //PolicySpec policy
@@ -86,6 +88,9 @@ type RuleResponse struct {
 	// rule status
 	Status RuleStatus `json:"status"`
 
+	// Error is the error of the rule evaluation, for the error status
+	Error error `json:"-" yaml:"-"`
+
 	// statistics
 	RuleStats `json:",inline"`
 }
@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+)
+
+// logSlowRule logs a warning if the processing time of the rule exceeds the slow rule threshold
+// of the policy context, and returns true if the rule is slow. It is a no-op when the threshold is not set.
+// The processing times of all the rules are exposed by the kyverno_policy_execution_duration_seconds metric.
+func logSlowRule(log logr.Logger, ctx *PolicyContext, ruleResp *response.RuleResponse) bool {
+	if ctx.SlowRuleThreshold <= 0 || ruleResp.RuleStats.ProcessingTime <= ctx.SlowRuleThreshold {
+		return false
+	}
+
+	log.Info("WARNING: slow rule, the rule processing time exceeds the slow rule threshold", "rule", ruleResp.Name,
+		"processingTime", ruleResp.RuleStats.ProcessingTime.String(), "threshold", ctx.SlowRuleThreshold.String())
+	return true
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/engine/utils"
+	utils2 "github.com/kyverno/kyverno/pkg/utils"
+	"gotest.tools/assert"
+)
+
+// recordingLogger records the messages of the Info calls
+type recordingLogger struct {
+	logr.DiscardLogger
+	messages *[]string
+}
+
+func (l recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	*l.messages = append(*l.messages, msg)
+}
+
+func (l recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return l
+}
+
+func (l recordingLogger) WithName(name string) logr.Logger {
+	return l
+}
+
+func (l recordingLogger) V(level int) logr.Logger {
+	return l
+}
+
+func Test_logSlowRule(t *testing.T) {
+	var messages []string
+	logger := recordingLogger{messages: &messages}
+	ruleResp := &response.RuleResponse{Name: "slow-rule", RuleStats: response.RuleStats{ProcessingTime: 2 * time.Second}}
+
+	// disabled
+	assert.Assert(t, !logSlowRule(logger, &PolicyContext{}, ruleResp))
+	assert.Equal(t, len(messages), 0)
+
+	// below the threshold
+	assert.Assert(t, !logSlowRule(logger, &PolicyContext{SlowRuleThreshold: 5 * time.Second}, ruleResp))
+	assert.Equal(t, len(messages), 0)
+
+	assert.Assert(t, logSlowRule(logger, &PolicyContext{SlowRuleThreshold: time.Second}, ruleResp))
+	assert.DeepEqual(t, messages, []string{"WARNING: slow rule, the rule processing time exceeds the slow rule threshold"})
+}
+
+func Test_Mutate_SlowRule(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "add-team-label"},
+		"spec": {
+			"rules": [
+				{
+					"name": "add-team-label",
+					"match": {"resources": {"kinds": ["Pod"]}},
+					"mutate": {"patchStrategicMerge": {"metadata": {"labels": {"+(team)": "platform"}}}}
+				}
+			]
+		}
+	}`)
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx"}}`)
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+	resource, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddResourceAsObject(resource.Object))
+
+	// every rule takes longer than a nanosecond
+	var messages []string
+	policyContext := &PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resource, SlowRuleThreshold: time.Nanosecond, Log: recordingLogger{messages: &messages}}
+	er := Mutate(policyContext)
+	assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+
+	rule := er.PolicyResponse.Rules[0]
+	assert.Assert(t, rule.RuleStats.ProcessingTime > 0)
+	assert.Assert(t, rule.RuleStats.RuleExecutionTimestamp > 0)
+	assert.Assert(t, utils2.ContainsString(messages, "WARNING: slow rule, the rule processing time exceeds the slow rule threshold"), "messages: %v", messages)
+}
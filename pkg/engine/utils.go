@@ -2,12 +2,14 @@ package engine
 
 import (
 	"fmt"
+	"path"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	pkgcommon "github.com/kyverno/kyverno/pkg/common"
 	"github.com/kyverno/kyverno/pkg/engine/context"
 	"github.com/kyverno/kyverno/pkg/engine/response"
 	engineUtils "github.com/kyverno/kyverno/pkg/engine/utils"
@@ -20,9 +22,11 @@ import (
 	authenticationv1 "k8s.io/api/authentication/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -34,8 +38,23 @@ type EngineStats struct {
 	RulesAppliedCount int
 }
 
-func checkKind(kinds []string, resource unstructured.Unstructured) bool {
-	for _, kind := range kinds {
+// checkKind returns true if the resource matches one of the kinds. A kind in the Kind/subresource format,
+// e.g. Pod/exec, only matches the admission requests of that subresource of the kind. The other formats match
+// the object of the kind, also in the subresources sent with the object of the parent kind, e.g. pods/status.
+// subresource is the subresource of the admission request with its parent resource, e.g. deployments/scale.
+// An entry with comma separated kinds matches each of the kinds.
+func checkKind(kinds []string, resource unstructured.Unstructured, subresource string) bool {
+	parentResource, requestSubresource := splitRequestSubresource(subresource)
+	for _, kind := range kyverno.ExpandKinds(kinds) {
+		if k, s := pkgcommon.SplitSubresource(kind); s != "" {
+			k = strings.Title(k)
+			if s == requestSubresource && isParentResource(k, parentResource) &&
+				(resource.GetKind() == k || resource.GetKind() == pkgcommon.GetSubresourceKind(k, s)) {
+				return true
+			}
+			continue
+		}
+
 		SplitGVK := strings.Split(kind, "/")
 		if len(SplitGVK) == 1 {
 			if resource.GetKind() == strings.Title(kind) || kind == "*" {
@@ -55,8 +74,52 @@ func checkKind(kinds []string, resource unstructured.Unstructured) bool {
 	return false
 }
 
+// splitRequestSubresource splits the subresource of an admission request, e.g. deployments/scale,
+// into the parent resource and the subresource
+func splitRequestSubresource(subresource string) (parentResource string, name string) {
+	split := strings.SplitN(subresource, "/", 2)
+	if len(split) == 1 {
+		return "", subresource
+	}
+	return split[0], split[1]
+}
+
+// isParentResource returns true if resource is the resource of the kind, e.g. deployments for Deployment.
+// The Scale object of the scale subresources does not reference its parent, the resource of the request is
+// compared instead. An empty resource is not checked.
+func isParentResource(kind, resource string) bool {
+	if resource == "" {
+		return true
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(schema.GroupVersionKind{Kind: kind})
+	return gvr.Resource == resource
+}
+
+// checkName returns true if the resource name matches the glob pattern of the name. The pattern
+// is anchored, it must match the whole resource name. A name without glob characters is compared
+// as is, and a malformed pattern, e.g. an unclosed "[", falls back to the "*" and "?" wildcards.
 func checkName(name, resourceName string) bool {
-	return wildcard.Match(name, resourceName)
+	if !strings.ContainsAny(name, "*?[\\") {
+		return name == resourceName
+	}
+
+	matched, err := path.Match(name, resourceName)
+	if err != nil {
+		return wildcard.Match(name, resourceName)
+	}
+
+	return matched
+}
+
+// matchedName returns the name of the resource that is matched by the name patterns. The name takes precedence,
+// the generateName is used only if the name is empty, e.g. for a Pod created by a controller before the API
+// server generates its name.
+func matchedName(resource unstructured.Unstructured) string {
+	if name := resource.GetName(); name != "" {
+		return name
+	}
+
+	return resource.GetGenerateName()
 }
 
 func checkNameSpace(namespaces []string, resource unstructured.Unstructured) bool {
@@ -96,6 +159,40 @@ func checkAnnotations(annotations map[string]string, resourceAnnotations map[str
 	return true
 }
 
+// checkAnnotationSelector evaluates the selector against the resource annotations. The label
+// selector conversion is not used as it rejects annotation values that are not valid label values.
+func checkAnnotationSelector(annotationSelector *metav1.LabelSelector, resourceAnnotations map[string]string) (bool, error) {
+	if !checkAnnotations(annotationSelector.MatchLabels, resourceAnnotations) {
+		return false, nil
+	}
+
+	for _, expression := range annotationSelector.MatchExpressions {
+		value, found := resourceAnnotations[expression.Key]
+		switch expression.Operator {
+		case metav1.LabelSelectorOpIn:
+			if !found || !utils.ContainsString(expression.Values, value) {
+				return false, nil
+			}
+		case metav1.LabelSelectorOpNotIn:
+			if found && utils.ContainsString(expression.Values, value) {
+				return false, nil
+			}
+		case metav1.LabelSelectorOpExists:
+			if !found {
+				return false, nil
+			}
+		case metav1.LabelSelectorOpDoesNotExist:
+			if found {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("%q is not a valid annotation selector operator", expression.Operator)
+		}
+	}
+
+	return true, nil
+}
+
 func checkSelector(labelSelector *metav1.LabelSelector, resourceLabels map[string]string) (bool, error) {
 	wildcards.ReplaceInSelector(labelSelector, resourceLabels)
 	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
@@ -118,6 +215,7 @@ func checkSelector(labelSelector *metav1.LabelSelector, resourceLabels map[strin
 // 		Name       string
 // 		Namespaces []string
 // 		Selector
+// 		AnnotationSelector
 // UserInfo:
 // 		Roles        []string
 // 		ClusterRoles []string
@@ -126,17 +224,18 @@ func checkSelector(labelSelector *metav1.LabelSelector, resourceLabels map[strin
 // should be: AND across attributes but an OR inside attributes that of type list
 // To filter out the targeted resources with UserInfo, the check
 // should be: OR (across & inside) attributes
-func doesResourceMatchConditionBlock(conditionBlock kyverno.ResourceDescription, userInfo kyverno.UserInfo, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string) []error {
+func doesResourceMatchConditionBlock(conditionBlock kyverno.ResourceDescription, userInfo kyverno.UserInfo, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string, subresource string) []error {
 	var errs []error
 
 	if len(conditionBlock.Kinds) > 0 {
-		if !checkKind(conditionBlock.Kinds, resource) {
+		if !checkKind(conditionBlock.Kinds, resource, subresource) {
 			errs = append(errs, fmt.Errorf("kind does not match %v", conditionBlock.Kinds))
 		}
 	}
 
+	resourceName := matchedName(resource)
 	if conditionBlock.Name != "" {
-		if !checkName(conditionBlock.Name, resource.GetName()) {
+		if !checkName(conditionBlock.Name, resourceName) {
 			errs = append(errs, fmt.Errorf("name does not match"))
 		}
 	}
@@ -144,7 +243,7 @@ func doesResourceMatchConditionBlock(conditionBlock kyverno.ResourceDescription,
 	if len(conditionBlock.Names) > 0 {
 		noneMatch := true
 		for i := range conditionBlock.Names {
-			if checkName(conditionBlock.Names[i], resource.GetName()) {
+			if checkName(conditionBlock.Names[i], resourceName) {
 				noneMatch = false
 				break
 			}
@@ -177,6 +276,17 @@ func doesResourceMatchConditionBlock(conditionBlock kyverno.ResourceDescription,
 		}
 	}
 
+	if conditionBlock.AnnotationSelector != nil {
+		hasPassed, err := checkAnnotationSelector(conditionBlock.AnnotationSelector, resource.GetAnnotations())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse annotation selector: %v", err))
+		} else {
+			if !hasPassed {
+				errs = append(errs, fmt.Errorf("annotation selector does not match"))
+			}
+		}
+	}
+
 	if conditionBlock.NamespaceSelector != nil && resource.GetKind() != "Namespace" && resource.GetKind() != "" {
 		hasPassed, err := checkSelector(conditionBlock.NamespaceSelector, namespaceLabels)
 		if err != nil {
@@ -261,8 +371,9 @@ func matchSubjects(ruleSubjects []rbacv1.Subject, userInfo authenticationv1.User
 	return false
 }
 
-//MatchesResourceDescription checks if the resource matches resource description of the rule or not
-func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef kyverno.Rule, admissionInfoRef kyverno.RequestInfo, dynamicConfig []string, namespaceLabels map[string]string, policyNamespace string) error {
+//MatchesResourceDescription checks if the resource matches resource description of the rule or not,
+// subresource is the subresource of the admission request with its parent resource, e.g. pods/exec
+func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef kyverno.Rule, admissionInfoRef kyverno.RequestInfo, dynamicConfig []string, namespaceLabels map[string]string, policyNamespace string, subresource string) error {
 
 	rule := ruleRef.DeepCopy()
 	resource := *resourceRef.DeepCopy()
@@ -278,7 +389,7 @@ func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef k
 		oneMatched := false
 		for _, rmr := range rule.MatchResources.Any {
 			// if there are no errors it means it was a match
-			if len(matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels)) == 0 {
+			if len(matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)) == 0 {
 				oneMatched = true
 				break
 			}
@@ -289,17 +400,17 @@ func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef k
 	} else if len(rule.MatchResources.All) > 0 {
 		// include object if ALL of the criterias match
 		for _, rmr := range rule.MatchResources.All {
-			reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels)...)
+			reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)...)
 		}
 	} else {
 		rmr := kyverno.ResourceFilter{UserInfo: rule.MatchResources.UserInfo, ResourceDescription: rule.MatchResources.ResourceDescription}
-		reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels)...)
+		reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionMatchHelper(rmr, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)...)
 	}
 
 	if len(rule.ExcludeResources.Any) > 0 {
 		// exclude the object if ANY of the criterias match
 		for _, rer := range rule.ExcludeResources.Any {
-			reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels)...)
+			reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)...)
 		}
 	} else if len(rule.ExcludeResources.All) > 0 {
 		// exlcude the object if ALL the criterias match
@@ -307,7 +418,7 @@ func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef k
 		for _, rer := range rule.ExcludeResources.All {
 			// we got no errors inplying a resource did NOT exclude it
 			// "matchesResourceDescriptionExcludeHelper" returns errors if resource is excluded by a filter
-			if len(matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels)) == 0 {
+			if len(matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)) == 0 {
 				excludedByAll = false
 				break
 			}
@@ -317,7 +428,7 @@ func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef k
 		}
 	} else {
 		rer := kyverno.ResourceFilter{UserInfo: rule.ExcludeResources.UserInfo, ResourceDescription: rule.ExcludeResources.ResourceDescription}
-		reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels)...)
+		reasonsForFailure = append(reasonsForFailure, matchesResourceDescriptionExcludeHelper(rer, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)...)
 	}
 
 	// creating final error
@@ -335,7 +446,7 @@ func MatchesResourceDescription(resourceRef unstructured.Unstructured, ruleRef k
 	return nil
 }
 
-func matchesResourceDescriptionMatchHelper(rmr kyverno.ResourceFilter, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string) []error {
+func matchesResourceDescriptionMatchHelper(rmr kyverno.ResourceFilter, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string, subresource string) []error {
 	var errs []error
 	if reflect.DeepEqual(admissionInfo, kyverno.RequestInfo{}) {
 		rmr.UserInfo = kyverno.UserInfo{}
@@ -344,7 +455,7 @@ func matchesResourceDescriptionMatchHelper(rmr kyverno.ResourceFilter, admission
 	// checking if resource matches the rule
 	if !reflect.DeepEqual(rmr.ResourceDescription, kyverno.ResourceDescription{}) ||
 		!reflect.DeepEqual(rmr.UserInfo, kyverno.UserInfo{}) {
-		matchErrs := doesResourceMatchConditionBlock(rmr.ResourceDescription, rmr.UserInfo, admissionInfo, resource, dynamicConfig, namespaceLabels)
+		matchErrs := doesResourceMatchConditionBlock(rmr.ResourceDescription, rmr.UserInfo, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)
 		errs = append(errs, matchErrs...)
 	} else {
 		errs = append(errs, fmt.Errorf("match cannot be empty"))
@@ -352,12 +463,12 @@ func matchesResourceDescriptionMatchHelper(rmr kyverno.ResourceFilter, admission
 	return errs
 }
 
-func matchesResourceDescriptionExcludeHelper(rer kyverno.ResourceFilter, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string) []error {
+func matchesResourceDescriptionExcludeHelper(rer kyverno.ResourceFilter, admissionInfo kyverno.RequestInfo, resource unstructured.Unstructured, dynamicConfig []string, namespaceLabels map[string]string, subresource string) []error {
 	var errs []error
 	// checking if resource matches the rule
 	if !reflect.DeepEqual(rer.ResourceDescription, kyverno.ResourceDescription{}) ||
 		!reflect.DeepEqual(rer.UserInfo, kyverno.UserInfo{}) {
-		excludeErrs := doesResourceMatchConditionBlock(rer.ResourceDescription, rer.UserInfo, admissionInfo, resource, dynamicConfig, namespaceLabels)
+		excludeErrs := doesResourceMatchConditionBlock(rer.ResourceDescription, rer.UserInfo, admissionInfo, resource, dynamicConfig, namespaceLabels, subresource)
 		// it was a match so we want to exclude it
 		if len(excludeErrs) == 0 {
 			errs = append(errs, fmt.Errorf("resource excluded since one of the criterias excluded it"))
@@ -473,7 +584,7 @@ func evaluateList(jmesPath string, ctx context.EvalInterface) ([]interface{}, er
 
 func ruleError(rule *kyverno.Rule, ruleType engineUtils.RuleType, msg string, err error) *response.RuleResponse {
 	msg = fmt.Sprintf("%s: %s", msg, err.Error())
-	return ruleResponse(rule, ruleType, msg, response.RuleStatusError)
+	return withRuleError(ruleResponse(rule, ruleType, msg, response.RuleStatusError), ErrRuleEvaluation, err)
 }
 
 func ruleResponse(rule *kyverno.Rule, ruleType engineUtils.RuleType, msg string, status response.RuleStatus) *response.RuleResponse {
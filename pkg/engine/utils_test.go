@@ -7,7 +7,10 @@ import (
 	v1 "github.com/kyverno/kyverno/api/kyverno/v1"
 	"github.com/kyverno/kyverno/pkg/engine/utils"
 	"gotest.tools/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestMatchesResourceDescription(t *testing.T) {
@@ -898,7 +901,7 @@ func TestMatchesResourceDescription(t *testing.T) {
 		resource, _ := utils.ConvertToUnstructured(tc.Resource)
 
 		for _, rule := range policy.Spec.Rules {
-			err := MatchesResourceDescription(*resource, rule, tc.AdmissionInfo, []string{}, nil, "")
+			err := MatchesResourceDescription(*resource, rule, tc.AdmissionInfo, []string{}, nil, "", "")
 			if err != nil {
 				if !tc.areErrorsExpected {
 					t.Errorf("Testcase %d Unexpected error: %v", i+1, err)
@@ -966,12 +969,50 @@ func TestResourceDescriptionMatch_MultipleKind(t *testing.T) {
 	}
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err != nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err != nil {
 		t.Errorf("Testcase has failed due to the following:%v", err)
 	}
 
 }
 
+func TestCheckKind_MultipleKindsAndWildcard(t *testing.T) {
+	deployment := unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+
+	testCases := []struct {
+		name     string
+		kinds    []string
+		expected bool
+	}{
+		{name: "comma separated kinds", kinds: []string{"Deployment,StatefulSet,DaemonSet"}, expected: true},
+		{name: "comma separated kinds with spaces", kinds: []string{"StatefulSet, Deployment"}, expected: true},
+		{name: "comma separated group version kinds", kinds: []string{"apps/v1/StatefulSet,apps/v1/Deployment"}, expected: true},
+		{name: "comma separated kinds of other resources", kinds: []string{"StatefulSet,DaemonSet"}, expected: false},
+		{name: "wildcard", kinds: []string{"*"}, expected: true},
+		{name: "kind not in the cluster", kinds: []string{"Widget"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, checkKind(tc.kinds, deployment, ""), tc.expected)
+		})
+	}
+}
+
+func TestResourceDescriptionMatch_CommaSeparatedKinds(t *testing.T) {
+	resource := unstructured.Unstructured{}
+	resource.SetAPIVersion("apps/v1")
+	resource.SetKind("DaemonSet")
+	resource.SetName("fluentd")
+
+	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: v1.ResourceDescription{Kinds: []string{"Deployment,StatefulSet,DaemonSet"}}}}
+	assert.NilError(t, MatchesResourceDescription(resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""))
+
+	rule.ExcludeResources = v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{Kinds: []string{"StatefulSet,DaemonSet"}}}
+	assert.Assert(t, MatchesResourceDescription(resource, rule, v1.RequestInfo{}, []string{}, nil, "", "") != nil)
+}
+
 // Match resource name
 func TestResourceDescriptionMatch_Name(t *testing.T) {
 	rawResource := []byte(`{
@@ -1027,7 +1068,7 @@ func TestResourceDescriptionMatch_Name(t *testing.T) {
 	}
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err != nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err != nil {
 		t.Errorf("Testcase has failed due to the following:%v", err)
 	}
 }
@@ -1087,7 +1128,7 @@ func TestResourceDescriptionMatch_Name_Regex(t *testing.T) {
 	}
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err != nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err != nil {
 		t.Errorf("Testcase has failed due to the following:%v", err)
 	}
 }
@@ -1155,7 +1196,7 @@ func TestResourceDescriptionMatch_Label_Expression_NotMatch(t *testing.T) {
 	}
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err != nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err != nil {
 		t.Errorf("Testcase has failed due to the following:%v", err)
 	}
 }
@@ -1224,7 +1265,7 @@ func TestResourceDescriptionMatch_Label_Expression_Match(t *testing.T) {
 	}
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err != nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err != nil {
 		t.Errorf("Testcase has failed due to the following:%v", err)
 	}
 }
@@ -1304,7 +1345,7 @@ func TestResourceDescriptionExclude_Label_Expression_Match(t *testing.T) {
 	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription},
 		ExcludeResources: v1.ExcludeResources{ResourceDescription: resourceDescriptionExclude}}
 
-	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, ""); err == nil {
+	if err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""); err == nil {
 		t.Errorf("Testcase has failed due to the following:\n Function has returned no error, even though it was supposed to fail")
 	}
 }
@@ -1448,3 +1489,481 @@ func TestManagedPodResource(t *testing.T) {
 		assert.Equal(t, res, tc.expectedResult, "test %d/%s failed, expect %v, got %v", i+1, tc.name, tc.expectedResult, res)
 	}
 }
+
+func Test_checkSelector(t *testing.T) {
+	resourceLabels := map[string]string{"app": "nginx", "tier": "frontend"}
+
+	testCases := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		matched  bool
+		err      bool
+	}{
+		{
+			name:     "empty selector matches all resources",
+			selector: &metav1.LabelSelector{},
+			matched:  true,
+		},
+		{
+			name:     "matchLabels matched",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			matched:  true,
+		},
+		{
+			name:     "matchLabels not matched",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}},
+			matched:  false,
+		},
+		{
+			name: "matchExpressions In matched",
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "web"}},
+				},
+			},
+			matched: true,
+		},
+		{
+			name: "matchExpressions DoesNotExist not matched",
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			matched: false,
+		},
+		{
+			name: "matchLabels and matchExpressions must both match",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "nginx"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"frontend"}},
+				},
+			},
+			matched: false,
+		},
+		{
+			name: "invalid operator",
+			selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: "Equals", Values: []string{"frontend"}},
+				},
+			},
+			matched: false,
+			err:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, err := checkSelector(tc.selector, resourceLabels)
+			assert.Equal(t, err != nil, tc.err)
+			assert.Equal(t, matched, tc.matched)
+		})
+	}
+}
+
+func TestResourceDescriptionMatch_Selector_MatchNothing(t *testing.T) {
+	rawResource := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "nginx",
+			"labels": {"app": "nginx"}
+		}
+	}`)
+	resource, err := utils.ConvertToUnstructured(rawResource)
+	assert.NilError(t, err)
+
+	// a selector without any requirement does not restrict the match
+	resourceDescription := v1.ResourceDescription{
+		Kinds:    []string{"Pod"},
+		Selector: &metav1.LabelSelector{},
+	}
+	rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
+	assert.NilError(t, MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""))
+
+	// a selector on a label that the resource does not have matches nothing
+	resourceDescription.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}}
+	rule = v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: resourceDescription}}
+	assert.Assert(t, MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", "") != nil)
+}
+
+func TestResourceDescriptionMatch_AnnotationSelector(t *testing.T) {
+	rawResource := []byte(`{
+		"apiVersion": "apps/v1",
+		"kind": "StatefulSet",
+		"metadata": {
+			"name": "postgres",
+			"annotations": {
+				"backup.io/enabled": "true",
+				"backup.io/schedule": "0 3 * * *"
+			}
+		}
+	}`)
+	resource, err := utils.ConvertToUnstructured(rawResource)
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		match    bool
+	}{
+		{
+			name:     "value matches",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"backup.io/enabled": "true"}},
+			match:    true,
+		},
+		{
+			name:     "value does not match",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"backup.io/enabled": "false"}},
+			match:    false,
+		},
+		{
+			name:     "value that is not a valid label value matches",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"backup.io/schedule": "0 3 * * *"}},
+			match:    true,
+		},
+		{
+			name:     "wildcard key and value",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"backup.io/*": "*"}},
+			match:    true,
+		},
+		{
+			name: "annotation exists",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/enabled", Operator: metav1.LabelSelectorOpExists},
+			}},
+			match: true,
+		},
+		{
+			name: "annotation does not exist",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/retention", Operator: metav1.LabelSelectorOpExists},
+			}},
+			match: false,
+		},
+		{
+			name: "annotation absence",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/retention", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			match: true,
+		},
+		{
+			name: "value in set",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/enabled", Operator: metav1.LabelSelectorOpIn, Values: []string{"true", "yes"}},
+			}},
+			match: true,
+		},
+		{
+			name: "value not in set",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/enabled", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"true"}},
+			}},
+			match: false,
+		},
+		{
+			name: "missing annotation is not in set",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "backup.io/retention", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"7d"}},
+			}},
+			match: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: v1.ResourceDescription{
+			Kinds:              []string{"StatefulSet"},
+			AnnotationSelector: tc.selector,
+		}}}
+
+		err := MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", "")
+		assert.Equal(t, err == nil, tc.match, tc.name)
+	}
+}
+
+func TestResourceDescriptionExclude_AnnotationSelector(t *testing.T) {
+	rawResource := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "nginx",
+			"annotations": {"policies.example.com/skip": "true"}
+		}
+	}`)
+	resource, err := utils.ConvertToUnstructured(rawResource)
+	assert.NilError(t, err)
+
+	rule := v1.Rule{
+		MatchResources: v1.MatchResources{ResourceDescription: v1.ResourceDescription{Kinds: []string{"Pod"}}},
+		ExcludeResources: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{
+			AnnotationSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"policies.example.com/skip": "true"}},
+		}},
+	}
+	assert.Assert(t, MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", "") != nil)
+
+	resource.SetAnnotations(nil)
+	assert.NilError(t, MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", ""))
+}
+
+func TestMatchesResourceDescription_MatchThenExclude(t *testing.T) {
+	rawResource := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "coredns",
+			"namespace": "kube-system",
+			"labels": {"k8s-app": "kube-dns"}
+		}
+	}`)
+	resource, err := utils.ConvertToUnstructured(rawResource)
+	assert.NilError(t, err)
+
+	match := v1.MatchResources{ResourceDescription: v1.ResourceDescription{Kinds: []string{"Pod"}}}
+	serviceAccountInfo := v1.RequestInfo{
+		AdmissionUserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:replicaset-controller"},
+	}
+
+	testCases := []struct {
+		name        string
+		exclude     v1.ExcludeResources
+		requestInfo v1.RequestInfo
+		matched     bool
+	}{
+		{
+			name:    "no exclude",
+			matched: true,
+		},
+		{
+			name:    "excluded by namespace",
+			exclude: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{Namespaces: []string{"kube-system"}}},
+			matched: false,
+		},
+		{
+			name:    "not excluded by other namespace",
+			exclude: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{Namespaces: []string{"default"}}},
+			matched: true,
+		},
+		{
+			name:    "excluded by name",
+			exclude: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{Name: "core*"}},
+			matched: false,
+		},
+		{
+			name: "excluded by selector",
+			exclude: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			}},
+			matched: false,
+		},
+		{
+			name: "not excluded when only part of the exclude block matches",
+			exclude: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{
+				Namespaces: []string{"kube-system"},
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "metrics-server"}},
+			}},
+			matched: true,
+		},
+		{
+			name: "excluded by service account",
+			exclude: v1.ExcludeResources{UserInfo: v1.UserInfo{
+				Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "kube-system", Name: "replicaset-controller"}},
+			}},
+			requestInfo: serviceAccountInfo,
+			matched:     false,
+		},
+		{
+			name: "not excluded by other service account",
+			exclude: v1.ExcludeResources{UserInfo: v1.UserInfo{
+				Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "kube-system", Name: "daemon-set-controller"}},
+			}},
+			requestInfo: serviceAccountInfo,
+			matched:     true,
+		},
+		{
+			name: "excluded by any filter",
+			exclude: v1.ExcludeResources{Any: v1.ResourceFilters{
+				{ResourceDescription: v1.ResourceDescription{Namespaces: []string{"default"}}},
+				{ResourceDescription: v1.ResourceDescription{Namespaces: []string{"kube-system"}}},
+			}},
+			matched: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := v1.Rule{Name: "test", MatchResources: match, ExcludeResources: tc.exclude}
+			err := MatchesResourceDescription(*resource, rule, tc.requestInfo, []string{}, nil, "", "")
+			assert.Equal(t, err == nil, tc.matched, "%v", err)
+		})
+	}
+}
+
+func TestMatchesResourceDescription_Subresource(t *testing.T) {
+	pod, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}}`))
+	assert.NilError(t, err)
+	execOptions, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "PodExecOptions", "command": ["sh"], "container": "nginx", "stdin": true, "tty": true}`))
+	assert.NilError(t, err)
+	scale, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "autoscaling/v1", "kind": "Scale", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"replicas": 3}}`))
+	assert.NilError(t, err)
+	ephemeralPod, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"ephemeralContainers": [{"name": "debugger", "image": "busybox"}]}}`))
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name        string
+		kinds       []string
+		resource    *unstructured.Unstructured
+		subresource string
+		matched     bool
+	}{
+		{name: "exec matches Pod/exec", kinds: []string{"Pod/exec"}, resource: execOptions, subresource: "pods/exec", matched: true},
+		{name: "pod creation does not match Pod/exec", kinds: []string{"Pod/exec"}, resource: pod, matched: false},
+		{name: "exec matches the kind of the exec object", kinds: []string{"PodExecOptions"}, resource: execOptions, subresource: "pods/exec", matched: true},
+		{name: "exec does not match Pod", kinds: []string{"Pod"}, resource: execOptions, subresource: "pods/exec", matched: false},
+		{name: "attach does not match Pod/exec", kinds: []string{"Pod/exec"}, resource: execOptions, subresource: "pods/attach", matched: false},
+		{name: "status matches Pod/status", kinds: []string{"Pod/status"}, resource: pod, subresource: "pods/status", matched: true},
+		{name: "status matches Pod", kinds: []string{"Pod"}, resource: pod, subresource: "pods/status", matched: true},
+		{name: "status matches v1/Pod", kinds: []string{"v1/Pod"}, resource: pod, subresource: "pods/status", matched: true},
+		{name: "ephemeralcontainers matches Pod", kinds: []string{"Pod"}, resource: ephemeralPod, subresource: "pods/ephemeralcontainers", matched: true},
+		{name: "ephemeralcontainers does not match Pod/status", kinds: []string{"Pod/status"}, resource: ephemeralPod, subresource: "pods/ephemeralcontainers", matched: false},
+		{name: "status matches the wildcard", kinds: []string{"*"}, resource: pod, subresource: "pods/status", matched: true},
+		{name: "pod creation matches v1/Pod", kinds: []string{"v1/Pod"}, resource: pod, matched: true},
+		{name: "scale matches Deployment/scale", kinds: []string{"Deployment/scale"}, resource: scale, subresource: "deployments/scale", matched: true},
+		{name: "statefulset scale does not match Deployment/scale", kinds: []string{"Deployment/scale"}, resource: scale, subresource: "statefulsets/scale", matched: false},
+		{name: "statefulset scale matches StatefulSet/scale", kinds: []string{"StatefulSet/scale"}, resource: scale, subresource: "statefulsets/scale", matched: true},
+		{name: "scale does not match Deployment", kinds: []string{"Deployment"}, resource: scale, subresource: "deployments/scale", matched: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := v1.Rule{Name: "test", MatchResources: match, ExcludeResources: tc.exclude}
+			err := MatchesResourceDescription(*resource, rule, tc.requestInfo, []string{}, nil, "", "")
+			assert.Equal(t, err == nil, tc.matched, "%v", err)
+		})
+	}
+}
+
+func TestMatchesResourceDescription_Subresource(t *testing.T) {
+	pod, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}}`))
+	assert.NilError(t, err)
+	execOptions, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "PodExecOptions", "command": ["sh"], "container": "nginx", "stdin": true, "tty": true}`))
+	assert.NilError(t, err)
+	scale, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "autoscaling/v1", "kind": "Scale", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"replicas": 3}}`))
+	assert.NilError(t, err)
+	ephemeralPod, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"ephemeralContainers": [{"name": "debugger", "image": "busybox"}]}}`))
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name        string
+		kinds       []string
+		resource    *unstructured.Unstructured
+		subresource string
+		matched     bool
+	}{
+		{name: "exec matches Pod/exec", kinds: []string{"Pod/exec"}, resource: execOptions, subresource: "exec", matched: true},
+		{name: "pod creation does not match Pod/exec", kinds: []string{"Pod/exec"}, resource: pod, matched: false},
+		{name: "exec matches the kind of the exec object", kinds: []string{"PodExecOptions"}, resource: execOptions, subresource: "exec", matched: true},
+		{name: "attach does not match Pod/exec", kinds: []string{"Pod/exec"}, resource: execOptions, subresource: "attach", matched: false},
+		{name: "status matches Pod/status", kinds: []string{"Pod/status"}, resource: pod, subresource: "status", matched: true},
+		{name: "status does not match Pod", kinds: []string{"Pod"}, resource: pod, subresource: "status", matched: false},
+		{name: "status does not match v1/Pod", kinds: []string{"v1/Pod"}, resource: pod, subresource: "status", matched: false},
+		{name: "status matches the wildcard", kinds: []string{"*"}, resource: pod, subresource: "status", matched: true},
+		{name: "pod creation matches v1/Pod", kinds: []string{"v1/Pod"}, resource: pod, matched: true},
+		{name: "scale matches Deployment/scale", kinds: []string{"Deployment/scale"}, resource: scale, subresource: "scale", matched: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := v1.Rule{Name: "test", MatchResources: v1.MatchResources{ResourceDescription: v1.ResourceDescription{Kinds: tc.kinds}}}
+			err := MatchesResourceDescription(*tc.resource, rule, v1.RequestInfo{}, []string{}, nil, "", tc.subresource)
+			assert.Equal(t, err == nil, tc.matched, "%v", err)
+		})
+	}
+}
+
+func Test_checkName(t *testing.T) {
+	testCases := []struct {
+		name         string
+		resourceName string
+		matched      bool
+	}{
+		{name: "kube-root-ca.crt", resourceName: "kube-root-ca.crt", matched: true},
+		{name: "kube-root-ca.crt", resourceName: "kube-root-ca", matched: false},
+		{name: "kube-*", resourceName: "kube-proxy", matched: true},
+		{name: "kube-*", resourceName: "kube-", matched: true},
+		{name: "kube-*", resourceName: "my-kube-config", matched: false},
+		{name: "*-config", resourceName: "my-kube-config", matched: true},
+		{name: "nginx-?", resourceName: "nginx-1", matched: true},
+		{name: "nginx-?", resourceName: "nginx-10", matched: false},
+		{name: "nginx-[0-9]", resourceName: "nginx-7", matched: true},
+		{name: "nginx-[0-9]", resourceName: "nginx-a", matched: false},
+		{name: "nginx-[^0-9]*", resourceName: "nginx-a1", matched: true},
+		{name: "*", resourceName: "anything", matched: true},
+		// a malformed pattern falls back to the "*" and "?" wildcards
+		{name: "nginx-[*", resourceName: "nginx-[1", matched: true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, checkName(tc.name, tc.resourceName), tc.matched, "name %s, resource name %s", tc.name, tc.resourceName)
+	}
+}
+
+func TestResourceDescriptionExclude_NameGlob(t *testing.T) {
+	rule := v1.Rule{
+		MatchResources:   v1.MatchResources{ResourceDescription: v1.ResourceDescription{Kinds: []string{"ConfigMap"}}},
+		ExcludeResources: v1.ExcludeResources{ResourceDescription: v1.ResourceDescription{Names: []string{"kube-*", "extension-apiserver-authentication"}}},
+	}
+
+	testCases := []struct {
+		resourceName string
+		excluded     bool
+	}{
+		{resourceName: "kube-root-ca.crt", excluded: true},
+		{resourceName: "kube-proxy", excluded: true},
+		{resourceName: "extension-apiserver-authentication", excluded: true},
+		{resourceName: "extension-apiserver-authentication-2", excluded: false},
+		{resourceName: "my-kube-config", excluded: false},
+	}
+
+	for _, tc := range testCases {
+		resource, err := utils.ConvertToUnstructured([]byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "` + tc.resourceName + `", "namespace": "kube-system"}}`))
+		assert.NilError(t, err)
+
+		err = MatchesResourceDescription(*resource, rule, v1.RequestInfo{}, []string{}, nil, "", "")
+		assert.Equal(t, err != nil, tc.excluded, "resource name %s", tc.resourceName)
+	}
+}
+
+func TestResourceDescriptionMatch_GenerateName(t *testing.T) {
+	testCases := []struct {
+		name         string
+		description  v1.ResourceDescription
+		resourceName string
+		generateName string
+		matched      bool
+	}{
+		{name: "name glob matches the generateName", description: v1.ResourceDescription{Name: "nginx-*"}, generateName: "nginx-7d4f9c-", matched: true},
+		{name: "names glob matches the generateName", description: v1.ResourceDescription{Names: []string{"redis-*", "nginx-*"}}, generateName: "nginx-7d4f9c-", matched: true},
+		{name: "generateName does not match", description: v1.ResourceDescription{Name: "redis-*"}, generateName: "nginx-7d4f9c-", matched: false},
+		{name: "name takes precedence over the generateName", description: v1.ResourceDescription{Name: "nginx-*"}, resourceName: "web-1", generateName: "nginx-7d4f9c-", matched: false},
+		{name: "name matches", description: v1.ResourceDescription{Names: []string{"web-*"}}, resourceName: "web-1", generateName: "nginx-7d4f9c-", matched: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resource := unstructured.Unstructured{}
+			resource.SetAPIVersion("v1")
+			resource.SetKind("Pod")
+			resource.SetNamespace("default")
+			resource.SetName(tc.resourceName)
+			resource.SetGenerateName(tc.generateName)
+
+			tc.description.Kinds = []string{"Pod"}
+			rule := v1.Rule{MatchResources: v1.MatchResources{ResourceDescription: tc.description}}
+			err := MatchesResourceDescription(resource, rule, v1.RequestInfo{}, []string{}, nil, "", "")
+			assert.Equal(t, err == nil, tc.matched, "%v", err)
+		})
+	}
+}
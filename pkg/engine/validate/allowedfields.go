@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	commonAnchors "github.com/kyverno/kyverno/pkg/engine/anchor/common"
+	"github.com/minio/pkg/wildcard"
+)
+
+// rootAllowedFields are the fields of the resource allowed without being declared in the pattern
+var rootAllowedFields = map[string]bool{"apiVersion": true, "kind": true, "metadata": true, "status": true}
+
+// MatchAllowedFields checks that the resource only contains the fields declared in the pattern.
+// It assumes that the resource matches the pattern, i.e. MatchPattern succeeded, and returns a
+// PatternError with the path of the first field that is not declared in the pattern.
+func MatchAllowedFields(resource, pattern interface{}) error {
+	resourceMap, ok := resource.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	patternMap, ok := pattern.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	root := make(map[string]interface{}, len(resourceMap))
+	for key, value := range resourceMap {
+		if !rootAllowedFields[key] {
+			root[key] = value
+		}
+	}
+
+	if path, err := validateAllowedFields(root, patternMap, "/"); err != nil {
+		return &PatternError{err, path, false}
+	}
+
+	return nil
+}
+
+// validateAllowedFields walks the resource and the pattern trees, the resource maps can only
+// have the keys of the pattern maps. A value pattern, e.g. "?*", allows any nested element.
+func validateAllowedFields(resourceElement, patternElement interface{}, path string) (string, error) {
+	switch typedPattern := patternElement.(type) {
+	case map[string]interface{}:
+		typedResource, ok := resourceElement.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+
+		// sorted keys, so that the first forbidden field is always the same
+		keys := make([]string, 0, len(typedResource))
+		for key := range typedResource {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			// the object metadata is allowed at any level, e.g. the metadata of the pod template of a Deployment
+			if key == "metadata" {
+				continue
+			}
+
+			currentPath := path + key + "/"
+			patternValue, ok := getAllowedField(typedPattern, key)
+			if !ok {
+				return currentPath, fmt.Errorf("field %s is not allowed by the pattern", currentPath)
+			}
+
+			if elemPath, err := validateAllowedFields(typedResource[key], patternValue, currentPath); err != nil {
+				return elemPath, err
+			}
+		}
+	case []interface{}:
+		typedResource, ok := resourceElement.([]interface{})
+		if !ok || len(typedPattern) == 0 {
+			return "", nil
+		}
+
+		for i, value := range typedResource {
+			currentPath := path + strconv.Itoa(i) + "/"
+			if elemPath, err := validateAllowedFields(value, typedPattern[0], currentPath); err != nil {
+				return elemPath, err
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// getAllowedField returns the pattern of the resource key. The anchors are removed from the
+// pattern keys, the negation anchors are skipped as they forbid the key, and the pattern keys
+// can have wildcards, e.g. the label keys. A pattern key equal to the resource key takes
+// precedence over the wildcard keys, e.g. "=(*)" in the autogen rules.
+func getAllowedField(patternMap map[string]interface{}, key string) (interface{}, bool) {
+	var wildcardValue interface{}
+	var found bool
+	for patternKey, patternValue := range patternMap {
+		if commonAnchors.IsNegationAnchor(patternKey) {
+			continue
+		}
+
+		allowedKey, _ := commonAnchors.RemoveAnchor(patternKey)
+		if allowedKey == key {
+			return patternValue, true
+		}
+
+		if !found && wildcard.Match(allowedKey, key) {
+			wildcardValue, found = patternValue, true
+		}
+	}
+
+	return wildcardValue, found
+}
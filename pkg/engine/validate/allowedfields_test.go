@@ -0,0 +1,138 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_MatchAllowedFields(t *testing.T) {
+	pattern := []byte(`{
+		"spec": {
+			"X(hostNetwork)": "null",
+			"containers": [
+				{
+					"(name)": "?*",
+					"image": "*:*",
+					"=(args)": ["?*"],
+					"=(env)": [{"name": "?*", "value": "*"}],
+					"=(resources)": "?*"
+				}
+			],
+			"nodeSelector": {"kubernetes.io/*": "?*"}
+		}
+	}`)
+
+	testCases := []struct {
+		name     string
+		resource string
+		path     string
+	}{
+		{
+			name:     "declared fields",
+			resource: `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "uid": "1"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}, "status": {"phase": "Running"}}`,
+		},
+		{
+			name:     "optional fields",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21", "args": ["-v"], "env": [{"name": "A", "value": "1"}]}]}}`,
+		},
+		{
+			name:     "value pattern allows nested fields",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21", "resources": {"limits": {"cpu": "1"}}}]}}`,
+		},
+		{
+			name:     "wildcard key",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}], "nodeSelector": {"kubernetes.io/os": "linux"}}}`,
+		},
+		{
+			name:     "forbidden root field",
+			resource: `{"apiVersion": "v1", "kind": "ConfigMap", "data": {"a": "b"}}`,
+			path:     "/data/",
+		},
+		{
+			name:     "forbidden field in array element",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}, {"name": "sidecar", "image": "busybox:1.34", "command": ["sh"]}]}}`,
+			path:     "/spec/containers/1/command/",
+		},
+		{
+			name:     "forbidden field in nested array element",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21", "env": [{"name": "A", "valueFrom": {}}]}]}}`,
+			path:     "/spec/containers/0/env/0/valueFrom/",
+		},
+		{
+			name:     "forbidden wildcard key",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}], "nodeSelector": {"disktype": "ssd"}}}`,
+			path:     "/spec/nodeSelector/disktype/",
+		},
+		{
+			name:     "negation anchor",
+			resource: `{"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}], "hostNetwork": true}}`,
+			path:     "/spec/hostNetwork/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p, r interface{}
+			assert.NilError(t, json.Unmarshal(pattern, &p))
+			assert.NilError(t, json.Unmarshal([]byte(tc.resource), &r))
+
+			err := MatchAllowedFields(r, p)
+			if tc.path == "" {
+				assert.NilError(t, err)
+				return
+			}
+
+			pe, ok := err.(*PatternError)
+			assert.Assert(t, ok)
+			assert.Equal(t, pe.Path, tc.path)
+			assert.Equal(t, pe.Skip, false)
+			assert.ErrorContains(t, err, "is not allowed by the pattern")
+		})
+	}
+}
+
+func Test_MatchAllowedFields_Controller(t *testing.T) {
+	// the pattern of the autogen rule of a Deployment
+	pattern := []byte(`{
+		"spec": {
+			"template": {"spec": {"containers": [{"name": "?*", "image": "?*"}]}},
+			"=(*)": "*"
+		}
+	}`)
+
+	testCases := []struct {
+		name     string
+		resource string
+		path     string
+	}{
+		{
+			name:     "controller fields and template metadata",
+			resource: `{"spec": {"replicas": 2, "selector": {"matchLabels": {"app": "nginx"}}, "template": {"metadata": {"labels": {"app": "nginx"}}, "spec": {"containers": [{"name": "nginx", "image": "nginx"}]}}}}`,
+		},
+		{
+			name:     "the declared template key is checked",
+			resource: `{"spec": {"template": {"spec": {"containers": [{"name": "nginx", "image": "nginx"}], "hostNetwork": true}}}}`,
+			path:     "/spec/template/spec/hostNetwork/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p, r interface{}
+			assert.NilError(t, json.Unmarshal(pattern, &p))
+			assert.NilError(t, json.Unmarshal([]byte(tc.resource), &r))
+
+			err := MatchAllowedFields(r, p)
+			if tc.path == "" {
+				assert.NilError(t, err)
+				return
+			}
+
+			pe, ok := err.(*PatternError)
+			assert.Assert(t, ok)
+			assert.Equal(t, pe.Path, tc.path)
+		})
+	}
+}
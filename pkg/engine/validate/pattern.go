@@ -255,7 +255,7 @@ func validateString(log logr.Logger, value interface{}, pattern string, operator
 
 		return wildcardResult
 	}
-	log.Info("Operators >, >=, <, <= are not applicable to strings")
+	log.V(4).Info("operators >, >=, <, <= are not applicable to strings", "value", value, "pattern", pattern)
 	return false
 }
 
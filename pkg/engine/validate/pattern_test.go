@@ -385,3 +385,48 @@ func TestGetOperatorFromStringPattern_OneChar(t *testing.T) {
 func TestGetOperatorFromStringPattern_EmptyString(t *testing.T) {
 	assert.Equal(t, operator.GetOperatorFromStringPattern(""), operator.Equal)
 }
+
+func TestValidateValueWithPattern_OperatorsAndWildcards(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		value   interface{}
+		matched bool
+	}{
+		// numeric operators
+		{pattern: ">1024", value: int64(8080), matched: true},
+		{pattern: ">1024", value: 8080.0, matched: true},
+		{pattern: ">1024", value: int64(1024), matched: false},
+		{pattern: ">=1024", value: int64(1024), matched: true},
+		{pattern: "<1024", value: int64(80), matched: true},
+		{pattern: "<=4", value: int64(4), matched: true},
+		{pattern: "<=4", value: int64(5), matched: false},
+		{pattern: "!5", value: int64(5), matched: false},
+		{pattern: "!5", value: int64(4), matched: true},
+		{pattern: "1-10", value: int64(10), matched: true},
+		{pattern: "1!-10", value: int64(10), matched: false},
+
+		// a numeric string field is compared as a number
+		{pattern: ">1024", value: "8080", matched: true},
+		{pattern: "<=4", value: "5", matched: false},
+
+		// wildcards
+		{pattern: "myregistry.io/*", value: "myregistry.io/nginx:1.21", matched: true},
+		{pattern: "myregistry.io/*", value: "docker.io/nginx:1.21", matched: false},
+		{pattern: "nginx:?.??", value: "nginx:1.21", matched: true},
+		{pattern: "nginx:?.??", value: "nginx:latest", matched: false},
+		{pattern: "!*:latest", value: "nginx:latest", matched: false},
+		{pattern: "!*:latest", value: "nginx:1.21", matched: true},
+		{pattern: "*", value: int64(8080), matched: true},
+
+		// type mismatches do not match
+		{pattern: ">1024", value: "http", matched: false},
+		{pattern: ">1024", value: true, matched: false},
+		{pattern: ">1024", value: nil, matched: false},
+		{pattern: "nginx*", value: int64(8080), matched: false},
+		{pattern: ">abc", value: "abc", matched: false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, ValidateValueWithPattern(log.Log, tc.value, tc.pattern), tc.matched, "pattern %s, value %v", tc.pattern, tc.value)
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"github.com/kyverno/kyverno/pkg/engine/validate"
 	"github.com/kyverno/kyverno/pkg/engine/variables"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 //Validate applies validation rules from policy on the resource
@@ -39,7 +38,7 @@ func Validate(policyContext *PolicyContext) (resp *response.EngineResponse) {
 }
 
 func buildLogger(ctx *PolicyContext) logr.Logger {
-	logger := log.Log.WithName("EngineValidate").WithValues("policy", ctx.Policy.Name)
+	logger := ctx.logger().WithName("EngineValidate").WithValues("policy", ctx.Policy.Name)
 	if reflect.DeepEqual(ctx.NewResource, unstructured.Unstructured{}) {
 		logger = logger.WithValues("kind", ctx.OldResource.GetKind(), "namespace", ctx.OldResource.GetNamespace(), "name", ctx.OldResource.GetName())
 	} else {
@@ -71,13 +70,22 @@ func buildResponse(ctx *PolicyContext, resp *response.EngineResponse, startTime
 	resp.PolicyResponse.Resource.Kind = resp.PatchedResource.GetKind()
 	resp.PolicyResponse.Resource.APIVersion = resp.PatchedResource.GetAPIVersion()
 	resp.PolicyResponse.ValidationFailureAction = ctx.Policy.Spec.ValidationFailureAction
+	if ctx.Policy.Spec.FailurePolicy != nil {
+		resp.PolicyResponse.FailurePolicy = string(*ctx.Policy.Spec.FailurePolicy)
+	}
 	resp.PolicyResponse.ProcessingTime = time.Since(startTime)
 	resp.PolicyResponse.PolicyExecutionTimestamp = startTime.Unix()
+	setRuleErrorContext(resp)
 }
 
 func validateResource(log logr.Logger, ctx *PolicyContext) *response.EngineResponse {
 	resp := &response.EngineResponse{}
 
+	// the namespace is loaded before the checkpoint to be kept for the next policies of the request
+	if err := loadNamespace(log, ctx); err != nil {
+		log.Error(err, "failed to load namespace in context")
+	}
+
 	ctx.JSONContext.Checkpoint()
 	defer ctx.JSONContext.Restore()
 
@@ -87,6 +95,11 @@ func validateResource(log logr.Logger, ctx *PolicyContext) *response.EngineRespo
 			continue
 		}
 
+		if ctx.Cancelled() {
+			log.V(3).Info("skipping the remaining validate rules, the evaluation of the request was cancelled")
+			break
+		}
+
 		log = log.WithValues("rule", rule.Name)
 		if !matches(log, rule, ctx) {
 			continue
@@ -99,6 +112,12 @@ func validateResource(log logr.Logger, ctx *PolicyContext) *response.EngineRespo
 		ruleResp := processValidationRule(log, ctx, rule)
 		if ruleResp != nil {
 			addRuleResponse(log, resp, ruleResp, startTime)
+			logSlowRule(log, ctx, ruleResp)
+
+			if ruleResp.Status == response.RuleStatusFail && ctx.Policy.FailFastEnabled() {
+				log.V(3).Info("skipping the remaining validate rules, failFast is set")
+				break
+			}
 		}
 	}
 
@@ -129,27 +148,29 @@ func addRuleResponse(log logr.Logger, resp *response.EngineResponse, ruleResp *r
 }
 
 type validator struct {
-	log              logr.Logger
-	ctx              *PolicyContext
-	rule             *kyverno.Rule
-	contextEntries   []kyverno.ContextEntry
-	anyAllConditions apiextensions.JSON
-	pattern          apiextensions.JSON
-	anyPattern       apiextensions.JSON
-	deny             *kyverno.Deny
+	log                 logr.Logger
+	ctx                 *PolicyContext
+	rule                *kyverno.Rule
+	contextEntries      []kyverno.ContextEntry
+	anyAllConditions    apiextensions.JSON
+	pattern             apiextensions.JSON
+	anyPattern          apiextensions.JSON
+	rejectUnknownFields bool
+	deny                *kyverno.Deny
 }
 
 func newValidator(log logr.Logger, ctx *PolicyContext, rule *kyverno.Rule) *validator {
 	ruleCopy := rule.DeepCopy()
 	return &validator{
-		log:              log,
-		rule:             ruleCopy,
-		ctx:              ctx,
-		contextEntries:   ruleCopy.Context,
-		anyAllConditions: ruleCopy.AnyAllConditions,
-		pattern:          ruleCopy.Validation.Pattern,
-		anyPattern:       ruleCopy.Validation.AnyPattern,
-		deny:             ruleCopy.Validation.Deny,
+		log:                 log,
+		rule:                ruleCopy,
+		ctx:                 ctx,
+		contextEntries:      ruleCopy.Context,
+		anyAllConditions:    ruleCopy.AnyAllConditions,
+		pattern:             ruleCopy.Validation.Pattern,
+		anyPattern:          ruleCopy.Validation.AnyPattern,
+		rejectUnknownFields: ruleCopy.Validation.RejectUnknownFields,
+		deny:                ruleCopy.Validation.Deny,
 	}
 }
 
@@ -248,7 +269,9 @@ func (v *validator) validateForEach() *response.RuleResponse {
 				continue
 			} else if r.Status != response.RuleStatusPass {
 				msg := fmt.Sprintf("validation failed in foreach rule for %v", r.Message)
-				return ruleResponse(v.rule, utils.Validation, msg, r.Status)
+				resp := ruleResponse(v.rule, utils.Validation, msg, r.Status)
+				resp.Error = r.Error
+				return resp
 			}
 			applyCount++
 		}
@@ -330,12 +353,25 @@ func (v *validator) getDenyMessage(deny bool) string {
 		return fmt.Sprintf("validation error: rule %s failed", v.rule.Name)
 	}
 
+	return v.substituteMessage(msg)
+}
+
+// substituteMessage resolves the variables in the rule message using the resource and
+// request data. If a variable cannot be resolved the message template is returned as is,
+// so that a missing field does not hide the rule failure.
+func (v *validator) substituteMessage(msg string) string {
 	raw, err := variables.SubstituteAll(v.log, v.ctx.JSONContext, msg)
 	if err != nil {
+		v.log.V(3).Info("failed to substitute variables in message", "message", msg, "error", err.Error())
+		return msg
+	}
+
+	res, ok := raw.(string)
+	if !ok {
 		return msg
 	}
 
-	return raw.(string)
+	return res
 }
 
 func (v *validator) validateResourceWithRule() *response.RuleResponse {
@@ -381,13 +417,18 @@ func isEmptyUnstructured(u *unstructured.Unstructured) bool {
 
 // matches checks if either the new or old resource satisfies the filter conditions defined in the rule
 func matches(logger logr.Logger, rule *kyverno.Rule, ctx *PolicyContext) bool {
-	err := MatchesResourceDescription(ctx.NewResource, *rule, ctx.AdmissionInfo, ctx.ExcludeGroupRole, ctx.NamespaceLabels, "")
+	if ctx.ExcludedUsername != "" && ctx.AdmissionInfo.AdmissionUserInfo.Username == ctx.ExcludedUsername {
+		logger.V(4).Info("the requests of the user are excluded from validation", "username", ctx.ExcludedUsername)
+		return false
+	}
+
+	err := MatchesResourceDescription(ctx.NewResource, *rule, ctx.AdmissionInfo, ctx.ExcludeGroupRole, ctx.NamespaceLabels, ctx.Policy.Namespace, ctx.SubResource)
 	if err == nil {
 		return true
 	}
 
 	if !reflect.DeepEqual(ctx.OldResource, unstructured.Unstructured{}) {
-		err := MatchesResourceDescription(ctx.OldResource, *rule, ctx.AdmissionInfo, ctx.ExcludeGroupRole, ctx.NamespaceLabels, "")
+		err := MatchesResourceDescription(ctx.OldResource, *rule, ctx.AdmissionInfo, ctx.ExcludeGroupRole, ctx.NamespaceLabels, ctx.Policy.Namespace, ctx.SubResource)
 		if err == nil {
 			return true
 		}
@@ -430,13 +471,18 @@ func (v *validator) validatePatterns(resource unstructured.Unstructured) *respon
 				}
 
 				if pe.Path == "" {
-					return ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, ""), response.RuleStatusError)
+					return withRuleError(ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, ""), response.RuleStatusError), ErrPatternMatch, err)
 				}
 
 				return ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, pe.Path), response.RuleStatusFail)
 			}
 
-			return ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, pe.Path), response.RuleStatusError)
+			return withRuleError(ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, ""), response.RuleStatusError), ErrPatternMatch, err)
+		}
+
+		if err := v.matchAllowedFields(resource, v.pattern); err != nil {
+			v.log.V(3).Info("validation error", "path", err.Path, "error", err.Error())
+			return ruleResponse(v.rule, utils.Validation, v.buildErrorMessage(err, err.Path), response.RuleStatusFail)
 		}
 
 		v.log.V(4).Info("successfully processed rule")
@@ -451,12 +497,18 @@ func (v *validator) validatePatterns(resource unstructured.Unstructured) *respon
 		anyPatterns, err := deserializeAnyPattern(v.anyPattern)
 		if err != nil {
 			msg := fmt.Sprintf("failed to deserialize anyPattern, expected type array: %v", err)
-			return ruleResponse(v.rule, utils.Validation, msg, response.RuleStatusError)
+			return withRuleError(ruleResponse(v.rule, utils.Validation, msg, response.RuleStatusError), ErrPatternMatch, err)
 		}
 
 		for idx, pattern := range anyPatterns {
 			err := validate.MatchPattern(v.log, resource.Object, pattern)
 			if err == nil {
+				if pe := v.matchAllowedFields(resource, pattern); pe != nil {
+					v.log.V(3).Info("validation rule failed", "anyPatternIndex", idx, "path", pe.Path)
+					failedAnyPatternsErrors = append(failedAnyPatternsErrors, fmt.Errorf("Rule %s[%d] failed at path %s.", v.rule.Name, idx, pe.Path))
+					continue
+				}
+
 				msg := fmt.Sprintf("validation rule '%s' anyPattern[%d] passed.", v.rule.Name, idx)
 				return ruleResponse(v.rule, utils.Validation, msg, response.RuleStatusPass)
 			}
@@ -489,6 +541,24 @@ func (v *validator) validatePatterns(resource unstructured.Unstructured) *respon
 	return ruleResponse(v.rule, utils.Validation, v.rule.Validation.Message, response.RuleStatusPass)
 }
 
+// matchAllowedFields returns the forbidden field error of the resource if the rule rejects the
+// fields that are not declared in the pattern
+func (v *validator) matchAllowedFields(resource unstructured.Unstructured, pattern interface{}) *validate.PatternError {
+	if !v.rejectUnknownFields {
+		return nil
+	}
+
+	if err := validate.MatchAllowedFields(resource.Object, pattern); err != nil {
+		if pe, ok := err.(*validate.PatternError); ok {
+			return pe
+		}
+
+		return &validate.PatternError{Err: err}
+	}
+
+	return nil
+}
+
 func deserializeAnyPattern(anyPattern apiextensions.JSON) ([]interface{}, error) {
 	if anyPattern == nil {
 		return nil, nil
@@ -516,12 +586,7 @@ func (v *validator) buildErrorMessage(err error, path string) string {
 		return fmt.Sprintf("validation error: rule %s execution error: %s", v.rule.Name, err.Error())
 	}
 
-	msgRaw, sErr := variables.SubstituteAll(v.log, v.ctx.JSONContext, v.rule.Validation.Message)
-	if sErr != nil {
-		v.log.Info("failed to substitute variables in message: %v", sErr)
-	}
-
-	msg := msgRaw.(string)
+	msg := v.substituteMessage(v.rule.Validation.Message)
 	if !strings.HasSuffix(msg, ".") {
 		msg = msg + "."
 	}
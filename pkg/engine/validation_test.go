@@ -2,9 +2,11 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	pkgcommon "github.com/kyverno/kyverno/pkg/common"
 	"github.com/kyverno/kyverno/pkg/engine/response"
 
 	"github.com/kyverno/kyverno/pkg/engine/context"
@@ -13,6 +15,7 @@ import (
 	utils2 "github.com/kyverno/kyverno/pkg/utils"
 	"gotest.tools/assert"
 	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 )
 
 func TestGetAnchorsFromMap_ThereAreAnchors(t *testing.T) {
@@ -3078,3 +3081,822 @@ func Test_delete_ignore_pattern(t *testing.T) {
 	engineResponseDelete := Validate(policyContextDelete)
 	assert.Equal(t, len(engineResponseDelete.PolicyResponse.Rules), 0)
 }
+
+func Test_deny_message_template(t *testing.T) {
+	resourceRaw := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {"name": "test-pod", "namespace": "default"},
+		"spec": {
+			"containers": [
+				{"name": "nginx", "image": "docker.io/nginx:latest"}
+			]
+		}}`)
+
+	policyraw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "deny-latest"},
+		"spec": {
+		  "rules": [
+			{
+			  "name": "deny-latest",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {
+				"message": "container {{request.object.spec.containers[0].name}} in pod {{request.object.metadata.name}} uses forbidden image {{request.object.spec.containers[0].image}}",
+				"deny": {
+				  "conditions": [
+					{
+					  "key": "{{ request.object.spec.containers[0].image }}",
+					  "operator": "Equals",
+					  "value": "docker.io/nginx:latest"
+					}
+				  ]
+				}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	testForEach(t, policyraw, resourceRaw, "container nginx in pod test-pod uses forbidden image docker.io/nginx:latest", response.RuleStatusFail)
+}
+
+func Test_deny_message_template_missing_field(t *testing.T) {
+	resourceRaw := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {"name": "test-pod", "namespace": "default"},
+		"spec": {
+			"containers": [
+				{"name": "nginx", "image": "docker.io/nginx:latest"}
+			]
+		}}`)
+
+	policyraw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "deny-latest"},
+		"spec": {
+		  "rules": [
+			{
+			  "name": "deny-latest",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {
+				"message": "pod owned by {{request.object.metadata.labels.owner}} is not allowed",
+				"deny": {
+				  "conditions": [
+					{
+					  "key": "{{ request.object.spec.containers[0].image }}",
+					  "operator": "Equals",
+					  "value": "docker.io/nginx:latest"
+					}
+				  ]
+				}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	testForEach(t, policyraw, resourceRaw, "pod owned by {{request.object.metadata.labels.owner}} is not allowed", response.RuleStatusFail)
+}
+
+func Test_pattern_message_template_missing_field(t *testing.T) {
+	resourceRaw := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {"name": "test-pod"},
+		"spec": {
+			"containers": [
+				{"name": "nginx", "image": "nginx:latest"}
+			]
+		}}`)
+
+	policyraw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "disallow-latest"},
+		"spec": {
+		  "rules": [
+			{
+			  "name": "disallow-latest",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {
+				"message": "image tag of {{request.object.metadata.labels.app}} must not be latest",
+				"pattern": {
+				  "spec": {
+					"containers": [{"image": "!*:latest"}]
+				  }
+				}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	testForEach(t, policyraw, resourceRaw, "validation error: image tag of {{request.object.metadata.labels.app}} must not be latest. Rule disallow-latest failed at path /spec/containers/0/image/", response.RuleStatusFail)
+}
+
+func Test_ImmutableLabel_OldObject(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "immutable-team-label"},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "background": false,
+		  "rules": [
+			{
+			  "name": "team-label-immutable",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "preconditions": {
+				"all": [{"key": "{{request.operation}}", "operator": "Equals", "value": "UPDATE"}]
+			  },
+			  "validate": {
+				"message": "label team cannot be changed from {{request.oldObject.metadata.labels.team}}",
+				"deny": {
+				  "conditions": {
+					"all": [
+					  {"key": "{{request.object.metadata.labels.team}}", "operator": "NotEquals", "value": "{{request.oldObject.metadata.labels.team}}"}
+					]
+				  }
+				}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	newPod := func(team string) string {
+		return `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","namespace":"default","labels":{"team":"` + team + `"}},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`
+	}
+
+	testCases := []struct {
+		name    string
+		request string
+		status  response.RuleStatus
+		message string
+	}{
+		{
+			name:    "changed label is denied",
+			request: `{"uid":"1","operation":"UPDATE","name":"nginx","namespace":"default","object":` + newPod("blue") + `,"oldObject":` + newPod("red") + `}`,
+			status:  response.RuleStatusFail,
+			message: "label team cannot be changed from red",
+		},
+		{
+			name:    "unchanged label is allowed",
+			request: `{"uid":"2","operation":"UPDATE","name":"nginx","namespace":"default","object":` + newPod("red") + `,"oldObject":` + newPod("red") + `}`,
+			status:  response.RuleStatusPass,
+		},
+		{
+			name:    "create without old object skips the rule",
+			request: `{"uid":"3","operation":"CREATE","name":"nginx","namespace":"default","object":` + newPod("red") + `,"oldObject":null}`,
+			status:  response.RuleStatusSkip,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+			var request *v1beta1.AdmissionRequest
+			assert.NilError(t, json.Unmarshal([]byte(tc.request), &request))
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddRequest(request))
+
+			newR, oldR, err := utils2.ExtractResources(nil, request)
+			assert.NilError(t, err)
+
+			er := Validate(&PolicyContext{
+				Policy:      policy,
+				NewResource: newR,
+				OldResource: oldR,
+				JSONContext: ctx,
+			})
+
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status)
+			if tc.message != "" {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Message, tc.message)
+			}
+		})
+	}
+}
+
+func Test_deny_jmespath_readiness_probe(t *testing.T) {
+	policyraw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "require-readiness-probe"},
+		"spec": {
+		  "rules": [
+			{
+			  "name": "require-readiness-probe",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {
+				"message": "at least one container must set a readinessProbe",
+				"deny": {
+				  "conditions": {
+					"all": [
+					  {
+						"key": "{{ length(request.object.spec.containers[?readinessProbe]) }}",
+						"operator": "Equals",
+						"value": 0
+					  }
+					]
+				  }
+				}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	testCases := []struct {
+		name       string
+		containers string
+		status     response.RuleStatus
+	}{
+		{
+			name:       "one container sets a readinessProbe",
+			containers: `[{"name": "app", "image": "app:v1", "readinessProbe": {"httpGet": {"path": "/ready", "port": 8080}}}, {"name": "sidecar", "image": "sidecar:v1"}]`,
+			status:     response.RuleStatusPass,
+		},
+		{
+			name:       "no container sets a readinessProbe",
+			containers: `[{"name": "app", "image": "app:v1"}, {"name": "sidecar", "image": "sidecar:v1"}]`,
+			status:     response.RuleStatusFail,
+		},
+		{
+			name:       "empty containers",
+			containers: `[]`,
+			status:     response.RuleStatusFail,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "test"}, "spec": {"containers": ` + tc.containers + `}}`)
+			testForEach(t, policyraw, resourceRaw, "", tc.status)
+		})
+	}
+}
+
+func Test_NamespacedPolicy_OwnNamespaceOnly(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "Policy",
+		"metadata": {"name": "require-team-label", "namespace": "team-a"},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "rules": [
+			{
+			  "name": "require-team-label",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {
+				"message": "label team is required",
+				"pattern": {"metadata": {"labels": {"team": "?*"}}}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+	for _, namespace := range []string{"team-a", "team-b"} {
+		resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "` + namespace + `"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`)
+		resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+		assert.NilError(t, err)
+
+		ctx := context.NewContext()
+		assert.NilError(t, ctx.AddResource(resourceRaw))
+
+		er := Validate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured})
+		if namespace == "team-a" {
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusFail)
+		} else {
+			assert.Equal(t, len(er.PolicyResponse.Rules), 0)
+		}
+	}
+}
+
+func Test_PrivilegedOnlyForGroupMembers(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "restrict-privileged"},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "rules": [
+			{
+			  "name": "privileged-for-platform-admins",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "exclude": {"subjects": [{"kind": "Group", "name": "platform-admins"}]},
+			  "validate": {
+				"message": "only members of platform-admins may create privileged pods",
+				"pattern": {"spec": {"containers": [{"=(securityContext)": {"=(privileged)": false}}]}}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	resourceRaw := []byte(`{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {"name": "debug", "namespace": "default"},
+		"spec": {"containers": [{"name": "debug", "image": "busybox:1.34", "securityContext": {"privileged": true}}]}
+	  }`)
+
+	testCases := []struct {
+		name     string
+		userInfo string
+		rules    int
+		status   response.RuleStatus
+	}{
+		{name: "group member", userInfo: `{"userInfo": {"username": "alice", "groups": ["system:authenticated", "platform-admins"]}}`, rules: 0},
+		{name: "not a group member", userInfo: `{"userInfo": {"username": "bob", "groups": ["system:authenticated", "developers"]}}`, rules: 1, status: response.RuleStatusFail},
+		{name: "empty user info", userInfo: `{}`, rules: 1, status: response.RuleStatusFail},
+	}
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+	resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var userInfo kyverno.RequestInfo
+			assert.NilError(t, json.Unmarshal([]byte(tc.userInfo), &userInfo))
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddResource(resourceRaw))
+			assert.NilError(t, ctx.AddUserInfo(userInfo))
+
+			er := Validate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured, AdmissionInfo: userInfo})
+			assert.Equal(t, len(er.PolicyResponse.Rules), tc.rules)
+			if tc.rules > 0 {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status)
+			}
+		})
+	}
+}
+
+func Test_DenyExecInNamespace(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "deny-exec"},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "rules": [
+			{
+			  "name": "deny-exec-in-production",
+			  "match": {"resources": {"kinds": ["Pod/exec"]}},
+			  "validate": {
+				"message": "exec into pods of {{request.namespace}} is not allowed",
+				"deny": {"conditions": [{"key": "{{request.namespace}}", "operator": "Equals", "value": "production"}]}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	newRequest := func(namespace, kind, subresource string) string {
+		return `{"uid":"1","kind":{"group":"","version":"v1","kind":"` + kind + `"},"resource":{"group":"","version":"v1","resource":"pods"},"operation":"CONNECT","subResource":"` + subresource + `","name":"nginx","namespace":"` + namespace + `",` +
+			`"object":{"apiVersion":"v1","kind":"` + kind + `","container":"nginx","command":["sh"],"stdin":true,"tty":true}}`
+	}
+
+	testCases := []struct {
+		name    string
+		request string
+		rules   int
+		status  response.RuleStatus
+		message string
+	}{
+		{
+			name:    "exec in production is denied",
+			request: newRequest("production", "PodExecOptions", "exec"),
+			rules:   1,
+			status:  response.RuleStatusFail,
+			message: "exec into pods of production is not allowed",
+		},
+		{
+			name:    "exec in another namespace is allowed",
+			request: newRequest("dev", "PodExecOptions", "exec"),
+			rules:   1,
+			status:  response.RuleStatusPass,
+		},
+		{
+			name:    "attach is not matched",
+			request: newRequest("production", "PodAttachOptions", "attach"),
+			rules:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+			var request *v1beta1.AdmissionRequest
+			assert.NilError(t, json.Unmarshal([]byte(tc.request), &request))
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddRequest(request))
+
+			newR, oldR, err := utils2.ExtractResources(nil, request)
+			assert.NilError(t, err)
+
+			er := Validate(&PolicyContext{
+				Policy:      policy,
+				NewResource: newR,
+				OldResource: oldR,
+				JSONContext: ctx,
+				SubResource: pkgcommon.RequestSubresource(request.Resource.Resource, request.SubResource),
+			})
+
+			assert.Equal(t, len(er.PolicyResponse.Rules), tc.rules)
+			if tc.rules > 0 {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status)
+			}
+			if tc.message != "" {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Message, tc.message)
+			}
+		})
+	}
+}
+
+func Test_Validate_RuleErrorTypes(t *testing.T) {
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}`)
+
+	testCases := []struct {
+		name     string
+		validate string
+		errType  error
+	}{
+		{
+			name:     "unresolved variable",
+			validate: `{"pattern": {"spec": {"containers": [{"name": "{{request.object.metadata.name1}}"}]}}}`,
+			errType:  ErrRuleEvaluation,
+		},
+		{
+			name:     "unresolved variable in deny conditions",
+			validate: `{"deny": {"conditions": [{"key": "{{request.object.spec.runtimeClass}}", "operator": "Equals", "value": "gvisor"}]}}`,
+			errType:  ErrRuleEvaluation,
+		},
+		{
+			name:     "anyPattern is not a list",
+			validate: `{"anyPattern": {"spec": {"containers": [{"name": "?*"}]}}}`,
+			errType:  ErrPatternMatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policyRaw := []byte(`{"apiVersion": "kyverno.io/v1", "kind": "ClusterPolicy", "metadata": {"name": "rule-errors"},
+				"spec": {"rules": [{"name": "check", "match": {"resources": {"kinds": ["Pod"]}}, "validate": ` + tc.validate + `}]}}`)
+
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+			resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+			assert.NilError(t, err)
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddResource(resourceRaw))
+
+			er := Validate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured})
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusError)
+			assert.Assert(t, errors.Is(er.PolicyResponse.Rules[0].Error, tc.errType))
+
+			ruleErr, ok := er.PolicyResponse.Rules[0].Error.(*RuleError)
+			assert.Assert(t, ok)
+			assert.Equal(t, ruleErr.Policy, "rule-errors")
+			assert.Equal(t, ruleErr.Rule, "check")
+			assert.Equal(t, ruleErr.Resource, "Pod/default/nginx")
+		})
+	}
+}
+
+func Test_Validate_FailFast(t *testing.T) {
+	newPolicy := func(failFast string) []byte {
+		return []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "pod-requirements"},
+		"spec": {
+		  "validationFailureAction": "enforce",` + failFast + `
+		  "rules": [
+			{
+			  "name": "require-name",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {"message": "a name is required", "pattern": {"metadata": {"name": "?*"}}}
+			},
+			{
+			  "name": "require-team-label",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {"message": "the team label is required", "pattern": {"metadata": {"labels": {"team": "?*"}}}}
+			},
+			{
+			  "name": "disallow-latest-tag",
+			  "match": {"resources": {"kinds": ["Pod"]}},
+			  "validate": {"message": "the latest tag is not allowed", "pattern": {"spec": {"containers": [{"image": "!*:latest"}]}}}
+			}
+		  ]
+		}
+	  }`)
+	}
+
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"containers": [{"name": "nginx", "image": "nginx:latest"}]}}`)
+
+	testCases := []struct {
+		name     string
+		failFast string
+		rules    []string
+	}{
+		{name: "all rules are evaluated by default", failFast: ``, rules: []string{"require-name", "require-team-label", "disallow-latest-tag"}},
+		{name: "all rules are evaluated without failFast", failFast: ` "failFast": false,`, rules: []string{"require-name", "require-team-label", "disallow-latest-tag"}},
+		{name: "evaluation stops on the first failure", failFast: ` "failFast": true,`, rules: []string{"require-name", "require-team-label"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(newPolicy(tc.failFast), &policy))
+			resourceUnstructured, err := utils.ConvertToUnstructured(resourceRaw)
+			assert.NilError(t, err)
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddResource(resourceRaw))
+
+			er := Validate(&PolicyContext{Policy: policy, JSONContext: ctx, NewResource: *resourceUnstructured})
+
+			var rules []string
+			for _, r := range er.PolicyResponse.Rules {
+				rules = append(rules, r.Name)
+			}
+			assert.DeepEqual(t, rules, tc.rules)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, response.RuleStatusPass)
+			assert.Equal(t, er.PolicyResponse.Rules[1].Status, response.RuleStatusFail)
+		})
+	}
+}
+
+func Test_Validate_Preconditions(t *testing.T) {
+	newPolicy := func(preconditions string) []byte {
+		return []byte(`{
+			"apiVersion": "kyverno.io/v1",
+			"kind": "ClusterPolicy",
+			"metadata": {"name": "require-team-label"},
+			"spec": {
+			  "validationFailureAction": "enforce",
+			  "rules": [
+				{
+				  "name": "require-team-label",
+				  "match": {"resources": {"kinds": ["Pod"]}},
+				  "preconditions": ` + preconditions + `,
+				  "validate": {
+					"message": "label team is required",
+					"pattern": {"metadata": {"labels": {"team": "?*"}}}
+				  }
+				}
+			  ]
+			}
+		  }`)
+	}
+
+	testCases := []struct {
+		name          string
+		preconditions string
+		status        response.RuleStatus
+	}{
+		{
+			name:          "Equals proceeds",
+			preconditions: `{"all": [{"key": "{{request.operation}}", "operator": "Equals", "value": "CREATE"}]}`,
+			status:        response.RuleStatusFail,
+		},
+		{
+			name:          "Equals skips",
+			preconditions: `{"all": [{"key": "{{request.operation}}", "operator": "Equals", "value": "UPDATE"}]}`,
+			status:        response.RuleStatusSkip,
+		},
+		{
+			name:          "NotEquals proceeds",
+			preconditions: `{"all": [{"key": "{{request.object.metadata.labels.app}}", "operator": "NotEquals", "value": "system"}]}`,
+			status:        response.RuleStatusFail,
+		},
+		{
+			name:          "NotEquals skips",
+			preconditions: `{"all": [{"key": "{{request.object.metadata.labels.app}}", "operator": "NotEquals", "value": "nginx"}]}`,
+			status:        response.RuleStatusSkip,
+		},
+		{
+			name:          "In proceeds",
+			preconditions: `{"all": [{"key": "{{request.operation}}", "operator": "In", "value": ["CREATE", "UPDATE"]}]}`,
+			status:        response.RuleStatusFail,
+		},
+		{
+			name:          "In skips",
+			preconditions: `{"all": [{"key": "{{request.operation}}", "operator": "In", "value": ["DELETE", "UPDATE"]}]}`,
+			status:        response.RuleStatusSkip,
+		},
+		{
+			name:          "NotIn proceeds",
+			preconditions: `{"all": [{"key": "{{request.object.metadata.namespace}}", "operator": "NotIn", "value": ["kube-system", "kyverno"]}]}`,
+			status:        response.RuleStatusFail,
+		},
+		{
+			name:          "NotIn skips",
+			preconditions: `{"all": [{"key": "{{request.object.metadata.namespace}}", "operator": "NotIn", "value": ["default", "kyverno"]}]}`,
+			status:        response.RuleStatusSkip,
+		},
+		{
+			name:          "failed condition of all skips",
+			preconditions: `{"all": [{"key": "{{request.operation}}", "operator": "Equals", "value": "CREATE"}, {"key": "{{request.object.metadata.labels.app}}", "operator": "Equals", "value": "httpd"}]}`,
+			status:        response.RuleStatusSkip,
+		},
+		{
+			name:          "passed condition of any proceeds",
+			preconditions: `{"any": [{"key": "{{request.operation}}", "operator": "Equals", "value": "UPDATE"}, {"key": "{{request.object.metadata.labels.app}}", "operator": "Equals", "value": "nginx"}]}`,
+			status:        response.RuleStatusFail,
+		},
+		{
+			name:          "list of conditions skips",
+			preconditions: `[{"key": "{{request.operation}}", "operator": "NotEquals", "value": "CREATE"}]`,
+			status:        response.RuleStatusSkip,
+		},
+	}
+
+	pod := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","namespace":"default","labels":{"app":"nginx"}},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(newPolicy(tc.preconditions), &policy))
+
+			var request *v1beta1.AdmissionRequest
+			assert.NilError(t, json.Unmarshal([]byte(`{"uid":"1","operation":"CREATE","name":"nginx","namespace":"default","object":`+pod+`}`), &request))
+
+			ctx := context.NewContext()
+			assert.NilError(t, ctx.AddRequest(request))
+
+			newR, oldR, err := utils2.ExtractResources(nil, request)
+			assert.NilError(t, err)
+
+			er := Validate(&PolicyContext{Policy: policy, NewResource: newR, OldResource: oldR, JSONContext: ctx})
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status)
+		})
+	}
+}
+
+func Test_Validate_RejectUnknownFields(t *testing.T) {
+	newPolicy := func(validate string) []byte {
+		return []byte(`{
+			"apiVersion": "kyverno.io/v1",
+			"kind": "ClusterPolicy",
+			"metadata": {"name": "lockdown-pod-spec"},
+			"spec": {
+			  "validationFailureAction": "enforce",
+			  "rules": [
+				{
+				  "name": "lockdown-pod-spec",
+				  "match": {"resources": {"kinds": ["Pod"]}},
+				  "validate": ` + validate + `
+				}
+			  ]
+			}
+		  }`)
+	}
+
+	pattern := `{"spec": {"containers": [{"name": "?*", "image": "?*", "=(imagePullPolicy)": "IfNotPresent|Always"}]}}`
+	testCases := []struct {
+		name     string
+		validate string
+		pod      string
+		status   response.RuleStatus
+		message  string
+	}{
+		{
+			name:     "clean resource",
+			validate: `{"rejectUnknownFields": true, "pattern": ` + pattern + `}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","labels":{"app":"nginx"}},"spec":{"containers":[{"name":"nginx","image":"nginx"}]},"status":{"phase":"Pending"}}`,
+			status:   response.RuleStatusPass,
+		},
+		{
+			name:     "optional field",
+			validate: `{"rejectUnknownFields": true, "pattern": ` + pattern + `}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"},{"name":"sidecar","image":"busybox","imagePullPolicy":"Always"}]}}`,
+			status:   response.RuleStatusPass,
+		},
+		{
+			name:     "forbidden field in array element",
+			validate: `{"rejectUnknownFields": true, "pattern": ` + pattern + `}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"},{"name":"sidecar","image":"busybox","securityContext":{"privileged":true}}]}}`,
+			status:   response.RuleStatusFail,
+			message:  "validation error: rule lockdown-pod-spec failed at path /spec/containers/1/securityContext/",
+		},
+		{
+			name:     "forbidden field",
+			validate: `{"rejectUnknownFields": true, "pattern": ` + pattern + `}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"hostNetwork":true,"containers":[{"name":"nginx","image":"nginx"}]}}`,
+			status:   response.RuleStatusFail,
+			message:  "validation error: rule lockdown-pod-spec failed at path /spec/hostNetwork/",
+		},
+		{
+			name:     "extra field allowed without rejectUnknownFields",
+			validate: `{"pattern": ` + pattern + `}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"hostNetwork":true,"containers":[{"name":"nginx","image":"nginx"}]}}`,
+			status:   response.RuleStatusPass,
+		},
+		{
+			name:     "forbidden field in anyPattern",
+			validate: `{"rejectUnknownFields": true, "anyPattern": [` + pattern + `, {"spec": {"containers": [{"name": "?*"}]}}]}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"hostNetwork":true,"containers":[{"name":"nginx","image":"nginx"}]}}`,
+			status:   response.RuleStatusFail,
+			message:  "validation error: Rule lockdown-pod-spec[0] failed at path /spec/hostNetwork/. Rule lockdown-pod-spec[1] failed at path /spec/containers/0/image/.",
+		},
+		{
+			name:     "clean resource in anyPattern",
+			validate: `{"rejectUnknownFields": true, "anyPattern": [{"spec": {"containers": [{"name": "?*"}]}}, ` + pattern + `]}`,
+			pod:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`,
+			status:   response.RuleStatusPass,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var policy kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(newPolicy(tc.validate), &policy))
+
+			resourceUnstructured, err := utils.ConvertToUnstructured([]byte(tc.pod))
+			assert.NilError(t, err)
+
+			er := Validate(&PolicyContext{Policy: policy, NewResource: *resourceUnstructured, JSONContext: context.NewContext()})
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status)
+			if tc.message != "" {
+				assert.Equal(t, er.PolicyResponse.Rules[0].Message, tc.message)
+			}
+		})
+	}
+}
+
+func Test_Validate_ExcludedUsername(t *testing.T) {
+	policyRaw := []byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "require-team-label"},
+		"spec": {
+			"validationFailureAction": "enforce",
+			"rules": [
+				{
+					"name": "require-team-label",
+					"match": {"resources": {"kinds": ["ConfigMap"]}},
+					"validate": {"message": "the team label is required", "pattern": {"metadata": {"labels": {"team": "?*"}}}}
+				}
+			]
+		}
+	}`)
+	resourceRaw := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "generated", "namespace": "default"}}`)
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(policyRaw, &policy))
+
+	resource, err := utils.ConvertToUnstructured(resourceRaw)
+	assert.NilError(t, err)
+
+	kyvernoSA := "system:serviceaccount:kyverno:kyverno-service-account"
+	testCases := []struct {
+		name             string
+		username         string
+		excludedUsername string
+		rules            int
+	}{
+		{name: "request of the excluded service account", username: kyvernoSA, excludedUsername: kyvernoSA, rules: 0},
+		{name: "request of another user", username: "system:serviceaccount:default:builder", excludedUsername: kyvernoSA, rules: 1},
+		{name: "exclusion disabled", username: kyvernoSA, rules: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policyContext := &PolicyContext{
+				Policy:           policy,
+				NewResource:      *resource,
+				JSONContext:      context.NewContext(),
+				AdmissionInfo:    kyverno.RequestInfo{AdmissionUserInfo: authenticationv1.UserInfo{Username: tc.username}},
+				ExcludedUsername: tc.excludedUsername,
+			}
+
+			er := Validate(policyContext)
+			assert.Equal(t, len(er.PolicyResponse.Rules), tc.rules)
+			assert.Equal(t, er.IsSuccessful(), tc.rules == 0)
+		})
+	}
+}
@@ -243,6 +243,34 @@ func TestEvaluate(t *testing.T) {
 		{kyverno.Condition{Key: 10, Operator: kyverno.LessThanOrEquals, Value: int64(1)}, false},
 		{kyverno.Condition{Key: 1, Operator: kyverno.LessThanOrEquals, Value: int64(10)}, true},
 
+		// Quantities
+		{kyverno.Condition{Key: "512Mi", Operator: kyverno.LessThan, Value: "1Gi"}, true},
+		{kyverno.Condition{Key: "512Mi", Operator: kyverno.GreaterThan, Value: "1Gi"}, false},
+		{kyverno.Condition{Key: "8Gi", Operator: kyverno.GreaterThan, Value: "4Gi"}, true},
+		{kyverno.Condition{Key: "4Gi", Operator: kyverno.GreaterThan, Value: "4096Mi"}, false},
+		{kyverno.Condition{Key: "4Gi", Operator: kyverno.LessThanOrEquals, Value: "4096Mi"}, true},
+		{kyverno.Condition{Key: "500Mi", Operator: kyverno.GreaterThanOrEquals, Value: "0.5Gi"}, false},
+		{kyverno.Condition{Key: "1Gi", Operator: kyverno.GreaterThan, Value: "1G"}, true},
+		{kyverno.Condition{Key: "250m", Operator: kyverno.LessThan, Value: "1"}, true},
+		{kyverno.Condition{Key: "1500m", Operator: kyverno.GreaterThan, Value: "1"}, true},
+		{kyverno.Condition{Key: "0.5", Operator: kyverno.LessThan, Value: "1Gi"}, true},
+		{kyverno.Condition{Key: "4Gi", Operator: kyverno.GreaterThan, Value: 1073741824}, true},
+		{kyverno.Condition{Key: "1Ki", Operator: kyverno.GreaterThanOrEquals, Value: 1024.0}, true},
+		{kyverno.Condition{Key: 2, Operator: kyverno.LessThan, Value: "1Ki"}, true},
+		{kyverno.Condition{Key: 0.25, Operator: kyverno.LessThan, Value: "1Ki"}, true},
+		{kyverno.Condition{Key: "1Gi", Operator: kyverno.Equals, Value: "1073741824"}, true},
+		{kyverno.Condition{Key: "1G", Operator: kyverno.Equals, Value: "1000M"}, true},
+		{kyverno.Condition{Key: "1G", Operator: kyverno.Equals, Value: "1Gi"}, false},
+		{kyverno.Condition{Key: "1000m", Operator: kyverno.Equals, Value: "1"}, true},
+		{kyverno.Condition{Key: "1k", Operator: kyverno.Equals, Value: 1000}, true},
+		{kyverno.Condition{Key: "1Ki", Operator: kyverno.Equals, Value: 1024.0}, true},
+		{kyverno.Condition{Key: 1, Operator: kyverno.Equals, Value: "1000m"}, true},
+		{kyverno.Condition{Key: 0.5, Operator: kyverno.Equals, Value: "500m"}, true},
+		{kyverno.Condition{Key: 1, Operator: kyverno.Equals, Value: "500m"}, false},
+		{kyverno.Condition{Key: "2Ki", Operator: kyverno.NotEquals, Value: 2048}, false},
+		{kyverno.Condition{Key: 1, Operator: kyverno.NotEquals, Value: "1000m"}, false},
+		{kyverno.Condition{Key: 0.5, Operator: kyverno.NotEquals, Value: "250m"}, true},
+
 		// In
 		{kyverno.Condition{Key: 1, Operator: kyverno.In, Value: []interface{}{1, 2, 3}}, true},
 		{kyverno.Condition{Key: 1.5, Operator: kyverno.In, Value: []interface{}{1, 1.5, 2, 3}}, true},
@@ -85,6 +85,10 @@ func (neh NotEqualHandler) validateValueWithStringPattern(key string, value inte
 				return false
 			}
 			return !resourceKey.Equal(resourceValue)
+		case int, int64, float64:
+			// compare with a number, e.g. 1000m and 1
+			resourceValue, _ := parseQuantity(typedValue)
+			return !resourceKey.Equal(resourceValue)
 		}
 	}
 
@@ -116,6 +120,10 @@ func (neh NotEqualHandler) validateValueWithFloatPattern(key float64, value inte
 		// extract float from string
 		float64Num, err := strconv.ParseFloat(typedValue, 64)
 		if err != nil {
+			// compare with a resource quantity, e.g. 0.5 and 500m
+			if result, ok := compareQuantities(key, typedValue); ok {
+				return result != 0
+			}
 			neh.log.Error(err, "Failed to parse float64 from string")
 			return true
 		}
@@ -153,6 +161,10 @@ func (neh NotEqualHandler) validateValueWithIntPattern(key int64, value interfac
 		// extract in64 from string
 		int64Num, err := strconv.ParseInt(typedValue, 10, 64)
 		if err != nil {
+			// compare with a resource quantity, e.g. 1 and 1000m
+			if result, ok := compareQuantities(key, typedValue); ok {
+				return result != 0
+			}
 			neh.log.Error(err, "Failed to parse int64 from string")
 			return true
 		}
@@ -89,7 +89,11 @@ func (noh NumericOperatorHandler) validateValueWithIntPattern(key int64, value i
 		if err == nil {
 			return compareByCondition(float64(key), float64(int64val), noh.condition, &noh.log)
 		}
-		noh.log.Error(fmt.Errorf("parse error: "), "Failed to parse both float64 and int64 from the string value")
+		// compare with a resource quantity, e.g. 2 > 500m
+		if result, ok := compareQuantities(key, typedValue); ok {
+			return compareByCondition(float64(result), 0, noh.condition, &noh.log)
+		}
+		noh.log.Error(fmt.Errorf("parse error: "), "Failed to parse float64, int64 and resource quantity from the string value")
 		return false
 	default:
 		noh.log.Info("Expected type int", "value", value, "type", fmt.Sprintf("%T", value))
@@ -118,7 +122,11 @@ func (noh NumericOperatorHandler) validateValueWithFloatPattern(key float64, val
 		if err == nil {
 			return compareByCondition(key, float64(int64val), noh.condition, &noh.log)
 		}
-		noh.log.Error(fmt.Errorf("parse error: "), "Failed to parse both float64 and int64 from the string value")
+		// compare with a resource quantity, e.g. 0.25 < 500m
+		if result, ok := compareQuantities(key, typedValue); ok {
+			return compareByCondition(float64(result), 0, noh.condition, &noh.log)
+		}
+		noh.log.Error(fmt.Errorf("parse error: "), "Failed to parse float64, int64 and resource quantity from the string value")
 		return false
 	default:
 		noh.log.Info("Expected type float", "value", value, "type", fmt.Sprintf("%T", value))
@@ -126,19 +134,15 @@ func (noh NumericOperatorHandler) validateValueWithFloatPattern(key float64, val
 	}
 }
 
+// validateValueWithResourcePattern compares the quantities regardless of their units, e.g. 512Mi < 1Gi,
+// the value is a quantity string or a number, e.g. 4Gi > 1073741824
 func (noh NumericOperatorHandler) validateValueWithResourcePattern(key resource.Quantity, value interface{}) bool {
-	switch typedValue := value.(type) {
-	case string:
-		resourceValue, err := resource.ParseQuantity(typedValue)
-		if err != nil {
-			noh.log.Error(fmt.Errorf("parse error: "), "Failed to parse value type doesn't match key type")
-			return false
-		}
-		return compareByCondition(float64(key.Cmp(resourceValue)), 0, noh.condition, &noh.log)
-	default:
-		noh.log.Info("Expected type string", "value", value, "type", fmt.Sprintf("%T", value))
+	resourceValue, err := parseQuantity(value)
+	if err != nil {
+		noh.log.Error(err, "Failed to parse value type doesn't match key type", "value", value)
 		return false
 	}
+	return compareByCondition(float64(key.Cmp(resourceValue)), 0, noh.condition, &noh.log)
 }
 
 func (noh NumericOperatorHandler) validateValueWithStringPattern(key string, value interface{}) bool {
@@ -0,0 +1,42 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// parseQuantity returns the resource quantity of a number, or of a string in the
+// Kubernetes quantity format, e.g. 512Mi, 1Gi, 500m or 1e3
+func parseQuantity(value interface{}) (resource.Quantity, error) {
+	switch typedValue := value.(type) {
+	case int:
+		return *resource.NewQuantity(int64(typedValue), resource.DecimalSI), nil
+	case int64:
+		return *resource.NewQuantity(typedValue, resource.DecimalSI), nil
+	case float64:
+		return resource.ParseQuantity(strconv.FormatFloat(typedValue, 'f', -1, 64))
+	case string:
+		return resource.ParseQuantity(typedValue)
+	default:
+		return resource.Quantity{}, fmt.Errorf("expected a number or a quantity string, found %T", value)
+	}
+}
+
+// compareQuantities compares the key with the value as resource quantities, regardless of their units,
+// e.g. 512Mi is less than 1Gi and 1Gi equals 1024Mi. It returns -1, 0 or 1 like resource.Quantity.Cmp,
+// ok is false if either the key or the value is not a quantity.
+func compareQuantities(key, value interface{}) (result int, ok bool) {
+	keyQuantity, err := parseQuantity(key)
+	if err != nil {
+		return 0, false
+	}
+
+	valueQuantity, err := parseQuantity(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return keyQuantity.Cmp(valueQuantity), true
+}
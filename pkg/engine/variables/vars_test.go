@@ -11,6 +11,9 @@ import (
 	"github.com/kyverno/kyverno/pkg/engine/context"
 	ju "github.com/kyverno/kyverno/pkg/engine/jsonutils"
 	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -1157,3 +1160,45 @@ func Test_getJMESPath(t *testing.T) {
 	assert.Equal(t, "spec.containers[0].volumes[1]", getJMESPath("/validate/pattern/spec/containers/0/volumes/1"))
 	assert.Equal(t, "[0]", getJMESPath("/mutate/overlay/0"))
 }
+
+func Test_SubstituteAllInRule_AdmissionRequest(t *testing.T) {
+	request := &v1beta1.AdmissionRequest{
+		Operation: v1beta1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+		Object: runtime.RawExtension{Raw: []byte(`{
+			"apiVersion": "apps/v1",
+			"kind": "Deployment",
+			"metadata": {"name": "web", "namespace": "team-a"},
+			"spec": {"template": {"spec": {"containers": [{"name": "nginx", "image": "nginx:1.21"}]}}}
+		}`)},
+	}
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddRequest(request))
+
+	newRule := func(message string) v1.Rule {
+		return v1.Rule{
+			Name: "check-owner",
+			Validation: v1.Validation{
+				Message: message,
+			},
+		}
+	}
+
+	rule, err := SubstituteAllInRule(log.Log, ctx, newRule("{{request.userInfo.username}} created {{request.object.metadata.namespace}}/{{request.object.metadata.name}}"))
+	assert.NilError(t, err)
+	assert.Equal(t, rule.Validation.Message, "jane created team-a/web")
+
+	// nested path
+	rule, err = SubstituteAllInRule(log.Log, ctx, newRule("image {{request.object.spec.template.spec.containers[0].image}} is not allowed"))
+	assert.NilError(t, err)
+	assert.Equal(t, rule.Validation.Message, "image nginx:1.21 is not allowed")
+
+	// unresolved variables fail closed
+	_, err = SubstituteAllInRule(log.Log, ctx, newRule("owner {{request.object.metadata.labels.owner}}"))
+	assert.Assert(t, err != nil)
+
+	// malformed expression
+	_, err = SubstituteAllInRule(log.Log, ctx, newRule("{{request.object.metadata.[}}"))
+	assert.ErrorContains(t, err, "incorrect query")
+}
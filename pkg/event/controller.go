@@ -60,9 +60,9 @@ func NewEventGenerator(client *client.Client, cpInformer kyvernoinformer.Cluster
 		pLister:              pInformer.Lister(),
 		pSynced:              pInformer.Informer().HasSynced,
 		queue:                workqueue.NewNamedRateLimitingQueue(rateLimiter(), eventWorkQueueName),
-		policyCtrRecorder:    initRecorder(client, PolicyController, log),
-		admissionCtrRecorder: initRecorder(client, AdmissionController, log),
-		genPolicyRecorder:    initRecorder(client, GeneratePolicyController, log),
+		policyCtrRecorder:    NewRecorder(client, PolicyController, log),
+		admissionCtrRecorder: NewRecorder(client, AdmissionController, log),
+		genPolicyRecorder:    NewRecorder(client, GeneratePolicyController, log),
 		resCache:             resCache,
 		log:                  log,
 	}
@@ -73,7 +73,8 @@ func rateLimiter() workqueue.RateLimiter {
 	return workqueue.DefaultItemBasedRateLimiter()
 }
 
-func initRecorder(client *client.Client, eventSource Source, log logr.Logger) record.EventRecorder {
+// NewRecorder creates an event recorder that writes the events of eventSource to the API server
+func NewRecorder(client *client.Client, eventSource Source, log logr.Logger) record.EventRecorder {
 	// Initliaze Event Broadcaster
 	err := scheme.AddToScheme(scheme.Scheme)
 	if err != nil {
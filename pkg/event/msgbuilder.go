@@ -13,6 +13,7 @@ const (
 	FPolicyApply = iota
 	FResourcePolicyApply
 	SPolicyApply
+	SResourcePolicyApply
 )
 
 func (k MsgKey) String() string {
@@ -20,6 +21,7 @@ func (k MsgKey) String() string {
 		"Rule(s) '%s' failed to apply on resource %s",
 		"Rule(s) '%s' of policy '%s' failed to apply on the resource",
 		"Rule(s) '%s' successfully applied on resource %s",
+		"Rule(s) '%s' of policy '%s' successfully applied on the resource",
 	}[k]
 }
 
@@ -0,0 +1,91 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	dclient "github.com/kyverno/kyverno/pkg/dclient"
+	"gotest.tools/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeControl struct {
+	deleted []string
+}
+
+func (c *fakeControl) Delete(gr string) error {
+	c.deleted = append(c.deleted, gr)
+	return nil
+}
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func newCleanupController(t *testing.T, objects ...runtime.Object) (*Controller, *fakeControl) {
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+		{Version: "v1", Resource: "namespaces"}: "NamespaceList",
+	}
+
+	client, err := dclient.NewMockClient(runtime.NewScheme(), gvrToListKind, objects...)
+	assert.NilError(t, err)
+	client.SetDiscovery(dclient.NewFakeDiscoveryClient(nil))
+
+	control := &fakeControl{}
+	return &Controller{client: client, control: control, log: logr.DiscardLogger{}}, control
+}
+
+func newGenerateRequest() kyverno.GenerateRequest {
+	gr := kyverno.GenerateRequest{}
+	gr.Name = "gr-1"
+	gr.Annotations = map[string]string{"generate.kyverno.io/retry-count": "5"}
+	gr.Spec.Resource = kyverno.ResourceSpec{Kind: "Namespace", Name: "team-a"}
+	gr.Status.GeneratedResources = []kyverno.ResourceSpec{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "team-a", Name: "default-config"}}
+	return gr
+}
+
+func Test_processGR_TriggerDeleted(t *testing.T) {
+	c, control := newCleanupController(t, newObject("v1", "ConfigMap", "team-a", "default-config"))
+
+	assert.NilError(t, c.processGR(newGenerateRequest()))
+
+	_, err := c.client.GetResource("v1", "ConfigMap", "team-a", "default-config")
+	assert.Assert(t, apierrors.IsNotFound(err), "the generated resource is not deleted: %v", err)
+	assert.DeepEqual(t, control.deleted, []string{"gr-1"})
+}
+
+func Test_processGR_TriggerExists(t *testing.T) {
+	c, control := newCleanupController(t,
+		newObject("v1", "Namespace", "", "team-a"),
+		newObject("v1", "ConfigMap", "team-a", "default-config"),
+	)
+
+	assert.NilError(t, c.processGR(newGenerateRequest()))
+
+	_, err := c.client.GetResource("v1", "ConfigMap", "team-a", "default-config")
+	assert.NilError(t, err)
+	assert.Assert(t, len(control.deleted) == 0)
+}
+
+func Test_processGR_RetriesPending(t *testing.T) {
+	c, control := newCleanupController(t, newObject("v1", "ConfigMap", "team-a", "default-config"))
+
+	// the generated resources are only deleted after 5 failed attempts to find the trigger
+	gr := newGenerateRequest()
+	gr.Annotations["generate.kyverno.io/retry-count"] = "2"
+	assert.NilError(t, c.processGR(gr))
+
+	_, err := c.client.GetResource("v1", "ConfigMap", "team-a", "default-config")
+	assert.NilError(t, err)
+	assert.Assert(t, len(control.deleted) == 0)
+}
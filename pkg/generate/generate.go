@@ -398,6 +398,9 @@ func applyRule(log logr.Logger, client *dclient.Client, rule kyverno.Rule, resou
 	// "kyverno.io/generated-by-namespace": namespace (trigger resource)
 	// "kyverno.io/generated-by-name": name (trigger resource)
 	manageLabels(newResource, resource)
+	if rule.Generation.OwnerRef {
+		setTriggerOwner(logger, newResource, resource)
+	}
 	// Add Synchronize label
 	label := newResource.GetLabels()
 	label["policy.kyverno.io/policy-name"] = policy
@@ -519,9 +522,13 @@ func manageClone(log logr.Logger, apiVersion, kind, namespace, name, policy stri
 	}
 
 	// check if the resource as reference in clone exists?
+	// the existing clones are kept if the source is deleted, they are synced again once it is re-created
 	obj, err := client.GetResource(apiVersion, kind, rNamespace, rName)
 	if err != nil {
-		return nil, Skip, fmt.Errorf("source resource %s %s/%s/%s not found. %v", apiVersion, kind, rNamespace, rName, err)
+		if apierrors.IsNotFound(err) {
+			return nil, Skip, fmt.Errorf("failed to clone the source resource: %w", NewNotFound(kind, rNamespace, rName))
+		}
+		return nil, Skip, fmt.Errorf("failed to get the source resource %s %s/%s/%s: %v", apiVersion, kind, rNamespace, rName, err)
 	}
 	// remove ownerReferences when cloning resources to other namespace
 	if rNamespace != namespace && obj.GetOwnerReferences() != nil {
@@ -0,0 +1,186 @@
+package generate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	dclient "github.com/kyverno/kyverno/pkg/dclient"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newConfigMap(namespace, name string, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": namespace, "name": name},
+		"data":       data,
+	}}
+}
+
+func newCloneClient(t *testing.T, objects ...runtime.Object) *dclient.Client {
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+	}
+
+	client, err := dclient.NewMockClient(runtime.NewScheme(), gvrToListKind, objects...)
+	assert.NilError(t, err)
+	client.SetDiscovery(dclient.NewFakeDiscoveryClient(nil))
+	return client
+}
+
+func newCloneRule(synchronize bool) kyverno.Rule {
+	return kyverno.Rule{
+		Name: "clone-golden-config",
+		Generation: kyverno.Generation{
+			ResourceSpec: kyverno.ResourceSpec{APIVersion: "v1", Kind: "ConfigMap", Namespace: "team-a", Name: "golden"},
+			Synchronize:  synchronize,
+			Clone:        kyverno.CloneFrom{Namespace: "default", Name: "golden"},
+		},
+	}
+}
+
+func cloneTrigger() unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": "team-a"},
+	}}
+}
+
+func Test_applyRule_Clone(t *testing.T) {
+	client := newCloneClient(t, newConfigMap("default", "golden", map[string]interface{}{"log-level": "info"}))
+	gr := kyverno.GenerateRequest{}
+	gr.Name = "gr-1"
+
+	genResource, err := applyRule(logr.DiscardLogger{}, client, newCloneRule(true), cloneTrigger(), context.NewContext(), "clone-config", gr)
+	assert.NilError(t, err)
+	assert.Equal(t, genResource, kyverno.ResourceSpec{APIVersion: "v1", Kind: "ConfigMap", Namespace: "team-a", Name: "golden"})
+
+	target, err := client.GetResource("v1", "ConfigMap", "team-a", "golden")
+	assert.NilError(t, err)
+	data, _, _ := unstructured.NestedStringMap(target.Object, "data")
+	assert.DeepEqual(t, data, map[string]string{"log-level": "info"})
+	assert.Equal(t, target.GetLabels()["policy.kyverno.io/synchronize"], "enable")
+	assert.Equal(t, target.GetLabels()["policy.kyverno.io/policy-name"], "clone-config")
+}
+
+func Test_applyRule_CloneSyncOnSourceChange(t *testing.T) {
+	testCases := []struct {
+		synchronize bool
+		expected    string
+	}{
+		{synchronize: true, expected: "debug"},
+		{synchronize: false, expected: "info"},
+	}
+
+	for _, tc := range testCases {
+		client := newCloneClient(t, newConfigMap("default", "golden", map[string]interface{}{"log-level": "info"}))
+		rule := newCloneRule(tc.synchronize)
+
+		_, err := applyRule(logr.DiscardLogger{}, client, rule, cloneTrigger(), context.NewContext(), "clone-config", kyverno.GenerateRequest{})
+		assert.NilError(t, err)
+
+		// the source is updated, the generate request is processed again
+		_, err = client.UpdateResource("v1", "ConfigMap", "default", newConfigMap("default", "golden", map[string]interface{}{"log-level": "debug"}), false)
+		assert.NilError(t, err)
+
+		_, err = applyRule(logr.DiscardLogger{}, client, rule, cloneTrigger(), context.NewContext(), "clone-config", kyverno.GenerateRequest{})
+		assert.NilError(t, err)
+
+		target, err := client.GetResource("v1", "ConfigMap", "team-a", "golden")
+		assert.NilError(t, err)
+		data, _, _ := unstructured.NestedStringMap(target.Object, "data")
+		assert.Equal(t, data["log-level"], tc.expected, "synchronize %v", tc.synchronize)
+	}
+}
+
+func Test_applyRule_CloneSourceNotFound(t *testing.T) {
+	client := newCloneClient(t)
+
+	_, err := applyRule(logr.DiscardLogger{}, client, newCloneRule(true), cloneTrigger(), context.NewContext(), "clone-config", kyverno.GenerateRequest{})
+	var notFound *NotFound
+	assert.Assert(t, errors.As(err, &notFound))
+	assert.ErrorContains(t, err, "ConfigMap/default/golden")
+
+	_, err = client.GetResource("v1", "ConfigMap", "team-a", "golden")
+	assert.Assert(t, err != nil)
+}
+
+func newOwnerRefRule(namespace string) kyverno.Rule {
+	return kyverno.Rule{
+		Name: "generate-config",
+		Generation: kyverno.Generation{
+			ResourceSpec: kyverno.ResourceSpec{APIVersion: "v1", Kind: "ConfigMap", Namespace: namespace, Name: "config"},
+			OwnerRef:     true,
+			Data:         map[string]interface{}{"data": map[string]interface{}{"log-level": "info"}},
+		},
+	}
+}
+
+func Test_applyRule_OwnerRef(t *testing.T) {
+	namespace := cloneTrigger()
+	namespace.SetUID("namespace-uid")
+
+	configMap := newConfigMap("team-a", "trigger", nil)
+	configMap.SetUID("configmap-uid")
+
+	testCases := []struct {
+		name      string
+		trigger   unstructured.Unstructured
+		namespace string
+		owner     string
+	}{
+		{name: "cluster-scoped trigger", trigger: namespace, namespace: "team-a", owner: "namespace-uid"},
+		{name: "trigger in the same namespace", trigger: *configMap, namespace: "team-a", owner: "configmap-uid"},
+		{name: "trigger in another namespace", trigger: *configMap, namespace: "team-b"},
+	}
+
+	for _, tc := range testCases {
+		client := newCloneClient(t)
+
+		_, err := applyRule(logr.DiscardLogger{}, client, newOwnerRefRule(tc.namespace), tc.trigger, context.NewContext(), "generate-config", kyverno.GenerateRequest{})
+		assert.NilError(t, err, tc.name)
+
+		target, err := client.GetResource("v1", "ConfigMap", tc.namespace, "config")
+		assert.NilError(t, err, tc.name)
+
+		owners := target.GetOwnerReferences()
+		if tc.owner == "" {
+			assert.Equal(t, len(owners), 0, tc.name)
+			continue
+		}
+
+		assert.Equal(t, len(owners), 1, tc.name)
+		assert.Equal(t, string(owners[0].UID), tc.owner, tc.name)
+		assert.Equal(t, owners[0].Kind, tc.trigger.GetKind(), tc.name)
+		assert.Equal(t, owners[0].Name, tc.trigger.GetName(), tc.name)
+	}
+}
+
+func Test_setTriggerOwner(t *testing.T) {
+	trigger := newConfigMap("team-a", "trigger", nil)
+	trigger.SetUID("trigger-uid")
+
+	// a namespaced trigger cannot own a cluster-scoped resource
+	clusterRole := &unstructured.Unstructured{}
+	clusterRole.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	clusterRole.SetKind("ClusterRole")
+	clusterRole.SetName("team-a")
+	assert.Assert(t, !setTriggerOwner(logr.DiscardLogger{}, clusterRole, *trigger))
+	assert.Equal(t, len(clusterRole.GetOwnerReferences()), 0)
+
+	// the owner is added once
+	generated := newConfigMap("team-a", "config", nil)
+	assert.Assert(t, setTriggerOwner(logr.DiscardLogger{}, generated, *trigger))
+	assert.Assert(t, setTriggerOwner(logr.DiscardLogger{}, generated, *trigger))
+	assert.Equal(t, len(generated.GetOwnerReferences()), 1)
+
+	// a trigger without UID, e.g. not yet created
+	assert.Assert(t, !setTriggerOwner(logr.DiscardLogger{}, newConfigMap("team-a", "config", nil), *newConfigMap("team-a", "trigger", nil)))
+}
@@ -0,0 +1,39 @@
+package generate
+
+import (
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// setTriggerOwner adds the trigger resource to the owners of the generated resource, so that the
+// generated resource is garbage collected when the trigger is deleted, and returns true if the owner is set.
+// Kubernetes only allows a cluster-scoped trigger, or a trigger in the namespace of the generated resource,
+// the owner is not set otherwise.
+func setTriggerOwner(log logr.Logger, generated *unstructured.Unstructured, trigger unstructured.Unstructured) bool {
+	if trigger.GetUID() == "" {
+		log.V(2).Info("trigger resource has no UID, the owner of the generated resource is not set")
+		return false
+	}
+
+	if ns := trigger.GetNamespace(); ns != "" && ns != generated.GetNamespace() {
+		log.Info("a namespaced trigger resource cannot own a cluster-scoped resource or a resource in another namespace, the owner of the generated resource is not set",
+			"triggerKind", trigger.GetKind(), "triggerNamespace", ns, "triggerName", trigger.GetName())
+		return false
+	}
+
+	owners := generated.GetOwnerReferences()
+	for _, owner := range owners {
+		if owner.UID == trigger.GetUID() {
+			return true
+		}
+	}
+
+	generated.SetOwnerReferences(append(owners, metav1.OwnerReference{
+		APIVersion: trigger.GetAPIVersion(),
+		Kind:       trigger.GetKind(),
+		Name:       trigger.GetName(),
+		UID:        trigger.GetUID(),
+	}))
+	return true
+}
@@ -80,3 +80,41 @@ func TestValidateFail(t *testing.T) {
 	_, err := ValidateResourceWithPattern(log, resource, pattern)
 	assert.Assert(t, err != nil)
 }
+
+func TestValidateResourceWithPattern_SynchronizeDrift(t *testing.T) {
+	// desired state of a generated NetworkPolicy, as rendered from the generate rule data
+	desired := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+			"policyTypes": []interface{}{"Ingress", "Egress"},
+		},
+	}
+
+	inSync := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"policy.kyverno.io/synchronize": "enable",
+			},
+		},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+			"policyTypes": []interface{}{"Ingress", "Egress"},
+		},
+	}
+
+	var log logr.Logger
+	_, err := ValidateResourceWithPattern(log, inSync, desired)
+	assert.NilError(t, err)
+
+	// a manual edit of the generated resource is detected as a drift
+	// and the generated resource is overwritten with the desired state
+	drifted := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+			"policyTypes": []interface{}{"Ingress"},
+		},
+	}
+
+	_, err = ValidateResourceWithPattern(log, drifted, desired)
+	assert.Assert(t, err != nil)
+}
@@ -63,3 +63,40 @@ func Test_Apply(t *testing.T) {
 		}
 	}
 }
+
+func Test_Apply_ResultCounts(t *testing.T) {
+	testcases := []struct {
+		name          string
+		policyPaths   []string
+		resourcePaths []string
+		pass          int
+		fail          int
+	}{
+		{
+			name:          "pass",
+			policyPaths:   []string{"../../../test/best_practices/disallow_latest_tag.yaml"},
+			resourcePaths: []string{"../../../test/resources/pod_with_version_tag.yaml"},
+			pass:          2,
+			fail:          0,
+		},
+		{
+			name:          "fail",
+			policyPaths:   []string{"../../../test/best_practices/disallow_latest_tag.yaml"},
+			resourcePaths: []string{"../../../test/resources/pod_with_latest_tag.yaml"},
+			pass:          1,
+			fail:          1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, resources, _, _, err := applyCommandHelper(tc.resourcePaths, false, false, "", "", "", "", tc.policyPaths, false)
+			assert.NilError(t, err)
+			assert.Equal(t, len(resources), 1)
+			// the command exits with a non-zero code when rc.Fail or rc.Error are set
+			assert.Equal(t, rc.Pass, tc.pass)
+			assert.Equal(t, rc.Fail, tc.fail)
+			assert.Equal(t, rc.Error, 0)
+		})
+	}
+}
@@ -964,10 +964,10 @@ func CheckVariableForPolicy(valuesMap map[string]map[string]Resource, globalValM
 func GetKindsFromPolicy(policy *v1.ClusterPolicy) map[string]struct{} {
 	var kindOnwhichPolicyIsApplied = make(map[string]struct{})
 	for _, rule := range policy.Spec.Rules {
-		for _, kind := range rule.MatchResources.ResourceDescription.Kinds {
+		for _, kind := range v1.ExpandKinds(rule.MatchResources.ResourceDescription.Kinds) {
 			kindOnwhichPolicyIsApplied[kind] = struct{}{}
 		}
-		for _, kind := range rule.ExcludeResources.ResourceDescription.Kinds {
+		for _, kind := range v1.ExpandKinds(rule.ExcludeResources.ResourceDescription.Kinds) {
 			kindOnwhichPolicyIsApplied[kind] = struct{}{}
 		}
 	}
@@ -121,7 +121,7 @@ func GetResourcesWithTest(fs billy.Filesystem, policies []*v1.ClusterPolicy, res
 	var resourceTypes []string
 	for _, policy := range policies {
 		for _, rule := range policy.Spec.Rules {
-			for _, kind := range rule.MatchResources.Kinds {
+			for _, kind := range v1.ExpandKinds(rule.MatchResources.Kinds) {
 				resourceTypesMap[kind] = true
 			}
 		}
@@ -289,7 +289,7 @@ func GetPatchedResource(patchResourceBytes []byte) (patchedResource unstructured
 // getKindsFromPolicy will return the kinds from policy match block
 func getKindsFromPolicy(rule v1.Rule) map[string]bool {
 	var resourceTypesMap = make(map[string]bool)
-	for _, kind := range rule.MatchResources.Kinds {
+	for _, kind := range v1.ExpandKinds(rule.MatchResources.Kinds) {
 		if strings.Contains(kind, "/") {
 			lastElement := kind[strings.LastIndex(kind, "/")+1:]
 			resourceTypesMap[strings.Title(lastElement)] = true
@@ -299,7 +299,7 @@ func getKindsFromPolicy(rule v1.Rule) map[string]bool {
 
 	if rule.MatchResources.Any != nil {
 		for _, resFilter := range rule.MatchResources.Any {
-			for _, kind := range resFilter.ResourceDescription.Kinds {
+			for _, kind := range v1.ExpandKinds(resFilter.ResourceDescription.Kinds) {
 				if strings.Contains(kind, "/") {
 					lastElement := kind[strings.LastIndex(kind, "/")+1:]
 					resourceTypesMap[strings.Title(lastElement)] = true
@@ -311,7 +311,7 @@ func getKindsFromPolicy(rule v1.Rule) map[string]bool {
 
 	if rule.MatchResources.All != nil {
 		for _, resFilter := range rule.MatchResources.All {
-			for _, kind := range resFilter.ResourceDescription.Kinds {
+			for _, kind := range v1.ExpandKinds(resFilter.ResourceDescription.Kinds) {
 				if strings.Contains(kind, "/") {
 					lastElement := kind[strings.LastIndex(kind, "/")+1:]
 					resourceTypesMap[strings.Title(lastElement)] = true
@@ -99,6 +99,20 @@ const PolicyCRD = `
 								  "name": {
 									"description": "Name is the variable name.",
 									"type": "string"
+								  },
+								  "resourceQuota": {
+									"description": "ResourceQuota fetches the ResourceQuotas of a namespace. The context entry contains the hard limit, the used and the remaining quantity of each quota resource, and exists is false if no quota is found.",
+									"properties": {
+									  "name": {
+										"description": "Name is the ResourceQuota name. If empty, all the ResourceQuotas of the namespace are fetched and the remaining quantity of a resource is the lowest of the quotas.",
+										"type": "string"
+									  },
+									  "namespace": {
+										"description": "Namespace is the ResourceQuota namespace. Defaults to the namespace of the resource.",
+										"type": "string"
+									  }
+									},
+									"type": "object"
 								  }
 								},
 								"type": "object"
@@ -506,7 +520,7 @@ const PolicyCRD = `
 								"x-kubernetes-preserve-unknown-fields": true
 							  },
 							  "patchStrategicMerge": {
-								"description": "PatchStrategicMerge is a strategic merge patch used to modify resources. See https://kubernetes.io/docs/tasks/manage-kubernetes-objects/update-api-object-kubectl-patch/ and https://kubectl.docs.kubernetes.io/references/kustomize/patchesstrategicmerge/.",
+								"description": "PatchStrategicMerge is a strategic merge patch used to modify resources. See https://kubernetes.io/docs/tasks/manage-kubernetes-objects/update-api-object-kubectl-patch/ and https://kubectl.docs.kubernetes.io/references/kustomize/patchesstrategicmerge/. A list of patches is applied in sequence, each patch to the resource patched by the previous ones, and the rule fails without patching the resource if a patch fails.",
 								"x-kubernetes-preserve-unknown-fields": true
 							  },
 							  "patches": {
@@ -586,7 +600,7 @@ const PolicyCRD = `
 					"type": "array"
 				  },
 				  "validationFailureAction": {
-					"description": "ValidationFailureAction controls if a validation policy rule failure should disallow the admission review request (enforce), or allow (audit) the admission review request and report an error in a policy report. Optional. The default value is \"audit\".",
+					"description": "ValidationFailureAction controls if a validation policy rule failure should disallow the admission review request (enforce), or allow the admission review request and report an error in a policy report (audit), or also return the messages of the failed rules as admission warnings (warn). Optional. The default value is \"audit\".",
 					"type": "string"
 				  }
 				},
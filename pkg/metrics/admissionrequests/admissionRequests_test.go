@@ -0,0 +1,59 @@
+package admissionrequests
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func newEngineResponse(namespace string, ruleTypes ...string) *response.EngineResponse {
+	er := &response.EngineResponse{}
+	er.PolicyResponse.Resource = response.ResourceSpec{Kind: "Pod", Namespace: namespace, Name: "nginx"}
+	for _, ruleType := range ruleTypes {
+		er.PolicyResponse.Rules = append(er.PolicyResponse.Rules, response.RuleResponse{Name: "rule", Type: ruleType, Status: response.RuleStatusPass})
+	}
+	return er
+}
+
+func Test_ProcessEngineResponses_IncrementsCounter(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	responses := []*response.EngineResponse{newEngineResponse("default", "Validation"), newEngineResponse("default", "Mutation")}
+	for i := 0; i < 2; i++ {
+		assert.NilError(t, pc.ProcessEngineResponses(responses, metrics.ResourceCreated))
+	}
+
+	counter := promConfig.Metrics.AdmissionRequests.With(prom.Labels{
+		"resource_kind":              "Pod",
+		"resource_namespace":         "default",
+		"resource_request_operation": "create",
+	})
+	assert.Equal(t, testutil.ToFloat64(counter), float64(2))
+}
+
+func Test_ProcessEngineResponses_NoRulesApplied(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	assert.NilError(t, pc.ProcessEngineResponses(nil, metrics.ResourceCreated))
+	assert.NilError(t, pc.ProcessEngineResponses([]*response.EngineResponse{newEngineResponse("default")}, metrics.ResourceUpdated))
+	assert.Equal(t, testutil.CollectAndCount(promConfig.Metrics.AdmissionRequests), 0)
+}
+
+func Test_ParseResourceRequestOperation(t *testing.T) {
+	op, err := ParseResourceRequestOperation("DELETE")
+	assert.NilError(t, err)
+	assert.Equal(t, op, metrics.ResourceDeleted)
+
+	_, err = ParseResourceRequestOperation("PATCH")
+	assert.ErrorContains(t, err, "unknown request operation")
+}
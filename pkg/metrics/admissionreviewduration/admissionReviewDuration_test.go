@@ -0,0 +1,41 @@
+package admissionreviewduration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	"gotest.tools/assert"
+)
+
+func Test_ProcessEngineResponses_ObservesLatency(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	er := &response.EngineResponse{}
+	er.PolicyResponse.Resource = response.ResourceSpec{Kind: "Pod", Namespace: "default", Name: "nginx"}
+	er.PolicyResponse.Rules = []response.RuleResponse{{Name: "validate-image-tag", Type: "Validation", Status: response.RuleStatusFail}}
+
+	assert.NilError(t, pc.ProcessEngineResponses([]*response.EngineResponse{er}, int64(250*time.Millisecond), metrics.ResourceCreated))
+	assert.NilError(t, pc.ProcessEngineResponses([]*response.EngineResponse{er}, int64(750*time.Millisecond), metrics.ResourceCreated))
+
+	families, err := promConfig.MetricsRegistry.Gather()
+	assert.NilError(t, err)
+
+	var found bool
+	for _, family := range families {
+		if family.GetName() != "kyverno_admission_review_duration_seconds" {
+			continue
+		}
+		found = true
+		assert.Equal(t, len(family.GetMetric()), 1)
+		histogram := family.GetMetric()[0].GetHistogram()
+		assert.Equal(t, histogram.GetSampleCount(), uint64(2))
+		assert.Equal(t, histogram.GetSampleSum(), float64(1))
+	}
+	assert.Assert(t, found)
+}
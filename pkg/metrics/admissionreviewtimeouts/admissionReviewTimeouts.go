@@ -0,0 +1,48 @@
+package admissionreviewtimeouts
+
+import (
+	"fmt"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func (pc PromConfig) registerAdmissionReviewTimeoutsMetric(
+	resourceKind, resourceNamespace string,
+	resourceRequestOperation metrics.ResourceRequestOperation,
+	webhookType WebhookType,
+	failurePolicy kyverno.FailurePolicyType,
+) error {
+	includeNamespaces, excludeNamespaces := pc.Config.GetIncludeNamespaces(), pc.Config.GetExcludeNamespaces()
+	if (resourceNamespace != "" && resourceNamespace != "-") && metrics.ElementInSlice(resourceNamespace, excludeNamespaces) {
+		pc.Log.Info(fmt.Sprintf("Skipping the registration of kyverno_admission_review_timeouts_total metric as the operation belongs to the namespace '%s' which is one of 'namespaces.exclude' %+v in values.yaml", resourceNamespace, excludeNamespaces))
+		return nil
+	}
+	if (resourceNamespace != "" && resourceNamespace != "-") && len(includeNamespaces) > 0 && !metrics.ElementInSlice(resourceNamespace, includeNamespaces) {
+		pc.Log.Info(fmt.Sprintf("Skipping the registration of kyverno_admission_review_timeouts_total metric as the operation belongs to the namespace '%s' which is not one of 'namespaces.include' %+v in values.yaml", resourceNamespace, includeNamespaces))
+		return nil
+	}
+	pc.Metrics.AdmissionReviewTimeouts.With(prom.Labels{
+		"resource_kind":              resourceKind,
+		"resource_namespace":         resourceNamespace,
+		"resource_request_operation": string(resourceRequestOperation),
+		"webhook_type":               string(webhookType),
+		"failure_policy":             string(failurePolicy),
+	}).Inc()
+	return nil
+}
+
+// ProcessTimeout records an admission review whose policy evaluation exceeded the deadline,
+// failurePolicy is the policy applied to the response of the request
+func (pc PromConfig) ProcessTimeout(
+	resourceKind, resourceNamespace string,
+	resourceRequestOperation metrics.ResourceRequestOperation,
+	webhookType WebhookType,
+	failurePolicy kyverno.FailurePolicyType,
+) error {
+	if resourceNamespace == "" {
+		resourceNamespace = "-"
+	}
+	return pc.registerAdmissionReviewTimeoutsMetric(resourceKind, resourceNamespace, resourceRequestOperation, webhookType, failurePolicy)
+}
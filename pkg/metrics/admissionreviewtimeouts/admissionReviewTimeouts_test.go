@@ -0,0 +1,41 @@
+package admissionreviewtimeouts
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func Test_ProcessTimeout(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	assert.NilError(t, pc.ProcessTimeout("Pod", "default", metrics.ResourceCreated, ValidatingWebhook, kyverno.Fail))
+	assert.NilError(t, pc.ProcessTimeout("Pod", "default", metrics.ResourceCreated, ValidatingWebhook, kyverno.Fail))
+	assert.NilError(t, pc.ProcessTimeout("Namespace", "", metrics.ResourceUpdated, MutatingWebhook, kyverno.Ignore))
+
+	counter := promConfig.Metrics.AdmissionReviewTimeouts.With(prom.Labels{
+		"resource_kind":              "Pod",
+		"resource_namespace":         "default",
+		"resource_request_operation": "create",
+		"webhook_type":               "validating",
+		"failure_policy":             "Fail",
+	})
+	assert.Equal(t, testutil.ToFloat64(counter), float64(2))
+
+	counter = promConfig.Metrics.AdmissionReviewTimeouts.With(prom.Labels{
+		"resource_kind":              "Namespace",
+		"resource_namespace":         "-",
+		"resource_request_operation": "update",
+		"webhook_type":               "mutating",
+		"failure_policy":             "Ignore",
+	})
+	assert.Equal(t, testutil.ToFloat64(counter), float64(1))
+}
@@ -0,0 +1,16 @@
+package admissionreviewtimeouts
+
+import (
+	"github.com/kyverno/kyverno/pkg/metrics"
+)
+
+type PromMetrics metrics.PromMetrics
+
+type PromConfig metrics.PromConfig
+
+type WebhookType string
+
+const (
+	MutatingWebhook   WebhookType = "mutating"
+	ValidatingWebhook WebhookType = "validating"
+)
@@ -5,6 +5,7 @@ type PolicyValidationMode string
 const (
 	Enforce PolicyValidationMode = "enforce"
 	Audit   PolicyValidationMode = "audit"
+	Warn    PolicyValidationMode = "warn"
 )
 
 type PolicyType string
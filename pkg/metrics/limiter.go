@@ -0,0 +1,43 @@
+package metrics
+
+import "sync"
+
+// OtherLabelValue is the label value of the series over the limit of a LabelLimiter
+const OtherLabelValue = "other"
+
+// LabelLimiter caps the cardinality of a metric label by tracking at most max distinct values,
+// the values seen after the limit is reached are reported as OtherLabelValue
+type LabelLimiter struct {
+	mutex  sync.Mutex
+	max    int
+	values map[string]struct{}
+}
+
+// NewLabelLimiter returns a limiter of max distinct label values
+func NewLabelLimiter(max int) *LabelLimiter {
+	return &LabelLimiter{max: max, values: make(map[string]struct{})}
+}
+
+// Limit returns the value if it is tracked or can still be tracked, OtherLabelValue otherwise
+func (l *LabelLimiter) Limit(value string) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.values[value]; ok {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return OtherLabelValue
+	}
+
+	l.values[value] = struct{}{}
+	return value
+}
+
+// Reset forgets the tracked values, it must be called when the metric is reset
+func (l *LabelLimiter) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.values = make(map[string]struct{})
+}
@@ -24,8 +24,18 @@ type PromMetrics struct {
 	PolicyExecutionDuration *prom.HistogramVec
 	AdmissionReviewDuration *prom.HistogramVec
 	AdmissionRequests       *prom.CounterVec
+	PolicyOutcomes          *prom.CounterVec
+	AdmissionReviewTimeouts *prom.CounterVec
+	WatcherRetries          *prom.CounterVec
+
+	// PolicyOutcomesLimiter caps the number of policies of the PolicyOutcomes metric
+	PolicyOutcomesLimiter *LabelLimiter
 }
 
+// MaxPolicyOutcomesPolicies is the maximum number of policies tracked by the kyverno_policy_outcomes_total metric,
+// the outcomes of the other policies are reported under the policy name "other"
+const MaxPolicyOutcomesPolicies = 500
+
 func NewPromConfig(metricsConfigData *config.MetricsConfigData, log logr.Logger) (*PromConfig, error) {
 	pc := new(PromConfig)
 	pc.Config = metricsConfigData
@@ -103,6 +113,39 @@ func NewPromConfig(metricsConfigData *config.MetricsConfigData, log logr.Logger)
 		admissionRequestsLabels,
 	)
 
+	policyOutcomesLabels := []string{
+		"policy_type", "policy_namespace", "policy_name", "policy_outcome",
+	}
+	policyOutcomesMetric := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kyverno_policy_outcomes_total",
+			Help: "can be used to track the outcome of the policies applied by the webhooks: matched when a rule of the policy was applied to the resource, skipped when no rule applied and error when a rule errored.",
+		},
+		policyOutcomesLabels,
+	)
+
+	admissionReviewTimeoutsLabels := []string{
+		"resource_kind", "resource_namespace", "resource_request_operation", "webhook_type", "failure_policy",
+	}
+	admissionReviewTimeoutsMetric := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kyverno_admission_review_timeouts_total",
+			Help: "can be used to track the admission reviews whose policy evaluation exceeded the engine timeout, the failure policy tells if the request was denied (Fail) or allowed (Ignore).",
+		},
+		admissionReviewTimeoutsLabels,
+	)
+
+	watcherRetriesLabels := []string{
+		"watcher",
+	}
+	watcherRetriesMetric := prom.NewCounterVec(
+		prom.CounterOpts{
+			Name: "kyverno_watcher_retries_total",
+			Help: "can be used to track the retries of the certificate watchers after a failure, i.e. the failed CA rotation checks and the reconnections of the TLS secrets watch. A steady increase means the watchers cannot reach the API server.",
+		},
+		watcherRetriesLabels,
+	)
+
 	pc.Metrics = &PromMetrics{
 		PolicyResults:           policyResultsMetric,
 		PolicyRuleInfo:          policyRuleInfoMetric,
@@ -110,6 +153,10 @@ func NewPromConfig(metricsConfigData *config.MetricsConfigData, log logr.Logger)
 		PolicyExecutionDuration: policyExecutionDurationMetric,
 		AdmissionReviewDuration: admissionReviewDurationMetric,
 		AdmissionRequests:       admissionRequestsMetric,
+		PolicyOutcomes:          policyOutcomesMetric,
+		AdmissionReviewTimeouts: admissionReviewTimeoutsMetric,
+		WatcherRetries:          watcherRetriesMetric,
+		PolicyOutcomesLimiter:   NewLabelLimiter(MaxPolicyOutcomesPolicies),
 	}
 
 	pc.MetricsRegistry.MustRegister(pc.Metrics.PolicyResults)
@@ -118,6 +165,9 @@ func NewPromConfig(metricsConfigData *config.MetricsConfigData, log logr.Logger)
 	pc.MetricsRegistry.MustRegister(pc.Metrics.PolicyExecutionDuration)
 	pc.MetricsRegistry.MustRegister(pc.Metrics.AdmissionReviewDuration)
 	pc.MetricsRegistry.MustRegister(pc.Metrics.AdmissionRequests)
+	pc.MetricsRegistry.MustRegister(pc.Metrics.PolicyOutcomes)
+	pc.MetricsRegistry.MustRegister(pc.Metrics.AdmissionReviewTimeouts)
+	pc.MetricsRegistry.MustRegister(pc.Metrics.WatcherRetries)
 
 	// configuring metrics periodic refresh
 	if pc.Config.GetMetricsRefreshInterval() != 0 {
@@ -132,6 +182,10 @@ func NewPromConfig(metricsConfigData *config.MetricsConfigData, log logr.Logger)
 				pc.Metrics.PolicyExecutionDuration.Reset()
 				pc.Metrics.AdmissionReviewDuration.Reset()
 				pc.Metrics.AdmissionRequests.Reset()
+				pc.Metrics.PolicyOutcomes.Reset()
+				pc.Metrics.AdmissionReviewTimeouts.Reset()
+				pc.Metrics.WatcherRetries.Reset()
+				pc.Metrics.PolicyOutcomesLimiter.Reset()
 			})
 			if err != nil {
 				return nil, err
@@ -13,8 +13,10 @@ func ParsePolicyValidationMode(validationFailureAction string) (PolicyValidation
 		return Enforce, nil
 	case "audit":
 		return Audit, nil
+	case "warn":
+		return Warn, nil
 	default:
-		return "", fmt.Errorf("wrong validation failure action found %s. Allowed: '%s', '%s'", validationFailureAction, "enforce", "audit")
+		return "", fmt.Errorf("wrong validation failure action found %s. Allowed: '%s', '%s', '%s'", validationFailureAction, "enforce", "audit", "warn")
 	}
 }
 
@@ -0,0 +1,28 @@
+package policyoutcomes
+
+import (
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/metrics"
+)
+
+func ParsePromMetrics(pm metrics.PromMetrics) PromMetrics {
+	return PromMetrics(pm)
+}
+
+func ParsePromConfig(pc metrics.PromConfig) PromConfig {
+	return PromConfig(pc)
+}
+
+// ParsePolicyOutcome returns error if a rule errored, matched if a rule was applied and skipped otherwise
+func ParsePolicyOutcome(engineResponse response.EngineResponse) PolicyOutcome {
+	outcome := PolicySkipped
+	for _, rule := range engineResponse.PolicyResponse.Rules {
+		switch rule.Status {
+		case response.RuleStatusError:
+			return PolicyError
+		case response.RuleStatusPass, response.RuleStatusFail, response.RuleStatusWarn:
+			outcome = PolicyMatched
+		}
+	}
+	return outcome
+}
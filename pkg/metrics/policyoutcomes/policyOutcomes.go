@@ -0,0 +1,56 @@
+package policyoutcomes
+
+import (
+	"fmt"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func (pc PromConfig) registerPolicyOutcomesMetric(
+	policyType metrics.PolicyType,
+	policyNamespace, policyName string,
+	resourceNamespace string,
+	policyOutcome PolicyOutcome,
+) error {
+	includeNamespaces, excludeNamespaces := pc.Config.GetIncludeNamespaces(), pc.Config.GetExcludeNamespaces()
+	if (resourceNamespace != "" && resourceNamespace != "-") && metrics.ElementInSlice(resourceNamespace, excludeNamespaces) {
+		pc.Log.Info(fmt.Sprintf("Skipping the registration of kyverno_policy_outcomes_total metric as the operation belongs to the namespace '%s' which is one of 'namespaces.exclude' %+v in values.yaml", resourceNamespace, excludeNamespaces))
+		return nil
+	}
+	if (resourceNamespace != "" && resourceNamespace != "-") && len(includeNamespaces) > 0 && !metrics.ElementInSlice(resourceNamespace, includeNamespaces) {
+		pc.Log.Info(fmt.Sprintf("Skipping the registration of kyverno_policy_outcomes_total metric as the operation belongs to the namespace '%s' which is not one of 'namespaces.include' %+v in values.yaml", resourceNamespace, includeNamespaces))
+		return nil
+	}
+
+	// the policies over the limit share a single series per outcome
+	if pc.Metrics.PolicyOutcomesLimiter.Limit(policyNamespace+"/"+policyName) == metrics.OtherLabelValue {
+		policyType, policyNamespace, policyName = metrics.PolicyType(metrics.OtherLabelValue), "-", metrics.OtherLabelValue
+	}
+
+	pc.Metrics.PolicyOutcomes.With(prom.Labels{
+		"policy_type":      string(policyType),
+		"policy_namespace": policyNamespace,
+		"policy_name":      policyName,
+		"policy_outcome":   string(policyOutcome),
+	}).Inc()
+	return nil
+}
+
+//policy - policy related data
+//engineResponse - resource and rule related data
+func (pc PromConfig) ProcessEngineResponse(policy kyverno.ClusterPolicy, engineResponse response.EngineResponse) error {
+	policyType := metrics.Namespaced
+	policyNamespace := policy.ObjectMeta.Namespace
+	if policyNamespace == "" {
+		policyNamespace = "-"
+		policyType = metrics.Cluster
+	}
+	policyName := policy.ObjectMeta.Name
+
+	resourceNamespace := engineResponse.PolicyResponse.Resource.Namespace
+
+	return pc.registerPolicyOutcomesMetric(policyType, policyNamespace, policyName, resourceNamespace, ParsePolicyOutcome(engineResponse))
+}
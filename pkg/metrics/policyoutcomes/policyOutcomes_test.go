@@ -0,0 +1,96 @@
+package policyoutcomes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPolicy(namespace, name string) kyverno.ClusterPolicy {
+	return kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+func newEngineResponse(statuses ...response.RuleStatus) response.EngineResponse {
+	er := response.EngineResponse{}
+	er.PolicyResponse.Resource = response.ResourceSpec{Kind: "Pod", Namespace: "default", Name: "nginx"}
+	for i, status := range statuses {
+		er.PolicyResponse.Rules = append(er.PolicyResponse.Rules, response.RuleResponse{Name: fmt.Sprintf("rule-%d", i), Type: "Validation", Status: status})
+	}
+	return er
+}
+
+func outcomeCount(promConfig *metrics.PromConfig, policyType, policyNamespace, policyName string, outcome PolicyOutcome) float64 {
+	return testutil.ToFloat64(promConfig.Metrics.PolicyOutcomes.With(prom.Labels{
+		"policy_type":      policyType,
+		"policy_namespace": policyNamespace,
+		"policy_name":      policyName,
+		"policy_outcome":   string(outcome),
+	}))
+}
+
+func Test_ParsePolicyOutcome(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses []response.RuleStatus
+		outcome  PolicyOutcome
+	}{
+		{name: "no rules", outcome: PolicySkipped},
+		{name: "skipped rules", statuses: []response.RuleStatus{response.RuleStatusSkip, response.RuleStatusSkip}, outcome: PolicySkipped},
+		{name: "passed rule", statuses: []response.RuleStatus{response.RuleStatusSkip, response.RuleStatusPass}, outcome: PolicyMatched},
+		{name: "failed rule", statuses: []response.RuleStatus{response.RuleStatusFail}, outcome: PolicyMatched},
+		{name: "warned rule", statuses: []response.RuleStatus{response.RuleStatusWarn}, outcome: PolicyMatched},
+		{name: "errored rule", statuses: []response.RuleStatus{response.RuleStatusPass, response.RuleStatusError, response.RuleStatusFail}, outcome: PolicyError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, ParsePolicyOutcome(newEngineResponse(tc.statuses...)), tc.outcome)
+		})
+	}
+}
+
+func Test_ProcessEngineResponse_IncrementsOutcome(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	policy := newPolicy("", "require-labels")
+	assert.NilError(t, pc.ProcessEngineResponse(policy, newEngineResponse(response.RuleStatusPass)))
+	assert.NilError(t, pc.ProcessEngineResponse(policy, newEngineResponse(response.RuleStatusFail)))
+	assert.NilError(t, pc.ProcessEngineResponse(policy, newEngineResponse(response.RuleStatusSkip)))
+	assert.NilError(t, pc.ProcessEngineResponse(policy, newEngineResponse(response.RuleStatusError)))
+
+	assert.Equal(t, outcomeCount(promConfig, "cluster", "-", "require-labels", PolicyMatched), float64(2))
+	assert.Equal(t, outcomeCount(promConfig, "cluster", "-", "require-labels", PolicySkipped), float64(1))
+	assert.Equal(t, outcomeCount(promConfig, "cluster", "-", "require-labels", PolicyError), float64(1))
+
+	assert.NilError(t, pc.ProcessEngineResponse(newPolicy("default", "require-labels"), newEngineResponse(response.RuleStatusPass)))
+	assert.Equal(t, outcomeCount(promConfig, "namespaced", "default", "require-labels", PolicyMatched), float64(1))
+	assert.Equal(t, outcomeCount(promConfig, "cluster", "-", "require-labels", PolicyMatched), float64(2))
+}
+
+func Test_ProcessEngineResponse_LimitsPolicies(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	for i := 0; i < metrics.MaxPolicyOutcomesPolicies+10; i++ {
+		assert.NilError(t, pc.ProcessEngineResponse(newPolicy("", fmt.Sprintf("policy-%d", i)), newEngineResponse(response.RuleStatusPass)))
+	}
+
+	assert.Equal(t, testutil.CollectAndCount(promConfig.Metrics.PolicyOutcomes), metrics.MaxPolicyOutcomesPolicies+1)
+	assert.Equal(t, outcomeCount(promConfig, metrics.OtherLabelValue, "-", metrics.OtherLabelValue, PolicyMatched), float64(10))
+
+	// a tracked policy keeps its own series
+	assert.NilError(t, pc.ProcessEngineResponse(newPolicy("", "policy-0"), newEngineResponse(response.RuleStatusError)))
+	assert.Equal(t, outcomeCount(promConfig, "cluster", "-", "policy-0", PolicyError), float64(1))
+}
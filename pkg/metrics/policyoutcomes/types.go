@@ -0,0 +1,17 @@
+package policyoutcomes
+
+import (
+	"github.com/kyverno/kyverno/pkg/metrics"
+)
+
+type PromMetrics metrics.PromMetrics
+
+type PromConfig metrics.PromConfig
+
+type PolicyOutcome string
+
+const (
+	PolicyMatched PolicyOutcome = "matched"
+	PolicySkipped PolicyOutcome = "skipped"
+	PolicyError   PolicyOutcome = "error"
+)
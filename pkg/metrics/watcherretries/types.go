@@ -0,0 +1,19 @@
+package watcherretries
+
+import (
+	"github.com/kyverno/kyverno/pkg/metrics"
+)
+
+type PromMetrics metrics.PromMetrics
+
+type PromConfig metrics.PromConfig
+
+type Watcher string
+
+const (
+	// CARotation is the periodic check of the CA bundle of the webhook configurations
+	CARotation Watcher = "ca_rotation"
+
+	// TLSSecrets is the watch of the secrets holding the CA and the serving certificate
+	TLSSecrets Watcher = "tls_secrets"
+)
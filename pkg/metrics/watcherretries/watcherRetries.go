@@ -0,0 +1,13 @@
+package watcherretries
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// ProcessRetry records a retry of the watcher after a failure, e.g. a reconnection of a watch
+func (pc PromConfig) ProcessRetry(watcher Watcher) error {
+	pc.Metrics.WatcherRetries.With(prom.Labels{
+		"watcher": string(watcher),
+	}).Inc()
+	return nil
+}
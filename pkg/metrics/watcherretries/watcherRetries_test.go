@@ -0,0 +1,25 @@
+package watcherretries
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func Test_ProcessRetry(t *testing.T) {
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+	pc := ParsePromConfig(*promConfig)
+
+	assert.NilError(t, pc.ProcessRetry(CARotation))
+	assert.NilError(t, pc.ProcessRetry(CARotation))
+	assert.NilError(t, pc.ProcessRetry(TLSSecrets))
+
+	assert.Equal(t, testutil.ToFloat64(promConfig.Metrics.WatcherRetries.With(prom.Labels{"watcher": "ca_rotation"})), float64(2))
+	assert.Equal(t, testutil.ToFloat64(promConfig.Metrics.WatcherRetries.With(prom.Labels{"watcher": "tls_secrets"})), float64(1))
+}
@@ -144,7 +144,7 @@ func (o *Controller) ValidatePolicyMutation(policy v1.ClusterPolicy) error {
 	var kindToRules = make(map[string][]v1.Rule)
 	for _, rule := range policy.Spec.Rules {
 		if rule.HasMutate() {
-			for _, kind := range rule.MatchResources.Kinds {
+			for _, kind := range v1.ExpandKinds(rule.MatchResources.Kinds) {
 				kindToRules[kind] = append(kindToRules[common.GetFormatedKind(kind)], rule)
 			}
 		}
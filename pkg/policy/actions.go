@@ -32,7 +32,7 @@ func validateActions(idx int, rule *kyverno.Rule, client *dclient.Client, mock b
 	if rule.HasMutate() {
 		checker = mutate.NewMutateFactory(rule.Mutation)
 		if path, err := checker.Validate(); err != nil {
-			return fmt.Errorf("path: spec.rules[%d].mutate.%s.: %v", idx, path, err)
+			return PathError{Path: fmt.Sprintf("spec.rules[%d].mutate.%s", idx, path), Err: err}
 		}
 	}
 
@@ -40,7 +40,7 @@ func validateActions(idx int, rule *kyverno.Rule, client *dclient.Client, mock b
 	if rule.HasValidate() {
 		checker = validate.NewValidateFactory(&rule.Validation)
 		if path, err := checker.Validate(); err != nil {
-			return fmt.Errorf("path: spec.rules[%d].validate.%s.: %v", idx, path, err)
+			return PathError{Path: fmt.Sprintf("spec.rules[%d].validate.%s", idx, path), Err: err}
 		}
 	}
 
@@ -52,16 +52,16 @@ func validateActions(idx int, rule *kyverno.Rule, client *dclient.Client, mock b
 		if mock {
 			checker = generate.NewFakeGenerate(rule.Generation)
 			if path, err := checker.Validate(); err != nil {
-				return fmt.Errorf("path: spec.rules[%d].generate.%s.: %v", idx, path, err)
+				return PathError{Path: fmt.Sprintf("spec.rules[%d].generate.%s", idx, path), Err: err}
 			}
 		} else {
 			checker = generate.NewGenerateFactory(client, rule.Generation, log.Log)
 			if path, err := checker.Validate(); err != nil {
-				return fmt.Errorf("path: spec.rules[%d].generate.%s.: %v", idx, path, err)
+				return PathError{Path: fmt.Sprintf("spec.rules[%d].generate.%s", idx, path), Err: err}
 			}
 		}
 
-		if utils.ContainsString(rule.MatchResources.Kinds, rule.Generation.Kind) {
+		if utils.ContainsString(kyverno.ExpandKinds(rule.MatchResources.Kinds), rule.Generation.Kind) {
 			return fmt.Errorf("generation kind and match resource kind should not be the same.")
 		}
 	}
@@ -288,7 +288,7 @@ func excludeResources(included map[string]unstructured.Unstructured, exclude kyv
 			return NotEvaluate
 		}
 
-		if findKind(kind, exclude.Kinds) {
+		if findKind(kind, kyverno.ExpandKinds(exclude.Kinds)) {
 			return Skip
 		}
 
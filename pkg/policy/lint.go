@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kyverno/kyverno/pkg/openapi"
+	"github.com/kyverno/kyverno/pkg/utils"
+)
+
+// ValidationError is an error of the static validation of a policy
+type ValidationError struct {
+	// Policy is the name of the invalid policy, it is empty if the policies cannot be parsed
+	Policy string
+
+	// Path is the field path of the error in the policy, e.g. spec.rules[0].match.resources.name,
+	// it is empty if the error does not refer to a field
+	Path string
+
+	// Message describes the error
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	msg := e.Message
+	if e.Path != "" {
+		msg = fmt.Sprintf("path: %s: %s", e.Path, msg)
+	}
+
+	if e.Policy != "" {
+		msg = fmt.Sprintf("policy %s: %s", e.Policy, msg)
+	}
+
+	return msg
+}
+
+var (
+	lintOpenAPIOnce       sync.Once
+	lintOpenAPIController *openapi.Controller
+	lintOpenAPIErr        error
+)
+
+// ValidatePolicy validates the YAML or JSON documents of the policies in raw, without a cluster, using the
+// checks of the policy webhook. It returns a ValidationError if the documents cannot be parsed, or one for
+// each invalid policy, and no error if all the policies are valid. As in the policy webhook, the validation
+// of a policy stops at its first error.
+//
+// The checks that require a cluster are skipped, e.g. the cluster-scoped kinds in the match and exclude
+// blocks of a namespaced Policy, and the permissions and clone sources of the generate rules.
+func ValidatePolicy(raw []byte) []error {
+	policies, err := utils.GetPolicy(raw)
+	if err != nil {
+		return []error{ValidationError{Message: fmt.Sprintf("failed to parse the policies: %v", err)}}
+	}
+
+	if len(policies) == 0 {
+		return []error{ValidationError{Message: "no policy found"}}
+	}
+
+	lintOpenAPIOnce.Do(func() {
+		lintOpenAPIController, lintOpenAPIErr = openapi.NewOpenAPIController()
+	})
+
+	if lintOpenAPIErr != nil {
+		return []error{ValidationError{Message: fmt.Sprintf("failed to initialize the OpenAPI controller: %v", lintOpenAPIErr)}}
+	}
+
+	var errs []error
+	for _, policy := range policies {
+		if err := Validate(policy, nil, true, lintOpenAPIController); err != nil {
+			errs = append(errs, newValidationError(policy.GetName(), err))
+		}
+	}
+
+	return errs
+}
+
+func newValidationError(policy string, err error) ValidationError {
+	var pathErr PathError
+	if errors.As(err, &pathErr) {
+		return ValidationError{Policy: policy, Path: pathErr.Path, Message: pathErr.Err.Error()}
+	}
+
+	return ValidationError{Policy: policy, Message: err.Error()}
+}
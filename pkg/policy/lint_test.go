@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_ValidatePolicy_Fixtures(t *testing.T) {
+	for _, name := range []string{"disallow_bind_mounts.yaml", "disallow_latest_tag.yaml"} {
+		raw, err := ioutil.ReadFile(filepath.Join("..", "..", "test", "best_practices", name))
+		assert.NilError(t, err)
+		assert.Equal(t, len(ValidatePolicy(raw)), 0, name)
+	}
+}
+
+func Test_ValidatePolicy(t *testing.T) {
+	rawPolicies := []byte(`
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: invalid-patch
+spec:
+  rules:
+  - name: add-app-label
+    match:
+      resources:
+        kinds:
+        - Pod
+    mutate:
+      patchesJson6902: |-
+        - op: add
+          path: /metadata/labels/app~2name
+          value: web
+---
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: duplicate-rules
+spec:
+  rules:
+  - name: check-labels
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      message: "The label app is required"
+      pattern:
+        metadata:
+          labels:
+            app: "?*"
+  - name: check-labels
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      message: "The label team is required"
+      pattern:
+        metadata:
+          labels:
+            team: "?*"
+---
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: require-labels
+spec:
+  rules:
+  - name: check-labels
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      message: "The label app is required"
+      pattern:
+        metadata:
+          labels:
+            app: "?*"
+`)
+
+	errs := ValidatePolicy(rawPolicies)
+	assert.DeepEqual(t, errs, []error{
+		ValidationError{
+			Policy:  "invalid-patch",
+			Path:    "spec.rules[0].mutate.patchesJson6902[0].path",
+			Message: "invalid path /metadata/labels/app~2name: invalid escape sequence in reference token 2 'app~2name', '~' must be followed by '0' or '1'",
+		},
+		ValidationError{
+			Policy:  "duplicate-rules",
+			Path:    "spec.rule[1]",
+			Message: "duplicate rule name: 'check-labels'",
+		},
+	})
+	assert.Error(t, errs[1], "policy duplicate-rules: path: spec.rule[1]: duplicate rule name: 'check-labels'")
+}
+
+func Test_ValidatePolicy_ParseErrors(t *testing.T) {
+	errs := ValidatePolicy([]byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config"}}`))
+	assert.DeepEqual(t, errs, []error{
+		ValidationError{Message: "failed to parse the policies: resource ConfigMap/config is not a Policy or a ClusterPolicy"},
+	})
+
+	errs = ValidatePolicy([]byte(``))
+	assert.DeepEqual(t, errs, []error{ValidationError{Message: "no policy found"}})
+}
@@ -3,10 +3,14 @@ package mutate
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
 	commonAnchors "github.com/kyverno/kyverno/pkg/engine/anchor/common"
+	"github.com/kyverno/kyverno/pkg/engine/variables"
 	"github.com/kyverno/kyverno/pkg/policy/common"
+	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
 // Mutate provides implementation to validate 'mutate' rule
@@ -34,6 +38,20 @@ func (m *Mutate) Validate() (string, error) {
 			}
 		}
 	}
+	// RFC 6902 JSON Patches
+	if rule.PatchesJSON6902 != "" {
+		if path, err := validatePatchesJSON6902(rule.PatchesJSON6902); err != nil {
+			return path, err
+		}
+	}
+	// Strategic merge patches applied in sequence
+	if patches, ok := rule.PatchStrategicMerge.([]interface{}); ok {
+		for i, patch := range patches {
+			if _, ok := patch.(map[string]interface{}); !ok {
+				return fmt.Sprintf("patchStrategicMerge[%d]", i), fmt.Errorf("a strategic merge patch in a list must be an object, found %T", patch)
+			}
+		}
+	}
 	// Overlay
 	if rule.Overlay != nil {
 		path, err := common.ValidatePattern(rule.Overlay, "/", []commonAnchors.IsAnchor{commonAnchors.IsConditionAnchor, commonAnchors.IsAddingAnchor})
@@ -49,6 +67,9 @@ func validatePatch(pp kyverno.Patch) error {
 	if pp.Path == "" {
 		return errors.New("JSONPatch field 'path' is mandatory")
 	}
+	if err := validateJSONPointer(pp.Path); err != nil {
+		return fmt.Errorf("invalid JSONPatch field 'path' %s: %v", pp.Path, err)
+	}
 	if pp.Operation == "add" || pp.Operation == "replace" {
 		if pp.Value == nil {
 			return fmt.Errorf("JSONPatch field 'value' is mandatory for operation '%s'", pp.Operation)
@@ -61,3 +82,81 @@ func validatePatch(pp kyverno.Patch) error {
 
 	return fmt.Errorf("unsupported JSONPatch operation '%s'", pp.Operation)
 }
+
+// validatePatchesJSON6902 checks the paths of the RFC 6902 JSON patch operations,
+// and returns the location of the invalid path
+func validatePatchesJSON6902(patches string) (string, error) {
+	jsonPatch, err := yaml.ToJSON([]byte(patches))
+	if err != nil {
+		return "patchesJson6902", err
+	}
+
+	decodedPatch, err := jsonpatch.DecodePatch(jsonPatch)
+	if err != nil {
+		return "patchesJson6902", err
+	}
+
+	for i, operation := range decodedPatch {
+		path, err := operation.Path()
+		if err != nil {
+			return fmt.Sprintf("patchesJson6902[%d]", i), err
+		}
+		if err := validateJSONPointer(path); err != nil {
+			return fmt.Sprintf("patchesJson6902[%d].path", i), fmt.Errorf("invalid path %s: %v", path, err)
+		}
+
+		// the source of the move and copy operations
+		if _, ok := operation["from"]; ok {
+			from, err := operation.From()
+			if err != nil {
+				return fmt.Sprintf("patchesJson6902[%d]", i), err
+			}
+			if err := validateJSONPointer(from); err != nil {
+				return fmt.Sprintf("patchesJson6902[%d].from", i), fmt.Errorf("invalid from %s: %v", from, err)
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// validateJSONPointer checks the syntax of the JSON pointer of a patch, see https://tools.ietf.org/html/rfc6901.
+// The pointer is either empty, referencing the whole document, or begins with a forward slash, and a '~' must
+// be escaped as '~0', a '/' within a key as '~1'.
+// The variables are substituted when the patch is applied, they are not checked.
+func validateJSONPointer(pointer string) error {
+	if pointer == "" {
+		return nil
+	}
+
+	stripped := variables.RegexVariables.ReplaceAllStringFunc(pointer, func(variable string) string {
+		if strings.HasPrefix(variable, "{{") {
+			return ""
+		}
+		// the character preceding the variable
+		return variable[:1]
+	})
+
+	// the pointer is a single variable
+	if stripped == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(stripped, "/") {
+		return errors.New("a JSON pointer must begin with a forward slash")
+	}
+
+	for i, token := range strings.Split(stripped[1:], "/") {
+		for j := 0; j < len(token); j++ {
+			if token[j] != '~' {
+				continue
+			}
+			if j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1') {
+				return fmt.Errorf("invalid escape sequence in reference token %d '%s', '~' must be followed by '0' or '1'", i, token)
+			}
+			j++
+		}
+	}
+
+	return nil
+}
@@ -149,3 +149,81 @@ func Test_Validate_Mutate_Unsupported(t *testing.T) {
 		assert.Assert(t, err != nil)
 	}
 }
+
+func Test_validateJSONPointer(t *testing.T) {
+	testCases := []struct {
+		pointer string
+		errMsg  string
+	}{
+		{pointer: ""},
+		{pointer: "/spec/containers/0/image"},
+		{pointer: "/spec/containers/-"},
+		{pointer: "/metadata/annotations/pod-policies.kyverno.io~1autogen-controllers"},
+		{pointer: "/metadata/labels/a~0b"},
+		{pointer: "/metadata/labels/{{ request.object.metadata.name }}"},
+		{pointer: "{{ request.object.metadata.annotations.path }}"},
+		{pointer: "spec/containers/0/image", errMsg: "a JSON pointer must begin with a forward slash"},
+		{pointer: "/metadata/labels/a~b", errMsg: "invalid escape sequence in reference token 2 'a~b', '~' must be followed by '0' or '1'"},
+		{pointer: "/metadata/labels/a~", errMsg: "invalid escape sequence in reference token 2 'a~'"},
+	}
+
+	for _, tc := range testCases {
+		err := validateJSONPointer(tc.pointer)
+		if tc.errMsg == "" {
+			assert.NilError(t, err, tc.pointer)
+		} else {
+			assert.ErrorContains(t, err, tc.errMsg, tc.pointer)
+		}
+	}
+}
+
+func Test_Validate_Mutate_PatchPath(t *testing.T) {
+	var mutate kyverno.Mutation
+	assert.NilError(t, json.Unmarshal([]byte(`{"patches": [{"path": "/metadata/labels/a~2", "op": "add", "value": "b"}]}`), &mutate))
+	path, err := NewMutateFactory(mutate).Validate()
+	assert.Equal(t, path, "patch[0]")
+	assert.ErrorContains(t, err, "invalid JSONPatch field 'path' /metadata/labels/a~2")
+
+	mutate = kyverno.Mutation{PatchesJSON6902: "- op: replace\n  path: /spec/containers/0/image\n  value: nginx:latest\n- op: remove\n  path: /spec/containers/0/ports~"}
+	path, err = NewMutateFactory(mutate).Validate()
+	assert.Equal(t, path, "patchesJson6902[1].path")
+	assert.ErrorContains(t, err, "invalid path /spec/containers/0/ports~")
+
+	mutate = kyverno.Mutation{PatchesJSON6902: "- op: replace\n  path: /spec/containers/0/image\n  value: nginx:latest"}
+	_, err = NewMutateFactory(mutate).Validate()
+	assert.NilError(t, err)
+
+	// the empty path references the whole resource
+	mutate = kyverno.Mutation{PatchesJSON6902: "- op: test\n  path: \"\"\n  value: {}"}
+	_, err = NewMutateFactory(mutate).Validate()
+	assert.NilError(t, err)
+}
+
+func Test_Validate_Mutate_PatchStrategicMergeList(t *testing.T) {
+	testCases := []struct {
+		name      string
+		rawMutate []byte
+		path      string
+	}{
+		{
+			name:      "valid patches",
+			rawMutate: []byte(`{"patchStrategicMerge": [{"metadata": {"labels": {"+(team)": "platform"}}}, {"spec": {"(serviceAccountName)": "*", "automountServiceAccountToken": false}}]}`),
+		},
+		{
+			name:      "patch is not an object",
+			rawMutate: []byte(`{"patchStrategicMerge": [{"metadata": {"labels": {"team": "platform"}}}, "platform"]}`),
+			path:      "patchStrategicMerge[1]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mutate kyverno.Mutation
+			assert.NilError(t, json.Unmarshal(tc.rawMutate, &mutate))
+
+			path, err := NewMutateFactory(mutate).Validate()
+			assert.Equal(t, path, tc.path)
+			assert.Equal(t, err != nil, tc.path != "", "%v", err)
+		})
+	}
+}
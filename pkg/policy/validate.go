@@ -19,6 +19,7 @@ import (
 	"github.com/kyverno/kyverno/pkg/kyverno/common"
 	"github.com/kyverno/kyverno/pkg/openapi"
 	"github.com/kyverno/kyverno/pkg/utils"
+	"github.com/kyverno/kyverno/pkg/webhookconfig"
 	"github.com/minio/pkg/wildcard"
 	"github.com/pkg/errors"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -28,48 +29,40 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var allowedVariables = regexp.MustCompile(`request\.|serviceAccountName|serviceAccountNamespace|element\.|@|images\.|([a-z_0-9]+\()[^{}]`)
+var allowedVariables = regexp.MustCompile(`request\.|serviceAccountName|serviceAccountNamespace|element\.|@|images\.|namespace\.|([a-z_0-9]+\()[^{}]`)
 
-var allowedVariablesBackground = regexp.MustCompile(`request\.|element\.|@|images\.|([a-z_0-9]+\()[^{}]`)
+var allowedVariablesBackground = regexp.MustCompile(`request\.|element\.|@|images\.|namespace\.|([a-z_0-9]+\()[^{}]`)
+
+// conditionOperators are the operators supported in conditions and preconditions
+var conditionOperators = []kyverno.ConditionOperator{
+	kyverno.Equal, kyverno.Equals, kyverno.NotEqual, kyverno.NotEquals,
+	kyverno.In, kyverno.AnyIn, kyverno.AllIn, kyverno.NotIn, kyverno.AnyNotIn, kyverno.AllNotIn,
+	kyverno.GreaterThanOrEquals, kyverno.GreaterThan, kyverno.LessThanOrEquals, kyverno.LessThan,
+	kyverno.DurationGreaterThanOrEquals, kyverno.DurationGreaterThan, kyverno.DurationLessThanOrEquals, kyverno.DurationLessThan,
+}
 
 // wildCardAllowedVariables represents regex for the allowed fields in wildcards
 var wildCardAllowedVariables = regexp.MustCompile(`\{\{\s*(request\.|serviceAccountName|serviceAccountNamespace)[^{}]*\}\}`)
 
-// validateJSONPatchPathForForwardSlash checks for forward slash
-func validateJSONPatchPathForForwardSlash(patch string) error {
-
-	re, err := regexp.Compile("^/")
-	if err != nil {
-		return err
-	}
-
-	jsonPatch, err := yaml.ToJSON([]byte(patch))
-	if err != nil {
-		return err
-	}
-
-	decodedPatch, err := jsonpatch.DecodePatch(jsonPatch)
-	if err != nil {
-		return err
-	}
-
-	for _, operation := range decodedPatch {
-		path, err := operation.Path()
-		if err != nil {
-			return err
-		}
+// PathError is an error of Validate that refers to a field of the policy
+type PathError struct {
+	// Path is the field path of the error, e.g. spec.rules[0].match.resources.name
+	Path string
 
-		val := re.MatchString(path)
+	// Err is the error of the field
+	Err error
+}
 
-		if !val {
-			return fmt.Errorf("%s", path)
-		}
+func (e PathError) Error() string {
+	return fmt.Sprintf("path: %s: %v", e.Path, e.Err)
+}
 
-	}
-	return nil
+func (e PathError) Unwrap() error {
+	return e.Err
 }
 
-// Validate checks the policy and rules declarations for required configurations
+// Validate checks the policy and rules declarations for required configurations, the errors that refer to a field
+// of the policy are a PathError
 func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool, openAPIController *openapi.Controller) error {
 	namespaced := false
 	background := policy.Spec.Background == nil || *policy.Spec.Background
@@ -86,7 +79,17 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 	}
 
 	if path, err := validateUniqueRuleName(*policy); err != nil {
-		return fmt.Errorf("path: spec.%s: %v", path, err)
+		return PathError{Path: "spec." + path, Err: err}
+	}
+
+	if policy.Spec.WebhookTimeoutSeconds != nil {
+		if err := webhookconfig.ValidateWebhookTimeout(int64(*policy.Spec.WebhookTimeoutSeconds)); err != nil {
+			return PathError{Path: "spec.webhookTimeoutSeconds", Err: err}
+		}
+	}
+
+	if err := webhookconfig.ValidateOperationsAnnotation(policy.GetAnnotations()); err != nil {
+		return PathError{Path: "metadata.annotations", Err: err}
 	}
 
 	if policy.ObjectMeta.Namespace != "" {
@@ -121,23 +124,19 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 
 	for i, rule := range policy.Spec.Rules {
 		//check for forward slash
-		if err := validateJSONPatchPathForForwardSlash(rule.Mutation.PatchesJSON6902); err != nil {
-			return fmt.Errorf("path must begin with a forward slash: spec.rules[%d]: %s", i, err)
-		}
-
 		if jsonPatchOnPod(rule) {
 			log.Log.V(1).Info("pods managed by workload controllers cannot be mutated using policies, use the auto-gen feature or write policies that match pod controllers")
 		}
 		// validate resource description
 		if path, err := validateResources(rule); err != nil {
-			return fmt.Errorf("path: spec.rules[%d].%s: %v", i, path, err)
+			return PathError{Path: fmt.Sprintf("spec.rules[%d].%s", i, path), Err: err}
 		}
 
 		// validate rule types
 		// only one type of rule is allowed per rule
 		if err := validateRuleType(rule); err != nil {
 			// as there are more than 1 operation in rule, not need to evaluate it further
-			return fmt.Errorf("path: spec.rules[%d]: %v", i, err)
+			return PathError{Path: fmt.Sprintf("spec.rules[%d]", i), Err: err}
 		}
 
 		err := validateElementInForEach(rule)
@@ -146,7 +145,7 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 		}
 
 		if err := validateRuleContext(rule); err != nil {
-			return fmt.Errorf("path: spec.rules[%d]: %v", i, err)
+			return PathError{Path: fmt.Sprintf("spec.rules[%d]", i), Err: err}
 		}
 
 		// validate Cluster Resources in namespaced policy
@@ -156,7 +155,7 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 		}
 
 		if doMatchAndExcludeConflict(rule) {
-			return fmt.Errorf("path: spec.rules[%v]: rule is matching an empty set", rule.Name)
+			return PathError{Path: fmt.Sprintf("spec.rules[%v]", rule.Name), Err: errors.New("rule is matching an empty set")}
 		}
 
 		// validate rule actions
@@ -187,15 +186,17 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 			}
 		}
 
-		if utils.ContainsString(rule.MatchResources.Kinds, "*") && (policy.Spec.Background == nil || *policy.Spec.Background) {
+		matchKinds := kyverno.ExpandKinds(rule.MatchResources.Kinds)
+		excludeKinds := kyverno.ExpandKinds(rule.ExcludeResources.Kinds)
+		if utils.ContainsString(matchKinds, "*") && (policy.Spec.Background == nil || *policy.Spec.Background) {
 			return fmt.Errorf("wildcard policy not allowed in background mode. Set spec.background=false to disable background mode for this policy rule ")
 		}
 
-		if (utils.ContainsString(rule.MatchResources.Kinds, "*") && len(rule.MatchResources.Kinds) > 1) || (utils.ContainsString(rule.ExcludeResources.Kinds, "*") && len(rule.ExcludeResources.Kinds) > 1) {
+		if (utils.ContainsString(matchKinds, "*") && len(matchKinds) > 1) || (utils.ContainsString(excludeKinds, "*") && len(excludeKinds) > 1) {
 			return fmt.Errorf("wildard policy can not deal more than one kind")
 		}
 
-		if utils.ContainsString(rule.MatchResources.Kinds, "*") || utils.ContainsString(rule.ExcludeResources.Kinds, "*") {
+		if utils.ContainsString(matchKinds, "*") || utils.ContainsString(excludeKinds, "*") {
 
 			if rule.HasGenerate() || rule.HasVerifyImages() || rule.Validation.ForEachValidation != nil {
 				return fmt.Errorf("wildcard policy does not support rule type")
@@ -268,7 +269,7 @@ func Validate(policy *kyverno.ClusterPolicy, client *dclient.Client, mock bool,
 				return fmt.Errorf("the kind defined in the all exclude resource is invalid")
 			}
 		}
-		if !utils.ContainsString(rule.MatchResources.Kinds, "*") {
+		if !utils.ContainsString(matchKinds, "*") {
 			err := validateKinds(rule.MatchResources.Kinds, mock, client, *policy)
 			if err != nil {
 				return errors.Wrapf(err, "match resource kind is invalid")
@@ -485,6 +486,10 @@ func addContextVariables(entries []kyverno.ContextEntry, ctx *context.MockContex
 		if contextEntry.ConfigMap != nil {
 			ctx.AddVariable(contextEntry.Name + ".data.*")
 		}
+
+		if contextEntry.ResourceQuota != nil {
+			ctx.AddVariable(contextEntry.Name + "*")
+		}
 	}
 }
 
@@ -569,7 +574,7 @@ func doMatchAndExcludeConflict(rule kyverno.Rule) bool {
 	}
 
 	excludeKinds := make(map[string]bool)
-	for _, kind := range rule.ExcludeResources.ResourceDescription.Kinds {
+	for _, kind := range kyverno.ExpandKinds(rule.ExcludeResources.ResourceDescription.Kinds) {
 		excludeKinds[kind] = true
 	}
 
@@ -676,7 +681,7 @@ func doMatchAndExcludeConflict(rule kyverno.Rule) bool {
 			return false
 		}
 
-		for _, kind := range rule.MatchResources.ResourceDescription.Kinds {
+		for _, kind := range kyverno.ExpandKinds(rule.MatchResources.ResourceDescription.Kinds) {
 			if !excludeKinds[kind] {
 				return false
 			}
@@ -758,6 +763,10 @@ func doMatchAndExcludeConflict(rule kyverno.Rule) bool {
 		return false
 	}
 
+	if !reflect.DeepEqual(rule.MatchResources.AnnotationSelector, rule.ExcludeResources.AnnotationSelector) {
+		return false
+	}
+
 	return true
 }
 
@@ -836,10 +845,12 @@ func ruleOnlyDealsWithResourceMetaData(rule kyverno.Rule) bool {
 		}
 	}
 
-	patternMapMutate, _ := rule.Mutation.PatchStrategicMerge.(map[string]interface{})
-	for k := range patternMapMutate {
-		if k != "metadata" {
-			return false
+	for _, patch := range rule.Mutation.StrategicMergePatches() {
+		patternMapMutate, _ := patch.(map[string]interface{})
+		for k := range patternMapMutate {
+			if k != "metadata" {
+				return false
+			}
 		}
 	}
 
@@ -1000,7 +1011,13 @@ func validateConditionValues(c kyverno.Condition) (string, error) {
 	if c.Key == nil || c.Value == nil || c.Operator == "" {
 		return "", fmt.Errorf("entered value of `key`, `value` or `operator` is missing or misspelled")
 	}
-	switch strings.ReplaceAll(c.Key.(string), " ", "") {
+
+	if path, err := validateConditionOperator(c.Operator); err != nil {
+		return path, err
+	}
+
+	key, _ := c.Key.(string)
+	switch strings.ReplaceAll(key, " ", "") {
 	case "{{request.operation}}":
 		return validateConditionValuesKeyRequestOperation(c)
 	default:
@@ -1008,6 +1025,18 @@ func validateConditionValues(c kyverno.Condition) (string, error) {
 	}
 }
 
+// validateConditionOperator validates that the operator is one of the condition operators, in any case.
+// A condition with an unknown operator always evaluates to false, i.e. the rule would be silently skipped
+func validateConditionOperator(op kyverno.ConditionOperator) (string, error) {
+	for _, known := range conditionOperators {
+		if strings.EqualFold(string(op), string(known)) {
+			return "", nil
+		}
+	}
+
+	return fmt.Sprintf("operator: %s", op), fmt.Errorf("unknown operator '%s' found under the 'operator' field", op)
+}
+
 // validateConditionValuesKeyRequestOperation validates whether all the values under the 'value' field of a 'conditions' field
 // are one of ["CREATE", "UPDATE", "DELETE", "CONNECT"] when 'condition.key' is {{request.operation}}
 func validateConditionValuesKeyRequestOperation(c kyverno.Condition) (string, error) {
@@ -1095,8 +1124,10 @@ func validateRuleContext(rule kyverno.Rule) error {
 			err = validateConfigMap(entry)
 		} else if entry.APICall != nil {
 			err = validateAPICall(entry)
+		} else if entry.ResourceQuota != nil {
+			err = validateResourceQuota(entry)
 		} else {
-			return fmt.Errorf("a configMap or apiCall is required for context entries")
+			return fmt.Errorf("a configMap, apiCall or resourceQuota is required for context entries")
 		}
 
 		if err != nil {
@@ -1124,6 +1155,10 @@ func validateConfigMap(entry kyverno.ContextEntry) error {
 		return fmt.Errorf("both configMap and apiCall are not allowed in a context entry")
 	}
 
+	if entry.ResourceQuota != nil {
+		return fmt.Errorf("both configMap and resourceQuota are not allowed in a context entry")
+	}
+
 	if entry.ConfigMap.Name == "" {
 		return fmt.Errorf("a name is required for configMap context entry")
 	}
@@ -1144,6 +1179,10 @@ func validateAPICall(entry kyverno.ContextEntry) error {
 		return fmt.Errorf("both configMap and apiCall are not allowed in a context entry")
 	}
 
+	if entry.ResourceQuota != nil {
+		return fmt.Errorf("both apiCall and resourceQuota are not allowed in a context entry")
+	}
+
 	// Replace all variables to prevent validation failing on variable keys.
 	urlPath := variables.ReplaceAllVars(entry.APICall.URLPath, func(s string) string { return "kyvernoapicallvariable" })
 
@@ -1166,6 +1205,18 @@ func validateAPICall(entry kyverno.ContextEntry) error {
 	return nil
 }
 
+func validateResourceQuota(entry kyverno.ContextEntry) error {
+	if entry.ResourceQuota == nil {
+		return fmt.Errorf("resourceQuota is empty")
+	}
+
+	if entry.ConfigMap != nil || entry.APICall != nil {
+		return fmt.Errorf("only one of configMap, apiCall or resourceQuota is allowed in a context entry")
+	}
+
+	return nil
+}
+
 // validateResourceDescription checks if all necessary fields are present and have values. Also checks a Selector.
 // field type is checked through openapi
 // Returns error if
@@ -1267,6 +1318,40 @@ func validateResourceDescription(rd kyverno.ResourceDescription) error {
 			return errors.New("the requirements are not specified in selector")
 		}
 	}
+
+	if rd.AnnotationSelector != nil {
+		if err := validateAnnotationSelector(rd.AnnotationSelector); err != nil {
+			return fmt.Errorf("invalid annotationSelector: %v", err)
+		}
+	}
+	return nil
+}
+
+// validateAnnotationSelector returns error if the annotation selector is empty
+// or has an invalid expression
+func validateAnnotationSelector(selector *metav1.LabelSelector) error {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return errors.New("the requirements are not specified in selector")
+	}
+
+	for _, expression := range selector.MatchExpressions {
+		if expression.Key == "" {
+			return errors.New("the key is not specified in expression")
+		}
+
+		switch expression.Operator {
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn:
+			if len(expression.Values) == 0 {
+				return fmt.Errorf("values must be specified for the operator %s on key %s", expression.Operator, expression.Key)
+			}
+		case metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist:
+			if len(expression.Values) > 0 {
+				return fmt.Errorf("values must not be specified for the operator %s on key %s", expression.Operator, expression.Key)
+			}
+		default:
+			return fmt.Errorf("%q is not a valid operator on key %s", expression.Operator, expression.Key)
+		}
+	}
 	return nil
 }
 
@@ -1284,7 +1369,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 
 	if !mock {
 		// Contains "Cluster Wide Resources" in Match->ResourceDescription->Kinds
-		for _, kind := range rule.MatchResources.ResourceDescription.Kinds {
+		for _, kind := range kyverno.ExpandKinds(rule.MatchResources.ResourceDescription.Kinds) {
 			for _, k := range clusterResources {
 				if kind == k {
 					return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in match.resources.kinds", kind)
@@ -1295,7 +1380,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 		// Contains "Cluster Wide Resources" in Match->All->ResourceFilter->ResourceDescription->Kinds
 		for _, allResourceFilter := range rule.MatchResources.All {
 			fmt.Println(allResourceFilter.ResourceDescription)
-			for _, kind := range allResourceFilter.ResourceDescription.Kinds {
+			for _, kind := range kyverno.ExpandKinds(allResourceFilter.ResourceDescription.Kinds) {
 				for _, k := range clusterResources {
 					if kind == k {
 						return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in match.resources.kinds", kind)
@@ -1307,7 +1392,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 		// Contains "Cluster Wide Resources" in Match->Any->ResourceFilter->ResourceDescription->Kinds
 		for _, allResourceFilter := range rule.MatchResources.Any {
 			fmt.Println(allResourceFilter.ResourceDescription)
-			for _, kind := range allResourceFilter.ResourceDescription.Kinds {
+			for _, kind := range kyverno.ExpandKinds(allResourceFilter.ResourceDescription.Kinds) {
 				for _, k := range clusterResources {
 					if kind == k {
 						return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in match.resources.kinds", kind)
@@ -1317,7 +1402,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 		}
 
 		// Contains "Cluster Wide Resources" in Exclude->ResourceDescription->Kinds
-		for _, kind := range rule.ExcludeResources.ResourceDescription.Kinds {
+		for _, kind := range kyverno.ExpandKinds(rule.ExcludeResources.ResourceDescription.Kinds) {
 			for _, k := range clusterResources {
 				if kind == k {
 					return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in exclude.resources.kinds", kind)
@@ -1329,7 +1414,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 		// Contains "Cluster Wide Resources" in Exclude->All->ResourceFilter->ResourceDescription->Kinds
 		for _, allResourceFilter := range rule.ExcludeResources.All {
 			fmt.Println(allResourceFilter.ResourceDescription)
-			for _, kind := range allResourceFilter.ResourceDescription.Kinds {
+			for _, kind := range kyverno.ExpandKinds(allResourceFilter.ResourceDescription.Kinds) {
 				for _, k := range clusterResources {
 					if kind == k {
 						return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in match.resources.kinds", kind)
@@ -1341,7 +1426,7 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 		// Contains "Cluster Wide Resources" in Exclude->Any->ResourceFilter->ResourceDescription->Kinds
 		for _, allResourceFilter := range rule.ExcludeResources.Any {
 			fmt.Println(allResourceFilter.ResourceDescription)
-			for _, kind := range allResourceFilter.ResourceDescription.Kinds {
+			for _, kind := range kyverno.ExpandKinds(allResourceFilter.ResourceDescription.Kinds) {
 				for _, k := range clusterResources {
 					if kind == k {
 						return fmt.Errorf("namespaced policy : cluster-wide resource '%s' not allowed in match.resources.kinds", kind)
@@ -1362,11 +1447,11 @@ func checkClusterResourceInMatchAndExclude(rule kyverno.Rule, clusterResources [
 					if r.Kind == generateResourceKind {
 						if r.Namespaced {
 							if rule.Generation.Namespace == "" {
-								return fmt.Errorf("path: spec.rules[%v]: please mention the namespace to generate a namespaced resource", rule.Name)
+								return PathError{Path: fmt.Sprintf("spec.rules[%v]", rule.Name), Err: errors.New("please mention the namespace to generate a namespaced resource")}
 							}
 						} else {
 							if rule.Generation.Namespace != "" {
-								return fmt.Errorf("path: spec.rules[%v]: do not mention the namespace to generate a non namespaced resource", rule.Name)
+								return PathError{Path: fmt.Sprintf("spec.rules[%v]", rule.Name), Err: errors.New("do not mention the namespace to generate a non namespaced resource")}
 							}
 						}
 					}
@@ -1384,7 +1469,7 @@ func jsonPatchOnPod(rule kyverno.Rule) bool {
 		return false
 	}
 
-	if utils.ContainsString(rule.MatchResources.Kinds, "Pod") && rule.Mutation.PatchesJSON6902 != "" {
+	if utils.ContainsString(kyverno.ExpandKinds(rule.MatchResources.Kinds), "Pod") && rule.Mutation.PatchesJSON6902 != "" {
 		return true
 	}
 
@@ -1392,7 +1477,7 @@ func jsonPatchOnPod(rule kyverno.Rule) bool {
 }
 
 func validateKinds(kinds []string, mock bool, client *dclient.Client, p kyverno.ClusterPolicy) error {
-	for _, kind := range kinds {
+	for _, kind := range kyverno.ExpandKinds(kinds) {
 		_, k := comn.GetKindFromGVK(kind)
 		if k == p.Kind {
 			return fmt.Errorf("kind and match resource kind should not be the same")
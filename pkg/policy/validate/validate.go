@@ -31,6 +31,10 @@ func (v *Validate) Validate() (string, error) {
 		return "", err
 	}
 
+	if v.rule.RejectUnknownFields && v.rule.Pattern == nil && v.rule.AnyPattern == nil {
+		return "rejectUnknownFields", fmt.Errorf("rejectUnknownFields requires a pattern or anyPattern")
+	}
+
 	if v.rule.Pattern != nil {
 		if path, err := common.ValidatePattern(v.rule.Pattern, "/", []commonAnchors.IsAnchor{commonAnchors.IsConditionAnchor, commonAnchors.IsExistenceAnchor, commonAnchors.IsEqualityAnchor, commonAnchors.IsNegationAnchor, commonAnchors.IsGlobalAnchor}); err != nil {
 			return fmt.Sprintf("pattern.%s", path), err
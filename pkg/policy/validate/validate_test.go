@@ -379,3 +379,16 @@ func Test_Validate_Validate_Unsupported(t *testing.T) {
 	}
 
 }
+
+func Test_Validate_RejectUnknownFields(t *testing.T) {
+	var validation kyverno.Validation
+	assert.NilError(t, json.Unmarshal([]byte(`{"rejectUnknownFields": true, "pattern": {"spec": {"containers": [{"name": "?*"}]}}}`), &validation))
+	_, err := NewValidateFactory(&validation).Validate()
+	assert.NilError(t, err)
+
+	validation = kyverno.Validation{}
+	assert.NilError(t, json.Unmarshal([]byte(`{"rejectUnknownFields": true, "deny": {"conditions": []}}`), &validation))
+	path, err := NewValidateFactory(&validation).Validate()
+	assert.Equal(t, path, "rejectUnknownFields")
+	assert.ErrorContains(t, err, "requires a pattern or anyPattern")
+}
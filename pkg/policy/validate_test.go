@@ -2,6 +2,7 @@ package policy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -507,6 +508,31 @@ func Test_Validate_ResourceDescription_InvalidSelector(t *testing.T) {
 	assert.Assert(t, err != nil)
 }
 
+func Test_Validate_ResourceDescription_AnnotationSelector(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector string
+		valid    bool
+	}{
+		{name: "match labels", selector: `{"matchLabels": {"backup.io/enabled": "true"}}`, valid: true},
+		{name: "exists", selector: `{"matchExpressions": [{"key": "backup.io/enabled", "operator": "Exists"}]}`, valid: true},
+		{name: "in", selector: `{"matchExpressions": [{"key": "backup.io/enabled", "operator": "In", "values": ["true"]}]}`, valid: true},
+		{name: "empty", selector: `{}`, valid: false},
+		{name: "in without values", selector: `{"matchExpressions": [{"key": "backup.io/enabled", "operator": "In"}]}`, valid: false},
+		{name: "exists with values", selector: `{"matchExpressions": [{"key": "backup.io/enabled", "operator": "Exists", "values": ["true"]}]}`, valid: false},
+		{name: "unknown operator", selector: `{"matchExpressions": [{"key": "backup.io/enabled", "operator": "Equals", "values": ["true"]}]}`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		var rd kyverno.ResourceDescription
+		err := json.Unmarshal([]byte(`{"kinds": ["Pod"], "annotationSelector": `+tc.selector+`}`), &rd)
+		assert.NilError(t, err)
+
+		err = validateResourceDescription(rd)
+		assert.Equal(t, err == nil, tc.valid, tc.name)
+	}
+}
+
 func Test_Validate_Policy(t *testing.T) {
 	rawPolicy := []byte(`
 	{
@@ -1461,6 +1487,92 @@ func Test_Wildcards_Kind(t *testing.T) {
 	assert.Assert(t, err != nil)
 }
 
+func Test_Validate_CommaSeparatedKinds(t *testing.T) {
+	testCases := []struct {
+		name      string
+		kinds     string
+		expectErr bool
+	}{
+		{name: "multiple kinds", kinds: `"Deployment,StatefulSet,DaemonSet"`},
+		{name: "wildcard with other kinds", kinds: `"Deployment,*"`, expectErr: true},
+		{name: "policy kind", kinds: `"Deployment,ClusterPolicy"`, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rawPolicy := []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {"name": "require-labels"},
+  "spec": {
+    "background": false,
+    "rules": [
+      {
+        "name": "check-for-labels",
+        "match": {"resources": {"kinds": [` + tc.kinds + `]}},
+        "validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}
+      }
+    ]
+  }
+}`)
+
+			var policy *kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(rawPolicy, &policy))
+
+			openAPIController, _ := openapi.NewOpenAPIController()
+			err := Validate(policy, nil, true, openAPIController)
+			assert.Equal(t, err != nil, tc.expectErr, "unexpected result: %v", err)
+		})
+	}
+}
+
+func Test_Validate_OperationsAnnotation(t *testing.T) {
+	testCases := []struct {
+		name       string
+		operations string
+		expectErr  bool
+	}{
+		{name: "operations", operations: "UPDATE, delete"},
+		{name: "all operations", operations: "*"},
+		{name: "unknown operation", operations: "UPDATE,PATCH", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rawPolicy := []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {"name": "require-labels", "annotations": {"kyverno.io/operations": "` + tc.operations + `"}},
+  "spec": {
+    "background": false,
+    "rules": [
+      {
+        "name": "check-for-labels",
+        "match": {"resources": {"kinds": ["Pod"]}},
+        "validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}
+      }
+    ]
+  }
+}`)
+
+			var policy *kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(rawPolicy, &policy))
+
+			openAPIController, _ := openapi.NewOpenAPIController()
+			err := Validate(policy, nil, true, openAPIController)
+			if tc.expectErr {
+				assert.ErrorContains(t, err, `unknown operation "PATCH"`)
+
+				var pathErr PathError
+				assert.Assert(t, errors.As(err, &pathErr))
+				assert.Equal(t, pathErr.Path, "metadata.annotations")
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
 func Test_Namespced_Policy(t *testing.T) {
 	rawPolicy := []byte(`
 	{
@@ -1511,3 +1623,237 @@ func Test_Namespced_Policy(t *testing.T) {
 	fmt.Println(err)
 	assert.Assert(t, err != nil)
 }
+
+func Test_Validate_InvalidJMESPathInDenyConditions(t *testing.T) {
+	rawPolicy := []byte(`
+	{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {
+		  "name": "require-readiness-probe"
+		},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "background": true,
+		  "rules": [
+			{
+			  "name": "require-readiness-probe",
+			  "match": {
+				"resources": {
+				  "kinds": ["Pod"]
+				}
+			  },
+			  "validate": {
+				"message": "at least one container must set a readinessProbe",
+				"deny": {
+				  "conditions": {
+					"all": [
+					  {
+						"key": "{{ length(request.object.spec.containers[?readinessProbe) }}",
+						"operator": "Equals",
+						"value": 0
+					  }
+					]
+				  }
+				}
+			  }
+			}
+		  ]
+		}
+	  }
+	`)
+
+	var policy *kyverno.ClusterPolicy
+	err := json.Unmarshal(rawPolicy, &policy)
+	assert.NilError(t, err)
+
+	openAPIController, _ := openapi.NewOpenAPIController()
+	err = Validate(policy, nil, true, openAPIController)
+	assert.ErrorContains(t, err, "invalid JMESPath query")
+}
+
+func Test_Validate_MalformedPolicies(t *testing.T) {
+	testCases := []struct {
+		name   string
+		rule   string
+		errMsg string
+	}{
+		{
+			name:   "valid policy",
+			rule:   `"validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}`,
+			errMsg: "",
+		},
+		{
+			name:   "no rule type",
+			rule:   `"context": []`,
+			errMsg: "path: spec.rules[0]: no operation defined in the rule",
+		},
+		{
+			name:   "multiple rule types",
+			rule:   `"validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}, "mutate": {"patchStrategicMerge": {"metadata": {"labels": {"app": "default"}}}}`,
+			errMsg: "path: spec.rules[0]: multiple operations defined in the rule",
+		},
+		{
+			name:   "patch path without forward slash",
+			rule:   `"mutate": {"patchesJson6902": "- op: add\n  path: metadata/labels/app\n  value: default"}`,
+			errMsg: "path: spec.rules[0].mutate.patchesJson6902[0].path: invalid path metadata/labels/app: a JSON pointer must begin with a forward slash",
+		},
+		{
+			name:   "unsupported patch operation",
+			rule:   `"mutate": {"patches": [{"path": "/metadata/labels/app", "op": "move2", "value": "default"}]}`,
+			errMsg: "path: spec.rules[0].mutate.patch[0]: unsupported JSONPatch operation 'move2'",
+		},
+		{
+			name:   "valid patch path",
+			rule:   `"mutate": {"patchesJson6902": "- op: add\n  path: /metadata/labels/app.kubernetes.io~1name\n  value: default"}`,
+			errMsg: "",
+		},
+		{
+			name:   "patch of the whole document",
+			rule:   `"mutate": {"patchesJson6902": "- op: replace\n  path: \"\"\n  value: {apiVersion: v1, kind: Pod, metadata: {name: nginx}}"}`,
+			errMsg: "",
+		},
+		{
+			name:   "invalid patch path",
+			rule:   `"mutate": {"patchesJson6902": "- op: add\n  path: /metadata/labels/app.kubernetes.io~name\n  value: default"}`,
+			errMsg: "path: spec.rules[0].mutate.patchesJson6902[0].path: invalid path /metadata/labels/app.kubernetes.io~name",
+		},
+		{
+			name:   "invalid patch from",
+			rule:   `"mutate": {"patchesJson6902": "- op: move\n  from: /metadata/labels/app~\n  path: /metadata/labels/name"}`,
+			errMsg: "path: spec.rules[0].mutate.patchesJson6902[0].from: invalid from /metadata/labels/app~",
+		},
+		{
+			name:   "multiple validation types",
+			rule:   `"validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}, "deny": {}}`,
+			errMsg: "only one of pattern, anyPattern, deny, foreach can be specified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rawPolicy := []byte(`{
+				"apiVersion": "kyverno.io/v1",
+				"kind": "ClusterPolicy",
+				"metadata": {"name": "test"},
+				"spec": {
+				  "background": false,
+				  "rules": [
+					{
+					  "name": "test",
+					  "match": {"resources": {"kinds": ["Pod"]}},
+					  ` + tc.rule + `
+					}
+				  ]
+				}
+			  }`)
+
+			var policy *kyverno.ClusterPolicy
+			assert.NilError(t, json.Unmarshal(rawPolicy, &policy))
+
+			openAPIController, _ := openapi.NewOpenAPIController()
+			err := Validate(policy, nil, true, openAPIController)
+			if tc.errMsg == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func Test_Validate_Preconditions_UnknownOperator(t *testing.T) {
+	preConditions := []byte(`
+	{
+		"all": [
+			{
+				"key": "{{request.operation}}",
+				"operator": "equals",
+				"value": "CREATE"
+			},
+			{
+				"key": "{{request.object.metadata.labels.app}}",
+				"operator": "Matches",
+				"value": "nginx"
+			}
+		]
+	}
+	`)
+
+	var pcs apiextensions.JSON
+	err := json.Unmarshal(preConditions, &pcs)
+	assert.NilError(t, err)
+
+	path, err := validateConditions(pcs, "preconditions")
+	assert.ErrorContains(t, err, "unknown operator 'Matches'")
+	assert.Equal(t, path, "preconditions.all[1].operator: Matches")
+}
+
+func Test_Validate_Preconditions_NonStringKey(t *testing.T) {
+	preConditions := []byte(`
+	[
+		{
+			"key": 3,
+			"operator": "GreaterThan",
+			"value": 2
+		}
+	]
+	`)
+
+	var pcs apiextensions.JSON
+	err := json.Unmarshal(preConditions, &pcs)
+	assert.NilError(t, err)
+
+	_, err = validateConditions(pcs, "preconditions")
+	assert.NilError(t, err)
+}
+
+func Test_checkClusterResourceInMatchAndExclude_CommaSeparatedKinds(t *testing.T) {
+	clusterResources := []string{"Namespace", "ClusterRole"}
+
+	testCases := []struct {
+		name  string
+		rule  []byte
+		error string
+	}{
+		{
+			name:  "match",
+			rule:  []byte(`{"name": "test", "match": {"resources": {"kinds": ["Pod,Namespace"]}}}`),
+			error: "cluster-wide resource 'Namespace' not allowed in match.resources.kinds",
+		},
+		{
+			name:  "match any",
+			rule:  []byte(`{"name": "test", "match": {"any": [{"resources": {"kinds": ["Pod, ClusterRole"]}}]}}`),
+			error: "cluster-wide resource 'ClusterRole' not allowed in match.resources.kinds",
+		},
+		{
+			name:  "exclude",
+			rule:  []byte(`{"name": "test", "match": {"resources": {"kinds": ["Pod"]}}, "exclude": {"resources": {"kinds": ["ConfigMap,Namespace"]}}}`),
+			error: "cluster-wide resource 'Namespace' not allowed in exclude.resources.kinds",
+		},
+		{
+			name: "namespaced kinds",
+			rule: []byte(`{"name": "test", "match": {"resources": {"kinds": ["Pod,ConfigMap"]}}}`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rule kyverno.Rule
+			assert.NilError(t, json.Unmarshal(tc.rule, &rule))
+
+			err := checkClusterResourceInMatchAndExclude(rule, clusterResources, false, nil)
+			if tc.error == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.error)
+			}
+		})
+	}
+}
+
+func Test_jsonPatchOnPod_CommaSeparatedKinds(t *testing.T) {
+	var rule kyverno.Rule
+	assert.NilError(t, json.Unmarshal([]byte(`{"name": "test", "match": {"resources": {"kinds": ["Deployment,Pod"]}}, "mutate": {"patchesJson6902": "- op: add\n  path: /metadata/labels/foo\n  value: bar"}}`), &rule))
+	assert.Assert(t, jsonPatchOnPod(rule))
+}
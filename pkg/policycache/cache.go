@@ -50,6 +50,10 @@ type Interface interface {
 	// If the namespace is empty, only cluster-wide policies are returned
 	GetPolicies(pkey PolicyType, kind string, nspace string) []*kyverno.ClusterPolicy
 
+	// Dump returns the names of the cached policies by kind and policy type,
+	// the namespaced policies are named <namespace>/<name>
+	Dump() map[string]map[string][]string
+
 	get(pkey PolicyType, kind string, nspace string) []string
 }
 
@@ -94,6 +98,10 @@ func (pc *policyCache) GetPolicies(pkey PolicyType, kind, nspace string) []*kyve
 	return append(policies, nsPolicies...)
 }
 
+func (pc *policyCache) Dump() map[string]map[string][]string {
+	return pc.pMap.dump()
+}
+
 // Remove a policy from cache
 func (pc *policyCache) Remove(policy *kyverno.ClusterPolicy) {
 	pc.pMap.remove(policy)
@@ -104,7 +112,8 @@ func (m *pMap) add(policy *kyverno.ClusterPolicy) {
 	m.Lock()
 	defer m.Unlock()
 
-	enforcePolicy := policy.Spec.ValidationFailureAction == "enforce"
+	// the warn policies are applied with the enforce policies, to return their warnings in the admission response
+	enforcePolicy := policy.Spec.ValidationFailureAction == common.Enforce || policy.Spec.ValidationFailureAction == common.Warn
 	mutateMap := m.nameCacheMap[Mutate]
 	validateEnforceMap := m.nameCacheMap[ValidateEnforce]
 	validateAuditMap := m.nameCacheMap[ValidateAudit]
@@ -141,9 +150,8 @@ func (m *pMap) add(policy *kyverno.ClusterPolicy) {
 }
 
 func addCacheHelper(rmr kyverno.ResourceFilter, m *pMap, rule kyverno.Rule, mutateMap map[string]bool, pName string, enforcePolicy bool, validateEnforceMap map[string]bool, validateAuditMap map[string]bool, generateMap map[string]bool, imageVerifyMap map[string]bool) {
-	for _, gvk := range rmr.Kinds {
-		_, k := common.GetKindFromGVK(gvk)
-		kind := strings.Title(k)
+	for _, gvk := range kyverno.ExpandKinds(rmr.Kinds) {
+		kind := strings.Title(cacheKind(gvk))
 		_, ok := m.kindDataMap[kind]
 		if !ok {
 			m.kindDataMap[kind] = make(map[PolicyType][]string)
@@ -214,6 +222,26 @@ func (m *pMap) get(key PolicyType, gvk, namespace string) (names []string) {
 	return names
 }
 
+func (m *pMap) dump() map[string]map[string][]string {
+	m.RLock()
+	defer m.RUnlock()
+
+	kinds := make(map[string]map[string][]string, len(m.kindDataMap))
+	for kind, types := range m.kindDataMap {
+		names := make(map[string][]string, len(types))
+		for pType, policies := range types {
+			if len(policies) == 0 {
+				continue
+			}
+			names[pType.String()] = append([]string(nil), policies...)
+		}
+		if len(names) > 0 {
+			kinds[kind] = names
+		}
+	}
+	return kinds
+}
+
 func (m *pMap) remove(policy *kyverno.ClusterPolicy) {
 	m.Lock()
 	defer m.Unlock()
@@ -241,8 +269,8 @@ func (m *pMap) remove(policy *kyverno.ClusterPolicy) {
 }
 
 func removeCacheHelper(rmr kyverno.ResourceFilter, m *pMap, pName string) {
-	for _, gvk := range rmr.Kinds {
-		_, kind := common.GetKindFromGVK(gvk)
+	for _, gvk := range kyverno.ExpandKinds(rmr.Kinds) {
+		kind := cacheKind(gvk)
 		dataMap := m.kindDataMap[kind]
 		for policyType, policies := range dataMap {
 			var newPolicies []string
@@ -282,3 +310,14 @@ func (pc *policyCache) getPolicyObject(key PolicyType, gvk string, nspace string
 	}
 	return policyObject
 }
+
+// cacheKind returns the kind the policies matching gvk are cached by, i.e. the kind of the object
+// in the admission requests, e.g. PodExecOptions for Pod/exec
+func cacheKind(gvk string) string {
+	if kind, subresource := common.SplitSubresource(gvk); subresource != "" {
+		return common.GetSubresourceKind(strings.Title(kind), subresource)
+	}
+
+	_, kind := common.GetKindFromGVK(gvk)
+	return kind
+}
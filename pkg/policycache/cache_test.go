@@ -155,6 +155,31 @@ func Test_Add_Remove(t *testing.T) {
 	}
 }
 
+func Test_Add_Remove_CommaSeparatedKinds(t *testing.T) {
+	pCache := newPolicyCache(log.Log, dummyLister{}, dummyNsLister{})
+	policy := &kyverno.ClusterPolicy{}
+	policy.SetName("workloads")
+	policy.Spec.ValidationFailureAction = "enforce"
+	policy.Spec.Rules = []kyverno.Rule{
+		{
+			Name:           "validate-workloads",
+			MatchResources: kyverno.MatchResources{ResourceDescription: kyverno.ResourceDescription{Kinds: []string{"Deployment,StatefulSet", "apps/v1/DaemonSet"}}},
+			Validation:     kyverno.Validation{Message: "label app is required"},
+		},
+	}
+
+	pCache.Add(policy)
+	for _, kind := range []string{"Deployment", "StatefulSet", "DaemonSet"} {
+		assert.Equal(t, len(pCache.get(ValidateEnforce, kind, "")), 1, "kind %s", kind)
+	}
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "Deployment,StatefulSet", "")), 0)
+
+	pCache.Remove(policy)
+	for _, kind := range []string{"Deployment", "StatefulSet", "DaemonSet"} {
+		assert.Equal(t, len(pCache.get(ValidateEnforce, kind, "")), 0, "kind %s", kind)
+	}
+}
+
 func Test_Add_Remove_Any(t *testing.T) {
 	pCache := newPolicyCache(log.Log, dummyLister{}, dummyNsLister{})
 	policy := newAnyPolicy(t)
@@ -967,6 +992,15 @@ func Test_Add_Validate_Enforce(t *testing.T) {
 	}
 }
 
+func Test_Add_Validate_Warn(t *testing.T) {
+	pCache := newPolicyCache(log.Log, dummyLister{}, dummyNsLister{})
+	pCache.Add(newInformerTestPolicy("require-labels", "warn"))
+
+	// the warn policies are applied with the enforce policies in the admission request
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "Pod", "")), 1)
+	assert.Equal(t, len(pCache.get(ValidateAudit, "Pod", "")), 0)
+}
+
 func Test_Ns_Add_Remove_User(t *testing.T) {
 	pCache := newPolicyCache(log.Log, dummyLister{}, dummyNsLister{})
 	policy := newUserTestPolicy(t)
@@ -1045,3 +1079,42 @@ func Test_NsMutate_Policy(t *testing.T) {
 	}
 
 }
+
+func Test_Add_Remove_Subresource(t *testing.T) {
+	rawPolicy := []byte(`{
+		"metadata": {
+		  "name": "deny-exec"
+		},
+		"spec": {
+		  "validationFailureAction": "enforce",
+		  "rules": [
+			{
+			  "name": "deny-exec",
+			  "match": {
+				"resources": {
+				  "kinds": ["Pod/exec", "Deployment/scale"]
+				}
+			  },
+			  "validate": {
+				"message": "exec is not allowed",
+				"deny": {}
+			  }
+			}
+		  ]
+		}
+	  }`)
+
+	var policy *kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(rawPolicy, &policy))
+
+	pCache := newPolicyCache(log.Log, dummyLister{}, dummyNsLister{})
+	pCache.Add(policy)
+
+	// the policies are cached by the kind of the object in the admission requests of the subresource
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "PodExecOptions", "")), 1)
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "Scale", "")), 1)
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "Pod", "")), 0)
+
+	pCache.Remove(policy)
+	assert.Equal(t, len(pCache.get(ValidateEnforce, "PodExecOptions", "")), 0)
+}
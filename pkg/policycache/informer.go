@@ -14,6 +14,7 @@ import (
 // The cache is synced when a policy is add/update/delete.
 // This cache is only used in the admission webhook to fast retrieve
 // policies based on types (Mutate/ValidateEnforce/Generate).
+// The policies are read from the informer listers, the admission requests do not call the API server.
 type Controller struct {
 	pSynched   cache.InformerSynced
 	nspSynched cache.InformerSynced
@@ -76,7 +77,21 @@ func (c *Controller) updatePolicy(old, cur interface{}) {
 }
 
 func (c *Controller) deletePolicy(obj interface{}) {
-	p := obj.(*kyverno.ClusterPolicy)
+	p, ok := obj.(*kyverno.ClusterPolicy)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			c.log.Info("couldn't get object from tombstone", "obj", obj)
+			return
+		}
+
+		p, ok = tombstone.Obj.(*kyverno.ClusterPolicy)
+		if !ok {
+			c.log.Info("tombstone container object that is not a policy", "obj", obj)
+			return
+		}
+	}
+
 	c.Cache.Remove(p)
 }
 
@@ -99,17 +114,31 @@ func (c *Controller) updateNsPolicy(old, cur interface{}) {
 
 // deleteNsPolicy - Delete Policy from cache
 func (c *Controller) deleteNsPolicy(obj interface{}) {
-	p := obj.(*kyverno.Policy)
+	p, ok := obj.(*kyverno.Policy)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			c.log.Info("couldn't get object from tombstone", "obj", obj)
+			return
+		}
+
+		p, ok = tombstone.Obj.(*kyverno.Policy)
+		if !ok {
+			c.log.Info("tombstone container object that is not a policy", "obj", obj)
+			return
+		}
+	}
+
 	c.Cache.Remove(convertPolicyToClusterPolicy(p))
 }
 
-// Run waits until policy informer to be synced
+// Run waits until the policy informers are synced
 func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	logger := c.log
 	logger.Info("starting")
 	defer logger.Info("shutting down")
 
-	if !cache.WaitForCacheSync(stopCh, c.pSynched) {
+	if !cache.WaitForCacheSync(stopCh, c.pSynched, c.nspSynched) {
 		logger.Info("failed to sync informer cache")
 		return
 	}
@@ -0,0 +1,114 @@
+package policycache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/client/clientset/versioned/fake"
+	kyvernoinformer "github.com/kyverno/kyverno/pkg/client/informers/externalversions"
+	"gotest.tools/assert"
+	"gotest.tools/poll"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newInformerTestPolicy(name, validationFailureAction string) *kyverno.ClusterPolicy {
+	return &kyverno.ClusterPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: kyverno.Spec{
+			ValidationFailureAction: validationFailureAction,
+			Rules: []kyverno.Rule{
+				{
+					Name: "require-labels",
+					MatchResources: kyverno.MatchResources{
+						ResourceDescription: kyverno.ResourceDescription{Kinds: []string{"Pod"}},
+					},
+					Validation: kyverno.Validation{Message: "labels are required"},
+				},
+			},
+		},
+	}
+}
+
+func policyNames(policies []*kyverno.ClusterPolicy) []string {
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		names = append(names, p.GetName())
+	}
+	return names
+}
+
+// waitForPolicies waits until the cache returns the cluster policies of the type for Pods
+func waitForPolicies(t *testing.T, pc *Controller, pkey PolicyType, names ...string) {
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		got := policyNames(pc.Cache.GetPolicies(pkey, "Pod", ""))
+		if len(got) != len(names) {
+			return poll.Continue("got policies %v, expected %v", got, names)
+		}
+
+		for i := range got {
+			if got[i] != names[i] {
+				return poll.Continue("got policies %v, expected %v", got, names)
+			}
+		}
+		return poll.Success()
+	}, poll.WithTimeout(5*time.Second), poll.WithDelay(10*time.Millisecond))
+}
+
+func TestController_InformerEvents(t *testing.T) {
+	client := fake.NewSimpleClientset(newInformerTestPolicy("require-labels", "enforce"))
+	factory := kyvernoinformer.NewSharedInformerFactory(client, 0)
+	pc := NewPolicyCacheController(factory.Kyverno().V1().ClusterPolicies(), factory.Kyverno().V1().Policies(), log.Log)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	assert.Assert(t, cache.WaitForCacheSync(stopCh, pc.pSynched, pc.nspSynched))
+
+	waitForPolicies(t, pc, ValidateEnforce, "require-labels")
+
+	// the lookups are served from the informer cache
+	actions := len(client.Actions())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, len(pc.Cache.GetPolicies(ValidateEnforce, "Pod", "")), 1)
+	}
+	assert.Equal(t, len(client.Actions()), actions)
+
+	// an update is picked up from the informer events
+	_, err := client.KyvernoV1().ClusterPolicies().Update(context.TODO(), newInformerTestPolicy("require-labels", "audit"), metav1.UpdateOptions{})
+	assert.NilError(t, err)
+	waitForPolicies(t, pc, ValidateEnforce)
+	waitForPolicies(t, pc, ValidateAudit, "require-labels")
+
+	_, err = client.KyvernoV1().ClusterPolicies().Create(context.TODO(), newInformerTestPolicy("require-team", "enforce"), metav1.CreateOptions{})
+	assert.NilError(t, err)
+	waitForPolicies(t, pc, ValidateEnforce, "require-team")
+
+	assert.NilError(t, client.KyvernoV1().ClusterPolicies().Delete(context.TODO(), "require-team", metav1.DeleteOptions{}))
+	waitForPolicies(t, pc, ValidateEnforce)
+}
+
+func TestController_DeleteTombstone(t *testing.T) {
+	pc := &Controller{Cache: newPolicyCache(log.Log, dummyLister{}, dummyNsLister{}), log: log.Log}
+
+	policy := newInformerTestPolicy("require-labels", "enforce")
+	pc.addPolicy(policy)
+	assert.Equal(t, len(pc.Cache.get(ValidateEnforce, "Pod", "")), 1)
+
+	pc.deletePolicy(cache.DeletedFinalStateUnknown{Key: policy.GetName(), Obj: policy})
+	assert.Equal(t, len(pc.Cache.get(ValidateEnforce, "Pod", "")), 0)
+
+	nsPolicy := &kyverno.Policy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "require-labels"}, Spec: policy.Spec}
+	pc.addNsPolicy(nsPolicy)
+	assert.Equal(t, len(pc.Cache.get(ValidateEnforce, "Pod", "default")), 1)
+
+	pc.deleteNsPolicy(cache.DeletedFinalStateUnknown{Key: "default/require-labels", Obj: nsPolicy})
+	assert.Equal(t, len(pc.Cache.get(ValidateEnforce, "Pod", "default")), 0)
+
+	// an unknown object is ignored
+	pc.deletePolicy(cache.DeletedFinalStateUnknown{Key: "default/require-labels", Obj: nsPolicy})
+	pc.deleteNsPolicy("not a policy")
+}
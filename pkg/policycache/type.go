@@ -1,5 +1,7 @@
 package policycache
 
+import "fmt"
+
 // PolicyType represents types of policies
 type PolicyType uint8
 
@@ -11,3 +13,21 @@ const (
 	Generate
 	VerifyImages
 )
+
+// String returns the name of the policy type
+func (t PolicyType) String() string {
+	switch t {
+	case Mutate:
+		return "Mutate"
+	case ValidateEnforce:
+		return "ValidateEnforce"
+	case ValidateAudit:
+		return "ValidateAudit"
+	case Generate:
+		return "Generate"
+	case VerifyImages:
+		return "VerifyImages"
+	default:
+		return fmt.Sprintf("PolicyType(%d)", uint8(t))
+	}
+}
@@ -70,6 +70,17 @@ func generateCronJobRule(rule kyverno.Rule, controllers string, log logr.Logger)
 		return *cronJobRule
 	}
 
+	if jobRule.Mutation != nil {
+		if patches, ok := jobRule.Mutation.PatchStrategicMerge.([]interface{}); ok {
+			newMutation := &kyverno.Mutation{
+				PatchStrategicMerge: nestStrategicMergePatches(patches, "jobTemplate"),
+			}
+
+			cronJobRule.Mutation = newMutation.DeepCopy()
+			return *cronJobRule
+		}
+	}
+
 	if (jobRule.Mutation != nil) && (jobRule.Mutation.PatchStrategicMerge != nil) {
 		newMutation := &kyverno.Mutation{
 			PatchStrategicMerge: map[string]interface{}{
@@ -86,10 +97,11 @@ func generateCronJobRule(rule kyverno.Rule, controllers string, log logr.Logger)
 		newValidate := &kyverno.Validation{
 			Message: variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/jobTemplate/spec/template", "pattern"),
 			Pattern: map[string]interface{}{
-				"spec": map[string]interface{}{
+				"spec": allowControllerFields(map[string]interface{}{
 					"jobTemplate": jobRule.Validation.Pattern,
-				},
+				}, jobRule.Validation.RejectUnknownFields),
 			},
+			RejectUnknownFields: jobRule.Validation.RejectUnknownFields,
 		}
 		cronJobRule.Validation = newValidate.DeepCopy()
 		return *cronJobRule
@@ -104,17 +116,18 @@ func generateCronJobRule(rule kyverno.Rule, controllers string, log logr.Logger)
 
 		for _, pattern := range anyPatterns {
 			newPattern := map[string]interface{}{
-				"spec": map[string]interface{}{
+				"spec": allowControllerFields(map[string]interface{}{
 					"jobTemplate": pattern,
-				},
+				}, jobRule.Validation.RejectUnknownFields),
 			}
 
 			patterns = append(patterns, newPattern)
 		}
 
 		cronJobRule.Validation = &kyverno.Validation{
-			Message:    variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/jobTemplate/spec/template", "anyPattern"),
-			AnyPattern: patterns,
+			Message:             variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/jobTemplate/spec/template", "anyPattern"),
+			AnyPattern:          patterns,
+			RejectUnknownFields: jobRule.Validation.RejectUnknownFields,
 		}
 		return *cronJobRule
 	}
@@ -188,7 +201,7 @@ func stripCronJob(controllers string) string {
 func cronJobAnyAllAutogenRule(v kyverno.ResourceFilters) kyverno.ResourceFilters {
 	anyKind := v.DeepCopy()
 	for i, value := range v {
-		if utils.ContainsPod(value.Kinds, "Job") {
+		if utils.ContainsPod(kyverno.ExpandKinds(value.Kinds), "Job") {
 			anyKind[i].Kinds = []string{engine.PodControllerCronJob}
 		}
 	}
@@ -149,7 +149,7 @@ func checkForGVKFormatPatch(policy *kyverno.ClusterPolicy, log logr.Logger) (pat
 
 func convertGVKForKinds(path string, kinds []string, log logr.Logger) ([]byte, error) {
 	kindList := []string{}
-	for _, k := range kinds {
+	for _, k := range kyverno.ExpandKinds(kinds) {
 		gvk := common.GetFormatedKind(k)
 		if gvk == k {
 			continue
@@ -456,6 +456,7 @@ func CanAutoGen(policy *kyverno.ClusterPolicy, log logr.Logger) (applyAutoGen bo
 }
 
 func isKindOtherthanPod(kinds []string) bool {
+	kinds = kyverno.ExpandKinds(kinds)
 	if len(kinds) > 1 && utils.ContainsPod(kinds, "Pod") {
 		return true
 	}
@@ -712,6 +713,15 @@ func generateRuleForControllers(rule kyverno.Rule, controllers string, log logr.
 		return *controllerRule
 	}
 
+	if patches, ok := rule.Mutation.PatchStrategicMerge.([]interface{}); ok {
+		newMutation := &kyverno.Mutation{
+			PatchStrategicMerge: nestStrategicMergePatches(patches, "template"),
+		}
+
+		controllerRule.Mutation = newMutation.DeepCopy()
+		return *controllerRule
+	}
+
 	if rule.Mutation.PatchStrategicMerge != nil {
 		newMutation := &kyverno.Mutation{
 			PatchStrategicMerge: map[string]interface{}{
@@ -748,10 +758,11 @@ func generateRuleForControllers(rule kyverno.Rule, controllers string, log logr.
 		newValidate := &kyverno.Validation{
 			Message: variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/template", "pattern"),
 			Pattern: map[string]interface{}{
-				"spec": map[string]interface{}{
+				"spec": allowControllerFields(map[string]interface{}{
 					"template": rule.Validation.Pattern,
-				},
+				}, rule.Validation.RejectUnknownFields),
 			},
+			RejectUnknownFields: rule.Validation.RejectUnknownFields,
 		}
 		controllerRule.Validation = newValidate.DeepCopy()
 		return *controllerRule
@@ -764,10 +775,11 @@ func generateRuleForControllers(rule kyverno.Rule, controllers string, log logr.
 			logger.Error(err, "failed to deserialize anyPattern, expect type array")
 		}
 
-		patterns := validateAnyPattern(anyPatterns)
+		patterns := validateAnyPattern(anyPatterns, rule.Validation.RejectUnknownFields)
 		controllerRule.Validation = &kyverno.Validation{
-			Message:    variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/template", "anyPattern"),
-			AnyPattern: patterns,
+			Message:             variables.FindAndShiftReferences(log, rule.Validation.Message, "spec/template", "anyPattern"),
+			AnyPattern:          patterns,
+			RejectUnknownFields: rule.Validation.RejectUnknownFields,
 		}
 		return *controllerRule
 	}
@@ -797,13 +809,28 @@ func generateRuleForControllers(rule kyverno.Rule, controllers string, log logr.
 	return kyvernoRule{}
 }
 
-func validateAnyPattern(anyPatterns []interface{}) []interface{} {
+// nestStrategicMergePatches nests each strategic merge patch of the list under the spec field of the controller,
+// the patches are applied in the same sequence to the controller
+func nestStrategicMergePatches(patches []interface{}, field string) []interface{} {
+	nested := make([]interface{}, 0, len(patches))
+	for _, patch := range patches {
+		nested = append(nested, map[string]interface{}{
+			"spec": map[string]interface{}{
+				field: patch,
+			},
+		})
+	}
+
+	return nested
+}
+
+func validateAnyPattern(anyPatterns []interface{}, rejectUnknownFields bool) []interface{} {
 	var patterns []interface{}
 	for _, pattern := range anyPatterns {
 		newPattern := map[string]interface{}{
-			"spec": map[string]interface{}{
+			"spec": allowControllerFields(map[string]interface{}{
 				"template": pattern,
-			},
+			}, rejectUnknownFields),
 		}
 
 		patterns = append(patterns, newPattern)
@@ -811,11 +838,22 @@ func validateAnyPattern(anyPatterns []interface{}) []interface{} {
 	return patterns
 }
 
+// allowControllerFields adds the "=(*)" key to the spec pattern of a controller when the rule rejects the
+// unknown fields, so that only the pod template is checked and the other fields of the controller spec,
+// e.g. the replicas of a Deployment or the schedule of a CronJob, are allowed
+func allowControllerFields(spec map[string]interface{}, rejectUnknownFields bool) map[string]interface{} {
+	if rejectUnknownFields {
+		spec["=(*)"] = "*"
+	}
+
+	return spec
+}
+
 func getAnyAllAutogenRule(v kyverno.ResourceFilters, controllers string) kyverno.ResourceFilters {
 	anyKind := v.DeepCopy()
 
 	for i, value := range v {
-		if utils.ContainsPod(value.Kinds, "Pod") {
+		if utils.ContainsPod(kyverno.ExpandKinds(value.Kinds), "Pod") {
 			anyKind[i].Kinds = strings.Split(controllers, ",")
 		}
 	}
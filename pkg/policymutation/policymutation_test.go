@@ -11,6 +11,9 @@ import (
 
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
 	"github.com/kyverno/kyverno/pkg/engine"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	engineutils "github.com/kyverno/kyverno/pkg/engine/utils"
 	"github.com/kyverno/kyverno/pkg/utils"
 	"gotest.tools/assert"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -272,6 +275,11 @@ func Test_getControllers(t *testing.T) {
 			policy:              []byte(`{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"set-service-labels-env"},"spec":{"background":false,"rules":[{"name":"set-service-label","match":{"resources":{"kinds":["Pod","Deployment"]}},"preconditions":{"any":[{"key":"{{request.operation}}","operator":"Equals","value":"CREATE"}]},"mutate":{"patchStrategicMerge":{"metadata":{"labels":{"+(service)":"{{request.object.spec.template.metadata.labels.app}}"}}}}}]}}`),
 			expectedControllers: "none",
 		},
+		{
+			name:                "rule-with-match-comma-separated-kinds-pod-podcontrollers",
+			policy:              []byte(`{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"set-service-labels-env"},"spec":{"background":false,"rules":[{"name":"set-service-label","match":{"resources":{"kinds":["Pod,Deployment"]}},"mutate":{"patchStrategicMerge":{"metadata":{"labels":{"+(service)":"{{request.object.spec.template.metadata.labels.app}}"}}}}}]}}`),
+			expectedControllers: "none",
+		},
 		{
 			name:                "rule-with-exclude-mixed-kinds-pod-podcontrollers",
 			policy:              []byte(`{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"set-service-labels-env"},"spec":{"background":false,"rules":[{"name":"set-service-label","match":{"resources":{"kinds":["Pod"]}},"exclude":{"resources":{"kinds":["Pod","Deployment"]}},"mutate":{"patchStrategicMerge":{"metadata":{"labels":{"+(service)":"{{request.object.spec.template.metadata.labels.app}}"}}}}}]}}`),
@@ -426,3 +434,184 @@ func Test_checkForGVKFormatPatch(t *testing.T) {
 		}
 	}
 }
+
+func Test_PatchStrategicMergeList(t *testing.T) {
+	policies, err := utils.GetPolicy([]byte(`
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: latest-image
+spec:
+  rules:
+  - name: latest-image
+    match:
+      resources:
+        kinds:
+        - Pod
+    mutate:
+      patchStrategicMerge:
+      - spec:
+          containers:
+          - (name): nginx
+            image: nginx:latest
+      - spec:
+          containers:
+          - (image): "*:latest"
+            imagePullPolicy: Always
+`))
+	assert.NilError(t, err)
+
+	rulePatches, errs := generateRulePatches(*policies[0], "Deployment,CronJob", log.Log)
+	assert.Equal(t, len(errs), 0)
+	assert.Equal(t, len(rulePatches), 2)
+
+	strategicMergePatches := func(patch []byte) []interface{} {
+		var rulePatch struct {
+			Value struct {
+				Mutate struct {
+					PatchStrategicMerge []interface{} `json:"patchStrategicMerge"`
+				} `json:"mutate"`
+			} `json:"value"`
+		}
+		assert.NilError(t, json.Unmarshal(patch, &rulePatch))
+		return rulePatch.Value.Mutate.PatchStrategicMerge
+	}
+
+	nginxLatest := map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"(name)": "nginx", "image": "nginx:latest"}}}}
+	pullAlways := map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"(image)": "*:latest", "imagePullPolicy": "Always"}}}}
+	template := func(patch interface{}) interface{} {
+		return map[string]interface{}{"spec": map[string]interface{}{"template": patch}}
+	}
+	jobTemplate := func(patch interface{}) interface{} {
+		return map[string]interface{}{"spec": map[string]interface{}{"jobTemplate": patch}}
+	}
+
+	// each patch is nested in the template of the controllers, in the same sequence
+	assert.DeepEqual(t, strategicMergePatches(rulePatches[0]), []interface{}{template(nginxLatest), template(pullAlways)})
+	assert.DeepEqual(t, strategicMergePatches(rulePatches[1]), []interface{}{jobTemplate(template(nginxLatest)), jobTemplate(template(pullAlways))})
+}
+
+func Test_RejectUnknownFields(t *testing.T) {
+	policies, err := utils.GetPolicy([]byte(`
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: strict-pods
+spec:
+  rules:
+  - name: pattern
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      rejectUnknownFields: true
+      pattern:
+        spec:
+          containers:
+          - name: "*"
+  - name: any-pattern
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      rejectUnknownFields: true
+      anyPattern:
+      - spec:
+          containers:
+          - name: "*"
+`))
+	assert.NilError(t, err)
+
+	rulePatches, errs := generateRulePatches(*policies[0], "Deployment,CronJob", log.Log)
+	assert.Equal(t, len(errs), 0)
+	assert.Equal(t, len(rulePatches), 4)
+
+	for _, patch := range rulePatches {
+		var rulePatch struct {
+			Value struct {
+				Name     string             `json:"name"`
+				Validate kyverno.Validation `json:"validate"`
+			} `json:"value"`
+		}
+		assert.NilError(t, json.Unmarshal(patch, &rulePatch))
+		assert.Assert(t, rulePatch.Value.Validate.RejectUnknownFields, "rule %s", rulePatch.Value.Name)
+	}
+}
+
+func Test_RejectUnknownFields_Controllers(t *testing.T) {
+	policies, err := utils.GetPolicy([]byte(`
+apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: strict-pods
+spec:
+  validationFailureAction: enforce
+  rules:
+  - name: containers
+    match:
+      resources:
+        kinds:
+        - Pod
+    validate:
+      rejectUnknownFields: true
+      pattern:
+        spec:
+          containers:
+          - name: "?*"
+            image: "?*"
+`))
+	assert.NilError(t, err)
+
+	rulePatches, errs := generateRulePatches(*policies[0], "Deployment,CronJob", log.Log)
+	assert.Equal(t, len(errs), 0)
+
+	policy := kyverno.ClusterPolicy{}
+	policy.Name = "strict-pods"
+	for _, patch := range rulePatches {
+		var rulePatch struct {
+			Value kyverno.Rule `json:"value"`
+		}
+		assert.NilError(t, json.Unmarshal(patch, &rulePatch))
+		policy.Spec.Rules = append(policy.Spec.Rules, rulePatch.Value)
+	}
+
+	testCases := []struct {
+		name     string
+		resource string
+		status   response.RuleStatus
+	}{
+		{
+			name:     "deployment",
+			resource: `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"replicas": 2, "selector": {"matchLabels": {"app": "nginx"}}, "strategy": {"type": "RollingUpdate"}, "template": {"metadata": {"labels": {"app": "nginx"}}, "spec": {"containers": [{"name": "nginx", "image": "nginx"}]}}}}`,
+			status:   response.RuleStatusPass,
+		},
+		{
+			name:     "forbidden field in the deployment template",
+			resource: `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "nginx", "namespace": "default"}, "spec": {"replicas": 2, "template": {"metadata": {"labels": {"app": "nginx"}}, "spec": {"hostNetwork": true, "containers": [{"name": "nginx", "image": "nginx"}]}}}}`,
+			status:   response.RuleStatusFail,
+		},
+		{
+			name:     "cronjob",
+			resource: `{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "hello", "namespace": "default"}, "spec": {"schedule": "* * * * *", "concurrencyPolicy": "Forbid", "jobTemplate": {"metadata": {"labels": {"app": "hello"}}, "spec": {"backoffLimit": 2, "template": {"metadata": {"labels": {"app": "hello"}}, "spec": {"containers": [{"name": "hello", "image": "busybox"}]}}}}}}`,
+			status:   response.RuleStatusPass,
+		},
+		{
+			name:     "forbidden field in the cronjob template",
+			resource: `{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "hello", "namespace": "default"}, "spec": {"schedule": "* * * * *", "jobTemplate": {"spec": {"template": {"spec": {"restartPolicy": "OnFailure", "containers": [{"name": "hello", "image": "busybox"}]}}}}}}`,
+			status:   response.RuleStatusFail,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resource, err := engineutils.ConvertToUnstructured([]byte(tc.resource))
+			assert.NilError(t, err)
+
+			er := engine.Validate(&engine.PolicyContext{Policy: policy, NewResource: *resource, JSONContext: context.NewContext()})
+			assert.Equal(t, len(er.PolicyResponse.Rules), 1)
+			assert.Equal(t, er.PolicyResponse.Rules[0].Status, tc.status, er.PolicyResponse.Rules[0].Message)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package policyreport
 import (
 	"testing"
 
+	changerequest "github.com/kyverno/kyverno/api/kyverno/v1alpha2"
 	report "github.com/kyverno/kyverno/api/policyreport/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var validReportStatuses = []string{"pass", "fail", "error", "skip", "warn"}
@@ -68,3 +70,118 @@ func TestUpdateSummary_MissingResultField(t *testing.T) {
 		t.Errorf("Was expecting status warn to have a count of 0")
 	}
 }
+
+func newTestResult(message string, seconds int64) map[string]interface{} {
+	return map[string]interface{}{
+		"policy":  "disallow-latest-tag",
+		"rule":    "validate-image-tag",
+		"result":  "fail",
+		"message": message,
+		"resources": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"namespace":  "default",
+				"name":       "nginx",
+			},
+		},
+		"timestamp": map[string]interface{}{
+			"seconds": seconds,
+			"nanos":   int64(0),
+		},
+	}
+}
+
+func TestUpdateResults_AddsNewResult(t *testing.T) {
+	oldReport := map[string]interface{}{}
+	newReport := map[string]interface{}{
+		"results": []interface{}{newTestResult("using a mutable image tag is not allowed", 100)},
+	}
+
+	updated, hasDuplicate, err := updateResults(oldReport, newReport, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hasDuplicate {
+		t.Errorf("Was not expecting a duplicate result")
+	}
+
+	results := updated["results"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("Was expecting 1 result, found %d", len(results))
+	}
+
+	summary := updated["summary"].(map[string]interface{})
+	if summary["fail"] != float64(1) {
+		t.Errorf("Was expecting status fail to have a count of 1, found %v", summary["fail"])
+	}
+}
+
+func TestUpdateResults_UpdatesExistingResult(t *testing.T) {
+	oldReport := map[string]interface{}{
+		"results": []interface{}{newTestResult("using a mutable image tag is not allowed", 100)},
+	}
+	newReport := map[string]interface{}{
+		"results": []interface{}{newTestResult("using a mutable image tag is not allowed", 200)},
+	}
+
+	updated, hasDuplicate, err := updateResults(oldReport, newReport, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasDuplicate {
+		t.Errorf("Was expecting a duplicate result")
+	}
+
+	results := updated["results"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("Was expecting the duplicate result to be merged, found %d results", len(results))
+	}
+
+	timestamp := results[0].(map[string]interface{})["timestamp"].(map[string]interface{})
+	if timestamp["seconds"] != int64(200) {
+		t.Errorf("Was expecting the timestamp to be updated to 200, found %v", timestamp["seconds"])
+	}
+}
+
+func TestUpdateResults_ResolvesDeletedResource(t *testing.T) {
+	passed := newTestResult("validation rule 'validate-image-tag' passed.", 100)
+	passed["result"] = "pass"
+	passed["resources"] = []interface{}{
+		map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "namespace": "default", "name": "redis"},
+	}
+
+	oldReport := map[string]interface{}{
+		"results": []interface{}{newTestResult("using a mutable image tag is not allowed", 100), passed},
+	}
+	newReport := map[string]interface{}{}
+
+	// the violating Pod default/nginx is deleted
+	deleteRequest := &changerequest.ReportChangeRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{resourceLabelNamespace: "default"},
+			Annotations: map[string]string{
+				deletedAnnotationResourceKind: "Pod",
+				deletedAnnotationResourceName: "nginx",
+			},
+		},
+	}
+
+	updated, _, err := updateResults(oldReport, newReport, []*changerequest.ReportChangeRequest{deleteRequest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := updated["results"].([]interface{})
+	if len(results) != 1 {
+		t.Fatalf("Was expecting 1 result, found %d", len(results))
+	}
+
+	summary := updated["summary"].(map[string]interface{})
+	if summary["fail"] != float64(0) {
+		t.Errorf("Was expecting status fail to have a count of 0, found %v", summary["fail"])
+	}
+	if summary["pass"] != float64(1) {
+		t.Errorf("Was expecting status pass to have a count of 1, found %v", summary["pass"])
+	}
+}
@@ -0,0 +1,218 @@
+package policystatus
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	kyvernoclient "github.com/kyverno/kyverno/pkg/client/clientset/versioned"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// flushInterval is the interval to write the recorded mutations to the policy status
+	flushInterval = 10 * time.Second
+
+	// maxPatchesSize is the maximum size in bytes of the patches stored in the policy status
+	maxPatchesSize = 4096
+
+	// maxRuleErrors is the maximum number of rule errors stored in the policy status
+	maxRuleErrors = 10
+
+	// maxMessageLength is the maximum length of a rule error message stored in the policy status
+	maxMessageLength = 256
+)
+
+// Updater records the last mutation of each policy and periodically writes it
+// to the policy status, so that an admission request does not wait for a status update.
+// It is run by the leader only, the mutations are not recorded by the other instances.
+type Updater struct {
+	client kyvernoclient.Interface
+
+	// running is set once Run is invoked
+	running int32
+
+	mutex sync.Mutex
+	// pending holds the mutations not yet written to the status, by policy key
+	pending map[string]*kyverno.MutationStatus
+
+	log logr.Logger
+}
+
+// NewUpdater returns a new status updater
+func NewUpdater(client kyvernoclient.Interface, log logr.Logger) *Updater {
+	return &Updater{
+		client:  client,
+		pending: make(map[string]*kyverno.MutationStatus),
+		log:     log,
+	}
+}
+
+// RecordMutation records the patches and the rule errors of the engine response of a mutate policy.
+// Responses without patches or rule errors are ignored.
+func (u *Updater) RecordMutation(resp *response.EngineResponse) {
+	if resp == nil || atomic.LoadInt32(&u.running) == 0 {
+		return
+	}
+
+	status := buildMutationStatus(resp, metav1.Now())
+	if status.Patches == "" && len(status.RuleErrors) == 0 {
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.pending[policyKey(resp.PolicyResponse.Policy.Namespace, resp.PolicyResponse.Policy.Name)] = status
+}
+
+// Run writes the recorded mutations to the policy status until stopCh is closed
+func (u *Updater) Run(stopCh <-chan struct{}) {
+	atomic.StoreInt32(&u.running, 1)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	u.log.V(2).Info("started policy status updater", "interval", flushInterval.String())
+	for {
+		select {
+		case <-ticker.C:
+			u.flush()
+
+		case <-stopCh:
+			u.log.V(2).Info("stopped policy status updater")
+			return
+		}
+	}
+}
+
+// flush writes the pending mutations to the policy status
+func (u *Updater) flush() {
+	u.mutex.Lock()
+	pending := u.pending
+	u.pending = make(map[string]*kyverno.MutationStatus)
+	u.mutex.Unlock()
+
+	for key, status := range pending {
+		namespace, name := splitPolicyKey(key)
+		if err := u.updateStatus(namespace, name, status); err != nil {
+			u.log.Error(err, "failed to update policy status", "policy", key)
+		}
+	}
+}
+
+// updateStatus writes the mutation to the policy status, the write is retried on conflict
+// and skipped if the status already holds the same mutation
+func (u *Updater) updateStatus(namespace, name string, status *kyverno.MutationStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if namespace == "" {
+			policy, err := u.client.KyvernoV1().ClusterPolicies().Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if sameMutation(policy.Status.LastMutation, status) {
+				return nil
+			}
+
+			policy.Status.LastMutation = status
+			_, err = u.client.KyvernoV1().ClusterPolicies().UpdateStatus(context.TODO(), policy, metav1.UpdateOptions{})
+			return err
+		}
+
+		policy, err := u.client.KyvernoV1().Policies(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if sameMutation(policy.Status.LastMutation, status) {
+			return nil
+		}
+
+		policy.Status.LastMutation = status
+		_, err = u.client.KyvernoV1().Policies(namespace).UpdateStatus(context.TODO(), policy, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// sameMutation returns true if the mutations only differ by their time
+func sameMutation(current, status *kyverno.MutationStatus) bool {
+	if current == nil {
+		return false
+	}
+
+	c := *current
+	c.Time = status.Time
+	return reflect.DeepEqual(&c, status)
+}
+
+// buildMutationStatus converts the engine response to the mutation status,
+// the patches and rule errors exceeding the size limits are omitted
+func buildMutationStatus(resp *response.EngineResponse, now metav1.Time) *kyverno.MutationStatus {
+	resource := resp.PolicyResponse.Resource
+	status := &kyverno.MutationStatus{
+		Resource: kyverno.ResourceSpec{
+			APIVersion: resource.APIVersion,
+			Kind:       resource.Kind,
+			Namespace:  resource.Namespace,
+			Name:       resource.Name,
+		},
+		Time: now,
+	}
+
+	var patches []string
+	size := len("[]")
+	for _, patch := range resp.GetPatches() {
+		if size+len(patch)+1 > maxPatchesSize {
+			status.Truncated = true
+			break
+		}
+
+		patches = append(patches, string(patch))
+		size += len(patch) + 1
+	}
+
+	if len(patches) > 0 {
+		status.Patches = "[" + strings.Join(patches, ",") + "]"
+	}
+
+	for _, rule := range resp.PolicyResponse.Rules {
+		if rule.Status != response.RuleStatusFail && rule.Status != response.RuleStatusError {
+			continue
+		}
+
+		if len(status.RuleErrors) == maxRuleErrors {
+			status.Truncated = true
+			break
+		}
+
+		message := rule.Message
+		if len(message) > maxMessageLength {
+			message = message[:maxMessageLength]
+			status.Truncated = true
+		}
+
+		status.RuleErrors = append(status.RuleErrors, kyverno.RuleErrorStatus{Rule: rule.Name, Message: message})
+	}
+
+	return status
+}
+
+func policyKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func splitPolicyKey(key string) (namespace, name string) {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
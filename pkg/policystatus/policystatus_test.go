@@ -0,0 +1,175 @@
+package policystatus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/client/clientset/versioned/fake"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"gotest.tools/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newMutateResponse(namespace, name string, rules ...response.RuleResponse) *response.EngineResponse {
+	return &response.EngineResponse{
+		PolicyResponse: response.PolicyResponse{
+			Policy:   response.PolicySpec{Namespace: namespace, Name: name},
+			Resource: response.ResourceSpec{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "nginx"},
+			Rules:    rules,
+		},
+	}
+}
+
+// newRunningUpdater returns an Updater of the leader, Run sets running
+func newRunningUpdater(client *fake.Clientset) *Updater {
+	u := NewUpdater(client, logr.DiscardLogger{})
+	atomic.StoreInt32(&u.running, 1)
+	return u
+}
+
+func countStatusUpdates(client *fake.Clientset) int {
+	var updates int
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+			updates++
+		}
+	}
+	return updates
+}
+
+func Test_Updater_ClusterPolicyStatus(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "add-labels"}})
+	u := newRunningUpdater(client)
+
+	u.RecordMutation(newMutateResponse("", "add-labels",
+		response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"nginx"}`)}},
+		response.RuleResponse{Name: "add-team", Status: response.RuleStatusError, Message: "variable substitution failed"},
+	))
+	u.flush()
+
+	policy, err := client.KyvernoV1().ClusterPolicies().Get(context.TODO(), "add-labels", metav1.GetOptions{})
+	assert.NilError(t, err)
+	status := policy.Status.LastMutation
+	assert.Assert(t, status != nil)
+	assert.Equal(t, status.Resource, kyverno.ResourceSpec{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "nginx"})
+	assert.Equal(t, status.Patches, `[{"op":"add","path":"/metadata/labels/app","value":"nginx"}]`)
+	assert.DeepEqual(t, status.RuleErrors, []kyverno.RuleErrorStatus{{Rule: "add-team", Message: "variable substitution failed"}})
+	assert.Equal(t, status.Truncated, false)
+	assert.Assert(t, !status.Time.IsZero())
+	assert.Equal(t, len(u.pending), 0)
+}
+
+func Test_Updater_PolicyStatus(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.Policy{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "add-labels"}})
+	u := newRunningUpdater(client)
+
+	u.RecordMutation(newMutateResponse("test", "add-labels",
+		response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"v1"}`)}},
+	))
+	// the last mutation is kept
+	u.RecordMutation(newMutateResponse("test", "add-labels",
+		response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"v2"}`)}},
+	))
+	u.flush()
+
+	policy, err := client.KyvernoV1().Policies("test").Get(context.TODO(), "add-labels", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, policy.Status.LastMutation != nil)
+	assert.Equal(t, policy.Status.LastMutation.Patches, `[{"op":"add","path":"/metadata/labels/app","value":"v2"}]`)
+}
+
+func Test_Updater_IgnoresResponsesWithoutChanges(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "add-labels"}})
+	u := newRunningUpdater(client)
+
+	u.RecordMutation(newMutateResponse("", "add-labels", response.RuleResponse{Name: "add-app", Status: response.RuleStatusSkip}))
+	assert.Equal(t, len(u.pending), 0)
+
+	u.flush()
+	for _, action := range client.Actions() {
+		assert.Assert(t, action.GetVerb() != "update", "unexpected status update")
+	}
+}
+
+func Test_Updater_NotRunning(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "add-labels"}})
+	u := NewUpdater(client, logr.DiscardLogger{})
+
+	// the mutations are only recorded by the leader, which runs the updater
+	u.RecordMutation(newMutateResponse("", "add-labels",
+		response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"nginx"}`)}},
+	))
+	assert.Equal(t, len(u.pending), 0)
+
+	u.flush()
+	assert.Equal(t, countStatusUpdates(client), 0)
+}
+
+func Test_Updater_SkipsUnchangedMutation(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "add-labels"}})
+	u := newRunningUpdater(client)
+
+	for i := 0; i < 3; i++ {
+		u.RecordMutation(newMutateResponse("", "add-labels",
+			response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"nginx"}`)}},
+		))
+		u.flush()
+	}
+
+	// the same mutation recorded at a later time is not written again
+	assert.Equal(t, countStatusUpdates(client), 1)
+}
+
+func Test_Updater_RetriesOnConflict(t *testing.T) {
+	client := fake.NewSimpleClientset(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "add-labels"}})
+	conflicts := 0
+	client.PrependReactor("update", "clusterpolicies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" || conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "kyverno.io", Resource: "clusterpolicies"}, "add-labels", errors.New("the object has been modified"))
+	})
+	u := newRunningUpdater(client)
+
+	u.RecordMutation(newMutateResponse("", "add-labels",
+		response.RuleResponse{Name: "add-app", Status: response.RuleStatusPass, Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/app","value":"nginx"}`)}},
+	))
+	u.flush()
+
+	policy, err := client.KyvernoV1().ClusterPolicies().Get(context.TODO(), "add-labels", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, policy.Status.LastMutation != nil)
+	assert.Equal(t, conflicts, 1)
+	assert.Equal(t, countStatusUpdates(client), 2)
+}
+
+func Test_BuildMutationStatus_Limits(t *testing.T) {
+	patch := []byte(`{"op":"add","path":"/metadata/annotations/a","value":"` + strings.Repeat("x", 1000) + `"}`)
+	var rules []response.RuleResponse
+	for i := 0; i < 8; i++ {
+		rules = append(rules, response.RuleResponse{Name: "add", Status: response.RuleStatusPass, Patches: [][]byte{patch}})
+	}
+	for i := 0; i < maxRuleErrors+2; i++ {
+		rules = append(rules, response.RuleResponse{Name: "fail", Status: response.RuleStatusFail, Message: strings.Repeat("m", maxMessageLength+10)})
+	}
+
+	status := buildMutationStatus(newMutateResponse("", "add-annotations", rules...), metav1.Now())
+	assert.Assert(t, status.Truncated)
+	assert.Assert(t, len(status.Patches) <= maxPatchesSize)
+	assert.Assert(t, strings.HasPrefix(status.Patches, "[") && strings.HasSuffix(status.Patches, "]"))
+	assert.Equal(t, strings.Count(status.Patches, `"op":"add"`), 3)
+	assert.Equal(t, len(status.RuleErrors), maxRuleErrors)
+	for _, ruleErr := range status.RuleErrors {
+		assert.Equal(t, len(ruleErr.Message), maxMessageLength)
+	}
+}
@@ -1,6 +1,7 @@
 package tls
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -86,6 +87,24 @@ func (c *CertRenewer) InitTLSPemPair() (*PemPair, error) {
 	return c.buildTLSPemPairAndWriteToSecrets(certProps, c.serverIP)
 }
 
+// GenerateAndStoreTLS makes sure the self-signed CA and the TLS pair issued for the Kyverno service
+// exist in the secrets. New ones are generated and stored only if the secrets are missing, invalid
+// or about to expire. Returns the CA to be set in the webhook configurations.
+// The requests to the secrets are aborted when ctx is done.
+func (c *CertRenewer) GenerateAndStoreTLS(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	renewer := *c
+	renewer.client = c.client.WithContext(ctx)
+	if _, err := renewer.InitTLSPemPair(); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize TLS key/certificate pair")
+	}
+
+	return ReadRootCASecret(renewer.clientConfig, renewer.client)
+}
+
 // buildTLSPemPairAndWriteToSecrets Issues TLS certificate for webhook server using self-signed CA cert
 // Returns signed and approved TLS certificate in PEM format
 func (c *CertRenewer) buildTLSPemPairAndWriteToSecrets(props CertificateProps, serverIP string) (*PemPair, error) {
@@ -0,0 +1,86 @@
+package tls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	client "github.com/kyverno/kyverno/pkg/dclient"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newTestCertRenewer(t *testing.T, certRenewalInterval, certValidityDuration time.Duration) *CertRenewer {
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "secrets"}: "SecretList",
+	}
+
+	c, err := client.NewMockClient(runtime.NewScheme(), gvrToListKind)
+	assert.NilError(t, err)
+	c.SetDiscovery(client.NewFakeDiscoveryClient(nil))
+
+	clientConfig := &rest.Config{Host: "https://127.0.0.1:6443"}
+	return NewCertRenewer(c, clientConfig, certRenewalInterval, certValidityDuration, "", log.Log)
+}
+
+func TestGenerateAndStoreTLS_Fresh(t *testing.T) {
+	renewer := newTestCertRenewer(t, CertRenewalInterval, CertValidityDuration)
+
+	caData, err := renewer.GenerateAndStoreTLS(context.TODO())
+	assert.NilError(t, err)
+	assert.Assert(t, len(caData) != 0)
+
+	props, err := GetTLSCertProps(renewer.ClientConfig())
+	assert.NilError(t, err)
+
+	secret, err := renewer.Client().GetResource("", "Secret", config.KyvernoNamespace, generateRootCASecretName(props))
+	assert.NilError(t, err)
+	assert.Equal(t, secret.GetAnnotations()[SelfSignedAnnotation], "true")
+
+	_, err = ReadTLSPair(renewer.ClientConfig(), renewer.Client())
+	assert.NilError(t, err)
+
+	valid, err := renewer.ValidCert()
+	assert.NilError(t, err)
+	assert.Assert(t, valid)
+}
+
+func TestGenerateAndStoreTLS_SkipExisting(t *testing.T) {
+	renewer := newTestCertRenewer(t, CertRenewalInterval, CertValidityDuration)
+
+	caData, err := renewer.GenerateAndStoreTLS(context.TODO())
+	assert.NilError(t, err)
+
+	existing, err := renewer.GenerateAndStoreTLS(context.TODO())
+	assert.NilError(t, err)
+	assert.Equal(t, string(existing), string(caData))
+}
+
+func TestGenerateAndStoreTLS_RenewNearExpiry(t *testing.T) {
+	// the cert expires before the next renewal, it must be regenerated
+	renewer := newTestCertRenewer(t, 2*time.Hour, time.Hour)
+
+	caData, err := renewer.GenerateAndStoreTLS(context.TODO())
+	assert.NilError(t, err)
+
+	renewed, err := renewer.GenerateAndStoreTLS(context.TODO())
+	assert.NilError(t, err)
+	assert.Assert(t, string(renewed) != string(caData))
+}
+
+func TestGenerateAndStoreTLS_Cancelled(t *testing.T) {
+	renewer := newTestCertRenewer(t, CertRenewalInterval, CertValidityDuration)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := renewer.GenerateAndStoreTLS(ctx)
+	assert.ErrorContains(t, err, context.Canceled.Error())
+
+	_, err = ReadRootCASecret(renewer.ClientConfig(), renewer.Client())
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,87 @@
+package tls
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"sync"
+
+	"github.com/kyverno/kyverno/pkg/config"
+)
+
+// TLSPairSecretName returns the name of the secret that stores the serving TLS pair
+func TLSPairSecretName() string {
+	return generateTLSPairSecretName(CertificateProps{Service: config.KyvernoServiceName, Namespace: config.KyvernoNamespace})
+}
+
+// CertificateCache holds the certificate served by the webhook server.
+// The server reads the certificate on each TLS handshake, so that a rotated
+// TLS pair is served without restarting the server.
+type CertificateCache struct {
+	mutex          sync.RWMutex
+	certificate    *tls.Certificate
+	certificatePEM []byte
+	hmacKey        []byte
+}
+
+// NewCertificateCache returns an empty certificate cache
+func NewCertificateCache() *CertificateCache {
+	return &CertificateCache{}
+}
+
+// Update replaces the cached certificate with the TLS pair, the cached certificate
+// is left unchanged if the pair is invalid
+func (c *CertificateCache) Update(pemPair *PemPair) error {
+	if pemPair == nil {
+		return errors.New("TLS pair is not set")
+	}
+
+	certificate, err := tls.X509KeyPair(pemPair.Certificate, pemPair.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.certificate = &certificate
+	c.certificatePEM = pemPair.Certificate
+	c.hmacKey = deriveHMACKey(pemPair.PrivateKey)
+	return nil
+}
+
+// GetCertificate returns the cached certificate, it is used as the tls.Config GetCertificate callback
+func (c *CertificateCache) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.certificate == nil {
+		return nil, errors.New("serving certificate is not loaded")
+	}
+	return c.certificate, nil
+}
+
+// CertificatePEM returns the PEM encoded cached certificate
+func (c *CertificateCache) CertificatePEM() []byte {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.certificatePEM
+}
+
+// HMACKey returns a secret key derived from the private key of the cached TLS pair, it is empty
+// if no pair is loaded. The instances serving the same TLS pair share the key, and the key
+// changes when the pair is rotated.
+func (c *CertificateCache) HMACKey() []byte {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.hmacKey
+}
+
+func deriveHMACKey(privateKey []byte) []byte {
+	mac := hmac.New(sha256.New, privateKey)
+	mac.Write([]byte("kyverno.io/hmac-key"))
+	return mac.Sum(nil)
+}
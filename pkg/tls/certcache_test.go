@@ -0,0 +1,99 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func newTestPemPair(t *testing.T) *PemPair {
+	caCert, _, err := GenerateCACert(CertValidityDuration)
+	assert.NilError(t, err)
+
+	pemPair, err := GenerateCertPem(caCert, CertificateProps{Service: "kyverno-svc", Namespace: "kyverno"}, "", CertValidityDuration)
+	assert.NilError(t, err)
+	return pemPair
+}
+
+// servedCertificate returns the certificate presented by the server in a TLS handshake
+func servedCertificate(t *testing.T, addr string) []byte {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	assert.NilError(t, err)
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	assert.Assert(t, len(certs) > 0)
+	return certs[0].Raw
+}
+
+func TestCertificateCache_ServesUpdatedCertificate(t *testing.T) {
+	cache := NewCertificateCache()
+	_, err := cache.GetCertificate(nil)
+	assert.ErrorContains(t, err, "not loaded")
+
+	oldPair, newPair := newTestPemPair(t), newTestPemPair(t)
+	assert.NilError(t, cache.Update(oldPair))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: cache.GetCertificate})
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+
+	oldCert, err := cache.GetCertificate(nil)
+	assert.NilError(t, err)
+	assert.Assert(t, bytes.Equal(servedCertificate(t, listener.Addr().String()), oldCert.Certificate[0]))
+
+	// the TLS pair is rotated, the next handshake uses the new certificate
+	assert.NilError(t, cache.Update(newPair))
+	newCert, err := cache.GetCertificate(nil)
+	assert.NilError(t, err)
+	assert.Assert(t, !bytes.Equal(newCert.Certificate[0], oldCert.Certificate[0]))
+	assert.Assert(t, bytes.Equal(servedCertificate(t, listener.Addr().String()), newCert.Certificate[0]))
+	assert.Assert(t, bytes.Equal(cache.CertificatePEM(), newPair.Certificate))
+}
+
+func TestCertificateCache_InvalidPairKeepsCertificate(t *testing.T) {
+	cache := NewCertificateCache()
+	pemPair := newTestPemPair(t)
+	assert.NilError(t, cache.Update(pemPair))
+
+	err := cache.Update(&PemPair{Certificate: pemPair.Certificate, PrivateKey: []byte("invalid")})
+	assert.Assert(t, err != nil)
+	assert.Assert(t, bytes.Equal(cache.CertificatePEM(), pemPair.Certificate))
+
+	_, err = cache.GetCertificate(nil)
+	assert.NilError(t, err)
+}
+
+func TestCertificateCache_HMACKey(t *testing.T) {
+	cache := NewCertificateCache()
+	assert.Equal(t, len(cache.HMACKey()), 0)
+
+	pair := newTestPemPair(t)
+	assert.NilError(t, cache.Update(pair))
+	key := cache.HMACKey()
+	assert.Assert(t, len(key) > 0)
+
+	// the instances serving the same pair share the key, it changes with the pair
+	other := NewCertificateCache()
+	assert.NilError(t, other.Update(pair))
+	assert.DeepEqual(t, other.HMACKey(), key)
+
+	assert.NilError(t, cache.Update(newTestPemPair(t)))
+	assert.Assert(t, !bytes.Equal(cache.HMACKey(), key))
+}
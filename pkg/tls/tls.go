@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"math/big"
 	"net"
-	"strings"
 	"time"
 )
 
@@ -136,14 +135,18 @@ func GenerateCertPem(caCert *KeyPair, props CertificateProps, serverIP string, c
 		dnsNames = append(dnsNames, props.APIServerHost)
 	}
 
+	// serverIP is the "host:port" address of Kyverno running out of cluster, the host is an IP or a DNS name
 	if serverIP != "" {
-		if strings.Contains(serverIP, ":") {
-			host, _, _ := net.SplitHostPort(serverIP)
-			serverIP = host
+		host := serverIP
+		if h, _, err := net.SplitHostPort(serverIP); err == nil {
+			host = h
 		}
 
-		ip := net.ParseIP(serverIP)
-		ips = append(ips, ip)
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
 	}
 
 	templ := &x509.Certificate{
@@ -0,0 +1,46 @@
+package tls
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestGenerateCertPem_ServerIP(t *testing.T) {
+	caCert, _, err := GenerateCACert(CertValidityDuration)
+	assert.NilError(t, err)
+
+	testcases := []struct {
+		serverIP string
+		ip       net.IP
+		dnsName  string
+	}{
+		{serverIP: "192.168.10.117:9443", ip: net.ParseIP("192.168.10.117")},
+		{serverIP: "[2001:db8::1]:443", ip: net.ParseIP("2001:db8::1")},
+		{serverIP: "kyverno.local:9443", dnsName: "kyverno.local"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.serverIP, func(t *testing.T) {
+			pemPair, err := GenerateCertPem(caCert, CertificateProps{Service: "kyverno-svc", Namespace: "kyverno", APIServerHost: "10.0.0.1"}, tc.serverIP, CertValidityDuration)
+			assert.NilError(t, err)
+
+			block, _ := pem.Decode(pemPair.Certificate)
+			assert.Assert(t, block != nil)
+			cert, err := x509.ParseCertificate(block.Bytes)
+			assert.NilError(t, err)
+
+			if tc.ip != nil {
+				assert.Equal(t, len(cert.IPAddresses), 2)
+				assert.Assert(t, cert.IPAddresses[1].Equal(tc.ip))
+				return
+			}
+
+			assert.Equal(t, len(cert.IPAddresses), 1)
+			assert.NilError(t, cert.VerifyHostname(tc.dnsName))
+		})
+	}
+}
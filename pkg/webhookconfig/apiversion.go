@@ -0,0 +1,32 @@
+package webhookconfig
+
+import (
+	"github.com/go-logr/logr"
+	client "github.com/kyverno/kyverno/pkg/dclient"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	admregapiv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// webhookGroupVersion returns the API version of the webhook configurations of the kind served by the cluster
+func (wrc *Register) webhookGroupVersion(kind string) schema.GroupVersion {
+	return discoverWebhookGroupVersion(wrc.client.DiscoveryClient, kind, wrc.log)
+}
+
+// discoverWebhookGroupVersion returns admissionregistration.k8s.io/v1 if it is the preferred version of the cluster,
+// and falls back to admissionregistration.k8s.io/v1beta1 on the clusters that do not serve v1.
+// The webhook configurations are built against v1, the fields are the same in v1beta1.
+func discoverWebhookGroupVersion(discovery client.IDiscovery, kind string, log logr.Logger) schema.GroupVersion {
+	gvr, err := discovery.GetGVRFromKind(kind)
+	if err != nil || gvr.Group != admregapi.GroupName {
+		log.V(3).Info("failed to discover the admissionregistration API version, using the default version", "kind", kind, "apiVersion", admregapi.SchemeGroupVersion.String())
+		return admregapi.SchemeGroupVersion
+	}
+
+	if gvr.Version == admregapiv1beta1.SchemeGroupVersion.Version {
+		log.V(4).Info("admissionregistration v1 is not available, using v1beta1", "kind", kind)
+		return admregapiv1beta1.SchemeGroupVersion
+	}
+
+	return admregapi.SchemeGroupVersion
+}
@@ -0,0 +1,126 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	ktls "github.com/kyverno/kyverno/pkg/tls"
+	"github.com/pkg/errors"
+)
+
+// caFingerprint returns the hex encoded SHA-256 digest of the CA bundle, e.g. "sha256:3a7bd3...",
+// it identifies the CA bundle in the registration condition, the dry run and the debug output
+func caFingerprint(caData []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(caData))
+}
+
+// parseCAChain returns the certificates of the PEM encoded CA bundle, the bundle may concatenate
+// several CAs, e.g. a root and its intermediates or the old and new roots during a rotation.
+// An error is returned if a certificate is not a CA, or does not chain up to a self-signed root
+// of the bundle. The validity period is not checked, see checkCAExpiry.
+// No certificate is returned if the bundle has no PEM certificate.
+func parseCAChain(caData []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := caData
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA certificate %d: %v", len(certs), err)
+		}
+
+		if !cert.IsCA {
+			return nil, fmt.Errorf("certificate %d (%s) of the CA bundle is not a CA", len(certs), cert.Subject.String())
+		}
+		certs = append(certs, cert)
+	}
+
+	for i, cert := range certs {
+		if err := chainsToRoot(cert, certs); err != nil {
+			return nil, fmt.Errorf("certificate %d (%s) of the CA bundle is not a valid chain: %v", i, cert.Subject.String(), err)
+		}
+	}
+	return certs, nil
+}
+
+// chainsToRoot returns an error if the issuers of cert in certs do not lead to a self-signed root
+func chainsToRoot(cert *x509.Certificate, certs []*x509.Certificate) error {
+	// a chain cannot be longer than the bundle, a longer walk is a loop
+	for range certs {
+		if isSelfSigned(cert) {
+			return nil
+		}
+
+		issuer := findIssuer(cert, certs)
+		if issuer == nil {
+			return fmt.Errorf("issuer %s is not in the bundle", cert.Issuer.String())
+		}
+		cert = issuer
+	}
+	return errors.New("the issuers form a loop")
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func findIssuer(cert *x509.Certificate, certs []*x509.Certificate) *x509.Certificate {
+	for _, c := range certs {
+		if c != cert && bytes.Equal(c.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(c) == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// assembleCABundle validates the CA read by readCaData and returns the CA bundle set on the webhooks,
+// i.e. the certificates of the chain re-encoded one per PEM block.
+// The CA is rejected if it is not a valid chain, or if the serving certificate of the TLS pair secret
+// cannot be verified with it, as the API server would fail to call the webhooks.
+// A CA without PEM certificates is left to the API server to verify, and the serving certificate
+// is not checked if the TLS pair secret cannot be read, e.g. in debug mode.
+func (wrc *Register) assembleCABundle(caData []byte, now time.Time) ([]byte, error) {
+	certs, err := parseCAChain(caData)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certs) == 0 {
+		wrc.log.V(3).Info("skipping CA chain validation", "reason", "no PEM certificate found in CA data")
+		return caData, nil
+	}
+
+	var bundle []byte
+	for _, cert := range certs {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	if wrc.client == nil || wrc.clientConfig == nil {
+		return bundle, nil
+	}
+
+	tlsPair, err := ktls.ReadTLSPair(wrc.clientConfig, wrc.client)
+	if err != nil {
+		wrc.log.V(3).Info("skipping serving certificate verification against the CA bundle", "reason", err.Error())
+		return bundle, nil
+	}
+
+	if err := checkServingCert(tlsPair.Certificate, bundle, now); err != nil {
+		return nil, fmt.Errorf("CA bundle rejected: %v", err)
+	}
+
+	return bundle, nil
+}
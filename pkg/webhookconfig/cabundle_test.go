@@ -0,0 +1,169 @@
+package webhookconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	ktls "github.com/kyverno/kyverno/pkg/tls"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	rest "k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCert returns a certificate signed by parent, or a self-signed certificate if parent is nil
+func newTestCert(t *testing.T, cn string, isCA bool, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.DNSNames = []string{config.KyvernoServiceName + "." + config.KyvernoNamespace + ".svc"}
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	assert.NilError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func newTLSPairUnstructuredSecret(certPEM []byte) *unstructured.Unstructured {
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetNamespace(config.KyvernoNamespace)
+	secret.SetName(ktls.TLSPairSecretName())
+	_ = unstructured.SetNestedStringMap(secret.Object, map[string]string{
+		v1.TLSCertKey:       base64.StdEncoding.EncodeToString(certPEM),
+		v1.TLSPrivateKeyKey: base64.StdEncoding.EncodeToString([]byte("key")),
+	}, "data")
+	return secret
+}
+
+func concatPEM(certs ...*testCert) []byte {
+	var data []byte
+	for _, c := range certs {
+		data = append(data, c.pem...)
+	}
+	return data
+}
+
+func TestParseCAChain(t *testing.T) {
+	root := newTestCert(t, "root", true, nil)
+	intermediate := newTestCert(t, "intermediate", true, root)
+	otherRoot := newTestCert(t, "other-root", true, nil)
+	orphan := newTestCert(t, "orphan", true, otherRoot)
+	leaf := newTestCert(t, "leaf", false, root)
+
+	testcases := []struct {
+		name   string
+		caData []byte
+		certs  int
+		err    string
+	}{
+		{name: "single root", caData: root.pem, certs: 1},
+		{name: "two-cert chain", caData: concatPEM(intermediate, root), certs: 2},
+		{name: "two roots", caData: concatPEM(root, otherRoot), certs: 2},
+		{name: "broken chain", caData: concatPEM(intermediate, otherRoot), err: "certificate 0 (CN=intermediate) of the CA bundle is not a valid chain: issuer CN=root is not in the bundle"},
+		{name: "intermediate without root", caData: concatPEM(root, orphan), err: "issuer CN=other-root is not in the bundle"},
+		{name: "not a CA", caData: concatPEM(root, leaf), err: "certificate 1 (CN=leaf) of the CA bundle is not a CA"},
+		{name: "invalid certificate", caData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}), err: "failed to parse CA certificate 0"},
+		{name: "no PEM certificate", caData: []byte("ca"), certs: 0},
+	}
+
+	for _, tc := range testcases {
+		certs, err := parseCAChain(tc.caData)
+		if tc.err != "" {
+			assert.ErrorContains(t, err, tc.err, tc.name)
+			continue
+		}
+
+		assert.NilError(t, err, tc.name)
+		assert.Equal(t, len(certs), tc.certs, tc.name)
+	}
+}
+
+func TestReadCaData_Chain(t *testing.T) {
+	root := newTestCert(t, "root", true, nil)
+	intermediate := newTestCert(t, "intermediate", true, root)
+	otherRoot := newTestCert(t, "other-root", true, nil)
+	servingCert := newTestCert(t, "kyverno-svc", false, intermediate)
+
+	testcases := []struct {
+		name        string
+		caData      []byte
+		servingCert []byte
+		err         string
+	}{
+		{name: "two-cert chain", caData: concatPEM(intermediate, root), servingCert: servingCert.pem},
+		{name: "trailing data is dropped", caData: append(concatPEM(intermediate, root), []byte("\n\n")...), servingCert: servingCert.pem},
+		{name: "no serving certificate", caData: concatPEM(intermediate, root)},
+		{name: "broken chain", caData: concatPEM(intermediate, otherRoot), servingCert: servingCert.pem, err: "issuer CN=root is not in the bundle"},
+		{name: "serving certificate not signed by the chain", caData: otherRoot.pem, servingCert: servingCert.pem, err: "CA bundle rejected"},
+	}
+
+	for _, tc := range testcases {
+		caFile := filepath.Join(t.TempDir(), "ca.crt")
+		assert.NilError(t, ioutil.WriteFile(caFile, tc.caData, 0600))
+
+		c := newWebhookMockClient(t)
+		if tc.servingCert != nil {
+			c = newWebhookMockClient(t, newTLSPairUnstructuredSecret(tc.servingCert))
+		}
+
+		wrc := &Register{
+			client:       c,
+			clientConfig: &rest.Config{Host: "https://127.0.0.1:6443"},
+			caFilePath:   caFile,
+			log:          log.Log,
+		}
+
+		caData, err := wrc.readCaData()
+		if tc.err != "" {
+			assert.ErrorContains(t, err, tc.err, tc.name)
+			continue
+		}
+
+		assert.NilError(t, err, tc.name)
+		assert.DeepEqual(t, caData, concatPEM(intermediate, root))
+	}
+}
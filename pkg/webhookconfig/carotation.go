@@ -0,0 +1,114 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/metrics/watcherretries"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// caCheckInterval is the interval to compare the CA with the CA bundle of the webhook configurations
+	caCheckInterval = 30 * time.Second
+
+	// caRotationDebounce is the time a rotated CA must stay unchanged before the webhooks are updated
+	caRotationDebounce = time.Minute
+)
+
+// caDebouncer delays the webhook update until the rotated CA stops changing
+type caDebouncer struct {
+	pending []byte
+	since   time.Time
+	delay   time.Duration
+}
+
+// observe records the CA seen at the given time and returns true
+// if the CA has not changed for the debounce delay
+func (d *caDebouncer) observe(caData []byte, now time.Time) bool {
+	if d.since.IsZero() || !bytes.Equal(caData, d.pending) {
+		d.pending = caData
+		d.since = now
+	}
+	return now.Sub(d.since) >= d.delay
+}
+
+// watchCARotation periodically compares the CA with the CA bundle of the registered webhook
+// configurations, and updates the webhook configurations when the CA is rotated.
+// After a failed check, e.g. when the API server is unavailable, the checks back off up to watcherMaxBackoff.
+func (wrc *Register) watchCARotation(stopCh <-chan struct{}) {
+	logger := wrc.log.WithName("watchCARotation")
+	debouncer := &caDebouncer{delay: caRotationDebounce}
+
+	check := func() error {
+		return wrc.checkCARotation(debouncer, time.Now())
+	}
+
+	onRetry := func(err error, delay time.Duration) {
+		logger.Error(err, "failed to check CA rotation", "retryIn", delay.String())
+		recordWatcherRetry(logger, wrc.promConfig, watcherretries.CARotation)
+	}
+
+	runWatcher(stopCh, caCheckInterval, watcherBackOff(caCheckInterval, watcherMaxBackoff), check, onRetry)
+	logger.V(2).Info("stopping CA rotation watcher")
+}
+
+// checkCARotation updates the webhook configurations if their CA bundle is stale
+// and the current CA has not changed for the debounce delay
+func (wrc *Register) checkCARotation(debouncer *caDebouncer, now time.Time) error {
+	caData, err := wrc.readCaData()
+	if err != nil {
+		return err
+	}
+
+	stale, err := wrc.staleCABundle(caData)
+	if err != nil {
+		return err
+	}
+
+	if !stale {
+		return nil
+	}
+
+	if !debouncer.observe(caData, now) {
+		wrc.log.V(3).Info("CA rotation detected, waiting for the CA to settle", "delay", debouncer.delay.String())
+		return nil
+	}
+
+	wrc.log.Info("CA rotation detected, updating the CA bundle of webhook configurations")
+	if err := wrc.reconcileWebhookConfigurations(caData); err != nil {
+		return err
+	}
+
+	wrc.setRegisteredCABundle(caData)
+	return nil
+}
+
+// staleCABundle returns true if any registered webhook has a CA bundle different from caData
+func (wrc *Register) staleCABundle(caData []byte) (bool, error) {
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		obj, err := wrc.client.GetResource("", desired.kind, "", desired.config.GetName())
+		if err != nil {
+			return false, fmt.Errorf("failed to get %s %s: %v", desired.kind, desired.config.GetName(), err)
+		}
+
+		var live webhookConfiguration = &admregapi.ValidatingWebhookConfiguration{}
+		if desired.kind == kindMutating {
+			live = &admregapi.MutatingWebhookConfiguration{}
+		}
+
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), live); err != nil {
+			return false, fmt.Errorf("failed to convert %s %s from unstructured: %v", desired.kind, desired.config.GetName(), err)
+		}
+
+		for _, w := range webhookSettingsOf(live) {
+			if !bytes.Equal(w.clientConfig.CABundle, caData) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
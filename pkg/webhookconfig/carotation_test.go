@@ -0,0 +1,62 @@
+package webhookconfig
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/tls"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	rest "k8s.io/client-go/rest"
+)
+
+func newCASecret(caData string) *unstructured.Unstructured {
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetNamespace(config.KyvernoNamespace)
+	secret.SetName(config.KyvernoServiceName + "." + config.KyvernoNamespace + ".svc.kyverno-tls-ca")
+	_ = unstructured.SetNestedStringMap(secret.Object, map[string]string{
+		tls.RootCAKey: base64.StdEncoding.EncodeToString([]byte(caData)),
+	}, "data")
+	return secret
+}
+
+func Test_caDebouncer(t *testing.T) {
+	now := time.Now()
+	d := &caDebouncer{delay: time.Minute}
+
+	assert.Assert(t, !d.observe([]byte("ca-1"), now))
+	assert.Assert(t, !d.observe([]byte("ca-2"), now.Add(30*time.Second)))
+	assert.Assert(t, !d.observe([]byte("ca-2"), now.Add(80*time.Second)))
+	assert.Assert(t, d.observe([]byte("ca-2"), now.Add(90*time.Second)))
+}
+
+func TestCheckCARotation(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t, newCASecret("ca-1")))
+	wrc.clientConfig = &rest.Config{Host: "https://127.0.0.1:6443"}
+	assert.NilError(t, wrc.reconcileWebhookConfigurations([]byte("ca-1")))
+
+	stale, err := wrc.staleCABundle([]byte("ca-1"))
+	assert.NilError(t, err)
+	assert.Assert(t, !stale)
+
+	// rotate the CA
+	_, err = wrc.client.UpdateResource("", "Secret", config.KyvernoNamespace, newCASecret("ca-2"), false)
+	assert.NilError(t, err)
+
+	now := time.Now()
+	debouncer := &caDebouncer{delay: time.Minute}
+
+	assert.NilError(t, wrc.checkCARotation(debouncer, now))
+	stale, err = wrc.staleCABundle([]byte("ca-2"))
+	assert.NilError(t, err)
+	assert.Assert(t, stale, "the webhooks must not be updated before the CA settles")
+
+	assert.NilError(t, wrc.checkCARotation(debouncer, now.Add(time.Minute)))
+	stale, err = wrc.staleCABundle([]byte("ca-2"))
+	assert.NilError(t, err)
+	assert.Assert(t, !stale)
+}
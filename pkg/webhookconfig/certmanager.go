@@ -9,6 +9,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/kyverno/kyverno/pkg/common"
 	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	"github.com/kyverno/kyverno/pkg/metrics/watcherretries"
 	ktls "github.com/kyverno/kyverno/pkg/tls"
 	v1 "k8s.io/api/core/v1"
 	informerv1 "k8s.io/client-go/informers/core/v1"
@@ -33,12 +35,21 @@ type certManager struct {
 	secretQueue    chan bool
 	stopCh         <-chan struct{}
 	log            logr.Logger
+
+	// certCache holds the certificate served by the webhook server,
+	// it is refreshed by every instance when the TLS pair secret changes
+	certCache *ktls.CertificateCache
+
+	// promConfig records the watch errors of the secret informer, the metrics are disabled if it is nil
+	promConfig *metrics.PromConfig
 }
 
-func NewCertManager(secretInformer informerv1.SecretInformer, kubeClient kubernetes.Interface, certRenewer *ktls.CertRenewer, log logr.Logger, stopCh <-chan struct{}) (Interface, error) {
+func NewCertManager(secretInformer informerv1.SecretInformer, kubeClient kubernetes.Interface, certRenewer *ktls.CertRenewer, certCache *ktls.CertificateCache, promConfig *metrics.PromConfig, log logr.Logger, stopCh <-chan struct{}) (Interface, error) {
 	manager := &certManager{
 		renewer:        certRenewer,
 		secretInformer: secretInformer,
+		certCache:      certCache,
+		promConfig:     promConfig,
 		secretQueue:    make(chan bool, 1),
 		stopCh:         stopCh,
 		log:            log,
@@ -49,15 +60,29 @@ func NewCertManager(secretInformer informerv1.SecretInformer, kubeClient kuberne
 		UpdateFunc: manager.updateSecretFunc,
 	})
 
+	// the reflector of the informer re-establishes the watch with a backoff, the handler counts the retries;
+	// it cannot be set once the informer is started by another controller sharing it
+	if err := secretInformer.Informer().SetWatchErrorHandler(manager.watchErrorHandler); err != nil {
+		log.V(2).Info("failed to set the watch error handler of the secret informer", "reason", err.Error())
+	}
+
 	return manager, nil
 }
 
+// watchErrorHandler logs the watch error like the default handler and counts the retry of the watch
+func (m *certManager) watchErrorHandler(r *cache.Reflector, err error) {
+	cache.DefaultWatchErrorHandler(r, err)
+	recordWatcherRetry(m.log, m.promConfig, watcherretries.TLSSecrets)
+}
+
 func (m *certManager) addSecretFunc(obj interface{}) {
 	secret := obj.(*v1.Secret)
 	if secret.GetNamespace() != config.KyvernoNamespace {
 		return
 	}
 
+	m.reloadServingCert(secret)
+
 	val, ok := secret.GetAnnotations()[ktls.SelfSignedAnnotation]
 	if !ok || val != "true" {
 		return
@@ -73,6 +98,10 @@ func (m *certManager) updateSecretFunc(oldObj interface{}, newObj interface{}) {
 		return
 	}
 
+	if !reflect.DeepEqual(old.Data, new.Data) {
+		m.reloadServingCert(new)
+	}
+
 	val, ok := new.GetAnnotations()[ktls.SelfSignedAnnotation]
 	if !ok || val != "true" {
 		return
@@ -86,6 +115,25 @@ func (m *certManager) updateSecretFunc(oldObj interface{}, newObj interface{}) {
 	m.log.V(4).Info("secret updated, reconciling webhook configurations")
 }
 
+// reloadServingCert updates the served certificate if the secret is the TLS pair secret
+func (m *certManager) reloadServingCert(secret *v1.Secret) {
+	if m.certCache == nil || secret.GetName() != ktls.TLSPairSecretName() {
+		return
+	}
+
+	pemPair := &ktls.PemPair{
+		Certificate: secret.Data[v1.TLSCertKey],
+		PrivateKey:  secret.Data[v1.TLSPrivateKeyKey],
+	}
+
+	if err := m.certCache.Update(pemPair); err != nil {
+		m.log.Error(err, "failed to reload the serving certificate", "secret", secret.GetName())
+		return
+	}
+
+	m.log.Info("reloaded the serving certificate", "secret", secret.GetName())
+}
+
 func (m *certManager) InitTLSPemPair() {
 	_, err := m.renewer.InitTLSPemPair()
 	if err != nil {
@@ -0,0 +1,44 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	ktls "github.com/kyverno/kyverno/pkg/tls"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTLSPairSecret(t *testing.T, name string) *v1.Secret {
+	caCert, _, err := ktls.GenerateCACert(ktls.CertValidityDuration)
+	assert.NilError(t, err)
+
+	pemPair, err := ktls.GenerateCertPem(caCert, ktls.CertificateProps{Service: config.KyvernoServiceName, Namespace: config.KyvernoNamespace}, "", ktls.CertValidityDuration)
+	assert.NilError(t, err)
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: config.KyvernoNamespace, Name: name},
+		Data:       map[string][]byte{v1.TLSCertKey: pemPair.Certificate, v1.TLSPrivateKeyKey: pemPair.PrivateKey},
+		Type:       v1.SecretTypeTLS,
+	}
+}
+
+func TestCertManager_ReloadServingCert(t *testing.T) {
+	certCache := ktls.NewCertificateCache()
+	m := &certManager{certCache: certCache, secretQueue: make(chan bool, 1), log: logr.DiscardLogger{}}
+
+	secret := newTLSPairSecret(t, ktls.TLSPairSecretName())
+	m.addSecretFunc(secret)
+	assert.Assert(t, bytes.Equal(certCache.CertificatePEM(), secret.Data[v1.TLSCertKey]))
+
+	rotated := newTLSPairSecret(t, ktls.TLSPairSecretName())
+	m.updateSecretFunc(secret, rotated)
+	assert.Assert(t, bytes.Equal(certCache.CertificatePEM(), rotated.Data[v1.TLSCertKey]))
+
+	// other secrets are ignored
+	m.updateSecretFunc(rotated, newTLSPairSecret(t, "other-tls"))
+	assert.Assert(t, bytes.Equal(certCache.CertificatePEM(), rotated.Data[v1.TLSCertKey]))
+}
@@ -1,12 +1,18 @@
 package webhookconfig
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/kyverno/kyverno/pkg/config"
 	"github.com/kyverno/kyverno/pkg/tls"
+	"github.com/pkg/errors"
 	admregapi "k8s.io/api/admissionregistration/v1"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,27 +21,181 @@ import (
 	rest "k8s.io/client-go/rest"
 )
 
-func (wrc *Register) readCaData() []byte {
+// defaultCAConfigMapKey is the key of the CA in the CA ConfigMap
+const defaultCAConfigMapKey = "ca.crt"
+
+// CAConfigMapRef references the key of a ConfigMap that holds the CA in debug mode
+type CAConfigMapRef struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// ParseCAConfigMapRef parses a reference in the format "namespace/name" or "namespace/name:key",
+// the key defaults to ca.crt. An empty value returns an empty reference.
+func ParseCAConfigMapRef(value string) (CAConfigMapRef, error) {
+	if value == "" {
+		return CAConfigMapRef{}, nil
+	}
+
+	ref := CAConfigMapRef{Key: defaultCAConfigMapKey}
+	namespacedName := value
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		namespacedName, ref.Key = value[:i], value[i+1:]
+	}
+
+	parts := strings.Split(namespacedName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || ref.Key == "" {
+		return CAConfigMapRef{}, fmt.Errorf("invalid CA ConfigMap %q, the value must be in the format namespace/name[:key]", value)
+	}
+
+	ref.Namespace, ref.Name = parts[0], parts[1]
+	return ref, nil
+}
+
+func (ref CAConfigMapRef) String() string {
+	return fmt.Sprintf("%s/%s:%s", ref.Namespace, ref.Name, ref.Key)
+}
+
+// readCaData reads the CA used by the API server to verify the webhook server certificate.
+// The CA is resolved in the following order:
+// 1. the CA file set by caFilePath
+// 2. the key of the CA ConfigMap, in debug mode only
+// 3. the CA secret
+// 4. the kubeconfig
+// An error is returned if the CA file or the CA ConfigMap is set and cannot be read.
+// The CA may concatenate several PEM certificates, it is validated by assembleCABundle.
+func (wrc *Register) readCaData() ([]byte, error) {
+	caData, err := wrc.resolveCaData()
+	if err != nil {
+		return nil, err
+	}
+
+	return wrc.assembleCABundle(caData, time.Now())
+}
+
+// resolveCaData returns the CA of the first source of readCaData that is set
+func (wrc *Register) resolveCaData() ([]byte, error) {
 	logger := wrc.log.WithName("readCaData")
 	var caData []byte
 	var err error
 
+	logger.V(4).Info("resolving CA", "order", "caFile, caConfigMap (debug mode), secret, kubeconfig")
+	if wrc.caFilePath != "" {
+		// We accept the risk of including a user provided file here.
+		caData, err = ioutil.ReadFile(filepath.Clean(wrc.caFilePath)) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %v", wrc.caFilePath, err)
+		}
+
+		if len(caData) == 0 {
+			return nil, fmt.Errorf("CA file %s is empty", wrc.caFilePath)
+		}
+
+		logger.V(4).Info("read CA from file", "path", wrc.caFilePath)
+		return caData, nil
+	}
+
+	if wrc.serverIP != "" && wrc.caConfigMap.Name != "" {
+		if caData, err = wrc.readCAConfigMap(); err != nil {
+			return nil, err
+		}
+
+		logger.V(4).Info("read CA from ConfigMap", "configMap", wrc.caConfigMap.String())
+		return caData, nil
+	}
+
 	// Check if ca is defined in the secret tls-ca
 	// assume the key and signed cert have been defined in secret tls.kyverno
 	if caData, err = tls.ReadRootCASecret(wrc.clientConfig, wrc.client); err == nil {
 		logger.V(4).Info("read CA from secret")
-		return caData
+		return caData, nil
 	}
 
 	logger.V(4).Info("failed to read CA from secret, reading from kubeconfig", "reason", err.Error())
 	// load the CA from kubeconfig
 	if caData = extractCA(wrc.clientConfig); len(caData) != 0 {
 		logger.V(4).Info("read CA from kubeconfig")
-		return caData
+		return caData, nil
 	}
 
 	logger.V(4).Info("failed to read CA from kubeconfig")
-	return nil
+	return nil, errors.New("Unable to extract CA data from configuration")
+}
+
+// readCAConfigMap reads the CA from the key of the CA ConfigMap
+func (wrc *Register) readCAConfigMap() ([]byte, error) {
+	ref := wrc.caConfigMap
+	obj, err := wrc.client.GetResource("", "ConfigMap", ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA ConfigMap %s/%s: %v", ref.Namespace, ref.Name, err)
+	}
+
+	caData, found, err := unstructured.NestedString(obj.UnstructuredContent(), "data", ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s of CA ConfigMap %s/%s: %v", ref.Key, ref.Namespace, ref.Name, err)
+	}
+
+	if !found || caData == "" {
+		return nil, fmt.Errorf("key %s not found in CA ConfigMap %s/%s", ref.Key, ref.Namespace, ref.Name)
+	}
+	return []byte(caData), nil
+}
+
+// caExpiryWarningPeriod is the remaining validity of the CA under which registration warns
+const caExpiryWarningPeriod = 30 * 24 * time.Hour
+
+// caExpiry returns the earliest expiry time of the certificates in the PEM encoded CA bundle
+func caExpiry(caData []byte) (time.Time, error) {
+	var expiry time.Time
+	rest := caData
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse CA certificate: %v", err)
+		}
+
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+
+	if expiry.IsZero() {
+		return time.Time{}, errors.New("no certificate found in CA data")
+	}
+	return expiry, nil
+}
+
+// checkCAExpiry returns an error if the CA has expired, as the API server would reject
+// the webhook server certificate, and a warning if the CA expires within caExpiryWarningPeriod.
+// A CA that cannot be parsed is left to the API server to verify.
+func (wrc *Register) checkCAExpiry(caData []byte, now time.Time) (string, error) {
+	expiry, err := caExpiry(caData)
+	if err != nil {
+		wrc.log.V(3).Info("skipping CA expiry check", "reason", err.Error())
+		return "", nil
+	}
+
+	if !now.Before(expiry) {
+		return "", fmt.Errorf("CA expired at %s", expiry.UTC().Format(time.RFC3339))
+	}
+
+	if expiry.Sub(now) < caExpiryWarningPeriod {
+		wrc.log.Info("WARNING: CA expires soon, rotate it to avoid a webhook outage", "expiry", expiry.UTC().Format(time.RFC3339))
+		return fmt.Sprintf("CA expires at %s", expiry.UTC().Format(time.RFC3339)), nil
+	}
+
+	return "", nil
 }
 
 // ExtractCA used for extraction CA from config
@@ -57,6 +217,31 @@ func extractCA(config *rest.Config) (result []byte) {
 	return config.TLSClientConfig.CAData
 }
 
+// debugOwnerReferences returns the owner of the debug webhook configurations, i.e. the same cluster role
+// as in-cluster, so that they are garbage collected when Kyverno is uninstalled. The webhook configurations
+// are cluster-scoped and cannot be owned by the namespaced Kyverno deployment. No owner is returned if the
+// cluster role cannot be resolved, e.g. when running outside a cluster where Kyverno is not installed.
+func (wrc *Register) debugOwnerReferences() []v1.OwnerReference {
+	if wrc.client == nil {
+		return nil
+	}
+
+	clusterRole, err := wrc.GetKubePolicyClusterRoleName()
+	if err != nil {
+		wrc.log.V(3).Info("no owner set on the debug webhook configurations", "reason", err.Error())
+		return nil
+	}
+
+	return []v1.OwnerReference{
+		{
+			APIVersion: config.ClusterRoleAPIVersion,
+			Kind:       config.ClusterRoleKind,
+			Name:       clusterRole.GetName(),
+			UID:        clusterRole.GetUID(),
+		},
+	}
+}
+
 func (wrc *Register) constructOwner() v1.OwnerReference {
 	logger := wrc.log
 
@@ -80,6 +265,10 @@ func (wrc *Register) GetKubePolicyClusterRoleName() (*unstructured.Unstructured,
 		return nil, err
 	}
 
+	if len(clusterRole.Items) == 0 {
+		return nil, fmt.Errorf("no %s labeled app.kubernetes.io/ownerreference=true found", config.ClusterRoleKind)
+	}
+
 	return &clusterRole.Items[0], nil
 }
 
@@ -87,7 +276,7 @@ func (wrc *Register) GetKubePolicyClusterRoleName() (*unstructured.Unstructured,
 // it does not initialize any client call
 func (wrc *Register) GetKubePolicyDeployment() (*apps.Deployment, *unstructured.Unstructured, error) {
 	lister, _ := wrc.resCache.GetGVRCache("Deployment")
-	kubePolicyDeployment, err := lister.NamespacedLister(config.KyvernoNamespace).Get(config.KyvernoDeploymentName)
+	kubePolicyDeployment, err := lister.NamespacedLister(wrc.serviceNamespace).Get(wrc.deploymentName)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -98,9 +287,26 @@ func (wrc *Register) GetKubePolicyDeployment() (*apps.Deployment, *unstructured.
 	return &deploy, kubePolicyDeployment, nil
 }
 
+// serviceReference returns the reference to the Kyverno service with the given path
+func (wrc *Register) serviceReference(path string) *admregapi.ServiceReference {
+	return &admregapi.ServiceReference{
+		Namespace: wrc.serviceNamespace,
+		Name:      wrc.serviceName,
+		Path:      &path,
+	}
+}
+
+// admissionReviewVersions returns a copy of the AdmissionReview versions accepted by the webhooks
+func admissionReviewVersions() []string {
+	versions := make([]string, len(config.WebhookAdmissionReviewVersions))
+	copy(versions, config.WebhookAdmissionReviewVersions)
+	return versions
+}
+
 // debug mutating webhook
-func generateDebugMutatingWebhook(name, url string, caData []byte, validate bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType) admregapi.MutatingWebhook {
-	sideEffect := admregapi.SideEffectClassNoneOnDryRun
+func generateDebugMutatingWebhook(name, url string, caData []byte, validate bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType, namespaceSelector, objectSelector *v1.LabelSelector) admregapi.MutatingWebhook {
+	sideEffect := config.MutatingWebhookSideEffects
+	matchPolicy := config.WebhookMatchPolicy
 	reinvocationPolicy := admregapi.NeverReinvocationPolicy
 
 	w := admregapi.MutatingWebhook{
@@ -111,9 +317,12 @@ func generateDebugMutatingWebhook(name, url string, caData []byte, validate bool
 			CABundle: caData,
 		},
 		SideEffects:             &sideEffect,
-		AdmissionReviewVersions: []string{"v1beta1"},
+		AdmissionReviewVersions: admissionReviewVersions(),
+		MatchPolicy:             &matchPolicy,
 		TimeoutSeconds:          &timeoutSeconds,
 		FailurePolicy:           &failurePolicy,
+		NamespaceSelector:       namespaceSelector,
+		ObjectSelector:          objectSelector,
 	}
 
 	if !reflect.DeepEqual(rule, admregapi.Rule{}) {
@@ -128,8 +337,9 @@ func generateDebugMutatingWebhook(name, url string, caData []byte, validate bool
 	return w
 }
 
-func generateDebugValidatingWebhook(name, url string, caData []byte, validate bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType) admregapi.ValidatingWebhook {
-	sideEffect := admregapi.SideEffectClassNoneOnDryRun
+func generateDebugValidatingWebhook(name, url string, caData []byte, validate bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType, namespaceSelector, objectSelector *v1.LabelSelector) admregapi.ValidatingWebhook {
+	sideEffect := config.ValidatingWebhookSideEffects
+	matchPolicy := config.WebhookMatchPolicy
 	w := admregapi.ValidatingWebhook{
 		Name: name,
 		ClientConfig: admregapi.WebhookClientConfig{
@@ -137,9 +347,12 @@ func generateDebugValidatingWebhook(name, url string, caData []byte, validate bo
 			CABundle: caData,
 		},
 		SideEffects:             &sideEffect,
-		AdmissionReviewVersions: []string{"v1beta1"},
+		AdmissionReviewVersions: admissionReviewVersions(),
+		MatchPolicy:             &matchPolicy,
 		TimeoutSeconds:          &timeoutSeconds,
 		FailurePolicy:           &failurePolicy,
+		NamespaceSelector:       namespaceSelector,
+		ObjectSelector:          objectSelector,
 	}
 
 	if !reflect.DeepEqual(rule, admregapi.Rule{}) {
@@ -154,25 +367,25 @@ func generateDebugValidatingWebhook(name, url string, caData []byte, validate bo
 }
 
 // mutating webhook
-func generateMutatingWebhook(name, servicePath string, caData []byte, validation bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType) admregapi.MutatingWebhook {
-	sideEffect := admregapi.SideEffectClassNoneOnDryRun
+func generateMutatingWebhook(name string, service *admregapi.ServiceReference, caData []byte, validation bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType, namespaceSelector, objectSelector *v1.LabelSelector) admregapi.MutatingWebhook {
+	sideEffect := config.MutatingWebhookSideEffects
+	matchPolicy := config.WebhookMatchPolicy
 	reinvocationPolicy := admregapi.IfNeededReinvocationPolicy
 
 	w := admregapi.MutatingWebhook{
 		ReinvocationPolicy: &reinvocationPolicy,
 		Name:               name,
 		ClientConfig: admregapi.WebhookClientConfig{
-			Service: &admregapi.ServiceReference{
-				Namespace: config.KyvernoNamespace,
-				Name:      config.KyvernoServiceName,
-				Path:      &servicePath,
-			},
+			Service:  service,
 			CABundle: caData,
 		},
 		SideEffects:             &sideEffect,
-		AdmissionReviewVersions: []string{"v1beta1"},
+		AdmissionReviewVersions: admissionReviewVersions(),
+		MatchPolicy:             &matchPolicy,
 		TimeoutSeconds:          &timeoutSeconds,
 		FailurePolicy:           &failurePolicy,
+		NamespaceSelector:       namespaceSelector,
+		ObjectSelector:          objectSelector,
 	}
 
 	if !reflect.DeepEqual(rule, admregapi.Rule{}) {
@@ -187,22 +400,22 @@ func generateMutatingWebhook(name, servicePath string, caData []byte, validation
 }
 
 // validating webhook
-func generateValidatingWebhook(name, servicePath string, caData []byte, validation bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType) admregapi.ValidatingWebhook {
-	sideEffect := admregapi.SideEffectClassNoneOnDryRun
+func generateValidatingWebhook(name string, service *admregapi.ServiceReference, caData []byte, validation bool, timeoutSeconds int32, rule admregapi.Rule, operationTypes []admregapi.OperationType, failurePolicy admregapi.FailurePolicyType, namespaceSelector, objectSelector *v1.LabelSelector) admregapi.ValidatingWebhook {
+	sideEffect := config.ValidatingWebhookSideEffects
+	matchPolicy := config.WebhookMatchPolicy
 	w := admregapi.ValidatingWebhook{
 		Name: name,
 		ClientConfig: admregapi.WebhookClientConfig{
-			Service: &admregapi.ServiceReference{
-				Namespace: config.KyvernoNamespace,
-				Name:      config.KyvernoServiceName,
-				Path:      &servicePath,
-			},
+			Service:  service,
 			CABundle: caData,
 		},
 		SideEffects:             &sideEffect,
-		AdmissionReviewVersions: []string{"v1beta1"},
+		AdmissionReviewVersions: admissionReviewVersions(),
+		MatchPolicy:             &matchPolicy,
 		TimeoutSeconds:          &timeoutSeconds,
 		FailurePolicy:           &failurePolicy,
+		NamespaceSelector:       namespaceSelector,
+		ObjectSelector:          objectSelector,
 	}
 
 	if !reflect.DeepEqual(rule, admregapi.Rule{}) {
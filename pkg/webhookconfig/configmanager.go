@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -32,6 +33,22 @@ import (
 
 var DefaultWebhookTimeout int64 = 10
 
+const (
+	// MinWebhookTimeout is the minimum webhook timeout in seconds accepted by the API server
+	MinWebhookTimeout int64 = 1
+
+	// MaxWebhookTimeout is the maximum webhook timeout in seconds accepted by the API server
+	MaxWebhookTimeout int64 = 30
+)
+
+// ValidateWebhookTimeout returns an error if the timeout is not within the range allowed by the API server
+func ValidateWebhookTimeout(timeoutSeconds int64) error {
+	if timeoutSeconds < MinWebhookTimeout || timeoutSeconds > MaxWebhookTimeout {
+		return fmt.Errorf("invalid webhook timeout %ds, the value must be between %d and %d seconds", timeoutSeconds, MinWebhookTimeout, MaxWebhookTimeout)
+	}
+	return nil
+}
+
 // webhookConfigManager manges the webhook configuration dynamically
 // it is NOT multi-thread safe
 type webhookConfigManager struct {
@@ -62,11 +79,25 @@ type webhookConfigManager struct {
 
 	queue workqueue.RateLimitingInterface
 
+	// debounceWindow is the time the policy changes are collected before the webhooks are reconciled
+	debounceWindow time.Duration
+
+	// pendingPolicies are the keys of the changed policies whose status is updated
+	// by the next webhook reconciliation
+	pendingPolicies map[string]struct{}
+	pendingMutex    sync.Mutex
+
 	// serverIP used to get the name of debug webhooks
 	serverIP string
 
 	autoUpdateWebhooks bool
 
+	// resourceWebhookKinds are the kinds of the resource webhook configurations updated with the policies
+	resourceWebhookKinds []string
+
+	// operations are the default operations of the resource webhooks, the policies may add others
+	operations WebhookOperations
+
 	// wildcardPolicy indicates the number of policies that matches all kinds (*) defined
 	wildcardPolicy int64
 
@@ -89,6 +120,8 @@ func newWebhookConfigManager(
 	resCache resourcecache.ResourceCache,
 	serverIP string,
 	autoUpdateWebhooks bool,
+	resourceWebhookKinds []string,
+	operations WebhookOperations,
 	createDefaultWebhook chan<- string,
 	stopCh <-chan struct{},
 	log logr.Logger) manage {
@@ -100,9 +133,13 @@ func newWebhookConfigManager(
 		npInformer:           npInformer,
 		resCache:             resCache,
 		queue:                workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmanager"),
+		debounceWindow:       config.WebhookUpdateDebounce,
+		pendingPolicies:      make(map[string]struct{}),
 		wildcardPolicy:       0,
 		serverIP:             serverIP,
 		autoUpdateWebhooks:   autoUpdateWebhooks,
+		resourceWebhookKinds: resourceWebhookKinds,
+		operations:           operations,
 		createDefaultWebhook: createDefaultWebhook,
 		stopCh:               stopCh,
 		log:                  log,
@@ -133,13 +170,13 @@ func (m *webhookConfigManager) handleErr(err error, key interface{}) {
 	}
 
 	if m.queue.NumRequeues(key) < 3 {
-		logger.Error(err, "failed to sync policy", "key", key)
+		logger.Error(err, "failed to sync webhooks", "key", key)
 		m.queue.AddRateLimited(key)
 		return
 	}
 
 	utilruntime.HandleError(err)
-	logger.V(2).Info("dropping policy out of queue", "key", key)
+	logger.V(2).Info("dropping webhook reconciliation out of queue", "key", key)
 	m.queue.Forget(key)
 }
 
@@ -293,6 +330,12 @@ func (m *webhookConfigManager) enqueueAllPolicies() {
 	}
 }
 
+// webhookReconcileKey is the queue key of the webhook reconciliation, it is not a valid policy key
+const webhookReconcileKey = "#webhooks"
+
+// enqueue records the changed policy and schedules a webhook reconciliation after the debounce window.
+// The changes received within the window are reconciled together, so that a bulk policy apply
+// updates the webhook configurations once.
 func (m *webhookConfigManager) enqueue(policy *kyverno.ClusterPolicy) {
 	logger := m.log
 	key, err := cache.MetaNamespaceKeyFunc(policy)
@@ -300,7 +343,32 @@ func (m *webhookConfigManager) enqueue(policy *kyverno.ClusterPolicy) {
 		logger.Error(err, "failed to enqueue policy")
 		return
 	}
-	m.queue.Add(key)
+
+	m.addPendingPolicies([]string{key})
+	m.queue.AddAfter(webhookReconcileKey, m.debounceWindow)
+}
+
+func (m *webhookConfigManager) addPendingPolicies(keys []string) {
+	m.pendingMutex.Lock()
+	defer m.pendingMutex.Unlock()
+
+	for _, key := range keys {
+		m.pendingPolicies[key] = struct{}{}
+	}
+}
+
+// takePendingPolicies returns and clears the keys of the changed policies
+func (m *webhookConfigManager) takePendingPolicies() []string {
+	m.pendingMutex.Lock()
+	defer m.pendingMutex.Unlock()
+
+	keys := make([]string, 0, len(m.pendingPolicies))
+	for key := range m.pendingPolicies {
+		keys = append(keys, key)
+	}
+
+	m.pendingPolicies = make(map[string]struct{})
+	return keys
 }
 
 // start is a blocking call to configure webhook
@@ -355,55 +423,75 @@ func (m *webhookConfigManager) processNextWorkItem() bool {
 func (m *webhookConfigManager) sync(key string) error {
 	logger := m.log.WithName("sync")
 	startTime := time.Now()
-	logger.V(4).Info("started syncing policy", "key", key, "startTime", startTime)
+	logger.V(4).Info("started syncing webhooks", "key", key, "startTime", startTime)
 	defer func() {
-		logger.V(4).Info("finished syncing policy", "key", key, "processingTime", time.Since(startTime).String())
+		logger.V(4).Info("finished syncing webhooks", "key", key, "processingTime", time.Since(startTime).String())
 	}()
 
-	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	keys := m.takePendingPolicies()
+	failed, err := m.reconcileWebhooks(keys)
 	if err != nil {
-		logger.Info("invalid resource key", "key", key)
-		return nil
+		// the failed policies are reconciled again on retry
+		m.addPendingPolicies(failed)
 	}
-
-	return m.reconcileWebhook(namespace, name)
+	return err
 }
 
-func (m *webhookConfigManager) reconcileWebhook(namespace, name string) error {
-	logger := m.log.WithName("reconcileWebhook").WithValues("namespace", namespace, "policy", name)
-
-	policy, err := m.getPolicy(namespace, name)
-	if err != nil && !apierrors.IsNotFound(err) {
-		return errors.Wrapf(err, "unable to get policy object %s/%s", namespace, name)
-	}
+// reconcileWebhooks updates the webhook configurations once for all the policies, and then
+// the status of the changed policies. It returns the keys of the policies to retry on error.
+func (m *webhookConfigManager) reconcileWebhooks(keys []string) ([]string, error) {
+	logger := m.log.WithName("reconcileWebhooks")
 
 	ready := true
 	// build webhook only if auto-update is enabled, otherwise directly update status to ready
 	if m.autoUpdateWebhooks {
-		webhooks, err := m.buildWebhooks(namespace)
+		webhooks, err := m.buildWebhooks()
 		if err != nil {
-			return err
+			return keys, err
 		}
 
 		if err := m.updateWebhookConfig(webhooks); err != nil {
 			ready = false
-			logger.Error(err, "failed to update webhook configurations for policy")
+			logger.Error(err, "failed to update webhook configurations", "policies", len(keys))
 		}
+	}
 
-		// DELETION of the policy
-		if policy == nil {
-			return nil
+	var failed []string
+	var errs []string
+	for _, key := range keys {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			logger.Info("invalid resource key", "key", key)
+			continue
+		}
+
+		policy, err := m.getPolicy(namespace, name)
+		if err != nil {
+			// DELETION of the policy
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			failed = append(failed, key)
+			errs = append(errs, fmt.Sprintf("unable to get policy object %s: %v", key, err))
+			continue
+		}
+
+		if err := m.updateStatus(policy, ready); err != nil {
+			failed = append(failed, key)
+			errs = append(errs, fmt.Sprintf("failed to update policy status %s: %v", key, err))
+			continue
 		}
-	}
 
-	if err := m.updateStatus(policy, ready); err != nil {
-		return errors.Wrapf(err, "failed to update policy status %s/%s", namespace, name)
+		if ready {
+			logger.Info("policy is ready to serve admission requests", "namespace", namespace, "policy", name)
+		}
 	}
 
-	if ready {
-		logger.Info("policy is ready to serve admission requests")
+	if len(errs) > 0 {
+		return failed, errors.New(strings.Join(errs, "\n"))
 	}
-	return nil
+	return nil, nil
 }
 
 func (m *webhookConfigManager) getPolicy(namespace, name string) (*kyverno.ClusterPolicy, error) {
@@ -448,8 +536,20 @@ const (
 	apiGroups   string = "apiGroups"
 	apiVersions string = "apiVersions"
 	resources   string = "resources"
+	operations  string = "operations"
 )
 
+// annOperations lists the admission operations a policy registers the resource webhooks for,
+// in addition to the default operations, e.g. "UPDATE,DELETE"
+const annOperations string = "kyverno.io/operations"
+
+// ValidateOperationsAnnotation returns an error if the kyverno.io/operations annotation of a policy
+// has an unknown operation
+func ValidateOperationsAnnotation(annotations map[string]string) error {
+	_, err := parseOperations(annotations[annOperations])
+	return err
+}
+
 // webhook is the instance that aggregates the GVK of existing policies
 // based on kind, failurePolicy and webhookTimeout
 type webhook struct {
@@ -462,7 +562,27 @@ type webhook struct {
 	rule map[string]interface{}
 }
 
-func (m *webhookConfigManager) buildWebhooks(namespace string) (res []*webhook, err error) {
+// listAllPolicies returns the cluster policies and the policies of all namespaces
+func (m *webhookConfigManager) listAllPolicies() ([]*kyverno.ClusterPolicy, error) {
+	policies, err := m.listPolicies("")
+	if err != nil {
+		return nil, err
+	}
+
+	polList, err := m.npLister.List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Policy")
+	}
+
+	for _, pol := range polList {
+		p := kyverno.ClusterPolicy(*pol)
+		policies = append(policies, &p)
+	}
+	return policies, nil
+}
+
+// buildWebhooks computes the desired webhook rules of all the policies
+func (m *webhookConfigManager) buildWebhooks() (res []*webhook, err error) {
 	mutateIgnore := newWebhook(kindMutating, DefaultWebhookTimeout, kyverno.Ignore)
 	mutateFail := newWebhook(kindMutating, DefaultWebhookTimeout, kyverno.Fail)
 	validateIgnore := newWebhook(kindValidating, DefaultWebhookTimeout, kyverno.Ignore)
@@ -477,7 +597,7 @@ func (m *webhookConfigManager) buildWebhooks(namespace string) (res []*webhook,
 		return append(res, mutateIgnore, mutateFail, validateIgnore, validateFail), nil
 	}
 
-	policies, err := m.listPolicies(namespace)
+	policies, err := m.listAllPolicies()
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to list current policies")
 	}
@@ -513,14 +633,18 @@ func (m *webhookConfigManager) updateWebhookConfig(webhooks []*webhook) error {
 	}
 
 	var errs []string
-	if err := m.compareAndUpdateWebhook(kindMutating, getResourceMutatingWebhookConfigName(m.serverIP), webhooksMap); err != nil {
-		logger.V(4).Info("failed to update mutatingwebhookconfigurations", "error", err.Error())
-		errs = append(errs, err.Error())
+	if m.managesResourceWebhook(kindMutating) {
+		if err := m.compareAndUpdateWebhook(kindMutating, getResourceMutatingWebhookConfigName(m.serverIP), webhooksMap); err != nil {
+			logger.V(4).Info("failed to update mutatingwebhookconfigurations", "error", err.Error())
+			errs = append(errs, err.Error())
+		}
 	}
 
-	if err := m.compareAndUpdateWebhook(kindValidating, getResourceValidatingWebhookConfigName(m.serverIP), webhooksMap); err != nil {
-		logger.V(4).Info("failed to update validatingwebhookconfigurations", "error", err.Error())
-		errs = append(errs, err.Error())
+	if m.managesResourceWebhook(kindValidating) {
+		if err := m.compareAndUpdateWebhook(kindValidating, getResourceValidatingWebhookConfigName(m.serverIP), webhooksMap); err != nil {
+			logger.V(4).Info("failed to update validatingwebhookconfigurations", "error", err.Error())
+			errs = append(errs, err.Error())
+		}
 	}
 
 	if len(errs) != 0 {
@@ -530,6 +654,16 @@ func (m *webhookConfigManager) updateWebhookConfig(webhooks []*webhook) error {
 	return nil
 }
 
+// managesResourceWebhook returns true if the resource webhook configuration of the kind is registered
+func (m *webhookConfigManager) managesResourceWebhook(kind string) bool {
+	for _, k := range m.resourceWebhookKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *webhookConfigManager) getWebhook(webhookKind, webhookName string) (resourceWebhook *unstructured.Unstructured, err error) {
 	get := func() error {
 		webhookCache, _ := m.resCache.GetGVRCache(webhookKind)
@@ -598,17 +732,8 @@ func (m *webhookConfigManager) compareAndUpdateWebhook(webhookKind, webhookName
 			changed = true
 
 			tmpRules, ok := newWebooks[i].(map[string]interface{})["rules"].([]interface{})
-			if !ok {
-				// init operations
-				ops := []string{string(admregapi.Create), string(admregapi.Update), string(admregapi.Delete), string(admregapi.Connect)}
-				if webhookKind == kindMutating {
-					ops = []string{string(admregapi.Create), string(admregapi.Update)}
-				}
-
+			if !ok || len(tmpRules) == 0 {
 				tmpRules = []interface{}{map[string]interface{}{}}
-				if err = unstructured.SetNestedStringSlice(tmpRules[0].(map[string]interface{}), ops, "operations"); err != nil {
-					return errors.Wrapf(err, "unable to set webhooks[%d].rules[0].%s", i, apiGroups)
-				}
 			}
 
 			if w.rule == nil || reflect.DeepEqual(w.rule, map[string]interface{}{}) {
@@ -626,6 +751,9 @@ func (m *webhookConfigManager) compareAndUpdateWebhook(webhookKind, webhookName
 			if err = unstructured.SetNestedStringSlice(tmpRules[0].(map[string]interface{}), w.rule[resources].([]string), resources); err != nil {
 				return errors.Wrapf(err, "unable to set webhooks[%d].rules[0].%s", i, resources)
 			}
+			if err = unstructured.SetNestedStringSlice(tmpRules[0].(map[string]interface{}), webhookRuleOperations(m.operations, webhookKind, w), operations); err != nil {
+				return errors.Wrapf(err, "unable to set webhooks[%d].rules[0].%s", i, operations)
+			}
 
 			newWebooks[i].(map[string]interface{})["rules"] = tmpRules
 		}
@@ -665,6 +793,34 @@ func (m *webhookConfigManager) updateStatus(policy *kyverno.ClusterPolicy, statu
 
 // mergeWebhook merges the matching kinds of the policy to webhook.rule
 func (m *webhookConfigManager) mergeWebhook(dst *webhook, policy *kyverno.ClusterPolicy, updateValidate bool) {
+	findResource := func(gv, kind string) (schema.GroupVersionResource, error) {
+		_, gvr, err := m.client.DiscoveryClient.FindResource(gv, kind)
+		return gvr, err
+	}
+
+	gvrList, unresolved := matchedResources(policyMatchedKinds(policy, updateValidate), findResource)
+	for gvk, err := range unresolved {
+		m.log.Error(err, "unable to convert GVK to GVR", "GVK", gvk)
+	}
+
+	mergeWebhookRule(dst, gvrList)
+
+	ops, err := parseOperations(policy.GetAnnotations()[annOperations])
+	if err != nil {
+		m.log.Error(err, "invalid annotation, using the default operations", "policy", policy.GetName(), "annotation", annOperations)
+	}
+	mergeWebhookOperations(dst, ops)
+
+	if policy.Spec.WebhookTimeoutSeconds != nil {
+		if dst.maxWebhookTimeout < int64(*policy.Spec.WebhookTimeoutSeconds) {
+			dst.maxWebhookTimeout = int64(*policy.Spec.WebhookTimeoutSeconds)
+		}
+	}
+}
+
+// policyMatchedKinds returns the kinds matched by the policy rules that need to be
+// registered to the validating (updateValidate=true) or the mutating webhook
+func policyMatchedKinds(policy *kyverno.ClusterPolicy, updateValidate bool) []string {
 	matchedGVK := make([]string, 0)
 	for _, rule := range policy.Spec.Rules {
 		// matching kinds in generate policies need to be added to both webhook
@@ -681,40 +837,58 @@ func (m *webhookConfigManager) mergeWebhook(dst *webhook, policy *kyverno.Cluste
 		}
 	}
 
+	return matchedGVK
+}
+
+// resourceFinder returns the resource of the kind in the group version, gv may be empty
+type resourceFinder func(gv, kind string) (schema.GroupVersionResource, error)
+
+// matchedResources converts the kinds matched by policies to the distinct resources of the webhook rules,
+// so that the webhooks are only registered for the API groups and versions used by the policies.
+// The kinds that cannot be resolved are returned with the resolution error.
+func matchedResources(matchedGVK []string, findResource resourceFinder) ([]schema.GroupVersionResource, map[string]error) {
 	gvkMap := make(map[string]int)
 	gvrList := make([]schema.GroupVersionResource, 0)
+	unresolved := make(map[string]error)
 	for _, gvk := range matchedGVK {
-		if _, ok := gvkMap[gvk]; !ok {
-			gvkMap[gvk] = 1
-
-			// note: webhook stores GVR in its rules while policy stores GVK in its rules definition
-			gv, k := common.GetKindFromGVK(gvk)
-			switch k {
-			case "Binding":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/binding"})
-			case "NodeProxyOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "nodes/proxy"})
-			case "PodAttachOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/attach"})
-			case "PodExecOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/exec"})
-			case "PodPortForwardOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/portforward"})
-			case "PodProxyOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/proxy"})
-			case "ServiceProxyOptions":
-				gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services/proxy"})
-			default:
-				_, gvr, err := m.client.DiscoveryClient.FindResource(gv, k)
-				if err != nil {
-					m.log.Error(err, "unable to convert GVK to GVR", "GVK", gvk)
-					continue
-				}
-				gvrList = append(gvrList, gvr)
+		if _, ok := gvkMap[gvk]; ok {
+			continue
+		}
+		gvkMap[gvk] = 1
+
+		// a kind in the Kind/subresource format, e.g. Pod/exec, is registered as the subresource, e.g. pods/exec
+		if kind, subresource := common.SplitSubresource(gvk); subresource != "" {
+			gvr, err := findResource("", kind)
+			if err != nil {
+				unresolved[gvk] = err
+				continue
 			}
+			gvr.Resource = gvr.Resource + "/" + subresource
+			gvrList = append(gvrList, gvr)
+			continue
+		}
+
+		// note: webhook stores GVR in its rules while policy stores GVK in its rules definition
+		gv, k := common.GetKindFromGVK(gvk)
+		if resource, ok := common.GetSubresourceOfKind(k); ok {
+			gvrList = append(gvrList, schema.GroupVersionResource{Group: "", Version: "v1", Resource: resource})
+			continue
+		}
+
+		gvr, err := findResource(gv, k)
+		if err != nil {
+			unresolved[gvk] = err
+			continue
 		}
+		gvrList = append(gvrList, gvr)
 	}
 
+	return gvrList, unresolved
+}
+
+// mergeWebhookRule adds the group, version and resource of each GVR to webhook.rule,
+// a wildcard group, version or resource replaces the specific values
+func mergeWebhookRule(dst *webhook, gvrList []schema.GroupVersionResource) {
 	var groups, versions, rsrcs []string
 	if val, ok := dst.rule[apiGroups]; ok {
 		groups = make([]string, len(val.([]string)))
@@ -736,15 +910,69 @@ func (m *webhookConfigManager) mergeWebhook(dst *webhook, policy *kyverno.Cluste
 		rsrcs = append(rsrcs, gvr.Resource)
 	}
 
-	dst.rule[apiGroups] = removeDuplicates(groups)
-	dst.rule[apiVersions] = removeDuplicates(versions)
-	dst.rule[resources] = removeDuplicates(rsrcs)
+	dst.rule[apiGroups] = collapseWildcard(removeDuplicates(groups))
+	dst.rule[apiVersions] = collapseWildcard(removeDuplicates(versions))
+	dst.rule[resources] = collapseWildcard(removeDuplicates(rsrcs))
+}
+
+// parseOperations parses the comma separated operations of the kyverno.io/operations annotation,
+// an empty value returns no operations so that only the default operations apply
+func parseOperations(value string) ([]admregapi.OperationType, error) {
+	var ops []admregapi.OperationType
+	for _, op := range strings.Split(value, ",") {
+		op = strings.ToUpper(strings.TrimSpace(op))
+		if op == "" {
+			continue
+		}
 
-	if policy.Spec.WebhookTimeoutSeconds != nil {
-		if dst.maxWebhookTimeout < int64(*policy.Spec.WebhookTimeoutSeconds) {
-			dst.maxWebhookTimeout = int64(*policy.Spec.WebhookTimeoutSeconds)
+		switch admregapi.OperationType(op) {
+		case admregapi.OperationAll, admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect:
+			ops = append(ops, admregapi.OperationType(op))
+		default:
+			return nil, fmt.Errorf("unknown operation %q in %q, the supported operations are %s, %s, %s, %s and %s",
+				op, value, admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect, admregapi.OperationAll)
+		}
+	}
+	return ops, nil
+}
+
+// mergeWebhookOperations adds the operations requested by a policy to webhook.rule
+func mergeWebhookOperations(dst *webhook, ops []admregapi.OperationType) {
+	if len(ops) == 0 {
+		return
+	}
+
+	var merged []string
+	if val, ok := dst.rule[operations]; ok {
+		merged = append(merged, val.([]string)...)
+	}
+
+	merged = append(merged, operationStrings(ops)...)
+	dst.rule[operations] = collapseWildcard(removeDuplicates(merged))
+}
+
+// webhookRuleOperations returns the default operations of the webhook kind
+// together with the operations requested by the policies
+func webhookRuleOperations(defaults WebhookOperations, webhookKind string, w *webhook) []string {
+	ops := operationStrings(defaults.Validate)
+	if webhookKind == kindMutating {
+		ops = operationStrings(defaults.Mutate)
+	}
+
+	if val, ok := w.rule[operations]; ok {
+		ops = append(ops, val.([]string)...)
+	}
+	return collapseWildcard(removeDuplicates(ops))
+}
+
+// collapseWildcard returns ["*"] if the items contain "*", as it matches all other items
+func collapseWildcard(items []string) []string {
+	for _, item := range items {
+		if item == "*" {
+			return []string{"*"}
 		}
 	}
+	return items
 }
 
 func removeDuplicates(items []string) (res []string) {
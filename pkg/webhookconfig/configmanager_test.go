@@ -0,0 +1,470 @@
+package webhookconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	kyvernolister "github.com/kyverno/kyverno/pkg/client/listers/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/resourcecache"
+	"gotest.tools/assert"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamiclister"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestValidateWebhookTimeout(t *testing.T) {
+	testcases := []struct {
+		timeout int64
+		valid   bool
+	}{
+		{timeout: 0, valid: false},
+		{timeout: 1, valid: true},
+		{timeout: 10, valid: true},
+		{timeout: 30, valid: true},
+		{timeout: 31, valid: false},
+		{timeout: -5, valid: false},
+	}
+
+	for _, tc := range testcases {
+		err := ValidateWebhookTimeout(tc.timeout)
+		assert.Equal(t, tc.valid, err == nil, "timeout %d", tc.timeout)
+	}
+}
+
+func Test_policyMatchedKinds(t *testing.T) {
+	rawPolicy := []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {
+    "name": "test-policy"
+  },
+  "spec": {
+    "rules": [
+      {
+        "name": "validate-pod",
+        "match": {
+          "resources": {
+            "kinds": ["Pod"]
+          }
+        },
+        "validate": {
+          "message": "label app is required",
+          "pattern": {"metadata": {"labels": {"app": "?*"}}}
+        }
+      },
+      {
+        "name": "mutate-deployment",
+        "match": {
+          "resources": {
+            "kinds": ["Deployment"]
+          }
+        },
+        "mutate": {
+          "patchStrategicMerge": {"metadata": {"labels": {"app": "test"}}}
+        }
+      }
+    ]
+  }
+}`)
+
+	var policy kyverno.ClusterPolicy
+	err := json.Unmarshal(rawPolicy, &policy)
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, policyMatchedKinds(&policy, true), []string{"Pod"})
+	assert.DeepEqual(t, policyMatchedKinds(&policy, false), []string{"Deployment"})
+}
+
+func Test_policyMatchedKinds_CommaSeparatedKinds(t *testing.T) {
+	rawPolicy := []byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {"name": "workloads"},
+  "spec": {
+    "rules": [
+      {
+        "name": "validate-workloads",
+        "match": {"any": [{"resources": {"kinds": ["Deployment,StatefulSet", "DaemonSet, Deployment"]}}]},
+        "validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}
+      }
+    ]
+  }
+}`)
+
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal(rawPolicy, &policy))
+
+	kinds := policyMatchedKinds(&policy, true)
+	assert.DeepEqual(t, kinds, []string{"Deployment", "StatefulSet", "DaemonSet"})
+	assert.Assert(t, !hasWildcard(&policy))
+
+	findResource := func(gv, kind string) (schema.GroupVersionResource, error) {
+		return schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: strings.ToLower(kind) + "s"}, nil
+	}
+	gvrList, unresolved := matchedResources(kinds, findResource)
+	assert.Equal(t, len(unresolved), 0)
+
+	dst := newWebhook(kindValidating, DefaultWebhookTimeout, kyverno.Fail)
+	mergeWebhookRule(dst, gvrList)
+	assert.DeepEqual(t, dst.rule[resources], []string{"deployments", "statefulsets", "daemonsets"})
+
+	policy.Spec.Rules[0].MatchResources.Any[0].Kinds = []string{"Deployment,*"}
+	assert.Assert(t, hasWildcard(&policy))
+}
+
+func Test_mergeWebhookRule(t *testing.T) {
+	dst := newWebhook(kindValidating, DefaultWebhookTimeout, kyverno.Fail)
+
+	mergeWebhookRule(dst, []schema.GroupVersionResource{
+		{Group: "", Version: "v1", Resource: "pods"},
+	})
+	mergeWebhookRule(dst, []schema.GroupVersionResource{
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+		{Group: "", Version: "v1", Resource: "pods"},
+	})
+
+	assert.DeepEqual(t, dst.rule[apiGroups], []string{"", "apps"})
+	assert.DeepEqual(t, dst.rule[apiVersions], []string{"v1"})
+	assert.DeepEqual(t, dst.rule[resources], []string{"pods", "deployments"})
+}
+
+func Test_matchedResources(t *testing.T) {
+	rawPolicies := [][]byte{
+		[]byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {"name": "deployments"},
+  "spec": {
+    "rules": [
+      {
+        "name": "validate-deployment",
+        "match": {"resources": {"kinds": ["apps/v1/Deployment", "PodExecOptions"]}},
+        "validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}
+      }
+    ]
+  }
+}`),
+		[]byte(`{
+  "apiVersion": "kyverno.io/v1",
+  "kind": "ClusterPolicy",
+  "metadata": {"name": "widgets"},
+  "spec": {
+    "rules": [
+      {
+        "name": "validate-widget",
+        "match": {"resources": {"kinds": ["example.com/v1alpha1/Widget", "Gadget", "Deployment"]}},
+        "validate": {"message": "label app is required", "pattern": {"metadata": {"labels": {"app": "?*"}}}}
+      }
+    ]
+  }
+}`),
+	}
+
+	installed := map[string]schema.GroupVersionResource{
+		"apps/v1/Deployment":          {Group: "apps", Version: "v1", Resource: "deployments"},
+		"/Deployment":                 {Group: "apps", Version: "v1", Resource: "deployments"},
+		"example.com/v1alpha1/Widget": {Group: "example.com", Version: "v1alpha1", Resource: "widgets"},
+	}
+	findResource := func(gv, kind string) (schema.GroupVersionResource, error) {
+		if gvr, ok := installed[gv+"/"+kind]; ok {
+			return gvr, nil
+		}
+		return schema.GroupVersionResource{}, fmt.Errorf("kind %s not found in %s", kind, gv)
+	}
+
+	dst := newWebhook(kindValidating, DefaultWebhookTimeout, kyverno.Fail)
+	for _, raw := range rawPolicies {
+		var policy kyverno.ClusterPolicy
+		assert.NilError(t, json.Unmarshal(raw, &policy))
+
+		gvrList, unresolved := matchedResources(policyMatchedKinds(&policy, true), findResource)
+		mergeWebhookRule(dst, gvrList)
+
+		if policy.Name == "widgets" {
+			assert.Equal(t, len(unresolved), 1)
+			assert.Assert(t, unresolved["Gadget"] != nil)
+		} else {
+			assert.Equal(t, len(unresolved), 0)
+		}
+	}
+
+	assert.DeepEqual(t, dst.rule[apiGroups], []string{"apps", "", "example.com"})
+	assert.DeepEqual(t, dst.rule[apiVersions], []string{"v1", "v1alpha1"})
+	assert.DeepEqual(t, dst.rule[resources], []string{"deployments", "pods/exec", "widgets"})
+}
+
+func Test_matchedResources_Subresource(t *testing.T) {
+	installed := map[string]schema.GroupVersionResource{
+		"/Pod":        {Group: "", Version: "v1", Resource: "pods"},
+		"v1/Pod":      {Group: "", Version: "v1", Resource: "pods"},
+		"/Deployment": {Group: "apps", Version: "v1", Resource: "deployments"},
+	}
+	findResource := func(gv, kind string) (schema.GroupVersionResource, error) {
+		if gvr, ok := installed[gv+"/"+kind]; ok {
+			return gvr, nil
+		}
+		return schema.GroupVersionResource{}, fmt.Errorf("kind %s not found in %s", kind, gv)
+	}
+
+	gvrList, unresolved := matchedResources([]string{"Pod/exec", "Deployment/scale", "Widget/status", "v1/Pod"}, findResource)
+	assert.Equal(t, len(unresolved), 1)
+	assert.Assert(t, unresolved["Widget/status"] != nil)
+	assert.DeepEqual(t, gvrList, []schema.GroupVersionResource{
+		{Group: "", Version: "v1", Resource: "pods/exec"},
+		{Group: "apps", Version: "v1", Resource: "deployments/scale"},
+		{Group: "", Version: "v1", Resource: "pods"},
+	})
+}
+
+func Test_mergeWebhookRule_Wildcard(t *testing.T) {
+	dst := newWebhook(kindMutating, DefaultWebhookTimeout, kyverno.Ignore)
+
+	mergeWebhookRule(dst, []schema.GroupVersionResource{
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+	})
+	mergeWebhookRule(dst, []schema.GroupVersionResource{
+		{Group: "*", Version: "v1", Resource: "*"},
+	})
+
+	assert.DeepEqual(t, dst.rule[apiGroups], []string{"*"})
+	assert.DeepEqual(t, dst.rule[apiVersions], []string{"v1"})
+	assert.DeepEqual(t, dst.rule[resources], []string{"*"})
+}
+
+func Test_parseOperations(t *testing.T) {
+	testcases := []struct {
+		name          string
+		annotation    string
+		expected      []admregapi.OperationType
+		expectedError string
+	}{
+		{
+			name:       "default",
+			annotation: "",
+			expected:   nil,
+		},
+		{
+			name:       "update and delete",
+			annotation: "UPDATE,DELETE",
+			expected:   []admregapi.OperationType{admregapi.Update, admregapi.Delete},
+		},
+		{
+			name:       "spaces and lower case",
+			annotation: " update , connect,",
+			expected:   []admregapi.OperationType{admregapi.Update, admregapi.Connect},
+		},
+		{
+			name:       "all",
+			annotation: "*",
+			expected:   []admregapi.OperationType{admregapi.OperationAll},
+		},
+		{
+			name:          "unknown",
+			annotation:    "UPDATE,PATCH",
+			expectedError: `unknown operation "PATCH"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ops, err := parseOperations(tc.annotation)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, ops, tc.expected)
+		})
+	}
+}
+
+func Test_mergeWebhookOperations(t *testing.T) {
+	dst := newWebhook(kindMutating, DefaultWebhookTimeout, kyverno.Fail)
+	assert.DeepEqual(t, webhookRuleOperations(defaultWebhookOperations, kindMutating, dst), []string{"CREATE", "UPDATE"})
+
+	mergeWebhookOperations(dst, nil)
+	mergeWebhookOperations(dst, []admregapi.OperationType{admregapi.Update, admregapi.Delete})
+	mergeWebhookOperations(dst, []admregapi.OperationType{admregapi.Delete})
+	assert.DeepEqual(t, webhookRuleOperations(defaultWebhookOperations, kindMutating, dst), []string{"CREATE", "UPDATE", "DELETE"})
+
+	mergeWebhookOperations(dst, []admregapi.OperationType{admregapi.OperationAll})
+	assert.DeepEqual(t, webhookRuleOperations(defaultWebhookOperations, kindMutating, dst), []string{"*"})
+
+	// the configured operations replace the default operations of the webhook kind
+	validate := newWebhook(kindValidating, DefaultWebhookTimeout, kyverno.Fail)
+	custom := WebhookOperations{Validate: []admregapi.OperationType{admregapi.Update}}
+	assert.DeepEqual(t, webhookRuleOperations(custom, kindValidating, validate), []string{"UPDATE"})
+}
+
+func newDebounceTestManager(window time.Duration) *webhookConfigManager {
+	return &webhookConfigManager{
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmanager-test"),
+		debounceWindow:  window,
+		pendingPolicies: make(map[string]struct{}),
+		log:             logr.DiscardLogger{},
+	}
+}
+
+func TestEnqueue_DebouncesPolicyChanges(t *testing.T) {
+	window := 200 * time.Millisecond
+	m := newDebounceTestManager(window)
+	defer m.queue.ShutDown()
+
+	for i := 0; i < 50; i++ {
+		m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("policy-%d", i)}})
+		m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: fmt.Sprintf("policy-%d", i)}})
+	}
+
+	// nothing is reconciled before the end of the window
+	assert.Equal(t, m.queue.Len(), 0)
+
+	key, quit := m.queue.Get()
+	assert.Assert(t, !quit)
+	assert.Equal(t, key, webhookReconcileKey)
+
+	// all the changes are reconciled together
+	keys := m.takePendingPolicies()
+	assert.Equal(t, len(keys), 100)
+	m.queue.Done(key)
+
+	time.Sleep(2 * window)
+	assert.Equal(t, m.queue.Len(), 0)
+	assert.Equal(t, len(m.takePendingPolicies()), 0)
+}
+
+// fakeResourceCache serves the listers of the webhook configurations from indexers
+type fakeResourceCache struct {
+	caches map[string]resourcecache.GenericCache
+}
+
+func (c *fakeResourceCache) CreateInformers(gvks ...string) []error { return nil }
+
+func (c *fakeResourceCache) CreateGVKInformer(gvk string) (resourcecache.GenericCache, error) {
+	return c.caches[gvk], nil
+}
+
+func (c *fakeResourceCache) StopResourceInformer(gvk string) {}
+
+func (c *fakeResourceCache) GetGVRCache(gvk string) (resourcecache.GenericCache, bool) {
+	genericCache, ok := c.caches[gvk]
+	return genericCache, ok
+}
+
+type fakeGenericCache struct {
+	gvr     schema.GroupVersionResource
+	indexer cache.Indexer
+}
+
+func (c *fakeGenericCache) StopInformer() {}
+
+func (c *fakeGenericCache) IsNamespaced() bool { return false }
+
+func (c *fakeGenericCache) Lister() dynamiclister.Lister { return dynamiclister.New(c.indexer, c.gvr) }
+
+func (c *fakeGenericCache) NamespacedLister(namespace string) dynamiclister.NamespaceLister {
+	return dynamiclister.New(c.indexer, c.gvr).Namespace(namespace)
+}
+
+func (c *fakeGenericCache) GVR() schema.GroupVersionResource { return c.gvr }
+
+func (c *fakeGenericCache) GetInformer() cache.SharedIndexInformer { return nil }
+
+// newSyncTestManager returns a manager of the resource mutating webhook configuration, with wildcard policies so
+// that the webhooks are built without listing the policies
+func newSyncTestManager(t *testing.T, window time.Duration) *webhookConfigManager {
+	mutatingWebhook := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "admissionregistration.k8s.io/v1",
+		"kind":       kindMutating,
+		"metadata":   map[string]interface{}{"name": config.MutatingWebhookConfigurationName},
+		"webhooks": []interface{}{
+			map[string]interface{}{"name": config.MutatingWebhookName + "-fail", "failurePolicy": "Fail", "rules": []interface{}{}},
+		},
+	}}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NilError(t, indexer.Add(mutatingWebhook.DeepCopy()))
+
+	m := newDebounceTestManager(window)
+	m.client = newWebhookMockClient(t, mutatingWebhook)
+	m.resCache = &fakeResourceCache{caches: map[string]resourcecache.GenericCache{
+		kindMutating: &fakeGenericCache{
+			gvr:     schema.GroupVersionResource{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "mutatingwebhookconfigurations"},
+			indexer: indexer,
+		},
+	}}
+	m.pLister = kyvernolister.NewClusterPolicyLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+	m.npLister = kyvernolister.NewPolicyLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}))
+	m.autoUpdateWebhooks = true
+	m.resourceWebhookKinds = []string{kindMutating}
+	m.operations = defaultWebhookOperations
+	m.wildcardPolicy = 1
+	return m
+}
+
+func countUpdateActions(m *webhookConfigManager) int {
+	count := 0
+	for _, action := range m.client.GetDynamicInterface().(*fake.FakeDynamicClient).Actions() {
+		if action.GetVerb() == "update" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSync_PolicyBurstUpdatesWebhooksOnce(t *testing.T) {
+	window := 200 * time.Millisecond
+	m := newSyncTestManager(t, window)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m.processNextWorkItem() {
+		}
+	}()
+
+	// the policies are not found in the listers, as if they were deleted
+	for i := 0; i < 50; i++ {
+		m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("policy-%d", i)}})
+		m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: fmt.Sprintf("policy-%d", i)}})
+	}
+
+	time.Sleep(3 * window)
+	m.queue.ShutDown()
+	<-done
+
+	assert.Equal(t, countUpdateActions(m), 1)
+	assert.Equal(t, len(m.takePendingPolicies()), 0)
+}
+
+func TestEnqueue_ChangeDuringReconciliation(t *testing.T) {
+	m := newDebounceTestManager(0)
+	defer m.queue.ShutDown()
+
+	m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-1"}})
+	key, _ := m.queue.Get()
+	assert.DeepEqual(t, m.takePendingPolicies(), []string{"policy-1"})
+
+	// a change received while the webhooks are reconciled schedules another reconciliation
+	m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-2"}})
+	m.enqueue(&kyverno.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-3"}})
+	assert.Equal(t, m.queue.Len(), 0)
+	m.queue.Done(key)
+
+	assert.Equal(t, m.queue.Len(), 1)
+	key, _ = m.queue.Get()
+	assert.Equal(t, key, webhookReconcileKey)
+	assert.Equal(t, len(m.takePendingPolicies()), 2)
+	m.queue.Done(key)
+}
@@ -0,0 +1,100 @@
+package webhookconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	crdAPIVersion = "apiextensions.k8s.io/v1"
+	kindCRD       = "CustomResourceDefinition"
+)
+
+// constructConversion returns the conversion of the policy CRDs to the Kyverno conversion webhook
+func (wrc *Register) constructConversion(caData []byte) *apiextv1.CustomResourceConversion {
+	clientConfig := &apiextv1.WebhookClientConfig{CABundle: caData}
+	if wrc.serverIP != "" {
+		url := wrc.debugURL(config.ConversionWebhookServicePath)
+		clientConfig.URL = &url
+	} else {
+		path := config.ConversionWebhookServicePath
+		port := int32(443)
+		clientConfig.Service = &apiextv1.ServiceReference{
+			Namespace: wrc.serviceNamespace,
+			Name:      wrc.serviceName,
+			Path:      &path,
+			Port:      &port,
+		}
+	}
+
+	versions := make([]string, len(config.ConversionReviewVersions))
+	copy(versions, config.ConversionReviewVersions)
+
+	return &apiextv1.CustomResourceConversion{
+		Strategy: apiextv1.WebhookConverter,
+		Webhook: &apiextv1.WebhookConversion{
+			ClientConfig:             clientConfig,
+			ConversionReviewVersions: versions,
+		},
+	}
+}
+
+// registerConversionWebhook sets the Kyverno conversion webhook on the policy CRDs, so that the
+// API server converts the stored policies between the API versions through Kyverno.
+// The CRDs are only updated when their conversion differs, e.g. after a CA rotation or after
+// a Helm upgrade reset it. A CRD that cannot be updated does not stop the update of the others.
+func (wrc *Register) registerConversionWebhook(ctx context.Context, caData []byte) error {
+	conversion, err := runtime.DefaultUnstructuredConverter.ToUnstructured(wrc.constructConversion(caData))
+	if err != nil {
+		return fmt.Errorf("failed to convert the CRD conversion to unstructured: %v", err)
+	}
+
+	errs := make([]string, 0)
+	for _, name := range config.PolicyCRDNames {
+		if err := wrc.registerCRDConversion(ctx, name, conversion); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return nil
+}
+
+func (wrc *Register) registerCRDConversion(ctx context.Context, name string, conversion map[string]interface{}) error {
+	logger := wrc.log.WithValues("kind", kindCRD, "name", name)
+	client := wrc.client.WithContext(ctx)
+
+	crd, err := client.GetResource(crdAPIVersion, kindCRD, "", name)
+	if err != nil {
+		if errorsapi.IsNotFound(err) {
+			logger.V(3).Info("CRD not found, skipping the conversion webhook registration")
+			return nil
+		}
+		return fmt.Errorf("failed to get %s %s: %v", kindCRD, name, err)
+	}
+
+	current, _, _ := unstructured.NestedMap(crd.Object, "spec", "conversion")
+	if reflect.DeepEqual(current, conversion) {
+		return nil
+	}
+
+	if err := unstructured.SetNestedMap(crd.Object, conversion, "spec", "conversion"); err != nil {
+		return fmt.Errorf("failed to set the conversion of %s %s: %v", kindCRD, name, err)
+	}
+
+	if _, err := client.UpdateResource(crdAPIVersion, kindCRD, "", crd, false); err != nil {
+		return fmt.Errorf("failed to update %s %s: %v", kindCRD, name, err)
+	}
+	logger.Info("registered conversion webhook", "path", config.ConversionWebhookServicePath)
+	return nil
+}
@@ -0,0 +1,110 @@
+package webhookconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	client "github.com/kyverno/kyverno/pkg/dclient"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPolicyCRD(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": crdAPIVersion,
+		"kind":       kindCRD,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"group":      "kyverno.io",
+			"conversion": map[string]interface{}{"strategy": "None"},
+		},
+	}}
+}
+
+func newConversionRegister(t *testing.T, serverIP string) *Register {
+	crdGVR := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	objects := []runtime.Object{}
+	for _, name := range config.PolicyCRDNames {
+		objects = append(objects, newPolicyCRD(name))
+	}
+
+	c, err := client.NewMockClient(runtime.NewScheme(), map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"}, objects...)
+	assert.NilError(t, err)
+	c.SetDiscovery(client.NewFakeDiscoveryClient([]schema.GroupVersionResource{crdGVR}))
+
+	wrc := newTestRegister(c)
+	wrc.serverIP = serverIP
+	return wrc
+}
+
+func TestRegisterConversionWebhook(t *testing.T) {
+	wrc := newConversionRegister(t, "")
+	assert.NilError(t, wrc.registerConversionWebhook(context.TODO(), []byte(cert)))
+
+	for _, name := range config.PolicyCRDNames {
+		crd, err := wrc.client.GetResource(crdAPIVersion, kindCRD, "", name)
+		assert.NilError(t, err)
+
+		strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+		assert.Equal(t, strategy, "Webhook")
+
+		service, _, _ := unstructured.NestedMap(crd.Object, "spec", "conversion", "webhook", "clientConfig", "service")
+		assert.DeepEqual(t, service, map[string]interface{}{"name": "kyverno-svc", "namespace": "kyverno", "path": config.ConversionWebhookServicePath, "port": int64(443)})
+
+		versions, _, _ := unstructured.NestedStringSlice(crd.Object, "spec", "conversion", "webhook", "conversionReviewVersions")
+		assert.DeepEqual(t, versions, config.ConversionReviewVersions)
+
+		caBundle, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhook", "clientConfig", "caBundle")
+		assert.Assert(t, caBundle != "")
+
+		// the other fields of the CRD are kept
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		assert.Equal(t, group, "kyverno.io")
+	}
+
+	// the CRDs are not updated again if their conversion is unchanged
+	writes := countWriteActions(wrc)
+	assert.NilError(t, wrc.registerConversionWebhook(context.TODO(), []byte(cert)))
+	assert.Equal(t, countWriteActions(wrc), writes)
+
+	// a rotated CA is set on the CRDs
+	assert.NilError(t, wrc.registerConversionWebhook(context.TODO(), []byte("rotated-ca")))
+	assert.Equal(t, countWriteActions(wrc), writes+len(config.PolicyCRDNames))
+}
+
+func TestRegisterConversionWebhook_Debug(t *testing.T) {
+	wrc := newConversionRegister(t, "127.0.0.1:9443")
+	assert.NilError(t, wrc.registerConversionWebhook(context.TODO(), []byte(cert)))
+
+	crd, err := wrc.client.GetResource(crdAPIVersion, kindCRD, "", config.PolicyCRDNames[0])
+	assert.NilError(t, err)
+
+	url, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhook", "clientConfig", "url")
+	assert.Equal(t, url, "https://127.0.0.1:9443"+config.ConversionWebhookServicePath)
+}
+
+func TestRegisterConversionWebhook_CRDNotFound(t *testing.T) {
+	wrc := newReconcileRegister(t)
+	assert.NilError(t, wrc.registerConversionWebhook(context.TODO(), []byte(cert)))
+	assert.Equal(t, countWriteActions(wrc), 0)
+}
+
+func TestRegisterConversionWebhook_UpdateFailure(t *testing.T) {
+	wrc := newConversionRegister(t, "")
+	wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient).PrependReactor("update", "customresourcedefinitions",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("forbidden")
+		})
+
+	// the update of each CRD is attempted
+	err := wrc.registerConversionWebhook(context.TODO(), []byte(cert))
+	for _, name := range config.PolicyCRDNames {
+		assert.ErrorContains(t, err, "failed to update "+kindCRD+" "+name)
+	}
+}
@@ -0,0 +1,38 @@
+package webhookconfig
+
+// DebugState is the state of the webhook registration served by the debug endpoint
+type DebugState struct {
+	// Registered is false until the webhooks are registered
+	Registered bool `json:"registered"`
+
+	// CAFingerprint is the SHA-256 fingerprint of the CA bundle set on the webhooks
+	CAFingerprint string `json:"caFingerprint,omitempty"`
+
+	// WebhookConfigurations are the webhook configurations applied with the registered CA bundle,
+	// the CA bundles are replaced by their fingerprint
+	WebhookConfigurations []map[string]interface{} `json:"webhookConfigurations"`
+}
+
+// DebugState returns the webhook configurations Kyverno registered and the fingerprint of their CA bundle.
+// The configurations are built from the current settings, the rules of the resource webhooks managed
+// by the webhook config manager may differ from the live configurations.
+func (wrc *Register) DebugState() (DebugState, error) {
+	caData, err := wrc.getRegisteredCABundle()
+	if err != nil {
+		return DebugState{}, err
+	}
+	if caData == nil {
+		return DebugState{WebhookConfigurations: []map[string]interface{}{}}, nil
+	}
+
+	configs, err := wrc.redactedWebhookConfigurations(caData)
+	if err != nil {
+		return DebugState{}, err
+	}
+
+	return DebugState{
+		Registered:            true,
+		CAFingerprint:         caFingerprint(caData),
+		WebhookConfigurations: configs,
+	}, nil
+}
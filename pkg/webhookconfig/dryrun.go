@@ -0,0 +1,91 @@
+package webhookconfig
+
+import (
+	"fmt"
+	"io"
+
+	admregapi "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// RegisterDryRun writes the webhook configurations Register would apply to w as a multi-document YAML,
+// without sending them to the API server. The CA bundles are replaced by their SHA-256 fingerprint.
+func (wrc *Register) RegisterDryRun(w io.Writer) error {
+	caData, err := wrc.readCaData()
+	if err != nil {
+		wrc.log.Info("CA is not available, the caBundle is omitted", "reason", err.Error())
+		caData = nil
+	}
+
+	configs, err := wrc.redactedWebhookConfigurations(caData)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range configs {
+		raw, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %v", obj["kind"], getMetadataName(obj), err)
+		}
+
+		if _, err := fmt.Fprintf(w, "---\n%s", raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redactedWebhookConfigurations returns the webhook configurations Register applies with caData,
+// with the CA bundles replaced by their SHA-256 fingerprint
+func (wrc *Register) redactedWebhookConfigurations(caData []byte) ([]map[string]interface{}, error) {
+	var configs []map[string]interface{}
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		desired.config.GetObjectKind().SetGroupVersionKind(admregapi.SchemeGroupVersion.WithKind(desired.kind))
+
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s to unstructured: %v", desired.kind, desired.config.GetName(), err)
+		}
+
+		if err := redactCABundles(obj, caData); err != nil {
+			return nil, fmt.Errorf("failed to redact the CA bundle of %s %s: %v", desired.kind, desired.config.GetName(), err)
+		}
+
+		configs = append(configs, obj)
+	}
+	return configs, nil
+}
+
+func getMetadataName(obj map[string]interface{}) string {
+	name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+	return name
+}
+
+// redactCABundles replaces the caBundle of each webhook by the fingerprint of caData
+func redactCABundles(obj map[string]interface{}, caData []byte) error {
+	webhooks, _, err := unstructured.NestedSlice(obj, "webhooks")
+	if err != nil {
+		return err
+	}
+
+	for i := range webhooks {
+		webhook, ok := webhooks[i].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("type mismatched, expected map[string]interface{}, got %T", webhooks[i])
+		}
+
+		if len(caData) == 0 {
+			unstructured.RemoveNestedField(webhook, "clientConfig", "caBundle")
+			continue
+		}
+
+		if err := unstructured.SetNestedField(webhook, caFingerprint(caData), "clientConfig", "caBundle"); err != nil {
+			return err
+		}
+	}
+
+	return unstructured.SetNestedSlice(obj, webhooks, "webhooks")
+}
@@ -0,0 +1,73 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"gotest.tools/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRegisterDryRun(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, []byte(cert), 0600))
+
+	c := newWebhookMockClient(t)
+	wrc := newTestRegister(c)
+	wrc.caFilePath = caFile
+
+	var out bytes.Buffer
+	assert.NilError(t, wrc.RegisterDryRun(&out))
+
+	docs := strings.Split(strings.TrimPrefix(out.String(), "---\n"), "---\n")
+	assert.Equal(t, len(docs), 5)
+
+	names := map[string]string{}
+	for _, doc := range docs {
+		var obj map[string]interface{}
+		assert.NilError(t, yaml.Unmarshal([]byte(doc), &obj))
+		metadata := obj["metadata"].(map[string]interface{})
+		names[metadata["name"].(string)] = obj["kind"].(string)
+
+		for _, w := range obj["webhooks"].([]interface{}) {
+			clientConfig := w.(map[string]interface{})["clientConfig"].(map[string]interface{})
+			assert.Equal(t, clientConfig["caBundle"], caFingerprint([]byte(cert)))
+		}
+	}
+
+	assert.Equal(t, names[config.MutatingWebhookConfigurationDebugName], kindMutating)
+	assert.Equal(t, names[config.ValidatingWebhookConfigurationDebugName], kindValidating)
+	assert.Equal(t, names[config.PolicyValidatingWebhookConfigurationDebugName], kindValidating)
+	assert.Equal(t, names[config.PolicyMutatingWebhookConfigurationDebugName], kindMutating)
+	assert.Equal(t, names[config.VerifyMutatingWebhookConfigurationDebugName], kindMutating)
+
+	// the CA is not sent in full and nothing is applied
+	assert.Assert(t, !strings.Contains(out.String(), "BEGIN CERTIFICATE"))
+	_, err := c.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+	assert.Assert(t, err != nil)
+}
+
+func TestDebugState(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+
+	state, err := wrc.DebugState()
+	assert.NilError(t, err)
+	assert.Assert(t, !state.Registered)
+	assert.Equal(t, len(state.WebhookConfigurations), 0)
+
+	wrc.setRegisteredCABundle([]byte(cert))
+	state, err = wrc.DebugState()
+	assert.NilError(t, err)
+	assert.Assert(t, state.Registered)
+	assert.Equal(t, state.CAFingerprint, caFingerprint([]byte(cert)))
+	assert.Equal(t, len(state.WebhookConfigurations), 5)
+
+	raw, err := json.Marshal(state)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(raw), "BEGIN CERTIFICATE"))
+}
@@ -1,6 +1,7 @@
 package webhookconfig
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -79,6 +80,14 @@ func (t *Monitor) Run(register *Register, certRenewer *tls.CertRenewer, eventGen
 	logger.V(4).Info("starting webhook monitor", "interval", idleCheckInterval.String())
 	status := newStatusControl(register, eventGen, t.log.WithName("WebhookStatusControl"))
 
+	// cancel the in-flight registration on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
 	ticker := time.NewTicker(tickerInterval)
 	defer ticker.Stop()
 
@@ -86,14 +95,25 @@ func (t *Monitor) Run(register *Register, certRenewer *tls.CertRenewer, eventGen
 	for {
 		select {
 		case webhookKind := <-createDefaultWebhook:
+			if !register.resourceWebhookEnabled(webhookKind) {
+				logger.V(3).Info("skipping recreation request for disabled resource webhook", "kind", webhookKind)
+				continue
+			}
+
 			logger.Info("received recreation request for resource webhook")
+			caData, err := register.readCaData()
+			if err != nil {
+				logger.Error(err, "failed to read CA data, the webhook will be reconciled", "interval", tickerInterval)
+				continue
+			}
+
 			if webhookKind == kindMutating {
-				err := register.createResourceMutatingWebhookConfiguration(register.readCaData())
+				err := register.createResourceMutatingWebhookConfiguration(ctx, caData)
 				if err != nil {
 					logger.Error(err, "failed to create default MutatingWebhookConfiguration for resources, the webhook will be reconciled", "interval", tickerInterval)
 				}
 			} else if webhookKind == kindValidating {
-				err := register.createResourceValidatingWebhookConfiguration(register.readCaData())
+				err := register.createResourceValidatingWebhookConfiguration(ctx, caData)
 				if err != nil {
 					logger.Error(err, "failed to create default ValidatingWebhookConfiguration for resources, the webhook will be reconciled", "interval", tickerInterval)
 				}
@@ -101,7 +121,7 @@ func (t *Monitor) Run(register *Register, certRenewer *tls.CertRenewer, eventGen
 
 		case <-ticker.C:
 
-			err := registerWebhookIfNotPresent(register, t.log.WithName("registerWebhookIfNotPresent"))
+			err := registerWebhookIfNotPresent(ctx, register, t.log.WithName("registerWebhookIfNotPresent"))
 			if err != nil {
 				t.log.Error(err, "")
 			}
@@ -125,7 +145,7 @@ func (t *Monitor) Run(register *Register, certRenewer *tls.CertRenewer, eventGen
 					logger.Error(err, "failed to annotate deployment webhook status to failure")
 				}
 
-				if err := register.Register(); err != nil {
+				if err := register.Register(ctx); err != nil {
 					logger.Error(err, "Failed to register webhooks")
 				}
 
@@ -165,7 +185,7 @@ func (t *Monitor) Run(register *Register, certRenewer *tls.CertRenewer, eventGen
 	}
 }
 
-func registerWebhookIfNotPresent(register *Register, logger logr.Logger) error {
+func registerWebhookIfNotPresent(ctx context.Context, register *Register, logger logr.Logger) error {
 	if skipWebhookCheck(register, logger.WithName("skipWebhookCheck")) {
 		logger.Info("skip validating webhook status, Kyverno is in rolling update")
 		return nil
@@ -174,7 +194,7 @@ func registerWebhookIfNotPresent(register *Register, logger logr.Logger) error {
 	if err := register.Check(); err != nil {
 		logger.Error(err, "missing webhooks")
 
-		if err := register.Register(); err != nil {
+		if err := register.Register(ctx); err != nil {
 			return errors.Wrap(err, "failed to register webhooks")
 		}
 	}
@@ -1,8 +1,6 @@
 package webhookconfig
 
 import (
-	"fmt"
-
 	"github.com/kyverno/kyverno/pkg/config"
 	admregapi "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,7 +18,7 @@ func (wrc *Register) constructPolicyValidatingWebhookConfig(caData []byte) *admr
 		Webhooks: []admregapi.ValidatingWebhook{
 			generateValidatingWebhook(
 				config.PolicyValidatingWebhookName,
-				config.PolicyValidatingWebhookServicePath,
+				wrc.serviceReference(config.PolicyValidatingWebhookServicePath),
 				caData,
 				true,
 				wrc.timeoutSeconds,
@@ -31,6 +29,8 @@ func (wrc *Register) constructPolicyValidatingWebhookConfig(caData []byte) *admr
 				},
 				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
@@ -38,12 +38,13 @@ func (wrc *Register) constructPolicyValidatingWebhookConfig(caData []byte) *admr
 
 func (wrc *Register) constructDebugPolicyValidatingWebhookConfig(caData []byte) *admregapi.ValidatingWebhookConfiguration {
 	logger := wrc.log
-	url := fmt.Sprintf("https://%s%s", wrc.serverIP, config.PolicyValidatingWebhookServicePath)
+	url := wrc.debugURL(config.PolicyValidatingWebhookServicePath)
 	logger.V(4).Info("Debug PolicyValidatingWebhookConfig is registered with url ", "url", url)
 
 	return &admregapi.ValidatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
-			Name: config.PolicyValidatingWebhookConfigurationDebugName,
+			Name:            config.PolicyValidatingWebhookConfigurationDebugName,
+			OwnerReferences: wrc.debugOwnerReferences(),
 		},
 		Webhooks: []admregapi.ValidatingWebhook{
 			generateDebugValidatingWebhook(
@@ -59,6 +60,8 @@ func (wrc *Register) constructDebugPolicyValidatingWebhookConfig(caData []byte)
 				},
 				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
@@ -75,7 +78,7 @@ func (wrc *Register) constructPolicyMutatingWebhookConfig(caData []byte) *admreg
 		Webhooks: []admregapi.MutatingWebhook{
 			generateMutatingWebhook(
 				config.PolicyMutatingWebhookName,
-				config.PolicyMutatingWebhookServicePath,
+				wrc.serviceReference(config.PolicyMutatingWebhookServicePath),
 				caData,
 				true,
 				wrc.timeoutSeconds,
@@ -86,6 +89,8 @@ func (wrc *Register) constructPolicyMutatingWebhookConfig(caData []byte) *admreg
 				},
 				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
@@ -93,12 +98,13 @@ func (wrc *Register) constructPolicyMutatingWebhookConfig(caData []byte) *admreg
 
 func (wrc *Register) constructDebugPolicyMutatingWebhookConfig(caData []byte) *admregapi.MutatingWebhookConfiguration {
 	logger := wrc.log
-	url := fmt.Sprintf("https://%s%s", wrc.serverIP, config.PolicyMutatingWebhookServicePath)
+	url := wrc.debugURL(config.PolicyMutatingWebhookServicePath)
 	logger.V(4).Info("Debug PolicyMutatingWebhookConfig is registered with url ", "url", url)
 
 	return &admregapi.MutatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
-			Name: config.PolicyMutatingWebhookConfigurationDebugName,
+			Name:            config.PolicyMutatingWebhookConfigurationDebugName,
+			OwnerReferences: wrc.debugOwnerReferences(),
 		},
 		Webhooks: []admregapi.MutatingWebhook{
 			generateDebugMutatingWebhook(
@@ -114,6 +120,8 @@ func (wrc *Register) constructDebugPolicyMutatingWebhookConfig(caData []byte) *a
 				},
 				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
@@ -0,0 +1,238 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// serverReadyTimeout is the maximum time Register waits for the local webhook server
+	serverReadyTimeout = 30 * time.Second
+
+	// serverReadyPollInterval is the interval between readiness checks
+	serverReadyPollInterval = time.Second
+
+	// readyCheckInterval is the time the result of CheckReady is cached
+	readyCheckInterval = 10 * time.Second
+)
+
+// defaultServerReadinessURL is the readiness endpoint served by the local webhook server
+var defaultServerReadinessURL = "https://127.0.0.1:9443" + config.ReadinessServicePath
+
+// WaitForServerReady polls the readiness endpoint of the local webhook server until it
+// responds with 200 OK. It returns an error if the server is not ready within the timeout,
+// so that webhook configurations are not registered while the API server cannot reach Kyverno.
+func (wrc *Register) WaitForServerReady(ctx context.Context, timeout time.Duration) error {
+	logger := wrc.log.WithValues("url", wrc.readinessURL)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the server certificate is issued for the service name, the local connection skips verification
+	httpClient := &http.Client{
+		Timeout: wrc.readinessPollInterval,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	ticker := time.NewTicker(wrc.readinessPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		if lastErr = checkServerReady(ctx, httpClient, wrc.readinessURL); lastErr == nil {
+			logger.V(3).Info("webhook server is ready")
+			return nil
+		}
+		logger.V(4).Info("webhook server is not ready yet", "error", lastErr.Error())
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %v waiting for webhook server to be ready at %s: %v", timeout, wrc.readinessURL, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkServerReady(ctx context.Context, httpClient *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// readyState caches the result of the last readiness check
+type readyState struct {
+	mutex     sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// check returns the cached result if it is more recent than readyCheckInterval,
+// otherwise it runs checkFn and caches its result
+func (r *readyState) check(now time.Time, checkFn func(now time.Time) error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.checkedAt.IsZero() && now.Sub(r.checkedAt) < readyCheckInterval {
+		return r.err
+	}
+
+	r.err = checkFn(now)
+	r.checkedAt = now
+	return r.err
+}
+
+func (r *readyState) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.checkedAt = time.Time{}
+	r.err = nil
+}
+
+// CheckReady returns an error if the webhooks are removed, or if the serving certificate
+// is not valid or is not signed by the CA bundle set on the webhooks, as the API server
+// would fail to call the webhooks. The result is cached for readyCheckInterval.
+//
+// Until the leader registers the webhooks the serving certificate is checked with the CA,
+// the registration waits for the replicas to be ready in the endpoints of the Kyverno service.
+func (wrc *Register) CheckReady(servingCert []byte) error {
+	return wrc.ready.check(time.Now(), func(now time.Time) error {
+		caData, err := wrc.getRegisteredCABundle()
+		if err != nil {
+			return err
+		}
+
+		if caData == nil && !wrc.isDeregistered() {
+			if caData, err = wrc.readCaData(); err != nil {
+				return fmt.Errorf("webhooks are not registered and the CA is not available: %v", err)
+			}
+		}
+		return checkServingCert(servingCert, caData, now)
+	})
+}
+
+// setRegisteredCABundle records the CA bundle set on the webhooks by the leader
+func (wrc *Register) setRegisteredCABundle(caData []byte) {
+	wrc.mu.Lock()
+	wrc.registeredCABundle = caData
+	wrc.mu.Unlock()
+
+	wrc.ready.reset()
+}
+
+// setDeregistered records that the webhooks are removed on shutdown
+func (wrc *Register) setDeregistered() {
+	wrc.mu.Lock()
+	wrc.registeredCABundle = nil
+	wrc.deregistered = true
+	wrc.mu.Unlock()
+
+	wrc.ready.reset()
+}
+
+func (wrc *Register) isDeregistered() bool {
+	wrc.mu.RLock()
+	defer wrc.mu.RUnlock()
+	return wrc.deregistered
+}
+
+// getRegisteredCABundle returns the CA bundle set on the webhooks, or nil if the webhooks are not
+// registered. Only the leader registers the webhooks, the other replicas read the bundle from the
+// live verify mutating webhook configuration.
+func (wrc *Register) getRegisteredCABundle() ([]byte, error) {
+	wrc.mu.RLock()
+	caData, deregistered := wrc.registeredCABundle, wrc.deregistered
+	wrc.mu.RUnlock()
+
+	if deregistered || caData != nil {
+		return caData, nil
+	}
+	return wrc.liveCABundle()
+}
+
+// liveCABundle returns the CA bundle of the live verify mutating webhook configuration,
+// or nil if the configuration does not exist
+func (wrc *Register) liveCABundle() ([]byte, error) {
+	name := config.VerifyMutatingWebhookConfigurationName
+	if wrc.serverIP != "" {
+		name = config.VerifyMutatingWebhookConfigurationDebugName
+	}
+
+	obj, err := wrc.client.GetResource("", kindMutating, "", name)
+	if err != nil {
+		if errorsapi.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s %s: %v", kindMutating, name, err)
+	}
+
+	live := &admregapi.MutatingWebhookConfiguration{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), live); err != nil {
+		return nil, fmt.Errorf("failed to convert %s %s from unstructured: %v", kindMutating, name, err)
+	}
+
+	for _, w := range live.Webhooks {
+		if len(w.ClientConfig.CABundle) != 0 {
+			return w.ClientConfig.CABundle, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkServingCert returns an error if caData is empty, or if the serving certificate
+// is not valid at the given time or cannot be verified with caData
+func checkServingCert(servingCert, caData []byte, now time.Time) error {
+	if len(bytes.TrimSpace(caData)) == 0 {
+		return errors.New("webhooks are not registered")
+	}
+
+	block, _ := pem.Decode(servingCert)
+	if block == nil {
+		return errors.New("failed to decode the serving certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse the serving certificate: %v", err)
+	}
+
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("serving certificate is valid from %s to %s", cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caData) {
+		return errors.New("failed to parse the registered CA bundle")
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: now}); err != nil {
+		return fmt.Errorf("serving certificate does not match the registered CA bundle: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package webhookconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	ktls "github.com/kyverno/kyverno/pkg/tls"
+	"gotest.tools/assert"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newReadinessServer(readyAfter int32, polls *int32) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(polls, 1) < readyAfter {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestWaitForServerReady(t *testing.T) {
+	var polls int32
+	srv := newReadinessServer(3, &polls)
+	defer srv.Close()
+
+	wrc := &Register{
+		log:                   log.Log,
+		readinessURL:          srv.URL + config.ReadinessServicePath,
+		readinessPollInterval: 10 * time.Millisecond,
+	}
+
+	err := wrc.WaitForServerReady(context.TODO(), time.Second)
+	assert.NilError(t, err)
+	assert.Equal(t, atomic.LoadInt32(&polls), int32(3))
+}
+
+func TestWaitForServerReady_Timeout(t *testing.T) {
+	var polls int32
+	srv := newReadinessServer(1000, &polls)
+	defer srv.Close()
+
+	wrc := &Register{
+		log:                   log.Log,
+		readinessURL:          srv.URL + config.ReadinessServicePath,
+		readinessPollInterval: 10 * time.Millisecond,
+	}
+
+	err := wrc.WaitForServerReady(context.TODO(), 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func newServingCert(t *testing.T) (caPEM, certPEM []byte) {
+	caKeyPair, caPemPair, err := ktls.GenerateCACert(time.Hour)
+	assert.NilError(t, err)
+
+	props := ktls.CertificateProps{Service: config.KyvernoServiceName, Namespace: config.KyvernoNamespace, APIServerHost: "127.0.0.1"}
+	pemPair, err := ktls.GenerateCertPem(caKeyPair, props, "", time.Hour)
+	assert.NilError(t, err)
+	return caPemPair.Certificate, pemPair.Certificate
+}
+
+func TestCheckReady(t *testing.T) {
+	caPEM, certPEM := newServingCert(t)
+	otherCAPEM, _ := newServingCert(t)
+
+	testcases := []struct {
+		name         string
+		registeredCA []byte
+		servingCert  []byte
+		err          string
+	}{
+		{name: "registered and valid", registeredCA: caPEM, servingCert: certPEM},
+		{name: "cert mismatch", registeredCA: otherCAPEM, servingCert: certPEM, err: "does not match the registered CA bundle"},
+		{name: "invalid cert", registeredCA: caPEM, servingCert: []byte("invalid"), err: "failed to decode the serving certificate"},
+	}
+
+	for _, tc := range testcases {
+		wrc := newTestRegister(newWebhookMockClient(t))
+		wrc.setRegisteredCABundle(tc.registeredCA)
+
+		err := wrc.CheckReady(tc.servingCert)
+		if tc.err == "" {
+			assert.NilError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.err, tc.name)
+		}
+	}
+}
+
+func TestCheckReady_Follower(t *testing.T) {
+	caPEM, certPEM := newServingCert(t)
+	otherCAPEM, _ := newServingCert(t)
+
+	// the leader registered the webhooks with caPEM, the CA file of the follower holds another CA
+	leader := newTestRegister(newWebhookMockClient(t))
+	_, err := leader.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, leader.constructDebugVerifyMutatingWebhookConfig(caPEM))
+	assert.NilError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, otherCAPEM, 0600))
+
+	follower := newTestRegister(leader.client)
+	follower.caFilePath = caFile
+	assert.NilError(t, follower.CheckReady(certPEM))
+
+	state, err := follower.DebugState()
+	assert.NilError(t, err)
+	assert.Assert(t, state.Registered)
+	assert.Equal(t, state.CAFingerprint, caFingerprint(caPEM))
+}
+
+func TestCheckReady_NotRegistered(t *testing.T) {
+	caPEM, certPEM := newServingCert(t)
+
+	// the pods must be ready before the leader registers the webhooks, the certificate is checked with the CA
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, caPEM, 0600))
+
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.caFilePath = caFile
+	assert.NilError(t, wrc.CheckReady(certPEM))
+
+	state, err := wrc.DebugState()
+	assert.NilError(t, err)
+	assert.Assert(t, !state.Registered)
+
+	wrc.setDeregistered()
+	assert.ErrorContains(t, wrc.CheckReady(certPEM), "webhooks are not registered")
+}
+
+func TestCheckServingCert_Expired(t *testing.T) {
+	caPEM, certPEM := newServingCert(t)
+
+	err := checkServingCert(certPEM, caPEM, time.Now().Add(2*time.Hour))
+	assert.ErrorContains(t, err, "serving certificate is valid from")
+}
+
+func TestCheckReady_Cached(t *testing.T) {
+	caPEM, certPEM := newServingCert(t)
+	otherCAPEM, _ := newServingCert(t)
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.setRegisteredCABundle(otherCAPEM)
+
+	assert.ErrorContains(t, wrc.CheckReady(certPEM), "does not match the registered CA bundle")
+
+	// the registration resets the cached result
+	wrc.setRegisteredCABundle(caPEM)
+	assert.NilError(t, wrc.CheckReady(certPEM))
+
+	var checks int
+	now := time.Now()
+	state := &readyState{}
+	for i := 0; i < 3; i++ {
+		_ = state.check(now.Add(time.Duration(i)*time.Second), func(time.Time) error {
+			checks++
+			return nil
+		})
+	}
+	assert.Equal(t, checks, 1)
+
+	_ = state.check(now.Add(readyCheckInterval), func(time.Time) error {
+		checks++
+		return nil
+	})
+	assert.Equal(t, checks, 2)
+}
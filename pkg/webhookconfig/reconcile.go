@@ -0,0 +1,324 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	admregapi "k8s.io/api/admissionregistration/v1"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// reconcileInterval is the interval of the periodic reconciliation of the webhook configurations,
+// in addition to the reconciliation triggered by the webhook configuration events
+const reconcileInterval = 5 * time.Minute
+
+// desiredWebhookConfiguration is a webhook configuration as Kyverno registers it
+type desiredWebhookConfiguration struct {
+	kind   string
+	config webhookConfiguration
+
+	// syncRules is false for the resource webhooks whose rules are managed by the webhookConfigManager
+	syncRules bool
+}
+
+// webhookSettings references the fields of a single webhook that are reconciled
+type webhookSettings struct {
+	name         string
+	clientConfig *admregapi.WebhookClientConfig
+	rules        *[]admregapi.RuleWithOperations
+}
+
+// WatchRegistration watches the webhook configurations and re-applies the desired configuration
+// when one is deleted or its CA bundle, service reference or rules are changed, e.g. by a manual edit.
+// The live configurations are only updated when they drift from the desired state.
+// The reconciliation stops when stopCh is closed or when Remove is called, an in-flight
+// reconciliation is aborted when stopCh is closed.
+func (wrc *Register) WatchRegistration(stopCh <-chan struct{}) {
+	logger := wrc.log.WithName("WatchRegistration")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	trigger := make(chan struct{}, 1)
+	enqueue := func() {
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, _ interface{}) { enqueue() },
+		DeleteFunc: func(_ interface{}) { enqueue() },
+	}
+
+	for _, kind := range []string{kindMutating, kindValidating} {
+		if gvrCache, ok := wrc.resCache.GetGVRCache(kind); ok {
+			gvrCache.GetInformer().AddEventHandler(handler)
+		}
+	}
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	logger.V(2).Info("started watching webhook configurations", "interval", reconcileInterval.String())
+	for {
+		select {
+		case <-trigger:
+		case <-ticker.C:
+		case <-ctx.Done():
+			logger.V(2).Info("stopped watching webhook configurations")
+			return
+		}
+
+		// the webhook configurations are removed on shutdown, do not re-create them
+		if ctx.Err() != nil {
+			logger.V(2).Info("stopped watching webhook configurations")
+			return
+		}
+
+		caData, err := wrc.readCaData()
+		if err != nil {
+			logger.Error(err, "failed to reconcile webhook configurations")
+			continue
+		}
+
+		reconciled, err := wrc.reconcileRegistration(ctx, caData)
+		if !reconciled {
+			logger.V(2).Info("webhook configurations are removed, stopped watching webhook configurations")
+			return
+		}
+
+		if err != nil {
+			logger.Error(err, "failed to reconcile webhook configurations")
+		}
+	}
+}
+
+// reconcileRegistration reconciles the webhook configurations with caData. It returns false without
+// reconciling once Remove has started, and Remove waits for an in-flight reconciliation.
+func (wrc *Register) reconcileRegistration(ctx context.Context, caData []byte) (bool, error) {
+	wrc.reconcileMu.Lock()
+	defer wrc.reconcileMu.Unlock()
+
+	if wrc.removed {
+		return false, nil
+	}
+
+	if err := wrc.reconcileWebhookConfigurations(ctx, caData); err != nil {
+		return true, err
+	}
+
+	wrc.setRegisteredCABundle(caData)
+	return true, nil
+}
+
+// stopReconciliation waits for an in-flight reconciliation and prevents the next ones
+func (wrc *Register) stopReconciliation() {
+	wrc.reconcileMu.Lock()
+	defer wrc.reconcileMu.Unlock()
+
+	wrc.removed = true
+}
+
+// reconcileWebhookConfigurations re-creates the missing webhook configurations and restores
+// the drifted ones, then the conversion webhook of the policy CRDs
+func (wrc *Register) reconcileWebhookConfigurations(ctx context.Context, caData []byte) error {
+	errs := make([]string, 0)
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		if err := wrc.reconcileWebhookConfiguration(ctx, desired); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	// as in the registration, a failed update of the CRDs is retried at the next reconciliation
+	if err := wrc.registerConversionWebhook(ctx, caData); err != nil {
+		wrc.log.Error(err, "failed to reconcile the conversion webhook of the policy CRDs")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return nil
+}
+
+func (wrc *Register) desiredWebhookConfigurations(caData []byte) []desiredWebhookConfiguration {
+	var desired []desiredWebhookConfiguration
+	if wrc.serverIP != "" {
+		desired = []desiredWebhookConfiguration{
+			{kind: kindMutating, config: wrc.constructDebugVerifyMutatingWebhookConfig(caData), syncRules: true},
+			{kind: kindValidating, config: wrc.constructDebugPolicyValidatingWebhookConfig(caData), syncRules: true},
+			{kind: kindMutating, config: wrc.constructDebugPolicyMutatingWebhookConfig(caData), syncRules: true},
+		}
+		if wrc.resourceWebhookEnabled(kindValidating) {
+			desired = append(desired, desiredWebhookConfiguration{kind: kindValidating, config: wrc.constructDefaultDebugValidatingWebhookConfig(caData), syncRules: !wrc.autoUpdateWebhooks})
+		}
+		if wrc.resourceWebhookEnabled(kindMutating) {
+			desired = append(desired, desiredWebhookConfiguration{kind: kindMutating, config: wrc.constructDefaultDebugMutatingWebhookConfig(caData), syncRules: !wrc.autoUpdateWebhooks})
+		}
+		return desired
+	}
+
+	desired = []desiredWebhookConfiguration{
+		{kind: kindMutating, config: wrc.constructVerifyMutatingWebhookConfig(caData), syncRules: true},
+		{kind: kindValidating, config: wrc.constructPolicyValidatingWebhookConfig(caData), syncRules: true},
+		{kind: kindMutating, config: wrc.constructPolicyMutatingWebhookConfig(caData), syncRules: true},
+	}
+	if wrc.resourceWebhookEnabled(kindValidating) {
+		desired = append(desired, desiredWebhookConfiguration{kind: kindValidating, config: wrc.constructDefaultValidatingWebhookConfig(caData), syncRules: !wrc.autoUpdateWebhooks})
+	}
+	if wrc.resourceWebhookEnabled(kindMutating) {
+		desired = append(desired, desiredWebhookConfiguration{kind: kindMutating, config: wrc.constructDefaultMutatingWebhookConfig(caData), syncRules: !wrc.autoUpdateWebhooks})
+	}
+	return desired
+}
+
+func (wrc *Register) reconcileWebhookConfiguration(ctx context.Context, desired desiredWebhookConfiguration) error {
+	logger := wrc.log.WithValues("kind", desired.kind, "name", desired.config.GetName())
+	client := wrc.client.WithContext(ctx)
+
+	obj, err := client.GetResource("", desired.kind, "", desired.config.GetName())
+	if err != nil {
+		if !errorsapi.IsNotFound(err) {
+			return fmt.Errorf("failed to get %s %s: %v", desired.kind, desired.config.GetName(), err)
+		}
+
+		logger.Info("webhook configuration not found, re-creating")
+		_, err := wrc.createOrUpdateWebhookConfiguration(ctx, desired.kind, desired.config)
+		return err
+	}
+
+	var live webhookConfiguration = &admregapi.ValidatingWebhookConfiguration{}
+	if desired.kind == kindMutating {
+		live = &admregapi.MutatingWebhookConfiguration{}
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), live); err != nil {
+		return fmt.Errorf("failed to convert %s %s from unstructured: %v", desired.kind, desired.config.GetName(), err)
+	}
+
+	changed, matched := syncWebhookSettings(webhookSettingsOf(desired.config), webhookSettingsOf(live), desired.syncRules)
+	if !matched {
+		logger.Info("webhooks do not match the desired configuration, re-applying")
+		_, err := wrc.createOrUpdateWebhookConfiguration(ctx, desired.kind, desired.config)
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	logger.Info("webhook configuration drifted, restoring CA bundle, service reference and rules")
+	if _, err := client.UpdateResource("", desired.kind, "", live, false); err != nil {
+		return fmt.Errorf("failed to update %s %s: %v", desired.kind, desired.config.GetName(), err)
+	}
+	return nil
+}
+
+func webhookSettingsOf(config webhookConfiguration) []webhookSettings {
+	var res []webhookSettings
+	switch c := config.(type) {
+	case *admregapi.MutatingWebhookConfiguration:
+		for i := range c.Webhooks {
+			res = append(res, webhookSettings{name: c.Webhooks[i].Name, clientConfig: &c.Webhooks[i].ClientConfig, rules: &c.Webhooks[i].Rules})
+		}
+	case *admregapi.ValidatingWebhookConfiguration:
+		for i := range c.Webhooks {
+			res = append(res, webhookSettings{name: c.Webhooks[i].Name, clientConfig: &c.Webhooks[i].ClientConfig, rules: &c.Webhooks[i].Rules})
+		}
+	}
+	return res
+}
+
+// syncWebhookSettings copies the CA bundle, the service reference or URL and, if syncRules is set,
+// the rules of the desired webhooks to the live webhooks with the same name.
+// It returns whether any live webhook changed, and false for matched if the live configuration
+// does not contain the same set of webhooks.
+func syncWebhookSettings(desired, live []webhookSettings, syncRules bool) (changed bool, matched bool) {
+	if len(desired) != len(live) {
+		return false, false
+	}
+
+	liveByName := make(map[string]webhookSettings, len(live))
+	for _, w := range live {
+		liveByName[w.name] = w
+	}
+
+	for _, d := range desired {
+		l, ok := liveByName[d.name]
+		if !ok {
+			return false, false
+		}
+
+		if syncClientConfig(d.clientConfig, l.clientConfig) {
+			changed = true
+		}
+
+		if syncRules && !reflect.DeepEqual(defaultRuleScope(*d.rules), defaultRuleScope(*l.rules)) {
+			*l.rules = *d.rules
+			changed = true
+		}
+	}
+
+	return changed, true
+}
+
+func syncClientConfig(desired, live *admregapi.WebhookClientConfig) bool {
+	changed := false
+	if !bytes.Equal(desired.CABundle, live.CABundle) {
+		live.CABundle = desired.CABundle
+		changed = true
+	}
+
+	if !reflect.DeepEqual(desired.URL, live.URL) {
+		live.URL = desired.URL
+		changed = true
+	}
+
+	if desired.Service == nil || live.Service == nil {
+		if !reflect.DeepEqual(desired.Service, live.Service) {
+			live.Service = desired.Service
+			changed = true
+		}
+	} else {
+		if !reflect.DeepEqual(desired.Service.Path, live.Service.Path) {
+			live.Service.Path = desired.Service.Path
+			changed = true
+		}
+
+		if desired.Service.Name != live.Service.Name || desired.Service.Namespace != live.Service.Namespace {
+			live.Service.Name = desired.Service.Name
+			live.Service.Namespace = desired.Service.Namespace
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// defaultRuleScope returns a copy of the rules with the scope defaulted to "*"
+// as the API server does, so that the defaulted field is not reported as a drift
+func defaultRuleScope(rules []admregapi.RuleWithOperations) []admregapi.RuleWithOperations {
+	res := make([]admregapi.RuleWithOperations, len(rules))
+	for i := range rules {
+		rules[i].DeepCopyInto(&res[i])
+		if res[i].Scope == nil {
+			scope := admregapi.AllScopes
+			res[i].Scope = &scope
+		}
+	}
+	return res
+}
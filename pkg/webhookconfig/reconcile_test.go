@@ -0,0 +1,109 @@
+package webhookconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"gotest.tools/assert"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newReconcileRegister(t *testing.T) *Register {
+	return newTestRegister(newWebhookMockClient(t))
+}
+
+func countWriteActions(wrc *Register) int {
+	count := 0
+	for _, action := range wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient).Actions() {
+		if action.GetVerb() == "create" || action.GetVerb() == "update" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestReconcileWebhookConfigurations_RecreatesDeleted(t *testing.T) {
+	wrc := newReconcileRegister(t)
+	caData := []byte(cert)
+
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		_, err := wrc.client.GetResource("", desired.kind, "", desired.config.GetName())
+		assert.NilError(t, err)
+	}
+
+	err := wrc.client.DeleteResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName, false)
+	assert.NilError(t, err)
+
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+	_, err = wrc.client.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+	assert.NilError(t, err)
+}
+
+func TestReconcileWebhookConfigurations_RestoresDrift(t *testing.T) {
+	wrc := newReconcileRegister(t)
+	caData := []byte(cert)
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+
+	live, err := wrc.client.GetResource("", kindValidating, "", config.ValidatingWebhookConfigurationDebugName)
+	assert.NilError(t, err)
+
+	webhooks, _, err := unstructured.NestedSlice(live.UnstructuredContent(), "webhooks")
+	assert.NilError(t, err)
+	for _, w := range webhooks {
+		assert.NilError(t, unstructured.SetNestedField(w.(map[string]interface{}), "https://127.0.0.1:9443/tampered", "clientConfig", "url"))
+	}
+	assert.NilError(t, unstructured.SetNestedSlice(live.Object, webhooks, "webhooks"))
+	_, err = wrc.client.UpdateResource("", kindValidating, "", live, false)
+	assert.NilError(t, err)
+
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+
+	restored, err := wrc.client.GetResource("", kindValidating, "", config.ValidatingWebhookConfigurationDebugName)
+	assert.NilError(t, err)
+	webhooks, _, err = unstructured.NestedSlice(restored.UnstructuredContent(), "webhooks")
+	assert.NilError(t, err)
+	for _, w := range webhooks {
+		url, _, _ := unstructured.NestedString(w.(map[string]interface{}), "clientConfig", "url")
+		assert.Equal(t, url, "https://127.0.0.1:9443"+config.ValidatingWebhookServicePath)
+	}
+}
+
+func TestReconcileWebhookConfigurations_NoDrift(t *testing.T) {
+	wrc := newReconcileRegister(t)
+	caData := []byte(cert)
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+
+	wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient).ClearActions()
+	assert.NilError(t, wrc.reconcileWebhookConfigurations(context.TODO(), caData))
+	assert.Equal(t, countWriteActions(wrc), 0)
+}
+
+func TestReconcileRegistration_Stopped(t *testing.T) {
+	wrc := newReconcileRegister(t)
+	caData := []byte(cert)
+
+	reconciled, err := wrc.reconcileRegistration(context.TODO(), caData)
+	assert.NilError(t, err)
+	assert.Assert(t, reconciled)
+
+	// Remove stops the reconciliation before it deletes the webhook configurations
+	wrc.stopReconciliation()
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		assert.NilError(t, wrc.client.DeleteResource("", desired.kind, "", desired.config.GetName(), false))
+	}
+	writes := countWriteActions(wrc)
+
+	reconciled, err = wrc.reconcileRegistration(context.TODO(), caData)
+	assert.NilError(t, err)
+	assert.Assert(t, !reconciled)
+	assert.Equal(t, countWriteActions(wrc), writes)
+
+	for _, desired := range wrc.desiredWebhookConfigurations(caData) {
+		_, err := wrc.client.GetResource("", desired.kind, "", desired.config.GetName())
+		assert.Assert(t, errorsapi.IsNotFound(err))
+	}
+}
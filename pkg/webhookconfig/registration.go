@@ -1,8 +1,11 @@
 package webhookconfig
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +15,8 @@ import (
 	kyvernoinformer "github.com/kyverno/kyverno/pkg/client/informers/externalversions/kyverno/v1"
 	"github.com/kyverno/kyverno/pkg/config"
 	client "github.com/kyverno/kyverno/pkg/dclient"
+	"github.com/kyverno/kyverno/pkg/event"
+	"github.com/kyverno/kyverno/pkg/metrics"
 	"github.com/kyverno/kyverno/pkg/resourcecache"
 	"github.com/kyverno/kyverno/pkg/tls"
 	"github.com/pkg/errors"
@@ -21,7 +26,9 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	rest "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -39,20 +46,78 @@ type Register struct {
 	client             *client.Client
 	clientConfig       *rest.Config
 	resCache           resourcecache.ResourceCache
-	serverIP           string // when running outside a cluster
+	serverIP           string         // when running outside a cluster
+	serverPathPrefix   string         // prepended to the service paths of the debug webhook URLs
+	caFilePath         string         // takes precedence over the CA secret and kubeconfig when set
+	caConfigMap        CAConfigMapRef // read after caFilePath in debug mode
+	serviceName        string         // the service called by the webhooks
+	serviceNamespace   string         // the namespace of the service, the Kyverno deployment and its secrets
+	deploymentName     string         // the Kyverno deployment that records the webhook status
 	timeoutSeconds     int32
 	log                logr.Logger
 	debug              bool
 	autoUpdateWebhooks bool
 
+	// disableMutation and disableValidation skip the registration of the resource mutating
+	// and validating webhook configurations, the zero value registers both
+	disableMutation   bool
+	disableValidation bool
+
+	// readinessURL is polled by WaitForServerReady before the webhooks are registered
+	readinessURL          string
+	readinessPollInterval time.Duration
+
+	// operations are the admission operations registered for the resource webhooks
+	operations WebhookOperations
+
+	// retry bounds the retries of the webhook configuration requests on transient API errors
+	retry webhookRetry
+
+	// eventRecorder emits the registration events on the Kyverno deployment
+	eventRecorder record.EventRecorder
+
+	// promConfig records the retries of the CA rotation watcher, the metrics are disabled if it is nil
+	promConfig *metrics.PromConfig
+
+	// exclusions are the objects and namespaces the resource webhooks do not intercept
+	exclusions WebhookExclusions
+
+	// namespaceSelector is loaded from the init ConfigMap and set on the resource webhooks
+	namespaceSelector *v1.LabelSelector
+	mu                sync.RWMutex
+
 	UpdateWebhookChan    chan bool
 	createDefaultWebhook chan string
 
+	// registeredCABundle is the CA bundle set on the webhooks by the latest registration of the leader,
+	// deregistered is set once Remove removes the webhooks
+	registeredCABundle []byte
+	deregistered       bool
+
+	// ready caches the result of CheckReady
+	ready readyState
+
+	// reconcileMu serializes the reconciliations of WatchRegistration and Remove,
+	// removed is set by Remove to stop the reconciliations
+	reconcileMu sync.Mutex
+	removed     bool
+
+	// caWatcher starts the CA rotation watcher once, Register may be invoked multiple times
+	caWatcher sync.Once
+	stopCh    <-chan struct{}
+
 	// manage implements methods to manage webhook configurations
 	manage
 }
 
-// NewRegister creates new Register instance
+// NewRegister creates new Register instance,
+// it returns an error if serverIP is set and is not in the "host:port" format, if serverPathPrefix
+// is set and does not start with "/", or if caConfigMap is set and serverIP is not, as the CA ConfigMap
+// is only read in debug mode.
+// The service name, namespace and deployment name default to config.KyvernoServiceName,
+// config.KyvernoNamespace and config.KyvernoDeploymentName, the operations without a value
+// default to the operations of ParseWebhookOperations.
+// Logs are discarded if log is nil.
 func NewRegister(
 	clientConfig *rest.Config,
 	client *client.Client,
@@ -61,74 +126,262 @@ func NewRegister(
 	pInformer kyvernoinformer.ClusterPolicyInformer,
 	npInformer kyvernoinformer.PolicyInformer,
 	serverIP string,
+	serverPathPrefix string,
+	caFilePath string,
+	caConfigMap CAConfigMapRef,
+	serviceName string,
+	serviceNamespace string,
+	deploymentName string,
 	webhookTimeout int32,
 	debug bool,
 	autoUpdateWebhooks bool,
+	enableMutation bool,
+	enableValidation bool,
+	exclusions WebhookExclusions,
+	operations WebhookOperations,
+	promConfig *metrics.PromConfig,
 	stopCh <-chan struct{},
-	log logr.Logger) *Register {
+	log logr.Logger) (*Register, error) {
+	if log == nil {
+		log = logr.DiscardLogger{}
+	}
+
+	serverIP, err := NormalizeServerIP(serverIP)
+	if err != nil {
+		return nil, err
+	}
+
+	serverPathPrefix, err = normalizeServerPathPrefix(serverPathPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if caConfigMap.Name != "" && serverIP == "" {
+		return nil, fmt.Errorf("the CA ConfigMap %s is only read in debug mode, serverIP must be set", caConfigMap.String())
+	}
+
+	if serviceName == "" {
+		serviceName = config.KyvernoServiceName
+	}
+	if serviceNamespace == "" {
+		serviceNamespace = config.KyvernoNamespace
+	}
+	if deploymentName == "" {
+		deploymentName = config.KyvernoDeploymentName
+	}
+	if len(operations.Mutate) == 0 {
+		operations.Mutate = defaultWebhookOperations.Mutate
+	}
+	if len(operations.Validate) == 0 {
+		operations.Validate = defaultWebhookOperations.Validate
+	}
+
 	register := &Register{
-		clientConfig:         clientConfig,
-		client:               client,
-		resCache:             resCache,
-		serverIP:             serverIP,
-		timeoutSeconds:       webhookTimeout,
-		log:                  log.WithName("Register"),
-		debug:                debug,
-		autoUpdateWebhooks:   autoUpdateWebhooks,
-		UpdateWebhookChan:    make(chan bool),
-		createDefaultWebhook: make(chan string),
+		clientConfig:          clientConfig,
+		client:                client,
+		resCache:              resCache,
+		serverIP:              serverIP,
+		serverPathPrefix:      serverPathPrefix,
+		caFilePath:            caFilePath,
+		caConfigMap:           caConfigMap,
+		serviceName:           serviceName,
+		serviceNamespace:      serviceNamespace,
+		deploymentName:        deploymentName,
+		timeoutSeconds:        webhookTimeout,
+		log:                   log.WithName("Register"),
+		debug:                 debug,
+		autoUpdateWebhooks:    autoUpdateWebhooks,
+		disableMutation:       !enableMutation,
+		disableValidation:     !enableValidation,
+		readinessURL:          defaultServerReadinessURL,
+		readinessPollInterval: serverReadyPollInterval,
+		operations:            operations,
+		retry:                 defaultWebhookRetry,
+		eventRecorder:         event.NewRecorder(client, event.WebhookRegistration, log),
+		exclusions:            exclusions,
+		promConfig:            promConfig,
+		UpdateWebhookChan:     make(chan bool),
+		createDefaultWebhook:  make(chan string),
+		stopCh:                stopCh,
+	}
+
+	register.manage = newWebhookConfigManager(client, kyvernoClient, pInformer, npInformer, resCache, serverIP, register.autoUpdateWebhooks, register.resourceWebhookKinds(), operations, register.createDefaultWebhook, stopCh, log.WithName("WebhookConfigManager"))
+
+	return register, nil
+}
+
+// NormalizeServerIP strips the "https://" prefix from serverIP and validates that
+// the remainder is a "host:port" address, IPv6 hosts must be enclosed in brackets
+func NormalizeServerIP(serverIP string) (string, error) {
+	if serverIP == "" {
+		return "", nil
+	}
+
+	address := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(serverIP), "https://"), "/")
+	if strings.Contains(address, "://") {
+		return "", fmt.Errorf("invalid serverIP %q: only the https scheme is supported", serverIP)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid serverIP %q, expected the format host:port: %v", serverIP, err)
+	}
+
+	if net.ParseIP(host) == nil {
+		if strings.Contains(host, ":") {
+			return "", fmt.Errorf("invalid serverIP %q: invalid IPv6 address %q", serverIP, host)
+		}
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) != 0 {
+			return "", fmt.Errorf("invalid serverIP %q: invalid host %q: %s", serverIP, host, strings.Join(errs, ", "))
+		}
 	}
 
-	register.manage = newWebhookConfigManager(client, kyvernoClient, pInformer, npInformer, resCache, serverIP, register.autoUpdateWebhooks, register.createDefaultWebhook, stopCh, log.WithName("WebhookConfigManager"))
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return "", fmt.Errorf("invalid serverIP %q: port %q must be a number between 1 and 65535", serverIP, port)
+	}
 
-	return register
+	return net.JoinHostPort(host, port), nil
 }
 
-// Register clean up the old webhooks and re-creates admission webhooks configs on cluster
-func (wrc *Register) Register() error {
+// normalizeServerPathPrefix strips the trailing "/" from the path prefix, e.g. of an ingress
+// routing to Kyverno, and validates that it is an absolute path without query or fragment
+func normalizeServerPathPrefix(prefix string) (string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(prefix, "/") {
+		return "", fmt.Errorf("invalid serverPathPrefix %q: the prefix must start with /", prefix)
+	}
+	if strings.ContainsAny(prefix, "?# ") {
+		return "", fmt.Errorf("invalid serverPathPrefix %q: the prefix must be a path without query or fragment", prefix)
+	}
+
+	return strings.TrimRight(prefix, "/"), nil
+}
+
+// debugURL returns the URL of the service path when running outside a cluster,
+// the path is prefixed by serverPathPrefix
+func (wrc *Register) debugURL(path string) string {
+	return fmt.Sprintf("https://%s%s%s", wrc.serverIP, wrc.serverPathPrefix, path)
+}
+
+// Register clean up the old webhooks and re-creates admission webhooks configs on cluster,
+// the result is recorded in the registration condition of the Kyverno deployment.
+// The registration is aborted when ctx is done.
+func (wrc *Register) Register(ctx context.Context) error {
+	caData, caWarning, err := wrc.register(ctx)
+	wrc.updateRegistrationCondition(caData, caWarning, err)
+	wrc.recordRegistrationEvent(err)
+	if err != nil {
+		return err
+	}
+
+	wrc.setRegisteredCABundle(caData)
+	go wrc.manage.start()
+	wrc.caWatcher.Do(func() { go wrc.watchCARotation(wrc.stopCh) })
+	return nil
+}
+
+// register creates or updates the webhook configurations and returns the CA set on them,
+// and a warning if the CA expires soon
+func (wrc *Register) register(ctx context.Context) ([]byte, string, error) {
 	logger := wrc.log
 	if wrc.serverIP != "" {
-		logger.Info("Registering webhook", "url", fmt.Sprintf("https://%s", wrc.serverIP))
+		logger.Info("Registering webhook", "url", wrc.debugURL(""))
+	}
+	if err := wrc.WaitForServerReady(ctx, serverReadyTimeout); err != nil {
+		return nil, "", err
 	}
 	if !wrc.debug {
-		if err := wrc.checkEndpoint(); err != nil {
-			return err
+		if err := wrc.checkService(ctx); err != nil {
+			return nil, "", err
+		}
+
+		if err := wrc.checkEndpoint(ctx); err != nil {
+			return nil, "", err
 		}
 	}
-	wrc.removeWebhookConfigurations()
 
-	caData := wrc.readCaData()
-	if caData == nil {
-		return errors.New("Unable to extract CA data from configuration")
+	caData, err := wrc.readCaData()
+	if err != nil {
+		return nil, "", err
 	}
 
-	errors := make([]string, 0)
-	if err := wrc.createVerifyMutatingWebhookConfiguration(caData); err != nil {
-		errors = append(errors, err.Error())
+	caWarning, err := wrc.checkCAExpiry(caData, time.Now())
+	if err != nil {
+		return caData, "", err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return caData, caWarning, fmt.Errorf("webhook registration aborted: %v", err)
 	}
 
-	if err := wrc.createPolicyValidatingWebhookConfiguration(caData); err != nil {
-		errors = append(errors, err.Error())
+	if err := wrc.registerWebhookConfigurations(ctx, wrc.desiredWebhookConfigurations(caData)); err != nil {
+		return caData, caWarning, err
 	}
 
-	if err := wrc.createPolicyMutatingWebhookConfiguration(caData); err != nil {
-		errors = append(errors, err.Error())
+	if err := wrc.removeDisabledResourceWebhookConfigurations(ctx); err != nil {
+		return caData, caWarning, err
 	}
 
-	if err := wrc.createResourceValidatingWebhookConfiguration(caData); err != nil {
-		errors = append(errors, err.Error())
+	// v1 is the only served version, the policies are served without the conversion webhook,
+	// a failed update of the CRDs does not block the registration of the admission webhooks
+	if err := wrc.registerConversionWebhook(ctx, caData); err != nil {
+		wrc.log.Error(err, "failed to register the conversion webhook of the policy CRDs")
 	}
 
-	if err := wrc.createResourceMutatingWebhookConfiguration(caData); err != nil {
-		errors = append(errors, err.Error())
+	return caData, caWarning, nil
+}
+
+// registerWebhookConfigurations creates or updates the webhook configurations concurrently.
+// If any of them fails, the configurations created by this call are deleted so that Kyverno
+// is not left half registered, the configurations that existed before are kept.
+func (wrc *Register) registerWebhookConfigurations(ctx context.Context, desired []desiredWebhookConfiguration) error {
+	actions := make([]webhookRegistrationAction, len(desired))
+	errs := make([]error, len(desired))
+
+	var wg sync.WaitGroup
+	wg.Add(len(desired))
+	for i := range desired {
+		go func(i int) {
+			defer wg.Done()
+			actions[i], errs[i] = wrc.createOrUpdateWebhookConfiguration(ctx, desired[i].kind, desired[i].config)
+		}(i)
 	}
+	wg.Wait()
 
-	if len(errors) > 0 {
-		return fmt.Errorf("%s", strings.Join(errors, ","))
+	errors := make([]string, 0)
+	for i, d := range desired {
+		if errs[i] != nil {
+			wrc.log.Error(errs[i], "failed to register webhook configuration", "kind", d.kind, "name", d.config.GetName())
+			errors = append(errors, errs[i].Error())
+			continue
+		}
+
+		wrc.log.Info(string(actions[i])+" webhook", "kind", d.kind, "name", d.config.GetName())
 	}
 
-	go wrc.manage.start()
-	return nil
+	if len(errors) == 0 {
+		return nil
+	}
+
+	for i, d := range desired {
+		if actions[i] != webhookCreated {
+			continue
+		}
+
+		logger := wrc.log.WithValues("kind", d.kind, "name", d.config.GetName())
+		if err := wrc.client.DeleteResource("", d.kind, "", d.config.GetName(), false); err != nil && !errorsapi.IsNotFound(err) {
+			logger.Error(err, "failed to roll back webhook configuration")
+			continue
+		}
+		logger.Info("rolled back webhook configuration")
+	}
+
+	return fmt.Errorf("%s", strings.Join(errors, ","))
 }
 
 // Check returns an error if any of the webhooks are not configured
@@ -140,12 +393,16 @@ func (wrc *Register) Check() error {
 		return err
 	}
 
-	if _, err := mutatingCache.Lister().Get(getResourceMutatingWebhookConfigName(wrc.serverIP)); err != nil {
-		return err
+	if wrc.resourceWebhookEnabled(kindMutating) {
+		if _, err := mutatingCache.Lister().Get(getResourceMutatingWebhookConfigName(wrc.serverIP)); err != nil {
+			return err
+		}
 	}
 
-	if _, err := validatingCache.Lister().Get(getResourceValidatingWebhookConfigName(wrc.serverIP)); err != nil {
-		return err
+	if wrc.resourceWebhookEnabled(kindValidating) {
+		if _, err := validatingCache.Lister().Get(getResourceValidatingWebhookConfigName(wrc.serverIP)); err != nil {
+			return err
+		}
 	}
 
 	if _, err := mutatingCache.Lister().Get(getPolicyMutatingWebhookConfigurationName(wrc.serverIP)); err != nil {
@@ -159,22 +416,107 @@ func (wrc *Register) Check() error {
 	return nil
 }
 
-// Remove removes all webhook configurations
-func (wrc *Register) Remove(cleanUp chan<- struct{}) {
+// requestDrainer stops accepting new requests and waits for the in-flight requests to complete,
+// as http.Server.Shutdown does
+type requestDrainer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// DeregisterGraceful stops the webhook server from accepting new admission requests, waits for
+// the in-flight requests to complete and then removes the webhook configurations, so that the
+// requests being handled do not fail with their webhook configuration gone.
+// It blocks until the webhook configurations are removed or ctx is done.
+func (wrc *Register) DeregisterGraceful(ctx context.Context, server requestDrainer, cleanUp chan<- struct{}) error {
+	logger := wrc.log.WithName("DeregisterGraceful")
+
+	drainErr := server.Shutdown(ctx)
+	if drainErr != nil {
+		logger.Error(drainErr, "failed to drain in-flight admission requests")
+	} else {
+		logger.V(3).Info("drained in-flight admission requests")
+	}
+
+	wrc.Remove(ctx, cleanUp)
+
+	if drainErr != nil {
+		return fmt.Errorf("failed to drain in-flight admission requests: %v", drainErr)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("webhook deregistration aborted: %v", ctx.Err())
+	}
+	return nil
+}
+
+// Remove removes all webhook configurations, the API requests are aborted when ctx is done
+func (wrc *Register) Remove(ctx context.Context, cleanUp chan<- struct{}) {
 	defer close(cleanUp)
-	if !wrc.cleanupKyvernoResource() {
+	if !wrc.cleanupKyvernoResource(ctx) {
 		return
 	}
 
-	wrc.removeWebhookConfigurations()
-	wrc.removeSecrets()
-	err := wrc.client.DeleteResource("coordination.k8s.io/v1", "Lease", config.KyvernoNamespace, "kyvernopre-lock", false)
+	// the reconciliation would re-create the removed webhook configurations
+	wrc.stopReconciliation()
+	wrc.setDeregistered()
+	if err := wrc.removeWebhookConfigurations(ctx); err != nil {
+		wrc.log.WithName("cleanup").Error(err, "failed to remove webhook configurations")
+		return
+	}
+
+	wrc.setRegistrationCondition(false, "Deregistered", "webhook configurations are removed", nil)
+	wrc.removeSecrets(ctx)
+	err := wrc.client.WithContext(ctx).DeleteResource("coordination.k8s.io/v1", "Lease", wrc.serviceNamespace, "kyvernopre-lock", false)
 	if err != nil && errorsapi.IsNotFound(err) {
 		wrc.log.WithName("cleanup").Error(err, "failed to clean up Lease lock")
 	}
 
 }
 
+// allWebhookConfigurations returns the kind and name of the webhook configurations
+// registered in both the in-cluster and the debug (serverIP) mode
+func allWebhookConfigurations() [][2]string {
+	return [][2]string{
+		{kindMutating, config.MutatingWebhookConfigurationName},
+		{kindMutating, config.MutatingWebhookConfigurationDebugName},
+		{kindValidating, config.ValidatingWebhookConfigurationName},
+		{kindValidating, config.ValidatingWebhookConfigurationDebugName},
+		{kindMutating, config.PolicyMutatingWebhookConfigurationName},
+		{kindMutating, config.PolicyMutatingWebhookConfigurationDebugName},
+		{kindValidating, config.PolicyValidatingWebhookConfigurationName},
+		{kindValidating, config.PolicyValidatingWebhookConfigurationDebugName},
+		{kindMutating, config.VerifyMutatingWebhookConfigurationName},
+		{kindMutating, config.VerifyMutatingWebhookConfigurationDebugName},
+	}
+}
+
+// DeregisterAll deletes the webhook configurations of both the in-cluster and the debug mode,
+// Remove only deletes the ones of the current mode and leaves the others orphaned when
+// switching modes. The configurations that do not exist are ignored.
+func (wrc *Register) DeregisterAll() error {
+	errs := make([]string, 0)
+	for _, c := range allWebhookConfigurations() {
+		kind, name := c[0], c[1]
+		logger := wrc.log.WithValues("kind", kind, "name", name)
+
+		err := wrc.client.DeleteResource("", kind, "", name, false)
+		if errorsapi.IsNotFound(err) {
+			logger.V(4).Info("webhook configuration not found")
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to delete %s %s: %v", kind, name, err))
+			continue
+		}
+
+		logger.Info("webhook configuration deleted")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ","))
+	}
+	return nil
+}
+
 // UpdateWebhookConfigurations updates resource webhook configurations dynamically
 // base on the UPDATEs of Kyverno init-config ConfigMap
 //
@@ -189,7 +531,10 @@ func (wrc *Register) UpdateWebhookConfigurations(configHandler config.Interface)
 		var nsSelector map[string]interface{}
 		webhookCfgs := configHandler.GetWebhooks()
 		if webhookCfgs != nil {
-			selector := webhookCfgs[0].NamespaceSelector
+			wrc.setNamespaceSelector(webhookCfgs[0].NamespaceSelector)
+		}
+
+		if selector := wrc.resourceNamespaceSelector(); selector != nil {
 			selectorBytes, err := json.Marshal(*selector)
 			if err != nil {
 				logger.Error(err, "failed to serialize namespaceSelector")
@@ -202,22 +547,28 @@ func (wrc *Register) UpdateWebhookConfigurations(configHandler config.Interface)
 			}
 		}
 
-		if err := wrc.updateResourceMutatingWebhookConfiguration(nsSelector); err != nil {
-			logger.Error(err, "unable to update mutatingWebhookConfigurations", "name", getResourceMutatingWebhookConfigName(wrc.serverIP))
-			go func() { wrc.UpdateWebhookChan <- true }()
-		} else {
-			logger.Info("successfully updated mutatingWebhookConfigurations", "name", getResourceMutatingWebhookConfigName(wrc.serverIP))
+		if wrc.resourceWebhookEnabled(kindMutating) {
+			if err := wrc.updateResourceMutatingWebhookConfiguration(nsSelector); err != nil {
+				logger.Error(err, "unable to update mutatingWebhookConfigurations", "name", getResourceMutatingWebhookConfigName(wrc.serverIP))
+				go func() { wrc.UpdateWebhookChan <- true }()
+			} else {
+				logger.Info("successfully updated mutatingWebhookConfigurations", "name", getResourceMutatingWebhookConfigName(wrc.serverIP))
+			}
 		}
 
-		if err := wrc.updateResourceValidatingWebhookConfiguration(nsSelector); err != nil {
-			logger.Error(err, "unable to update validatingWebhookConfigurations", "name", getResourceValidatingWebhookConfigName(wrc.serverIP))
-			go func() { wrc.UpdateWebhookChan <- true }()
-		} else {
-			logger.Info("successfully updated validatingWebhookConfigurations", "name", getResourceValidatingWebhookConfigName(wrc.serverIP))
+		if wrc.resourceWebhookEnabled(kindValidating) {
+			if err := wrc.updateResourceValidatingWebhookConfiguration(nsSelector); err != nil {
+				logger.Error(err, "unable to update validatingWebhookConfigurations", "name", getResourceValidatingWebhookConfigName(wrc.serverIP))
+				go func() { wrc.UpdateWebhookChan <- true }()
+			} else {
+				logger.Info("successfully updated validatingWebhookConfigurations", "name", getResourceValidatingWebhookConfigName(wrc.serverIP))
+			}
 		}
 	}
 }
 
+// ValidateWebhookConfigurations validates the format of 'data.webhooks' in the init ConfigMap,
+// the namespaceSelector defined there is set on the resource webhooks when they are created
 func (wrc *Register) ValidateWebhookConfigurations(namespace, name string) error {
 	logger := wrc.log.WithName("ValidateWebhookConfigurations")
 
@@ -239,13 +590,39 @@ func (wrc *Register) ValidateWebhookConfigurations(namespace, name string) error
 	}
 
 	webhookCfgs := make([]config.WebhookConfig, 0, 10)
-	return json.Unmarshal([]byte(webhooks), &webhookCfgs)
+	if err := json.Unmarshal([]byte(webhooks), &webhookCfgs); err != nil {
+		return err
+	}
+
+	if len(webhookCfgs) != 0 {
+		wrc.setNamespaceSelector(webhookCfgs[0].NamespaceSelector)
+	}
+	return nil
+}
+
+// getNamespaceSelector returns a copy of the namespaceSelector defined in the init ConfigMap
+func (wrc *Register) getNamespaceSelector() *v1.LabelSelector {
+	wrc.mu.RLock()
+	defer wrc.mu.RUnlock()
+	return wrc.namespaceSelector.DeepCopy()
+}
+
+func (wrc *Register) setNamespaceSelector(selector *v1.LabelSelector) {
+	wrc.mu.Lock()
+	defer wrc.mu.Unlock()
+	wrc.namespaceSelector = selector.DeepCopy()
+}
+
+// resourceNamespaceSelector returns the namespaceSelector of the resource webhooks,
+// the namespaceSelector defined in the init ConfigMap with the excluded namespaces
+func (wrc *Register) resourceNamespaceSelector() *v1.LabelSelector {
+	return wrc.exclusions.namespaceSelector(wrc.getNamespaceSelector())
 }
 
 // cleanupKyvernoResource returns true if Kyverno deployment is terminating
-func (wrc *Register) cleanupKyvernoResource() bool {
+func (wrc *Register) cleanupKyvernoResource(ctx context.Context) bool {
 	logger := wrc.log.WithName("cleanupKyvernoResource")
-	deploy, err := wrc.client.GetResource("", "Deployment", deployNamespace, deployName)
+	deploy, err := wrc.client.WithContext(ctx).GetResource("", "Deployment", wrc.serviceNamespace, wrc.deploymentName)
 	if err != nil {
 		logger.Error(err, "failed to get deployment, cleanup kyverno resources anyway")
 		return true
@@ -270,127 +647,160 @@ func (wrc *Register) cleanupKyvernoResource() bool {
 	return false
 }
 
-func (wrc *Register) createResourceMutatingWebhookConfiguration(caData []byte) error {
-	var config *admregapi.MutatingWebhookConfiguration
-
-	if wrc.serverIP != "" {
-		config = wrc.constructDefaultDebugMutatingWebhookConfig(caData)
-	} else {
-		config = wrc.constructDefaultMutatingWebhookConfig(caData)
+// resourceWebhookEnabled returns true if the resource webhook configuration of the kind is registered
+func (wrc *Register) resourceWebhookEnabled(kind string) bool {
+	switch kind {
+	case kindMutating:
+		return !wrc.disableMutation
+	case kindValidating:
+		return !wrc.disableValidation
+	default:
+		return false
 	}
+}
 
-	logger := wrc.log.WithValues("kind", kindMutating, "name", config.Name)
+// resourceWebhookKinds returns the kinds of the registered resource webhook configurations
+func (wrc *Register) resourceWebhookKinds() []string {
+	kinds := make([]string, 0, 2)
+	for _, kind := range []string{kindMutating, kindValidating} {
+		if wrc.resourceWebhookEnabled(kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
 
-	_, err := wrc.client.CreateResource("", kindMutating, "", *config, false)
-	if errorsapi.IsAlreadyExists(err) {
-		logger.V(6).Info("resource mutating webhook configuration already exists", "name", config.Name)
-		return nil
+// removeDisabledResourceWebhookConfigurations deletes the resource webhook configurations that are
+// disabled, e.g. registered by a previous instance with a different configuration
+func (wrc *Register) removeDisabledResourceWebhookConfigurations(ctx context.Context) error {
+	names := map[string]string{
+		kindMutating:   getResourceMutatingWebhookConfigName(wrc.serverIP),
+		kindValidating: getResourceValidatingWebhookConfigName(wrc.serverIP),
 	}
 
-	if err != nil {
-		logger.Error(err, "failed to create resource mutating webhook configuration", "name", config.Name)
-		return err
+	for _, kind := range []string{kindMutating, kindValidating} {
+		if wrc.resourceWebhookEnabled(kind) {
+			continue
+		}
+
+		logger := wrc.log.WithValues("kind", kind, "name", names[kind])
+		err := wrc.client.WithContext(ctx).DeleteResource("", kind, "", names[kind], false)
+		if errorsapi.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to delete the disabled %s %s: %v", kind, names[kind], err)
+		}
+		logger.Info("disabled webhook configuration deleted")
 	}
 
-	logger.Info("created webhook")
 	return nil
 }
 
-func (wrc *Register) createResourceValidatingWebhookConfiguration(caData []byte) error {
-	var config *admregapi.ValidatingWebhookConfiguration
+func (wrc *Register) createResourceMutatingWebhookConfiguration(ctx context.Context, caData []byte) error {
+	var config *admregapi.MutatingWebhookConfiguration
 
 	if wrc.serverIP != "" {
-		config = wrc.constructDefaultDebugValidatingWebhookConfig(caData)
+		config = wrc.constructDefaultDebugMutatingWebhookConfig(caData)
 	} else {
-		config = wrc.constructDefaultValidatingWebhookConfig(caData)
-	}
-
-	logger := wrc.log.WithValues("kind", kindValidating, "name", config.Name)
-
-	_, err := wrc.client.CreateResource("", kindValidating, "", *config, false)
-	if errorsapi.IsAlreadyExists(err) {
-		logger.V(6).Info("resource validating webhook configuration already exists", "name", config.Name)
-		return nil
+		config = wrc.constructDefaultMutatingWebhookConfig(caData)
 	}
 
+	action, err := wrc.createOrUpdateWebhookConfiguration(ctx, kindMutating, config)
 	if err != nil {
-		logger.Error(err, "failed to create resource")
+		wrc.log.Error(err, "failed to register resource mutating webhook configuration", "kind", kindMutating, "name", config.Name)
 		return err
 	}
 
-	logger.Info("created webhook")
+	wrc.log.Info(string(action)+" webhook", "kind", kindMutating, "name", config.Name)
 	return nil
 }
 
-//registerPolicyValidatingWebhookConfiguration create a Validating webhook configuration for Policy CRD
-func (wrc *Register) createPolicyValidatingWebhookConfiguration(caData []byte) error {
+func (wrc *Register) createResourceValidatingWebhookConfiguration(ctx context.Context, caData []byte) error {
 	var config *admregapi.ValidatingWebhookConfiguration
 
 	if wrc.serverIP != "" {
-		config = wrc.constructDebugPolicyValidatingWebhookConfig(caData)
+		config = wrc.constructDefaultDebugValidatingWebhookConfig(caData)
 	} else {
-		config = wrc.constructPolicyValidatingWebhookConfig(caData)
+		config = wrc.constructDefaultValidatingWebhookConfig(caData)
 	}
 
-	if _, err := wrc.client.CreateResource("", kindValidating, "", *config, false); err != nil {
-		if errorsapi.IsAlreadyExists(err) {
-			wrc.log.V(6).Info("webhook already exists", "kind", kindValidating, "name", config.Name)
-			return nil
-		}
-
+	action, err := wrc.createOrUpdateWebhookConfiguration(ctx, kindValidating, config)
+	if err != nil {
+		wrc.log.Error(err, "failed to register resource validating webhook configuration", "kind", kindValidating, "name", config.Name)
 		return err
 	}
 
-	wrc.log.Info("created webhook", "kind", kindValidating, "name", config.Name)
+	wrc.log.Info(string(action)+" webhook", "kind", kindValidating, "name", config.Name)
 	return nil
 }
 
-func (wrc *Register) createPolicyMutatingWebhookConfiguration(caData []byte) error {
-	var config *admregapi.MutatingWebhookConfiguration
+// webhookRegistrationAction describes how a webhook configuration was registered
+type webhookRegistrationAction string
 
-	if wrc.serverIP != "" {
-		config = wrc.constructDebugPolicyMutatingWebhookConfig(caData)
-	} else {
-		config = wrc.constructPolicyMutatingWebhookConfig(caData)
-	}
+const (
+	webhookCreated webhookRegistrationAction = "created"
+	webhookUpdated webhookRegistrationAction = "updated"
+)
 
-	// create mutating webhook configuration resource
-	if _, err := wrc.client.CreateResource("", kindMutating, "", *config, false); err != nil {
-		if errorsapi.IsAlreadyExists(err) {
-			wrc.log.V(6).Info("webhook already exists", "kind", kindMutating, "name", config.Name)
-			return nil
-		}
+// webhookConfiguration is a typed Mutating or Validating webhook configuration
+type webhookConfiguration interface {
+	v1.Object
+	runtime.Object
+}
 
+// createOrUpdateWebhookConfiguration creates the webhook configuration if it does not exist,
+// otherwise the existing configuration is updated in place with its resourceVersion preserved,
+// so there is no window in which the webhook is missing.
+// Requests failing with a transient API error are retried with an exponential backoff.
+// The configuration is created with the admissionregistration API version served by the cluster.
+// The requests and the retries are aborted when ctx is done.
+func (wrc *Register) createOrUpdateWebhookConfiguration(ctx context.Context, kind string, config webhookConfiguration) (webhookRegistrationAction, error) {
+	config.GetObjectKind().SetGroupVersionKind(wrc.webhookGroupVersion(kind).WithKind(kind))
+
+	var action webhookRegistrationAction
+	err := wrc.retryWebhookRequest(ctx, kind, config.GetName(), func() error {
+		var err error
+		action, err = wrc.applyWebhookConfiguration(ctx, kind, config)
 		return err
-	}
-
-	wrc.log.Info("created webhook", "kind", kindMutating, "name", config.Name)
-	return nil
+	})
+	return action, err
 }
 
-func (wrc *Register) createVerifyMutatingWebhookConfiguration(caData []byte) error {
-	var config *admregapi.MutatingWebhookConfiguration
+func (wrc *Register) applyWebhookConfiguration(ctx context.Context, kind string, config webhookConfiguration) (webhookRegistrationAction, error) {
+	client := wrc.client.WithContext(ctx)
+	existing, err := client.GetResource("", kind, "", config.GetName())
+	if err != nil {
+		if !errorsapi.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get %s %s: %w", kind, config.GetName(), err)
+		}
 
-	if wrc.serverIP != "" {
-		config = wrc.constructDebugVerifyMutatingWebhookConfig(caData)
-	} else {
-		config = wrc.constructVerifyMutatingWebhookConfig(caData)
-	}
+		config.SetResourceVersion("")
+		_, err := client.CreateResource("", kind, "", config, false)
+		if err == nil {
+			return webhookCreated, nil
+		}
 
-	if _, err := wrc.client.CreateResource("", kindMutating, "", *config, false); err != nil {
-		if errorsapi.IsAlreadyExists(err) {
-			wrc.log.V(6).Info("webhook already exists", "kind", kindMutating, "name", config.Name)
-			return nil
+		if !errorsapi.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create %s %s: %w", kind, config.GetName(), err)
 		}
 
-		return err
+		// created in the meantime, e.g. by a previous instance, update it in place
+		wrc.log.V(3).Info("webhook configuration already exists, updating", "kind", kind, "name", config.GetName())
+		if existing, err = client.GetResource("", kind, "", config.GetName()); err != nil {
+			return "", fmt.Errorf("failed to get %s %s: %w", kind, config.GetName(), err)
+		}
 	}
 
-	wrc.log.Info("created webhook", "kind", kindMutating, "name", config.Name)
-	return nil
+	config.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := client.UpdateResource("", kind, "", config, false); err != nil {
+		return "", fmt.Errorf("failed to update %s %s: %w", kind, config.GetName(), err)
+	}
+	return webhookUpdated, nil
 }
 
-func (wrc *Register) removeWebhookConfigurations() {
+func (wrc *Register) removeWebhookConfigurations(ctx context.Context) error {
 	startTime := time.Now()
 	wrc.log.V(3).Info("deleting all webhook configurations")
 	defer func() {
@@ -400,16 +810,21 @@ func (wrc *Register) removeWebhookConfigurations() {
 	var wg sync.WaitGroup
 	wg.Add(5)
 
-	go wrc.removeResourceMutatingWebhookConfiguration(&wg)
-	go wrc.removeResourceValidatingWebhookConfiguration(&wg)
-	go wrc.removePolicyMutatingWebhookConfiguration(&wg)
-	go wrc.removePolicyValidatingWebhookConfiguration(&wg)
-	go wrc.removeVerifyWebhookMutatingWebhookConfig(&wg)
+	go wrc.removeResourceMutatingWebhookConfiguration(ctx, &wg)
+	go wrc.removeResourceValidatingWebhookConfiguration(ctx, &wg)
+	go wrc.removePolicyMutatingWebhookConfiguration(ctx, &wg)
+	go wrc.removePolicyValidatingWebhookConfiguration(ctx, &wg)
+	go wrc.removeVerifyWebhookMutatingWebhookConfig(ctx, &wg)
 
+	// the delete requests return once ctx is done
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("webhook configurations removal aborted: %v", err)
+	}
+	return nil
 }
 
-func (wrc *Register) removePolicyMutatingWebhookConfiguration(wg *sync.WaitGroup) {
+func (wrc *Register) removePolicyMutatingWebhookConfiguration(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	mutatingConfig := getPolicyMutatingWebhookConfigurationName(wrc.serverIP)
@@ -423,7 +838,7 @@ func (wrc *Register) removePolicyMutatingWebhookConfiguration(wg *sync.WaitGroup
 		}
 	}
 
-	err := wrc.client.DeleteResource("", kindMutating, "", mutatingConfig, false)
+	err := wrc.client.WithContext(ctx).DeleteResource("", kindMutating, "", mutatingConfig, false)
 	if errorsapi.IsNotFound(err) {
 		logger.V(5).Info("policy mutating webhook configuration not found")
 		return
@@ -447,7 +862,7 @@ func getPolicyMutatingWebhookConfigurationName(serverIP string) string {
 	return mutatingConfig
 }
 
-func (wrc *Register) removePolicyValidatingWebhookConfiguration(wg *sync.WaitGroup) {
+func (wrc *Register) removePolicyValidatingWebhookConfiguration(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	validatingConfig := getPolicyValidatingWebhookConfigurationName(wrc.serverIP)
@@ -461,7 +876,7 @@ func (wrc *Register) removePolicyValidatingWebhookConfiguration(wg *sync.WaitGro
 	}
 
 	logger.V(4).Info("removing validating webhook configuration")
-	err := wrc.client.DeleteResource("", kindValidating, "", validatingConfig, false)
+	err := wrc.client.WithContext(ctx).DeleteResource("", kindValidating, "", validatingConfig, false)
 	if errorsapi.IsNotFound(err) {
 		logger.V(5).Info("policy validating webhook configuration not found")
 		return
@@ -496,7 +911,7 @@ func (wrc *Register) constructVerifyMutatingWebhookConfig(caData []byte) *admreg
 		Webhooks: []admregapi.MutatingWebhook{
 			generateMutatingWebhook(
 				config.VerifyMutatingWebhookName,
-				config.VerifyMutatingWebhookServicePath,
+				wrc.serviceReference(config.VerifyMutatingWebhookServicePath),
 				caData,
 				true,
 				wrc.timeoutSeconds,
@@ -507,6 +922,8 @@ func (wrc *Register) constructVerifyMutatingWebhookConfig(caData []byte) *admreg
 				},
 				[]admregapi.OperationType{admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
@@ -514,11 +931,12 @@ func (wrc *Register) constructVerifyMutatingWebhookConfig(caData []byte) *admreg
 
 func (wrc *Register) constructDebugVerifyMutatingWebhookConfig(caData []byte) *admregapi.MutatingWebhookConfiguration {
 	logger := wrc.log
-	url := fmt.Sprintf("https://%s%s", wrc.serverIP, config.VerifyMutatingWebhookServicePath)
+	url := wrc.debugURL(config.VerifyMutatingWebhookServicePath)
 	logger.V(4).Info("Debug VerifyMutatingWebhookConfig is registered with url", "url", url)
 	return &admregapi.MutatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
-			Name: config.VerifyMutatingWebhookConfigurationDebugName,
+			Name:            config.VerifyMutatingWebhookConfigurationDebugName,
+			OwnerReferences: wrc.debugOwnerReferences(),
 		},
 		Webhooks: []admregapi.MutatingWebhook{
 			generateDebugMutatingWebhook(
@@ -534,12 +952,14 @@ func (wrc *Register) constructDebugVerifyMutatingWebhookConfig(caData []byte) *a
 				},
 				[]admregapi.OperationType{admregapi.Update},
 				admregapi.Ignore,
+				nil,
+				nil,
 			),
 		},
 	}
 }
 
-func (wrc *Register) removeVerifyWebhookMutatingWebhookConfig(wg *sync.WaitGroup) {
+func (wrc *Register) removeVerifyWebhookMutatingWebhookConfig(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	var err error
@@ -553,7 +973,7 @@ func (wrc *Register) removeVerifyWebhookMutatingWebhookConfig(wg *sync.WaitGroup
 		}
 	}
 
-	err = wrc.client.DeleteResource("", kindMutating, "", mutatingConfig, false)
+	err = wrc.client.WithContext(ctx).DeleteResource("", kindMutating, "", mutatingConfig, false)
 	if errorsapi.IsNotFound(err) {
 		logger.V(5).Info("verify webhook configuration not found")
 		return
@@ -583,21 +1003,22 @@ func (wrc *Register) GetWebhookTimeOut() time.Duration {
 }
 
 // removeSecrets removes Kyverno managed secrets
-func (wrc *Register) removeSecrets() {
+func (wrc *Register) removeSecrets(ctx context.Context) {
+	client := wrc.client.WithContext(ctx)
 	selector := &v1.LabelSelector{
 		MatchLabels: map[string]string{
 			tls.ManagedByLabel: "kyverno",
 		},
 	}
 
-	secretList, err := wrc.client.ListResource("", "Secret", config.KyvernoNamespace, selector)
+	secretList, err := client.ListResource("", "Secret", wrc.serviceNamespace, selector)
 	if err != nil {
 		wrc.log.Error(err, "failed to clean up Kyverno managed secrets")
 		return
 	}
 
 	for _, secret := range secretList.Items {
-		if err := wrc.client.DeleteResource("", "Secret", secret.GetNamespace(), secret.GetName(), false); err != nil {
+		if err := client.DeleteResource("", "Secret", secret.GetNamespace(), secret.GetName(), false); err != nil {
 			if !errorsapi.IsNotFound(err) {
 				wrc.log.Error(err, "failed to delete secret", "ns", secret.GetNamespace(), "name", secret.GetName())
 			}
@@ -605,18 +1026,33 @@ func (wrc *Register) removeSecrets() {
 	}
 }
 
-func (wrc *Register) checkEndpoint() error {
-	obj, err := wrc.client.GetResource("", "Endpoints", config.KyvernoNamespace, config.KyvernoServiceName)
+// checkService returns an error if the service referenced by the webhooks does not exist,
+// the API server could not reach Kyverno and the admission requests would fail
+func (wrc *Register) checkService(ctx context.Context) error {
+	_, err := wrc.client.WithContext(ctx).GetResource("", "Service", wrc.serviceNamespace, wrc.serviceName)
+	if errorsapi.IsNotFound(err) {
+		return fmt.Errorf("service %s/%s referenced by the webhooks not found", wrc.serviceNamespace, wrc.serviceName)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get service %s/%s: %v", wrc.serviceNamespace, wrc.serviceName, err)
+	}
+	return nil
+}
+
+func (wrc *Register) checkEndpoint(ctx context.Context) error {
+	client := wrc.client.WithContext(ctx)
+	obj, err := client.GetResource("", "Endpoints", wrc.serviceNamespace, wrc.serviceName)
 	if err != nil {
-		return fmt.Errorf("failed to get endpoint %s/%s: %v", config.KyvernoNamespace, config.KyvernoServiceName, err)
+		return fmt.Errorf("failed to get endpoint %s/%s: %v", wrc.serviceNamespace, wrc.serviceName, err)
 	}
 	var endpoint corev1.Endpoints
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &endpoint)
 	if err != nil {
-		return fmt.Errorf("failed to convert endpoint %s/%s from unstructured: %v", config.KyvernoNamespace, config.KyvernoServiceName, err)
+		return fmt.Errorf("failed to convert endpoint %s/%s from unstructured: %v", wrc.serviceNamespace, wrc.serviceName, err)
 	}
 
-	pods, err := wrc.client.ListResource("", "Pod", config.KyvernoNamespace, &v1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": "kyverno"}})
+	pods, err := client.ListResource("", "Pod", wrc.serviceNamespace, &v1.LabelSelector{MatchLabels: config.KyvernoAppLabels})
 	if err != nil {
 		return fmt.Errorf("failed to list Kyverno Pod: %v", err)
 	}
@@ -638,17 +1074,19 @@ func (wrc *Register) checkEndpoint() error {
 
 		for _, addr := range subset.Addresses {
 			if addr.IP == podIP {
-				wrc.log.Info("Endpoint ready", "ns", config.KyvernoNamespace, "name", config.KyvernoServiceName)
+				wrc.log.Info("Endpoint ready", "ns", wrc.serviceNamespace, "name", wrc.serviceName)
 				return nil
 			}
 		}
 	}
 
 	// clean up old webhook configurations, if any
-	wrc.removeWebhookConfigurations()
+	if err := wrc.removeWebhookConfigurations(ctx); err != nil {
+		wrc.log.Error(err, "failed to clean up webhook configurations")
+	}
 
 	err = fmt.Errorf("endpoint not ready")
-	wrc.log.V(3).Info(err.Error(), "ns", config.KyvernoNamespace, "name", config.KyvernoServiceName)
+	wrc.log.V(3).Info(err.Error(), "ns", wrc.serviceNamespace, "name", wrc.serviceName)
 	return err
 }
 
@@ -2,10 +2,41 @@ package webhookconfig
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	client "github.com/kyverno/kyverno/pkg/dclient"
 	"gotest.tools/assert"
+	admregapi "k8s.io/api/admissionregistration/v1"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
 	rest "k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var cert = `
@@ -73,3 +104,1315 @@ func TestExtractCA_InvalidFile(t *testing.T) {
 	actual := extractCA(config)
 	assert.Assert(t, actual == nil)
 }
+
+func TestConstructDefaultDebugWebhookConfig_NamespaceSelector(t *testing.T) {
+	selector := &v1.LabelSelector{
+		MatchExpressions: []v1.LabelSelectorRequirement{
+			{
+				Key:      "environment",
+				Operator: v1.LabelSelectorOpNotIn,
+				Values:   []string{"kube-system", "monitoring"},
+			},
+		},
+	}
+
+	wrc := newTestRegister(nil)
+	wrc.setNamespaceSelector(selector)
+
+	mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	for _, w := range mutating.Webhooks {
+		assert.DeepEqual(t, selector, w.NamespaceSelector)
+	}
+
+	validating := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert))
+	for _, w := range validating.Webhooks {
+		assert.DeepEqual(t, selector, w.NamespaceSelector)
+	}
+
+	policyValidating := wrc.constructDebugPolicyValidatingWebhookConfig([]byte(cert))
+	for _, w := range policyValidating.Webhooks {
+		assert.Assert(t, w.NamespaceSelector == nil)
+	}
+}
+
+func TestConstructDefaultDebugWebhookConfig_Exclusions(t *testing.T) {
+	wrc := newTestRegister(nil)
+	wrc.exclusions = DefaultWebhookExclusions(wrc.serviceNamespace)
+	wrc.setNamespaceSelector(&v1.LabelSelector{MatchLabels: map[string]string{"environment": "prod"}})
+
+	var objectSelectors, namespaceSelectors []*v1.LabelSelector
+	for _, w := range wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert)).Webhooks {
+		objectSelectors = append(objectSelectors, w.ObjectSelector)
+		namespaceSelectors = append(namespaceSelectors, w.NamespaceSelector)
+	}
+	for _, w := range wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)).Webhooks {
+		objectSelectors = append(objectSelectors, w.ObjectSelector)
+		namespaceSelectors = append(namespaceSelectors, w.NamespaceSelector)
+	}
+	assert.Equal(t, len(objectSelectors), 4)
+
+	for _, s := range objectSelectors {
+		selector, err := v1.LabelSelectorAsSelector(s)
+		assert.NilError(t, err)
+		assert.Assert(t, !selector.Matches(labels.Set{"app.kubernetes.io/name": "kyverno", "app": "web"}))
+		assert.Assert(t, selector.Matches(labels.Set{"app.kubernetes.io/name": "nginx"}))
+		assert.Assert(t, selector.Matches(labels.Set{}))
+	}
+
+	for _, s := range namespaceSelectors {
+		selector, err := v1.LabelSelectorAsSelector(s)
+		assert.NilError(t, err)
+		assert.Assert(t, !selector.Matches(labels.Set{namespaceNameLabel: config.KyvernoNamespace, "environment": "prod"}))
+		assert.Assert(t, !selector.Matches(labels.Set{namespaceNameLabel: "default"}))
+		assert.Assert(t, selector.Matches(labels.Set{namespaceNameLabel: "default", "environment": "prod"}))
+	}
+
+	// the configured namespaceSelector is not modified
+	assert.Equal(t, len(wrc.getNamespaceSelector().MatchExpressions), 0)
+
+	for _, w := range wrc.constructDebugPolicyValidatingWebhookConfig([]byte(cert)).Webhooks {
+		assert.Assert(t, w.ObjectSelector == nil)
+	}
+}
+
+func TestConstructDefaultDebugWebhookConfig_ReinvocationPolicy(t *testing.T) {
+	assert.Equal(t, config.WebhookReinvocationPolicy, admregapi.IfNeededReinvocationPolicy)
+	defer func(policy admregapi.ReinvocationPolicyType) { config.WebhookReinvocationPolicy = policy }(config.WebhookReinvocationPolicy)
+
+	wrc := newTestRegister(nil)
+	for _, policy := range []admregapi.ReinvocationPolicyType{admregapi.NeverReinvocationPolicy, admregapi.IfNeededReinvocationPolicy} {
+		config.WebhookReinvocationPolicy = policy
+
+		mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+		for _, w := range mutating.Webhooks {
+			assert.Assert(t, w.ReinvocationPolicy != nil)
+			assert.Equal(t, *w.ReinvocationPolicy, policy)
+		}
+
+		validating, err := runtime.DefaultUnstructuredConverter.ToUnstructured(wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)))
+		assert.NilError(t, err)
+		webhooks, _, err := unstructured.NestedSlice(validating, "webhooks")
+		assert.NilError(t, err)
+		for _, w := range webhooks {
+			_, ok := w.(map[string]interface{})["reinvocationPolicy"]
+			assert.Assert(t, !ok)
+		}
+
+		// the policy mutating webhook is never re-invoked
+		for _, w := range wrc.constructDebugPolicyMutatingWebhookConfig([]byte(cert)).Webhooks {
+			assert.Equal(t, *w.ReinvocationPolicy, admregapi.NeverReinvocationPolicy)
+		}
+	}
+
+	assert.NilError(t, ValidateReinvocationPolicy("IfNeeded"))
+	assert.Assert(t, ValidateReinvocationPolicy("Always") != nil)
+}
+
+func TestGenerateWebhooks_SideEffectsAndAdmissionReviewVersions(t *testing.T) {
+	wrc := newTestRegister(nil)
+	rule := wrc.defaultResourceWebhookRule()
+
+	mutatingWebhooks := append(wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert)).Webhooks,
+		generateMutatingWebhook(config.MutatingWebhookName, wrc.serviceReference(config.MutatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Mutate, admregapi.Fail, nil, nil))
+	for _, w := range mutatingWebhooks {
+		assert.Assert(t, w.SideEffects != nil)
+		assert.Equal(t, *w.SideEffects, admregapi.SideEffectClassNoneOnDryRun)
+		assert.DeepEqual(t, w.AdmissionReviewVersions, []string{"v1beta1", "v1"})
+	}
+
+	validatingWebhooks := append(wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)).Webhooks,
+		generateValidatingWebhook(config.ValidatingWebhookName, wrc.serviceReference(config.ValidatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Validate, admregapi.Fail, nil, nil))
+	for _, w := range validatingWebhooks {
+		assert.Assert(t, w.SideEffects != nil)
+		assert.Equal(t, *w.SideEffects, admregapi.SideEffectClassNone)
+		assert.DeepEqual(t, w.AdmissionReviewVersions, []string{"v1beta1", "v1"})
+	}
+}
+
+func TestGenerateWebhooks_MatchPolicy(t *testing.T) {
+	defer func(policy admregapi.MatchPolicyType) { config.WebhookMatchPolicy = policy }(config.WebhookMatchPolicy)
+
+	wrc := newTestRegister(nil)
+	rule := wrc.defaultResourceWebhookRule()
+	for _, policy := range []admregapi.MatchPolicyType{admregapi.Equivalent, admregapi.Exact} {
+		config.WebhookMatchPolicy = policy
+
+		mutatingWebhooks := append(wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert)).Webhooks,
+			generateMutatingWebhook(config.MutatingWebhookName, wrc.serviceReference(config.MutatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Mutate, admregapi.Fail, nil, nil))
+		for _, w := range mutatingWebhooks {
+			assert.Assert(t, w.MatchPolicy != nil)
+			assert.Equal(t, *w.MatchPolicy, policy)
+		}
+
+		validatingWebhooks := append(wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)).Webhooks,
+			generateValidatingWebhook(config.ValidatingWebhookName, wrc.serviceReference(config.ValidatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Validate, admregapi.Fail, nil, nil))
+		for _, w := range validatingWebhooks {
+			assert.Assert(t, w.MatchPolicy != nil)
+			assert.Equal(t, *w.MatchPolicy, policy)
+		}
+	}
+
+	assert.NilError(t, ValidateMatchPolicy("Exact"))
+	assert.Assert(t, ValidateMatchPolicy("exact") != nil)
+}
+
+func TestGenerateWebhooks_AdmissionReviewVersionsOverride(t *testing.T) {
+	defer func(versions []string) { config.WebhookAdmissionReviewVersions = versions }(config.WebhookAdmissionReviewVersions)
+	config.WebhookAdmissionReviewVersions = []string{"v1"}
+
+	wrc := newTestRegister(nil)
+	webhooks := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)).Webhooks
+	for _, w := range webhooks {
+		assert.DeepEqual(t, w.AdmissionReviewVersions, []string{"v1"})
+	}
+
+	// the webhooks do not share the configured slice
+	webhooks[0].AdmissionReviewVersions[0] = "v1beta1"
+	assert.DeepEqual(t, config.WebhookAdmissionReviewVersions, []string{"v1"})
+}
+
+func TestGenerateWebhooks_ServiceReference(t *testing.T) {
+	wrc := &Register{serviceName: "kyverno-tenant-a-svc", serviceNamespace: "tenant-a", operations: defaultWebhookOperations, log: log.Log}
+	rule := wrc.defaultResourceWebhookRule()
+
+	mutating := generateMutatingWebhook(config.MutatingWebhookName, wrc.serviceReference(config.MutatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Mutate, admregapi.Fail, nil, nil)
+	assert.Assert(t, mutating.ClientConfig.Service != nil)
+	assert.Equal(t, mutating.ClientConfig.Service.Name, "kyverno-tenant-a-svc")
+	assert.Equal(t, mutating.ClientConfig.Service.Namespace, "tenant-a")
+	assert.Equal(t, *mutating.ClientConfig.Service.Path, config.MutatingWebhookServicePath)
+
+	validating := generateValidatingWebhook(config.ValidatingWebhookName, wrc.serviceReference(config.ValidatingWebhookServicePath), []byte(cert), false, 10, rule, defaultWebhookOperations.Validate, admregapi.Fail, nil, nil)
+	assert.Assert(t, validating.ClientConfig.Service != nil)
+	assert.Equal(t, validating.ClientConfig.Service.Name, "kyverno-tenant-a-svc")
+	assert.Equal(t, validating.ClientConfig.Service.Namespace, "tenant-a")
+	assert.Equal(t, *validating.ClientConfig.Service.Path, config.ValidatingWebhookServicePath)
+}
+
+func TestParseWebhookExclusions(t *testing.T) {
+	testcases := []struct {
+		name         string
+		objectLabels string
+		namespaces   string
+		expected     WebhookExclusions
+		expectedErr  bool
+	}{
+		{
+			name:         "default",
+			objectLabels: "app.kubernetes.io/name=kyverno",
+			namespaces:   "kyverno",
+			expected:     WebhookExclusions{ObjectLabels: map[string]string{"app.kubernetes.io/name": "kyverno"}, Namespaces: []string{"kyverno"}},
+		},
+		{
+			name:         "multiple",
+			objectLabels: "app=kyverno, team=platform",
+			namespaces:   "kyverno, kube-system,",
+			expected:     WebhookExclusions{ObjectLabels: map[string]string{"app": "kyverno", "team": "platform"}, Namespaces: []string{"kyverno", "kube-system"}},
+		},
+		{
+			name:     "empty",
+			expected: WebhookExclusions{},
+		},
+		{
+			name:         "invalid labels",
+			objectLabels: "app",
+			expectedErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			exclusions, err := ParseWebhookExclusions(tc.objectLabels, tc.namespaces)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expected, exclusions)
+		})
+	}
+}
+
+func TestParseWebhookOperations(t *testing.T) {
+	testcases := []struct {
+		name        string
+		mutate      string
+		validate    string
+		expected    WebhookOperations
+		expectedErr bool
+	}{
+		{
+			name:     "default",
+			expected: defaultWebhookOperations,
+		},
+		{
+			name:     "custom",
+			mutate:   "create, update, delete",
+			validate: "UPDATE",
+			expected: WebhookOperations{
+				Mutate:   []admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete},
+				Validate: []admregapi.OperationType{admregapi.Update},
+			},
+		},
+		{
+			name:     "mutate only",
+			mutate:   "*",
+			expected: WebhookOperations{Mutate: []admregapi.OperationType{admregapi.OperationAll}, Validate: defaultWebhookOperations.Validate},
+		},
+		{
+			name:        "invalid",
+			validate:    "CREATE,PATCH",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			operations, err := ParseWebhookOperations(tc.mutate, tc.validate)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expected, operations)
+		})
+	}
+}
+
+func TestWebhookExclusions_Empty(t *testing.T) {
+	exclusions := WebhookExclusions{}
+	assert.Assert(t, exclusions.objectSelector() == nil)
+	assert.Assert(t, exclusions.namespaceSelector(nil) == nil)
+}
+
+func TestConstructDefaultDebugWebhookConfig_Operations(t *testing.T) {
+	wrc := newTestRegister(nil)
+
+	mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	for _, w := range mutating.Webhooks {
+		assert.Equal(t, len(w.Rules), 1)
+		assert.DeepEqual(t, []admregapi.OperationType{admregapi.Create, admregapi.Update}, w.Rules[0].Operations)
+	}
+
+	// CONNECT is only registered for the exec and attach subresources of pods
+	validating := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert))
+	for _, w := range validating.Webhooks {
+		assert.Equal(t, len(w.Rules), 2)
+		assert.DeepEqual(t, []admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete}, w.Rules[0].Operations)
+		assert.DeepEqual(t, []string{"*/*"}, w.Rules[0].Resources)
+		assert.DeepEqual(t, []admregapi.OperationType{admregapi.Connect}, w.Rules[1].Operations)
+		assert.DeepEqual(t, []string{"pods/attach", "pods/exec"}, w.Rules[1].Resources)
+	}
+}
+
+func TestLimitConnectRule(t *testing.T) {
+	// the rules of policy kinds, e.g. pods/portforward with the auto-update of webhooks, are kept
+	w := admregapi.ValidatingWebhook{Rules: []admregapi.RuleWithOperations{
+		{
+			Operations: []admregapi.OperationType{admregapi.Create, admregapi.Connect},
+			Rule:       admregapi.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"pods/portforward"}},
+		},
+	}}
+	assert.DeepEqual(t, limitConnectRule(*w.DeepCopy()), w)
+
+	// a wildcard rule with only CONNECT is replaced
+	w = admregapi.ValidatingWebhook{Rules: []admregapi.RuleWithOperations{
+		{
+			Operations: []admregapi.OperationType{admregapi.Connect},
+			Rule:       admregapi.Rule{APIGroups: []string{"*"}, APIVersions: []string{"*"}, Resources: []string{"*/*"}},
+		},
+	}}
+	rules := limitConnectRule(w).Rules
+	assert.Equal(t, len(rules), 1)
+	assert.DeepEqual(t, rules[0].Resources, connectResources)
+	assert.DeepEqual(t, rules[0].Operations, []admregapi.OperationType{admregapi.Connect})
+
+	// webhooks without rules are unchanged
+	assert.Equal(t, len(limitConnectRule(admregapi.ValidatingWebhook{}).Rules), 0)
+}
+
+func TestConstructDefaultDebugWebhookConfig_CustomOperations(t *testing.T) {
+	operations := WebhookOperations{
+		Mutate:   []admregapi.OperationType{admregapi.Create},
+		Validate: []admregapi.OperationType{admregapi.Update},
+	}
+	wrc := newTestRegister(nil)
+	wrc.operations = operations
+
+	mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	for _, w := range mutating.Webhooks {
+		assert.DeepEqual(t, operations.Mutate, w.Rules[0].Operations)
+	}
+
+	validating := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert))
+	for _, w := range validating.Webhooks {
+		assert.DeepEqual(t, operations.Validate, w.Rules[0].Operations)
+	}
+}
+
+func TestConstructDefaultDebugWebhookConfig_FailurePolicy(t *testing.T) {
+	wrc := newTestRegister(nil)
+
+	expected := map[string]admregapi.FailurePolicyType{
+		config.MutatingWebhookName + "-ignore":   admregapi.Ignore,
+		config.MutatingWebhookName + "-fail":     admregapi.Fail,
+		config.ValidatingWebhookName + "-ignore": admregapi.Ignore,
+		config.ValidatingWebhookName + "-fail":   admregapi.Fail,
+	}
+
+	mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	assert.Equal(t, len(mutating.Webhooks), 2)
+	for _, w := range mutating.Webhooks {
+		assert.Assert(t, w.FailurePolicy != nil)
+		assert.Equal(t, expected[w.Name], *w.FailurePolicy)
+	}
+
+	validating := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert))
+	assert.Equal(t, len(validating.Webhooks), 2)
+	for _, w := range validating.Webhooks {
+		assert.Assert(t, w.FailurePolicy != nil)
+		assert.Equal(t, expected[w.Name], *w.FailurePolicy)
+	}
+}
+
+func newWebhookMockClient(t *testing.T, objects ...runtime.Object) *client.Client {
+	return newWebhookMockClientForVersion(t, "v1", objects...)
+}
+
+// newWebhookMockClientForVersion returns a client of a cluster serving the webhook configurations in the admissionregistration version
+func newWebhookMockClientForVersion(t *testing.T, version string, objects ...runtime.Object) *client.Client {
+	mutatingGVR := schema.GroupVersionResource{Group: "admissionregistration.k8s.io", Version: version, Resource: "mutatingwebhookconfigurations"}
+	validatingGVR := schema.GroupVersionResource{Group: "admissionregistration.k8s.io", Version: version, Resource: "validatingwebhookconfigurations"}
+	clusterRoleGVR := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	gvrToListKind := map[schema.GroupVersionResource]string{
+		mutatingGVR:    "MutatingWebhookConfigurationList",
+		validatingGVR:  "ValidatingWebhookConfigurationList",
+		clusterRoleGVR: "ClusterRoleList",
+	}
+
+	c, err := client.NewMockClient(runtime.NewScheme(), gvrToListKind, objects...)
+	assert.NilError(t, err)
+
+	c.SetDiscovery(client.NewFakeDiscoveryClient([]schema.GroupVersionResource{mutatingGVR, validatingGVR, clusterRoleGVR}))
+	return c
+}
+
+// newTestRegister returns a Register of Kyverno running in debug mode on 127.0.0.1:9443
+func newTestRegister(c *client.Client) *Register {
+	return &Register{
+		client:           c,
+		serverIP:         "127.0.0.1:9443",
+		serviceName:      config.KyvernoServiceName,
+		serviceNamespace: config.KyvernoNamespace,
+		deploymentName:   config.KyvernoDeploymentName,
+		log:              log.Log,
+		operations:       defaultWebhookOperations,
+	}
+}
+
+func newRunningDeployment() *unstructured.Unstructured {
+	deploy := &unstructured.Unstructured{}
+	deploy.SetAPIVersion("apps/v1")
+	deploy.SetKind("Deployment")
+	deploy.SetNamespace(config.KyvernoNamespace)
+	deploy.SetName(config.KyvernoDeploymentName)
+	_ = unstructured.SetNestedField(deploy.Object, int64(1), "spec", "replicas")
+	return deploy
+}
+
+// newReadyTestRegister returns a Register of a running Kyverno deployment in debug mode, the server is
+// ready and the CA file holds cert, the webhook configurations are registered in the objects' cluster
+func newReadyTestRegister(t *testing.T, objects ...runtime.Object) *Register {
+	var polls int32
+	srv := newReadinessServer(1, &polls)
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, []byte(cert), 0600))
+
+	wrc := newTestRegister(newWebhookMockClient(t, append([]runtime.Object{newRunningDeployment()}, objects...)...))
+	wrc.caFilePath = caFile
+	wrc.debug = true
+	wrc.readinessURL = srv.URL + config.ReadinessServicePath
+	wrc.readinessPollInterval = 10 * time.Millisecond
+	wrc.manage = noopManager{}
+	return wrc
+}
+
+func TestCreateOrUpdateWebhookConfiguration_Create(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	action, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, action, webhookCreated)
+
+	created, err := wrc.client.GetResource("", kindMutating, "", webhookConfig.Name)
+	assert.NilError(t, err)
+
+	webhooks, _, err := unstructured.NestedSlice(created.UnstructuredContent(), "webhooks")
+	assert.NilError(t, err)
+	assert.Equal(t, len(webhooks), 2)
+}
+
+func TestCreateOrUpdateWebhookConfiguration_APIVersion(t *testing.T) {
+	for _, version := range []string{"v1", "v1beta1"} {
+		t.Run(version, func(t *testing.T) {
+			wrc := newTestRegister(newWebhookMockClientForVersion(t, version))
+
+			mutatingConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+			_, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, mutatingConfig)
+			assert.NilError(t, err)
+
+			validatingConfig := wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert))
+			_, err = wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindValidating, validatingConfig)
+			assert.NilError(t, err)
+
+			created, err := wrc.client.GetResource("", kindMutating, "", mutatingConfig.Name)
+			assert.NilError(t, err)
+			assert.Equal(t, created.GetAPIVersion(), "admissionregistration.k8s.io/"+version)
+
+			created, err = wrc.client.GetResource("", kindValidating, "", validatingConfig.Name)
+			assert.NilError(t, err)
+			assert.Equal(t, created.GetAPIVersion(), "admissionregistration.k8s.io/"+version)
+
+			webhooks, _, err := unstructured.NestedSlice(created.UnstructuredContent(), "webhooks")
+			assert.NilError(t, err)
+			assert.Equal(t, len(webhooks), 2)
+		})
+	}
+}
+
+func TestDiscoverWebhookGroupVersion(t *testing.T) {
+	v1beta1 := client.NewFakeDiscoveryClient([]schema.GroupVersionResource{
+		{Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "mutatingwebhookconfigurations"},
+	})
+	assert.Equal(t, discoverWebhookGroupVersion(v1beta1, kindMutating, log.Log).String(), "admissionregistration.k8s.io/v1beta1")
+
+	v1 := client.NewFakeDiscoveryClient([]schema.GroupVersionResource{
+		{Group: "admissionregistration.k8s.io", Version: "v1", Resource: "mutatingwebhookconfigurations"},
+	})
+	assert.Equal(t, discoverWebhookGroupVersion(v1, kindMutating, log.Log).String(), "admissionregistration.k8s.io/v1")
+
+	// the kind is not discovered
+	assert.Equal(t, discoverWebhookGroupVersion(client.NewFakeDiscoveryClient(nil), kindMutating, log.Log).String(), "admissionregistration.k8s.io/v1")
+}
+
+func TestCreateOrUpdateWebhookConfiguration_Update(t *testing.T) {
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion("admissionregistration.k8s.io/v1")
+	existing.SetKind(kindMutating)
+	existing.SetName(config.MutatingWebhookConfigurationDebugName)
+	existing.SetResourceVersion("42")
+
+	wrc := newTestRegister(newWebhookMockClient(t, existing))
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	action, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, action, webhookUpdated)
+
+	updated, err := wrc.client.GetResource("", kindMutating, "", webhookConfig.Name)
+	assert.NilError(t, err)
+	assert.Equal(t, updated.GetResourceVersion(), "42")
+
+	webhooks, _, err := unstructured.NestedSlice(updated.UnstructuredContent(), "webhooks")
+	assert.NilError(t, err)
+	assert.Equal(t, len(webhooks), 2)
+}
+
+func TestRegisterWebhookConfigurations_Concurrent(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+
+	// the validating creates are slow, the mutating creates only succeed if they are
+	// issued while a validating create is in flight
+	validatingStarted := make(chan struct{})
+	var once sync.Once
+	fakeClient := wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient)
+	fakeClient.PrependReactor("create", "validatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		once.Do(func() { close(validatingStarted) })
+		time.Sleep(100 * time.Millisecond)
+		return false, nil, nil
+	})
+	fakeClient.PrependReactor("create", "mutatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		select {
+		case <-validatingStarted:
+			return false, nil, nil
+		case <-time.After(time.Second):
+			return true, nil, fmt.Errorf("mutating webhook configuration created before the validating ones")
+		}
+	})
+
+	desired := wrc.desiredWebhookConfigurations([]byte(cert))
+	assert.NilError(t, wrc.registerWebhookConfigurations(context.TODO(), desired))
+
+	for _, d := range desired {
+		_, err := wrc.client.GetResource("", d.kind, "", d.config.GetName())
+		assert.NilError(t, err)
+	}
+}
+
+func TestRegisterWebhookConfigurations_RollbackOnPartialFailure(t *testing.T) {
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion("admissionregistration.k8s.io/v1")
+	existing.SetKind(kindMutating)
+	existing.SetName(config.MutatingWebhookConfigurationDebugName)
+	existing.SetResourceVersion("42")
+
+	wrc := newTestRegister(newWebhookMockClient(t, existing))
+
+	fakeClient := wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient)
+	fakeClient.PrependReactor("create", "validatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errorsapi.NewForbidden(schema.GroupResource{Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations"}, "", fmt.Errorf("denied"))
+	})
+
+	err := wrc.registerWebhookConfigurations(context.TODO(), wrc.desiredWebhookConfigurations([]byte(cert)))
+	assert.ErrorContains(t, err, "denied")
+
+	// the created mutating configurations are rolled back, the updated one is kept
+	for _, name := range []string{config.VerifyMutatingWebhookConfigurationDebugName, config.PolicyMutatingWebhookConfigurationDebugName} {
+		_, err := wrc.client.GetResource("", kindMutating, "", name)
+		assert.Assert(t, errorsapi.IsNotFound(err), "expected %s to be deleted, got %v", name, err)
+	}
+
+	_, err = wrc.client.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+	assert.NilError(t, err)
+}
+
+func TestDeregisterAll(t *testing.T) {
+	var objects []runtime.Object
+	for _, c := range [][2]string{
+		{kindMutating, config.MutatingWebhookConfigurationName},
+		{kindValidating, config.ValidatingWebhookConfigurationDebugName},
+	} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("admissionregistration.k8s.io/v1")
+		obj.SetKind(c[0])
+		obj.SetName(c[1])
+		objects = append(objects, obj)
+	}
+
+	wrc := &Register{
+		client: newWebhookMockClient(t, objects...),
+		log:    log.Log,
+	}
+
+	// the configurations that do not exist are ignored
+	assert.NilError(t, wrc.DeregisterAll())
+
+	deleted := map[string]bool{}
+	for _, a := range wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient).Actions() {
+		if a.GetVerb() == "delete" {
+			deleted[a.(k8stesting.DeleteAction).GetName()] = true
+		}
+	}
+
+	for _, name := range []string{
+		config.MutatingWebhookConfigurationName,
+		config.MutatingWebhookConfigurationDebugName,
+		config.ValidatingWebhookConfigurationName,
+		config.ValidatingWebhookConfigurationDebugName,
+	} {
+		assert.Assert(t, deleted[name], "expected %s to be deleted", name)
+	}
+
+	for _, obj := range objects {
+		_, err := wrc.client.GetResource("", obj.GetObjectKind().GroupVersionKind().Kind, "", obj.(*unstructured.Unstructured).GetName())
+		assert.Assert(t, errorsapi.IsNotFound(err))
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	newClient := func(objects ...runtime.Object) *client.Client {
+		c, err := client.NewMockClient(runtime.NewScheme(), nil, objects...)
+		assert.NilError(t, err)
+		c.SetDiscovery(client.NewFakeDiscoveryClient([]schema.GroupVersionResource{{Version: "v1", Resource: "services"}}))
+		return c
+	}
+
+	wrc := &Register{
+		client:           newClient(),
+		serviceName:      config.KyvernoServiceName,
+		serviceNamespace: config.KyvernoNamespace,
+		log:              log.Log,
+	}
+	assert.ErrorContains(t, wrc.checkService(context.TODO()), fmt.Sprintf("service %s/%s referenced by the webhooks not found", config.KyvernoNamespace, config.KyvernoServiceName))
+
+	svc := &unstructured.Unstructured{}
+	svc.SetAPIVersion("v1")
+	svc.SetKind("Service")
+	svc.SetNamespace(config.KyvernoNamespace)
+	svc.SetName(config.KyvernoServiceName)
+
+	wrc.client = newClient(svc)
+	assert.NilError(t, wrc.checkService(context.TODO()))
+}
+
+func TestReadCaData_Precedence(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, []byte("file-ca"), 0600))
+
+	caSecret := newCASecret("secret-ca")
+
+	kubeconfig := &rest.Config{
+		Host: "https://127.0.0.1:6443",
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: []byte("kubeconfig-ca"),
+		},
+	}
+
+	testcases := []struct {
+		name       string
+		caFilePath string
+		withSecret bool
+		clientCfg  *rest.Config
+		expected   string
+		expectErr  bool
+	}{
+		{name: "file takes precedence", caFilePath: caFile, withSecret: true, clientCfg: kubeconfig, expected: "file-ca"},
+		{name: "missing file is an error", caFilePath: filepath.Join(t.TempDir(), "missing.crt"), withSecret: true, clientCfg: kubeconfig, expectErr: true},
+		{name: "secret takes precedence over kubeconfig", withSecret: true, clientCfg: kubeconfig, expected: "secret-ca"},
+		{name: "fallback to kubeconfig", clientCfg: kubeconfig, expected: "kubeconfig-ca"},
+		{name: "no CA", clientCfg: &rest.Config{Host: "https://127.0.0.1:6443"}, expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		var c *client.Client
+		if tc.withSecret {
+			c = newWebhookMockClient(t, caSecret)
+		} else {
+			c = newWebhookMockClient(t)
+		}
+
+		wrc := &Register{
+			client:       c,
+			clientConfig: tc.clientCfg,
+			caFilePath:   tc.caFilePath,
+			log:          log.Log,
+		}
+
+		caData, err := wrc.readCaData()
+		if tc.expectErr {
+			assert.Assert(t, err != nil, tc.name)
+			continue
+		}
+
+		assert.NilError(t, err, tc.name)
+		assert.Equal(t, string(caData), tc.expected, tc.name)
+	}
+}
+
+func TestReadCaData_ConfigMap(t *testing.T) {
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace("proxy")
+	cm.SetName("corporate-ca")
+	assert.NilError(t, unstructured.SetNestedStringMap(cm.Object, map[string]string{"bundle.pem": cert}, "data"))
+
+	ref, err := ParseCAConfigMapRef("proxy/corporate-ca:bundle.pem")
+	assert.NilError(t, err)
+
+	wrc := newTestRegister(newWebhookMockClient(t, cm, newCASecret("secret-ca")))
+	wrc.clientConfig = &rest.Config{Host: "https://127.0.0.1:6443"}
+	wrc.caConfigMap = ref
+
+	// the ConfigMap takes precedence over the secret in debug mode
+	caData, err := wrc.readCaData()
+	assert.NilError(t, err)
+	assert.Equal(t, string(caData), cert)
+
+	for _, w := range wrc.constructDefaultDebugMutatingWebhookConfig(caData).Webhooks {
+		assert.DeepEqual(t, w.ClientConfig.CABundle, []byte(cert))
+	}
+
+	wrc.caConfigMap.Key = "missing.pem"
+	_, err = wrc.readCaData()
+	assert.ErrorContains(t, err, "key missing.pem not found in CA ConfigMap proxy/corporate-ca")
+}
+
+func TestParseCAConfigMapRef(t *testing.T) {
+	ref, err := ParseCAConfigMapRef("kyverno/kyverno-ca")
+	assert.NilError(t, err)
+	assert.Equal(t, ref, CAConfigMapRef{Namespace: "kyverno", Name: "kyverno-ca", Key: "ca.crt"})
+
+	ref, err = ParseCAConfigMapRef("")
+	assert.NilError(t, err)
+	assert.Equal(t, ref, CAConfigMapRef{})
+
+	for _, value := range []string{"kyverno-ca", "kyverno/", "kyverno/kyverno-ca:", "a/b/c"} {
+		_, err := ParseCAConfigMapRef(value)
+		assert.Assert(t, err != nil, value)
+	}
+}
+
+type noopManager struct{}
+
+func (noopManager) start() {}
+
+func TestRegister_RegistrationCondition(t *testing.T) {
+	wrc := newReadyTestRegister(t)
+	assert.NilError(t, wrc.Register(context.TODO()))
+
+	condition, err := wrc.GetRegistrationCondition()
+	assert.NilError(t, err)
+	assert.Equal(t, condition.Type, WebhookRegisteredCondition)
+	assert.Equal(t, condition.Status, "True")
+	assert.Equal(t, condition.Reason, "Registered")
+	assert.Equal(t, condition.CABundleFingerprint, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(cert))))
+}
+
+func TestRegistrationCondition_Deployment(t *testing.T) {
+	deploy := newRunningDeployment()
+	deploy.SetNamespace("tenant-a")
+	deploy.SetName("kyverno-tenant-a")
+
+	// the condition is recorded on the deployment of the Register
+	wrc := newTestRegister(newWebhookMockClient(t, deploy))
+	wrc.serviceNamespace = "tenant-a"
+	wrc.deploymentName = "kyverno-tenant-a"
+	wrc.setRegistrationCondition(true, reasonRegistered, "", nil)
+
+	condition, err := wrc.GetRegistrationCondition()
+	assert.NilError(t, err)
+	assert.Equal(t, condition.Status, "True")
+	assert.Equal(t, condition.Reason, reasonRegistered)
+}
+
+func TestRegister_EnabledResourceWebhooks(t *testing.T) {
+	testCases := []struct {
+		name              string
+		disableMutation   bool
+		disableValidation bool
+	}{
+		{name: "mutation and validation"},
+		{name: "mutation only", disableValidation: true},
+		{name: "validation only", disableMutation: true},
+		{name: "neither", disableMutation: true, disableValidation: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrc := newReadyTestRegister(t)
+			wrc.disableMutation = tc.disableMutation
+			wrc.disableValidation = tc.disableValidation
+
+			assert.NilError(t, wrc.Register(context.TODO()))
+
+			_, err := wrc.client.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+			assert.Equal(t, err == nil, !tc.disableMutation)
+			assert.Equal(t, errorsapi.IsNotFound(err), tc.disableMutation)
+
+			_, err = wrc.client.GetResource("", kindValidating, "", config.ValidatingWebhookConfigurationDebugName)
+			assert.Equal(t, err == nil, !tc.disableValidation)
+			assert.Equal(t, errorsapi.IsNotFound(err), tc.disableValidation)
+
+			// the policy and verify webhooks are always registered
+			for _, c := range [][2]string{
+				{kindMutating, config.PolicyMutatingWebhookConfigurationDebugName},
+				{kindValidating, config.PolicyValidatingWebhookConfigurationDebugName},
+				{kindMutating, config.VerifyMutatingWebhookConfigurationDebugName},
+			} {
+				_, err := wrc.client.GetResource("", c[0], "", c[1])
+				assert.NilError(t, err)
+			}
+
+			// the deregistration cleans up the registered configurations
+			assert.NilError(t, wrc.DeregisterAll())
+			for _, c := range allWebhookConfigurations() {
+				_, err := wrc.client.GetResource("", c[0], "", c[1])
+				assert.Assert(t, errorsapi.IsNotFound(err), "expected %s %s to be deleted", c[0], c[1])
+			}
+		})
+	}
+}
+
+func TestRegister_RemovesDisabledResourceWebhook(t *testing.T) {
+	// registered by a previous instance with the mutation enabled
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion("admissionregistration.k8s.io/v1")
+	existing.SetKind(kindMutating)
+	existing.SetName(config.MutatingWebhookConfigurationDebugName)
+
+	wrc := newReadyTestRegister(t, existing)
+	wrc.disableMutation = true
+
+	assert.NilError(t, wrc.Register(context.TODO()))
+
+	_, err := wrc.client.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+	assert.Assert(t, errorsapi.IsNotFound(err))
+	_, err = wrc.client.GetResource("", kindValidating, "", config.ValidatingWebhookConfigurationDebugName)
+	assert.NilError(t, err)
+}
+
+func TestDesiredWebhookConfigurations_EnabledResourceWebhooks(t *testing.T) {
+	wrc := &Register{log: log.Log, operations: defaultWebhookOperations, disableValidation: true}
+	names := map[string]bool{}
+	for _, desired := range wrc.desiredWebhookConfigurations([]byte(cert)) {
+		names[desired.config.GetName()] = true
+	}
+
+	assert.Equal(t, len(names), 4)
+	assert.Assert(t, names[config.MutatingWebhookConfigurationName])
+	assert.Assert(t, !names[config.ValidatingWebhookConfigurationName])
+	assert.DeepEqual(t, wrc.resourceWebhookKinds(), []string{kindMutating})
+}
+
+// generateTestCA returns a PEM encoded self-signed CA valid until notAfter
+func generateTestCA(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "*.kyverno.svc"},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCaExpiry(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	expiry, err := caExpiry(generateTestCA(t, notAfter))
+	assert.NilError(t, err)
+	assert.Assert(t, expiry.Equal(notAfter), "expected %v, got %v", notAfter, expiry)
+
+	// the earliest expiry of a bundle
+	bundle := append(generateTestCA(t, notAfter.Add(24*time.Hour)), generateTestCA(t, notAfter)...)
+	expiry, err = caExpiry(bundle)
+	assert.NilError(t, err)
+	assert.Assert(t, expiry.Equal(notAfter), "expected %v, got %v", notAfter, expiry)
+
+	_, err = caExpiry([]byte(cert))
+	assert.ErrorContains(t, err, "no certificate found")
+
+	_, err = caExpiry(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}))
+	assert.ErrorContains(t, err, "failed to parse CA certificate")
+}
+
+func TestCheckCAExpiry(t *testing.T) {
+	now := time.Now()
+	wrc := &Register{log: log.Log}
+
+	testcases := []struct {
+		name          string
+		caData        []byte
+		expectWarning bool
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			caData: generateTestCA(t, now.Add(365*24*time.Hour)),
+		},
+		{
+			name:          "expires soon",
+			caData:        generateTestCA(t, now.Add(24*time.Hour)),
+			expectWarning: true,
+		},
+		{
+			name:          "expired",
+			caData:        generateTestCA(t, now.Add(-time.Hour)),
+			expectedError: "CA expired at",
+		},
+		{
+			name:   "not parsable",
+			caData: []byte(cert),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			warning, err := wrc.checkCAExpiry(tc.caData, now)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, warning != "", tc.expectWarning, "unexpected warning %q", warning)
+		})
+	}
+}
+
+func TestRegister_RegistrationConditionCAExpiringSoon(t *testing.T) {
+	notAfter := time.Now().Add(7 * 24 * time.Hour)
+	wrc := newReadyTestRegister(t)
+	assert.NilError(t, ioutil.WriteFile(wrc.caFilePath, generateTestCA(t, notAfter), 0600))
+
+	assert.NilError(t, wrc.Register(context.TODO()))
+
+	condition, err := wrc.GetRegistrationCondition()
+	assert.NilError(t, err)
+	assert.Equal(t, condition.Status, "True")
+	assert.Equal(t, condition.Reason, "CAExpiringSoon")
+	assert.Equal(t, condition.CAExpiry, notAfter.UTC().Format(time.RFC3339))
+	assert.Assert(t, strings.Contains(condition.Message, condition.CAExpiry))
+}
+
+func TestRegister_Events(t *testing.T) {
+	testcases := []struct {
+		name          string
+		caFilePath    string
+		expectedEvent string
+	}{
+		{
+			name:          "registered",
+			expectedEvent: "Normal Registered webhook configurations registered",
+		},
+		{
+			name:          "failed",
+			caFilePath:    filepath.Join(t.TempDir(), "missing.crt"),
+			expectedEvent: "Warning RegistrationFailed failed to register webhook configurations: ",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			wrc := newReadyTestRegister(t)
+			wrc.eventRecorder = recorder
+			if tc.caFilePath != "" {
+				wrc.caFilePath = tc.caFilePath
+			}
+
+			_ = wrc.Register(context.TODO())
+
+			assert.Equal(t, len(recorder.Events), 1)
+			e := <-recorder.Events
+			assert.Assert(t, strings.HasPrefix(e, tc.expectedEvent), "unexpected event %q", e)
+		})
+	}
+}
+
+func TestRegister_ContextCanceled(t *testing.T) {
+	// the server never becomes ready, Register blocks until the context is canceled
+	var polls int32
+	srv := newReadinessServer(1000, &polls)
+	defer srv.Close()
+
+	wrc := newReadyTestRegister(t)
+	wrc.readinessURL = srv.URL + config.ReadinessServicePath
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- wrc.Register(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.Assert(t, err != nil)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Register did not abort after the context was canceled")
+	}
+
+	_, err := wrc.client.GetResource("", kindMutating, "", config.MutatingWebhookConfigurationDebugName)
+	assert.Assert(t, err != nil, "no webhook configuration must be created")
+}
+
+func TestConstructDebugPolicyValidatingWebhookConfig(t *testing.T) {
+	wrc := newTestRegister(nil)
+
+	webhookConfig := wrc.constructDebugPolicyValidatingWebhookConfig([]byte(cert))
+	assert.Equal(t, len(webhookConfig.Webhooks), 1)
+
+	webhook := webhookConfig.Webhooks[0]
+	assert.Equal(t, webhook.Name, config.PolicyValidatingWebhookName)
+	assert.Equal(t, *webhook.ClientConfig.URL, "https://127.0.0.1:9443"+config.PolicyValidatingWebhookServicePath)
+	assert.Assert(t, config.PolicyValidatingWebhookServicePath != config.ValidatingWebhookServicePath)
+
+	assert.Equal(t, len(webhook.Rules), 1)
+	assert.DeepEqual(t, webhook.Rules[0].APIGroups, []string{"kyverno.io"})
+	assert.DeepEqual(t, webhook.Rules[0].Resources, []string{"clusterpolicies/*", "policies/*"})
+	assert.DeepEqual(t, webhook.Rules[0].Operations, []admregapi.OperationType{admregapi.Create, admregapi.Update})
+}
+
+func TestConstructDebugWebhookConfig_OwnerReferences(t *testing.T) {
+	owner := &unstructured.Unstructured{}
+	owner.SetAPIVersion(config.ClusterRoleAPIVersion)
+	owner.SetKind(config.ClusterRoleKind)
+	owner.SetName("kyverno:webhook")
+	owner.SetUID("3f2a9c1e")
+	owner.SetLabels(map[string]string{"app.kubernetes.io/ownerreference": "true"})
+
+	// a cluster role without the owner label is not an owner
+	other := &unstructured.Unstructured{}
+	other.SetAPIVersion(config.ClusterRoleAPIVersion)
+	other.SetKind(config.ClusterRoleKind)
+	other.SetName("kyverno:view")
+
+	constructs := func(wrc *Register) map[string][]v1.OwnerReference {
+		caData := []byte(cert)
+		return map[string][]v1.OwnerReference{
+			config.MutatingWebhookConfigurationDebugName:         wrc.constructDefaultDebugMutatingWebhookConfig(caData).OwnerReferences,
+			config.ValidatingWebhookConfigurationDebugName:       wrc.constructDefaultDebugValidatingWebhookConfig(caData).OwnerReferences,
+			config.PolicyMutatingWebhookConfigurationDebugName:   wrc.constructDebugPolicyMutatingWebhookConfig(caData).OwnerReferences,
+			config.PolicyValidatingWebhookConfigurationDebugName: wrc.constructDebugPolicyValidatingWebhookConfig(caData).OwnerReferences,
+			config.VerifyMutatingWebhookConfigurationDebugName:   wrc.constructDebugVerifyMutatingWebhookConfig(caData).OwnerReferences,
+		}
+	}
+
+	wrc := newTestRegister(newWebhookMockClient(t, owner, other))
+	expected := []v1.OwnerReference{{APIVersion: config.ClusterRoleAPIVersion, Kind: config.ClusterRoleKind, Name: "kyverno:webhook", UID: "3f2a9c1e"}}
+	for name, owners := range constructs(wrc) {
+		assert.Assert(t, len(owners) == 1, "expected an owner on %s", name)
+		assert.DeepEqual(t, owners, expected)
+	}
+
+	// running outside a cluster where Kyverno is not installed
+	wrc.client = newWebhookMockClient(t, other)
+	for name, owners := range constructs(wrc) {
+		assert.Equal(t, len(owners), 0, "expected no owner on %s", name)
+	}
+
+	wrc.client = nil
+	for name, owners := range constructs(wrc) {
+		assert.Equal(t, len(owners), 0, "expected no owner on %s", name)
+	}
+}
+
+func TestConstructDebugWebhookConfig_ServerPathPrefix(t *testing.T) {
+	wrc := &Register{serverIP: "192.168.10.117:443", serverPathPrefix: "/kyverno", log: log.Log, operations: defaultWebhookOperations}
+
+	urls := map[string]*string{}
+	for _, w := range wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert)).Webhooks {
+		urls[config.MutatingWebhookServicePath] = w.ClientConfig.URL
+	}
+	for _, w := range wrc.constructDefaultDebugValidatingWebhookConfig([]byte(cert)).Webhooks {
+		urls[config.ValidatingWebhookServicePath] = w.ClientConfig.URL
+	}
+	for _, w := range wrc.constructDebugPolicyMutatingWebhookConfig([]byte(cert)).Webhooks {
+		urls[config.PolicyMutatingWebhookServicePath] = w.ClientConfig.URL
+	}
+	for _, w := range wrc.constructDebugPolicyValidatingWebhookConfig([]byte(cert)).Webhooks {
+		urls[config.PolicyValidatingWebhookServicePath] = w.ClientConfig.URL
+	}
+	for _, w := range wrc.constructDebugVerifyMutatingWebhookConfig([]byte(cert)).Webhooks {
+		urls[config.VerifyMutatingWebhookServicePath] = w.ClientConfig.URL
+	}
+	urls[config.ConversionWebhookServicePath] = wrc.constructConversion([]byte(cert)).Webhook.ClientConfig.URL
+
+	assert.Equal(t, len(urls), 6)
+	for path, url := range urls {
+		assert.Assert(t, url != nil, "expected an URL for %s", path)
+		assert.Equal(t, *url, "https://192.168.10.117:443/kyverno"+path)
+	}
+
+	// without a prefix the service path directly follows the server address
+	wrc.serverPathPrefix = ""
+	mutating := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	assert.Equal(t, *mutating.Webhooks[0].ClientConfig.URL, "https://192.168.10.117:443"+config.MutatingWebhookServicePath)
+}
+
+func TestNewRegister_CAConfigMapRequiresServerIP(t *testing.T) {
+	caConfigMap := CAConfigMapRef{Namespace: "kyverno", Name: "proxy-ca", Key: defaultCAConfigMapKey}
+
+	_, err := NewRegister(nil, nil, nil, nil, nil, nil, "", "", "", caConfigMap, "", "", "", 10, false, false, true, true,
+		WebhookExclusions{}, WebhookOperations{}, nil, nil, log.Log)
+	assert.ErrorContains(t, err, "the CA ConfigMap kyverno/proxy-ca:ca.crt is only read in debug mode")
+}
+
+func TestNormalizeServerPathPrefix(t *testing.T) {
+	testcases := []struct {
+		prefix      string
+		expected    string
+		expectedErr bool
+	}{
+		{prefix: "", expected: ""},
+		{prefix: "/kyverno", expected: "/kyverno"},
+		{prefix: "/kyverno/", expected: "/kyverno"},
+		{prefix: " /gateway/kyverno ", expected: "/gateway/kyverno"},
+		{prefix: "/", expected: ""},
+		{prefix: "kyverno", expectedErr: true},
+		{prefix: "https://ingress/kyverno", expectedErr: true},
+		{prefix: "/kyverno?debug=true", expectedErr: true},
+		{prefix: "/kyverno#webhooks", expectedErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			actual, err := normalizeServerPathPrefix(tc.prefix)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil, "expected an error for %q, got %q", tc.prefix, actual)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestNormalizeServerIP(t *testing.T) {
+	testcases := []struct {
+		serverIP    string
+		expected    string
+		expectedErr bool
+	}{
+		{serverIP: "", expected: ""},
+		{serverIP: "192.168.10.117:9443", expected: "192.168.10.117:9443"},
+		{serverIP: "https://192.168.10.117:9443", expected: "192.168.10.117:9443"},
+		{serverIP: "https://192.168.10.117:9443/", expected: "192.168.10.117:9443"},
+		{serverIP: "kyverno.local:9443", expected: "kyverno.local:9443"},
+		{serverIP: "[::1]:9443", expected: "[::1]:9443"},
+		{serverIP: "https://[2001:db8::1]:443", expected: "[2001:db8::1]:443"},
+		{serverIP: "192.168.10.117", expectedErr: true},
+		{serverIP: "http://192.168.10.117:9443", expectedErr: true},
+		{serverIP: "192.168.10.117:", expectedErr: true},
+		{serverIP: "192.168.10.117:port", expectedErr: true},
+		{serverIP: "192.168.10.117:70000", expectedErr: true},
+		{serverIP: ":9443", expectedErr: true},
+		{serverIP: "kyverno_local:9443", expectedErr: true},
+		{serverIP: "::1:9443", expectedErr: true},
+		{serverIP: "[::1]", expectedErr: true},
+		{serverIP: "[2001:db8::zz]:9443", expectedErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.serverIP, func(t *testing.T) {
+			actual, err := NormalizeServerIP(tc.serverIP)
+			if tc.expectedErr {
+				assert.Assert(t, err != nil, "expected an error for %q, got %q", tc.serverIP, actual)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+type logEntry struct {
+	msg           string
+	err           error
+	keysAndValues []interface{}
+}
+
+// recordingLogger records the entries logged at any level
+type recordingLogger struct {
+	entries *[]logEntry
+	values  []interface{}
+}
+
+func newRecordingLogger() recordingLogger {
+	return recordingLogger{entries: &[]logEntry{}}
+}
+
+func (l recordingLogger) Enabled() bool { return true }
+
+func (l recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	*l.entries = append(*l.entries, logEntry{msg: msg, keysAndValues: append(append([]interface{}{}, l.values...), keysAndValues...)})
+}
+
+func (l recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	*l.entries = append(*l.entries, logEntry{msg: msg, err: err, keysAndValues: append(append([]interface{}{}, l.values...), keysAndValues...)})
+}
+
+func (l recordingLogger) V(level int) logr.Logger { return l }
+
+func (l recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return recordingLogger{entries: l.entries, values: append(append([]interface{}{}, l.values...), keysAndValues...)}
+}
+
+func (l recordingLogger) WithName(name string) logr.Logger { return l }
+
+func TestCreateResourceMutatingWebhookConfiguration_Logs(t *testing.T) {
+	logger := newRecordingLogger()
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.log = logger
+
+	assert.NilError(t, wrc.createResourceMutatingWebhookConfiguration(context.TODO(), []byte(cert)))
+
+	var found bool
+	for _, e := range *logger.entries {
+		if e.msg == "created webhook" {
+			found = true
+			assert.Assert(t, e.err == nil)
+			assert.DeepEqual(t, e.keysAndValues, []interface{}{"kind", kindMutating, "name", config.MutatingWebhookConfigurationDebugName})
+		}
+	}
+	assert.Assert(t, found, "expected the entry 'created webhook', got %v", *logger.entries)
+}
+
+// newInflightServer returns a server whose handler blocks until release is closed
+func newInflightServer(started, release chan struct{}) *httptest.Server {
+	var once sync.Once
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestDeregisterGraceful_WaitsForInflightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := newInflightServer(started, release)
+	defer srv.Close()
+
+	// the running deployment skips the resource cleanup, only the ordering is verified
+	wrc := newTestRegister(newWebhookMockClient(t, newRunningDeployment()))
+
+	statusCh := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			statusCh <- 0
+			return
+		}
+		resp.Body.Close()
+		statusCh <- resp.StatusCode
+	}()
+	<-started
+
+	cleanUp := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- wrc.DeregisterGraceful(context.TODO(), srv.Config, cleanUp)
+	}()
+
+	select {
+	case <-cleanUp:
+		t.Fatal("webhook configurations are removed while a request is in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NilError(t, <-errCh)
+	<-cleanUp
+	assert.Equal(t, <-statusCh, http.StatusOK)
+
+	// new requests are rejected once the server is drained
+	_, err := http.Get(srv.URL)
+	assert.Assert(t, err != nil)
+}
+
+func TestDeregisterGraceful_ContextExpired(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := newInflightServer(started, release)
+	defer srv.Close()
+	defer close(release)
+
+	wrc := newTestRegister(newWebhookMockClient(t, newRunningDeployment()))
+
+	go func() {
+		if resp, err := http.Get(srv.URL); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	cleanUp := make(chan struct{})
+	err := wrc.DeregisterGraceful(ctx, srv.Config, cleanUp)
+	assert.ErrorContains(t, err, "failed to drain in-flight admission requests")
+	<-cleanUp
+}
@@ -1,15 +1,181 @@
 package webhookconfig
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/utils"
 	admregapi "k8s.io/api/admissionregistration/v1"
 	errorsapi "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// WebhookOperations defines the admission operations the resource webhooks are registered for
+type WebhookOperations struct {
+	// Mutate is the set of operations sent to the resource mutating webhooks
+	Mutate []admregapi.OperationType
+
+	// Validate is the set of operations sent to the resource validating webhooks
+	Validate []admregapi.OperationType
+}
+
+// defaultWebhookOperations is used to build the resource webhook configurations,
+// validate includes DELETE and CONNECT to support generate rule cleanup and exec/attach checks
+var defaultWebhookOperations = WebhookOperations{
+	Mutate:   []admregapi.OperationType{admregapi.Create, admregapi.Update},
+	Validate: []admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect},
+}
+
+// ParseWebhookOperations parses the comma separated operations of the resource mutating and validating
+// webhooks, e.g. "CREATE,UPDATE". An empty value keeps the default operations of the webhook kind.
+func ParseWebhookOperations(mutate, validate string) (WebhookOperations, error) {
+	operations := defaultWebhookOperations
+
+	mutateOps, err := parseOperations(mutate)
+	if err != nil {
+		return operations, fmt.Errorf("invalid mutating webhook operations: %v", err)
+	}
+	if len(mutateOps) != 0 {
+		operations.Mutate = mutateOps
+	}
+
+	validateOps, err := parseOperations(validate)
+	if err != nil {
+		return operations, fmt.Errorf("invalid validating webhook operations: %v", err)
+	}
+	if len(validateOps) != 0 {
+		operations.Validate = validateOps
+	}
+
+	return operations, nil
+}
+
+// operationStrings converts the operations to the string representation used in unstructured webhooks
+func operationStrings(ops []admregapi.OperationType) []string {
+	res := make([]string, len(ops))
+	for i, op := range ops {
+		res[i] = string(op)
+	}
+	return res
+}
+
+// namespaceNameLabel is set by the API server on every namespace since Kubernetes v1.21
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// WebhookExclusions defines the objects the resource webhooks do not intercept,
+// so that Kyverno does not block the admission of its own resources
+type WebhookExclusions struct {
+	// ObjectLabels excludes the objects that carry any of these labels
+	ObjectLabels map[string]string
+
+	// Namespaces excludes the objects in these namespaces
+	Namespaces []string
+}
+
+// DefaultWebhookExclusions excludes the objects with the Kyverno app labels and the Kyverno namespace
+func DefaultWebhookExclusions(namespace string) WebhookExclusions {
+	return WebhookExclusions{
+		ObjectLabels: config.KyvernoAppLabels,
+		Namespaces:   []string{namespace},
+	}
+}
+
+// ParseWebhookExclusions parses the excluded object labels in the format "key1=value1,key2=value2"
+// and the comma separated list of excluded namespaces
+func ParseWebhookExclusions(objectLabels, namespaces string) (WebhookExclusions, error) {
+	exclusions := WebhookExclusions{}
+
+	if strings.TrimSpace(objectLabels) != "" {
+		labelMap, err := labels.ConvertSelectorToLabelsMap(objectLabels)
+		if err != nil {
+			return exclusions, fmt.Errorf("invalid excluded object labels %q: %v", objectLabels, err)
+		}
+		exclusions.ObjectLabels = labelMap
+	}
+
+	for _, ns := range strings.Split(namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			exclusions.Namespaces = append(exclusions.Namespaces, ns)
+		}
+	}
+
+	return exclusions, nil
+}
+
+// objectSelector returns the objectSelector that excludes the objects carrying any of the excluded labels
+func (e WebhookExclusions) objectSelector() *v1.LabelSelector {
+	if len(e.ObjectLabels) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(e.ObjectLabels))
+	for key := range e.ObjectLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	selector := &v1.LabelSelector{}
+	for _, key := range keys {
+		selector.MatchExpressions = append(selector.MatchExpressions, v1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: v1.LabelSelectorOpNotIn,
+			Values:   []string{e.ObjectLabels[key]},
+		})
+	}
+	return selector
+}
+
+// namespaceSelector returns a copy of selector that additionally excludes the excluded namespaces
+func (e WebhookExclusions) namespaceSelector(selector *v1.LabelSelector) *v1.LabelSelector {
+	if len(e.Namespaces) == 0 {
+		return selector
+	}
+
+	if selector == nil {
+		selector = &v1.LabelSelector{}
+	} else {
+		selector = selector.DeepCopy()
+	}
+
+	selector.MatchExpressions = append(selector.MatchExpressions, v1.LabelSelectorRequirement{
+		Key:      namespaceNameLabel,
+		Operator: v1.LabelSelectorOpNotIn,
+		Values:   e.Namespaces,
+	})
+	return selector
+}
+
+// ValidateReinvocationPolicy returns an error if the reinvocation policy is neither Never nor IfNeeded
+func ValidateReinvocationPolicy(policy string) error {
+	switch admregapi.ReinvocationPolicyType(policy) {
+	case admregapi.NeverReinvocationPolicy, admregapi.IfNeededReinvocationPolicy:
+		return nil
+	}
+	return fmt.Errorf("invalid webhook reinvocation policy %q, the value must be %s or %s", policy, admregapi.NeverReinvocationPolicy, admregapi.IfNeededReinvocationPolicy)
+}
+
+// ValidateMatchPolicy returns an error if the match policy is neither Exact nor Equivalent
+func ValidateMatchPolicy(policy string) error {
+	switch admregapi.MatchPolicyType(policy) {
+	case admregapi.Exact, admregapi.Equivalent:
+		return nil
+	}
+	return fmt.Errorf("invalid webhook match policy %q, the value must be %s or %s", policy, admregapi.Exact, admregapi.Equivalent)
+}
+
+// setReinvocationPolicy sets the reinvocation policy on all webhooks of the configuration
+func setReinvocationPolicy(mutating *admregapi.MutatingWebhookConfiguration, policy admregapi.ReinvocationPolicyType) {
+	for i := range mutating.Webhooks {
+		reinvocationPolicy := policy
+		mutating.Webhooks[i].ReinvocationPolicy = &reinvocationPolicy
+	}
+}
+
 func (wrc *Register) defaultResourceWebhookRule() admregapi.Rule {
 	if wrc.autoUpdateWebhooks {
 		return admregapi.Rule{}
@@ -24,11 +190,12 @@ func (wrc *Register) defaultResourceWebhookRule() admregapi.Rule {
 
 func (wrc *Register) constructDefaultDebugMutatingWebhookConfig(caData []byte) *admregapi.MutatingWebhookConfiguration {
 	logger := wrc.log
-	url := fmt.Sprintf("https://%s%s", wrc.serverIP, config.MutatingWebhookServicePath)
+	url := wrc.debugURL(config.MutatingWebhookServicePath)
 	logger.V(4).Info("Debug MutatingWebhookConfig registered", "url", url)
-	return &admregapi.MutatingWebhookConfiguration{
+	mutating := &admregapi.MutatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
-			Name: config.MutatingWebhookConfigurationDebugName,
+			Name:            config.MutatingWebhookConfigurationDebugName,
+			OwnerReferences: wrc.debugOwnerReferences(),
 		},
 		Webhooks: []admregapi.MutatingWebhook{
 			generateDebugMutatingWebhook(
@@ -38,8 +205,10 @@ func (wrc *Register) constructDefaultDebugMutatingWebhookConfig(caData []byte) *
 				true,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
+				wrc.operations.Mutate,
 				admregapi.Ignore,
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
 			),
 			generateDebugMutatingWebhook(
 				config.MutatingWebhookName+"-fail",
@@ -48,15 +217,20 @@ func (wrc *Register) constructDefaultDebugMutatingWebhookConfig(caData []byte) *
 				true,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
+				wrc.operations.Mutate,
 				admregapi.Fail,
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
 			),
 		},
 	}
+
+	setReinvocationPolicy(mutating, config.WebhookReinvocationPolicy)
+	return mutating
 }
 
 func (wrc *Register) constructDefaultMutatingWebhookConfig(caData []byte) *admregapi.MutatingWebhookConfiguration {
-	return &admregapi.MutatingWebhookConfiguration{
+	mutating := &admregapi.MutatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
 			Name: config.MutatingWebhookConfigurationName,
 			OwnerReferences: []v1.OwnerReference{
@@ -66,26 +240,33 @@ func (wrc *Register) constructDefaultMutatingWebhookConfig(caData []byte) *admre
 		Webhooks: []admregapi.MutatingWebhook{
 			generateMutatingWebhook(
 				config.MutatingWebhookName+"-ignore",
-				config.MutatingWebhookServicePath,
+				wrc.serviceReference(config.MutatingWebhookServicePath),
 				caData,
 				false,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
+				wrc.operations.Mutate,
 				admregapi.Ignore,
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
 			),
 			generateMutatingWebhook(
 				config.MutatingWebhookName+"-fail",
-				config.MutatingWebhookServicePath,
+				wrc.serviceReference(config.MutatingWebhookServicePath),
 				caData,
 				false,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update},
+				wrc.operations.Mutate,
 				admregapi.Fail,
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
 			),
 		},
 	}
+
+	setReinvocationPolicy(mutating, config.WebhookReinvocationPolicy)
+	return mutating
 }
 
 //getResourceMutatingWebhookConfigName returns the webhook configuration name
@@ -96,7 +277,7 @@ func getResourceMutatingWebhookConfigName(serverIP string) string {
 	return config.MutatingWebhookConfigurationName
 }
 
-func (wrc *Register) removeResourceMutatingWebhookConfiguration(wg *sync.WaitGroup) {
+func (wrc *Register) removeResourceMutatingWebhookConfiguration(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	configName := getResourceMutatingWebhookConfigName(wrc.serverIP)
@@ -110,7 +291,7 @@ func (wrc *Register) removeResourceMutatingWebhookConfiguration(wg *sync.WaitGro
 	}
 
 	// delete webhook configuration
-	err := wrc.client.DeleteResource("", kindMutating, "", configName, false)
+	err := wrc.client.WithContext(ctx).DeleteResource("", kindMutating, "", configName, false)
 	if errorsapi.IsNotFound(err) {
 		logger.V(4).Info("webhook configuration not found")
 		return
@@ -125,33 +306,38 @@ func (wrc *Register) removeResourceMutatingWebhookConfiguration(wg *sync.WaitGro
 }
 
 func (wrc *Register) constructDefaultDebugValidatingWebhookConfig(caData []byte) *admregapi.ValidatingWebhookConfiguration {
-	url := fmt.Sprintf("https://%s%s", wrc.serverIP, config.ValidatingWebhookServicePath)
+	url := wrc.debugURL(config.ValidatingWebhookServicePath)
 
 	return &admregapi.ValidatingWebhookConfiguration{
 		ObjectMeta: v1.ObjectMeta{
-			Name: config.ValidatingWebhookConfigurationDebugName,
+			Name:            config.ValidatingWebhookConfigurationDebugName,
+			OwnerReferences: wrc.debugOwnerReferences(),
 		},
 		Webhooks: []admregapi.ValidatingWebhook{
-			generateDebugValidatingWebhook(
+			limitConnectRule(generateDebugValidatingWebhook(
 				config.ValidatingWebhookName+"-ignore",
 				url,
 				caData,
 				true,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect},
+				wrc.operations.Validate,
 				admregapi.Ignore,
-			),
-			generateDebugValidatingWebhook(
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
+			)),
+			limitConnectRule(generateDebugValidatingWebhook(
 				config.ValidatingWebhookName+"-fail",
 				url,
 				caData,
 				true,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect},
+				wrc.operations.Validate,
 				admregapi.Fail,
-			),
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
+			)),
 		},
 	}
 }
@@ -165,30 +351,74 @@ func (wrc *Register) constructDefaultValidatingWebhookConfig(caData []byte) *adm
 			},
 		},
 		Webhooks: []admregapi.ValidatingWebhook{
-			generateValidatingWebhook(
+			limitConnectRule(generateValidatingWebhook(
 				config.ValidatingWebhookName+"-ignore",
-				config.ValidatingWebhookServicePath,
+				wrc.serviceReference(config.ValidatingWebhookServicePath),
 				caData,
 				false,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect},
+				wrc.operations.Validate,
 				admregapi.Ignore,
-			),
-			generateValidatingWebhook(
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
+			)),
+			limitConnectRule(generateValidatingWebhook(
 				config.ValidatingWebhookName+"-fail",
-				config.ValidatingWebhookServicePath,
+				wrc.serviceReference(config.ValidatingWebhookServicePath),
 				caData,
 				false,
 				wrc.timeoutSeconds,
 				wrc.defaultResourceWebhookRule(),
-				[]admregapi.OperationType{admregapi.Create, admregapi.Update, admregapi.Delete, admregapi.Connect},
+				wrc.operations.Validate,
 				admregapi.Fail,
-			),
+				wrc.resourceNamespaceSelector(),
+				wrc.exclusions.objectSelector(),
+			)),
 		},
 	}
 }
 
+// connectResources are the subresources the default validating webhooks receive the CONNECT requests of,
+// the CONNECT requests of the other subresources, e.g. pods/portforward or nodes/proxy, are not intercepted
+var connectResources = []string{"pods/attach", "pods/exec"}
+
+// limitConnectRule moves the CONNECT operation of the wildcard rule of a validating webhook
+// to a separate rule for connectResources
+func limitConnectRule(w admregapi.ValidatingWebhook) admregapi.ValidatingWebhook {
+	var rules []admregapi.RuleWithOperations
+	for _, rule := range w.Rules {
+		var ops []admregapi.OperationType
+		for _, op := range rule.Operations {
+			if op != admregapi.Connect {
+				ops = append(ops, op)
+			}
+		}
+
+		if len(ops) == len(rule.Operations) || !utils.ContainsString(rule.Resources, "*/*") {
+			rules = append(rules, rule)
+			continue
+		}
+
+		if len(ops) > 0 {
+			rule.Operations = ops
+			rules = append(rules, rule)
+		}
+
+		rules = append(rules, admregapi.RuleWithOperations{
+			Operations: []admregapi.OperationType{admregapi.Connect},
+			Rule: admregapi.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   connectResources,
+			},
+		})
+	}
+
+	w.Rules = rules
+	return w
+}
+
 // getResourceValidatingWebhookConfigName returns the webhook configuration name
 func getResourceValidatingWebhookConfigName(serverIP string) string {
 	if serverIP != "" {
@@ -198,7 +428,7 @@ func getResourceValidatingWebhookConfigName(serverIP string) string {
 	return config.ValidatingWebhookConfigurationName
 }
 
-func (wrc *Register) removeResourceValidatingWebhookConfiguration(wg *sync.WaitGroup) {
+func (wrc *Register) removeResourceValidatingWebhookConfiguration(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	configName := getResourceValidatingWebhookConfigName(wrc.serverIP)
@@ -211,7 +441,7 @@ func (wrc *Register) removeResourceValidatingWebhookConfiguration(wg *sync.WaitG
 		}
 	}
 
-	err := wrc.client.DeleteResource("", kindValidating, "", configName, false)
+	err := wrc.client.WithContext(ctx).DeleteResource("", kindValidating, "", configName, false)
 	if errorsapi.IsNotFound(err) {
 		logger.V(5).Info("webhook configuration not found")
 		return
@@ -0,0 +1,88 @@
+package webhookconfig
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	backoff "github.com/cenkalti/backoff"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// webhookRetry bounds the retries of the webhook configuration requests on transient API errors
+type webhookRetry struct {
+	// maxAttempts is the maximum number of attempts, including the first one
+	maxAttempts uint64
+
+	// initialDelay is the delay before the first retry, it grows exponentially up to maxDelay
+	initialDelay time.Duration
+
+	// maxDelay is the maximum delay between two attempts
+	maxDelay time.Duration
+}
+
+// defaultWebhookRetry retries for about 15 seconds, e.g. while the API server is rolled out
+var defaultWebhookRetry = webhookRetry{
+	maxAttempts:  6,
+	initialDelay: 500 * time.Millisecond,
+	maxDelay:     5 * time.Second,
+}
+
+func (r webhookRetry) backOff() backoff.BackOff {
+	attempts := r.maxAttempts
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	exbackoff := &backoff.ExponentialBackOff{
+		InitialInterval:     r.initialDelay,
+		RandomizationFactor: 0.5,
+		Multiplier:          2,
+		MaxInterval:         r.maxDelay,
+		Clock:               backoff.SystemClock,
+	}
+	return backoff.WithMaxRetries(exbackoff, attempts-1)
+}
+
+// retryWebhookRequest runs request until it succeeds, fails with an error that is not retryable
+// or the maximum number of attempts is reached, the last error is returned.
+// No retry is attempted once ctx is done.
+func (wrc *Register) retryWebhookRequest(ctx context.Context, kind, name string, request func() error) error {
+	logger := wrc.log.WithValues("kind", kind, "name", name)
+	attempt := 0
+
+	operation := func() error {
+		attempt++
+		err := request()
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryableError(err) {
+			return backoff.Permanent(err)
+		}
+
+		logger.V(3).Info("request failed with a transient error, retrying", "attempt", attempt, "error", err.Error())
+		return err
+	}
+
+	return backoff.Retry(operation, backoff.WithContext(wrc.retry.backOff(), ctx))
+}
+
+// isRetryableError returns true for the errors caused by an unavailable or overloaded API server,
+// and for conflicts which are resolved by fetching the latest resourceVersion.
+// AlreadyExists, validation and authorization errors are not retried.
+func isRetryableError(err error) bool {
+	var status errorsapi.APIStatus
+	if !errors.As(err, &status) {
+		return utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+	}
+
+	return errorsapi.IsServerTimeout(err) ||
+		errorsapi.IsTimeout(err) ||
+		errorsapi.IsTooManyRequests(err) ||
+		errorsapi.IsServiceUnavailable(err) ||
+		errorsapi.IsInternalError(err) ||
+		errorsapi.IsConflict(err)
+}
@@ -0,0 +1,156 @@
+package webhookconfig
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"gotest.tools/assert"
+	errorsapi "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var testWebhookRetry = webhookRetry{
+	maxAttempts:  5,
+	initialDelay: time.Millisecond,
+	maxDelay:     10 * time.Millisecond,
+}
+
+// failCreates makes the first n create requests of the webhook configurations fail with err
+func failCreates(wrc *Register, n int, err error) *int {
+	creates := 0
+	fakeClient := wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient)
+	fakeClient.PrependReactor("create", "mutatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates++
+		if creates <= n {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &creates
+}
+
+func TestCreateOrUpdateWebhookConfiguration_RetryTransientError(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.retry = testWebhookRetry
+	creates := failCreates(wrc, 2, errorsapi.NewServiceUnavailable("API server is restarting"))
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	action, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, action, webhookCreated)
+	assert.Equal(t, *creates, 3)
+
+	_, err = wrc.client.GetResource("", kindMutating, "", webhookConfig.Name)
+	assert.NilError(t, err)
+}
+
+func TestCreateOrUpdateWebhookConfiguration_RetryExhausted(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.retry = testWebhookRetry
+	creates := failCreates(wrc, 10, errorsapi.NewTimeoutError("request timed out", 1))
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	_, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+	assert.Assert(t, errorsapi.IsTimeout(err))
+	assert.Equal(t, *creates, 5)
+}
+
+func TestCreateOrUpdateWebhookConfiguration_RetryCancelled(t *testing.T) {
+	wrc := newTestRegister(newWebhookMockClient(t))
+	wrc.retry = testWebhookRetry
+	creates := failCreates(wrc, 10, errorsapi.NewServiceUnavailable("API server is restarting"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	_, err := wrc.createOrUpdateWebhookConfiguration(ctx, kindMutating, webhookConfig)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, *creates, 1)
+}
+
+func TestCreateOrUpdateWebhookConfiguration_NoRetry(t *testing.T) {
+	gr := schema.GroupResource{Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"}
+	gk := schema.GroupKind{Group: "admissionregistration.k8s.io", Kind: kindMutating}
+
+	testcases := []struct {
+		name string
+		err  error
+	}{
+		{name: "invalid", err: errorsapi.NewInvalid(gk, "kyverno", field.ErrorList{field.Required(field.NewPath("webhooks"), "")})},
+		{name: "forbidden", err: errorsapi.NewForbidden(gr, "kyverno", fmt.Errorf("denied"))},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrc := newTestRegister(newWebhookMockClient(t))
+			wrc.retry = testWebhookRetry
+			creates := failCreates(wrc, 10, tc.err)
+
+			webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+			_, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+			assert.Assert(t, err != nil)
+			assert.Equal(t, errorsapi.ReasonForError(err), errorsapi.ReasonForError(tc.err))
+			assert.Equal(t, *creates, 1)
+		})
+	}
+}
+
+func TestCreateOrUpdateWebhookConfiguration_AlreadyExists(t *testing.T) {
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion("admissionregistration.k8s.io/v1")
+	existing.SetKind(kindMutating)
+	existing.SetName(config.MutatingWebhookConfigurationDebugName)
+	existing.SetResourceVersion("7")
+
+	wrc := newTestRegister(newWebhookMockClient(t, existing))
+
+	// the first get misses the configuration, e.g. created concurrently, so that the create fails with AlreadyExists
+	gets := 0
+	fakeClient := wrc.client.GetDynamicInterface().(*fake.FakeDynamicClient)
+	fakeClient.PrependReactor("get", "mutatingwebhookconfigurations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets == 1 {
+			return true, nil, errorsapi.NewNotFound(schema.GroupResource{Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"}, existing.GetName())
+		}
+		return false, nil, nil
+	})
+
+	webhookConfig := wrc.constructDefaultDebugMutatingWebhookConfig([]byte(cert))
+	action, err := wrc.createOrUpdateWebhookConfiguration(context.TODO(), kindMutating, webhookConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, action, webhookUpdated)
+	assert.Equal(t, gets, 2)
+
+	var verbs []string
+	for _, a := range fakeClient.Actions() {
+		verbs = append(verbs, a.GetVerb())
+	}
+	assert.DeepEqual(t, verbs, []string{"get", "create", "get", "update"})
+
+	updated, err := wrc.client.GetResource("", kindMutating, "", webhookConfig.Name)
+	assert.NilError(t, err)
+	webhooks, _, err := unstructured.NestedSlice(updated.UnstructuredContent(), "webhooks")
+	assert.NilError(t, err)
+	assert.Equal(t, len(webhooks), 2)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	gr := schema.GroupResource{Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"}
+
+	assert.Assert(t, isRetryableError(errorsapi.NewServiceUnavailable("unavailable")))
+	assert.Assert(t, isRetryableError(errorsapi.NewTooManyRequests("slow down", 1)))
+	assert.Assert(t, isRetryableError(errorsapi.NewConflict(gr, "kyverno", fmt.Errorf("modified"))))
+	assert.Assert(t, isRetryableError(fmt.Errorf("failed to create: %w", errorsapi.NewInternalError(fmt.Errorf("etcd")))))
+	assert.Assert(t, !isRetryableError(errorsapi.NewAlreadyExists(gr, "kyverno")))
+	assert.Assert(t, !isRetryableError(errorsapi.NewBadRequest("bad")))
+	assert.Assert(t, !isRetryableError(fmt.Errorf("unable to create resource ")))
+}
@@ -1,26 +1,55 @@
 package webhookconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
-	"github.com/kyverno/kyverno/pkg/config"
 	"github.com/kyverno/kyverno/pkg/event"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-var deployName string = config.KyvernoDeploymentName
-var deployNamespace string = config.KyvernoNamespace
+const (
+	annCounter             string = "kyverno.io/generationCounter"
+	annWebhookStatus       string = "kyverno.io/webhookActive"
+	annLastRequestTime     string = "kyverno.io/last-request-time"
+	annWebhookRegistration string = "kyverno.io/webhook-registration"
+)
 
+// WebhookRegisteredCondition is the type of the webhook registration condition
+const WebhookRegisteredCondition string = "WebhookRegistered"
+
+// reasons of the webhook registration condition and events
 const (
-	annCounter         string = "kyverno.io/generationCounter"
-	annWebhookStatus   string = "kyverno.io/webhookActive"
-	annLastRequestTime string = "kyverno.io/last-request-time"
+	reasonRegistered         string = "Registered"
+	reasonRegistrationFailed string = "RegistrationFailed"
+	reasonCAExpiringSoon     string = "CAExpiringSoon"
 )
 
+// RegistrationCondition records the result of the latest webhook registration,
+// it is stored as JSON in the annotation kyverno.io/webhook-registration of the Kyverno deployment
+type RegistrationCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the error of a failed registration, or the warning of a registration with a CA about to expire
+	Message string `json:"message,omitempty"`
+
+	// CABundleFingerprint is the SHA-256 fingerprint of the CA bundle set on the webhooks
+	CABundleFingerprint string `json:"caBundleFingerprint,omitempty"`
+
+	// CAExpiry is the expiry time of the CA bundle in RFC 3339 format
+	CAExpiry string `json:"caExpiry,omitempty"`
+
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
 //statusControl controls the webhook status
 type statusControl struct {
 	register *Register
@@ -48,6 +77,7 @@ func newStatusControl(register *Register, eventGen event.Interface, log logr.Log
 }
 
 func (vc statusControl) setStatus(status string) error {
+	deployName, deployNamespace := vc.register.deploymentName, vc.register.serviceNamespace
 	logger := vc.log.WithValues("name", deployName, "namespace", deployNamespace)
 	var ann map[string]string
 	var err error
@@ -83,11 +113,11 @@ func (vc statusControl) setStatus(status string) error {
 	}
 
 	// create event on kyverno deployment
-	createStatusUpdateEvent(status, vc.eventGen)
+	createStatusUpdateEvent(status, deployNamespace, deployName, vc.eventGen)
 	return nil
 }
 
-func createStatusUpdateEvent(status string, eventGen event.Interface) {
+func createStatusUpdateEvent(status, deployNamespace, deployName string, eventGen event.Interface) {
 	e := event.Info{}
 	e.Kind = "Deployment"
 	e.Namespace = deployNamespace
@@ -99,6 +129,7 @@ func createStatusUpdateEvent(status string, eventGen event.Interface) {
 
 //IncrementAnnotation ...
 func (vc statusControl) IncrementAnnotation() error {
+	deployName, deployNamespace := vc.register.deploymentName, vc.register.serviceNamespace
 	logger := vc.log
 	var ann map[string]string
 	var err error
@@ -169,3 +200,118 @@ func (vc statusControl) UpdateLastRequestTimestmap(new time.Time) error {
 
 	return nil
 }
+
+// updateRegistrationCondition records the result of Register in the registration condition
+func (wrc *Register) updateRegistrationCondition(caData []byte, caWarning string, regErr error) {
+	if regErr != nil {
+		wrc.setRegistrationCondition(false, reasonRegistrationFailed, regErr.Error(), caData)
+		return
+	}
+
+	if caWarning != "" {
+		wrc.setRegistrationCondition(true, reasonCAExpiringSoon, caWarning, caData)
+		return
+	}
+	wrc.setRegistrationCondition(true, reasonRegistered, "", caData)
+}
+
+// recordRegistrationEvent emits an event on the Kyverno deployment with the result of Register,
+// a Warning event with the failure reason or a Normal event on success
+func (wrc *Register) recordRegistrationEvent(regErr error) {
+	deployName, deployNamespace := wrc.deploymentName, wrc.serviceNamespace
+	if wrc.eventRecorder == nil {
+		return
+	}
+
+	logger := wrc.log.WithName("recordRegistrationEvent").WithValues("name", deployName, "namespace", deployNamespace)
+	deploy, err := wrc.client.GetResource("", "Deployment", deployNamespace, deployName)
+	if err != nil {
+		logger.Error(err, "failed to get deployment")
+		return
+	}
+
+	if regErr != nil {
+		wrc.eventRecorder.Eventf(deploy, corev1.EventTypeWarning, reasonRegistrationFailed, "failed to register webhook configurations: %v", regErr)
+		return
+	}
+	wrc.eventRecorder.Event(deploy, corev1.EventTypeNormal, reasonRegistered, "webhook configurations registered")
+}
+
+func (wrc *Register) setRegistrationCondition(registered bool, reason, message string, caData []byte) {
+	deployName, deployNamespace := wrc.deploymentName, wrc.serviceNamespace
+	logger := wrc.log.WithName("setRegistrationCondition").WithValues("name", deployName, "namespace", deployNamespace)
+
+	condition := RegistrationCondition{
+		Type:           WebhookRegisteredCondition,
+		Status:         "False",
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	}
+	if registered {
+		condition.Status = "True"
+	}
+
+	if len(caData) != 0 {
+		condition.CABundleFingerprint = caFingerprint(caData)
+		if expiry, err := caExpiry(caData); err == nil {
+			condition.CAExpiry = expiry.UTC().Format(time.RFC3339)
+		}
+	}
+
+	deploy, err := wrc.client.GetResource("", "Deployment", deployNamespace, deployName)
+	if err != nil {
+		logger.Error(err, "failed to get deployment")
+		return
+	}
+
+	ann := deploy.GetAnnotations()
+	if ann == nil {
+		ann = map[string]string{}
+	}
+
+	if current, ok := ann[annWebhookRegistration]; ok {
+		var existing RegistrationCondition
+		if err := json.Unmarshal([]byte(current), &existing); err == nil {
+			existing.LastUpdateTime = condition.LastUpdateTime
+			if existing == condition {
+				return
+			}
+		}
+	}
+
+	raw, err := json.Marshal(condition)
+	if err != nil {
+		logger.Error(err, "failed to marshal webhook registration condition")
+		return
+	}
+
+	ann[annWebhookRegistration] = string(raw)
+	deploy.SetAnnotations(ann)
+	if _, err := wrc.client.UpdateResource("", "Deployment", deployNamespace, deploy, false); err != nil {
+		logger.Error(err, "failed to update webhook registration condition", "key", annWebhookRegistration)
+		return
+	}
+
+	logger.V(3).Info("updated webhook registration condition", "status", condition.Status, "reason", condition.Reason)
+}
+
+// GetRegistrationCondition returns the condition of the latest webhook registration
+func (wrc *Register) GetRegistrationCondition() (*RegistrationCondition, error) {
+	deploy, err := wrc.client.GetResource("", "Deployment", wrc.serviceNamespace, wrc.deploymentName)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get Kyverno deployment")
+	}
+
+	raw, ok := deploy.GetAnnotations()[annWebhookRegistration]
+	if !ok {
+		return nil, errors.Errorf("annotation %s not found", annWebhookRegistration)
+	}
+
+	var condition RegistrationCondition
+	if err := json.Unmarshal([]byte(raw), &condition); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal annotation %s", annWebhookRegistration)
+	}
+
+	return &condition, nil
+}
@@ -0,0 +1,64 @@
+package webhookconfig
+
+import (
+	"time"
+
+	backoff "github.com/cenkalti/backoff"
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	"github.com/kyverno/kyverno/pkg/metrics/watcherretries"
+)
+
+// watcherMaxBackoff caps the delay of the watcher retries after consecutive failures
+const watcherMaxBackoff = 5 * time.Minute
+
+// watcherBackOff returns the delays of the watcher retries, starting at initial and doubling after each
+// consecutive failure up to max. The delays are randomized by 50% so that the Kyverno instances do not
+// retry in sync, and the retries never stop.
+func watcherBackOff(initial, max time.Duration) backoff.BackOff {
+	exbackoff := &backoff.ExponentialBackOff{
+		InitialInterval:     initial,
+		RandomizationFactor: 0.5,
+		Multiplier:          2,
+		MaxInterval:         max,
+		Clock:               backoff.SystemClock,
+	}
+	exbackoff.Reset()
+	return exbackoff
+}
+
+// runWatcher runs check every interval until stopCh is closed. After a failure the next check is delayed
+// by the backoff instead and onRetry is invoked with the delay, the interval is restored after a success.
+// The loop returns as soon as stopCh is closed, including while waiting for a retry.
+func runWatcher(stopCh <-chan struct{}, interval time.Duration, retry backoff.BackOff, check func() error, onRetry func(err error, delay time.Duration)) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			delay := interval
+			if err := check(); err != nil {
+				delay = retry.NextBackOff()
+				onRetry(err, delay)
+			} else {
+				retry.Reset()
+			}
+			timer.Reset(delay)
+
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// recordWatcherRetry counts a retry of the watcher, the metrics are disabled if promConfig is nil
+func recordWatcherRetry(log logr.Logger, promConfig *metrics.PromConfig, watcher watcherretries.Watcher) {
+	if promConfig == nil {
+		return
+	}
+
+	if err := watcherretries.ParsePromConfig(*promConfig).ProcessRetry(watcher); err != nil {
+		log.Error(err, "error occurred while registering kyverno_watcher_retries_total metrics")
+	}
+}
@@ -0,0 +1,137 @@
+package webhookconfig
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	backoff "github.com/cenkalti/backoff"
+	"gotest.tools/assert"
+)
+
+// stepBackOff returns delays growing by a millisecond after each failure
+type stepBackOff struct {
+	steps int
+}
+
+func (b *stepBackOff) NextBackOff() time.Duration {
+	b.steps++
+	return time.Duration(b.steps) * time.Millisecond
+}
+
+func (b *stepBackOff) Reset() {
+	b.steps = 0
+}
+
+// startWatcher runs the watcher until the returned stop function is called,
+// the stop function waits for the watcher to return
+func startWatcher(interval time.Duration, retry backoff.BackOff, check func() error, onRetry func(err error, delay time.Duration)) (stop func()) {
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runWatcher(stopCh, interval, retry, check, onRetry)
+	}()
+
+	return func() {
+		close(stopCh)
+		<-done
+	}
+}
+
+func Test_runWatcher_BoundedRetries(t *testing.T) {
+	var mu sync.Mutex
+	var checks int
+	var delays []time.Duration
+
+	check := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		checks++
+		return errors.New("the server is currently unable to handle the request")
+	}
+	onRetry := func(err error, delay time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		delays = append(delays, delay)
+	}
+
+	// without the backoff the check would run about 100 times
+	stop := startWatcher(5*time.Millisecond, watcherBackOff(5*time.Millisecond, 40*time.Millisecond), check, onRetry)
+	time.Sleep(500 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Assert(t, checks >= 3, "checks: %d", checks)
+	assert.Assert(t, checks <= 30, "checks: %d", checks)
+	assert.Equal(t, len(delays), checks)
+	for _, delay := range delays {
+		// the randomization adds up to 50% to the capped delay
+		assert.Assert(t, delay <= 60*time.Millisecond, "delay: %v", delay)
+	}
+	assert.Assert(t, delays[len(delays)-1] >= 20*time.Millisecond, "delay: %v", delays[len(delays)-1])
+}
+
+func Test_runWatcher_StopDuringBackoff(t *testing.T) {
+	retrying := make(chan struct{})
+	var once sync.Once
+
+	check := func() error {
+		return errors.New("connection refused")
+	}
+	onRetry := func(err error, delay time.Duration) {
+		once.Do(func() { close(retrying) })
+	}
+
+	stop := startWatcher(time.Millisecond, watcherBackOff(time.Hour, time.Hour), check, onRetry)
+	<-retrying
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher did not stop while waiting for a retry")
+	}
+}
+
+func Test_runWatcher_ResetAfterSuccess(t *testing.T) {
+	results := []error{errors.New("timeout"), errors.New("timeout"), nil, errors.New("timeout")}
+
+	var mu sync.Mutex
+	var delays []time.Duration
+	completed := make(chan struct{})
+
+	check := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(results) == 0 {
+			return nil
+		}
+		err := results[0]
+		results = results[1:]
+		if len(results) == 0 {
+			close(completed)
+		}
+		return err
+	}
+	onRetry := func(err error, delay time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		delays = append(delays, delay)
+	}
+
+	stop := startWatcher(time.Millisecond, &stepBackOff{}, check, onRetry)
+	<-completed
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.DeepEqual(t, delays, []time.Duration{time.Millisecond, 2 * time.Millisecond, time.Millisecond})
+}
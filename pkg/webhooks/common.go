@@ -1,12 +1,15 @@
 package webhooks
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/go-logr/logr"
 	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
 	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine"
 	"github.com/kyverno/kyverno/pkg/engine/response"
 	engineutils "github.com/kyverno/kyverno/pkg/engine/utils"
 	yamlv2 "gopkg.in/yaml.v2"
@@ -29,22 +32,59 @@ func isResponseSuccessful(engineReponses []*response.EngineResponse) bool {
 // returns false -> if all the policies are meant to report only, we dont block resource request
 func toBlockResource(engineReponses []*response.EngineResponse, log logr.Logger) bool {
 	for _, er := range engineReponses {
-		if !er.IsSuccessful() && er.PolicyResponse.ValidationFailureAction == common.Enforce {
+		if blocksResource(er) {
 			log.Info("spec.ValidationFailureAction set to enforce blocking resource request", "policy", er.PolicyResponse.Policy.Name)
 			return true
 		}
+
+		for _, rule := range er.PolicyResponse.Rules {
+			if rule.Error != nil {
+				log.Error(rule.Error, "failed to apply rule", "failurePolicy", er.PolicyResponse.FailurePolicy)
+			}
+		}
 	}
 
 	log.V(4).Info("spec.ValidationFailureAction set to audit for all applicable policies, won't block resource operation")
 	return false
 }
 
+// blocksResource returns true if an enforce policy failed, or could not be applied and
+// its failure policy does not ignore the errors
+func blocksResource(er *response.EngineResponse) bool {
+	if er.IsSuccessful() || er.PolicyResponse.ValidationFailureAction != common.Enforce {
+		return false
+	}
+
+	return !ignoresRuleErrors(er)
+}
+
+// ignoresRuleErrors returns true if the failure policy of the policy is Ignore and no rule failed,
+// i.e. the unsuccessful rules could not be evaluated or have an invalid pattern
+func ignoresRuleErrors(er *response.EngineResponse) bool {
+	if er.PolicyResponse.FailurePolicy != string(kyverno.Ignore) {
+		return false
+	}
+
+	for _, rule := range er.PolicyResponse.Rules {
+		switch rule.Status {
+		case response.RuleStatusFail:
+			return false
+		case response.RuleStatusError:
+			if !errors.Is(rule.Error, engine.ErrRuleEvaluation) && !errors.Is(rule.Error, engine.ErrPatternMatch) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 // getEnforceFailureErrorMsg gets the error messages for failed enforce policy
 func getEnforceFailureErrorMsg(engineResponses []*response.EngineResponse) string {
 	policyToRule := make(map[string]interface{})
 	var resourceName string
 	for _, er := range engineResponses {
-		if !er.IsSuccessful() && er.PolicyResponse.ValidationFailureAction == common.Enforce {
+		if blocksResource(er) {
 			ruleToReason := make(map[string]string)
 			for _, rule := range er.PolicyResponse.Rules {
 				if rule.Status != response.RuleStatusPass {
@@ -61,6 +101,37 @@ func getEnforceFailureErrorMsg(engineResponses []*response.EngineResponse) strin
 	return "\n\nresource " + resourceName + " was blocked due to the following policies\n\n" + string(result)
 }
 
+// getWarnings returns a warning for each rule of the warn policies that did not pass, in the
+// order the policies were applied. The messages of the rules are the ones with the variables
+// substituted, the duplicates are returned once.
+func getWarnings(engineResponses []*response.EngineResponse) []string {
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, er := range engineResponses {
+		if er.IsSuccessful() || er.PolicyResponse.ValidationFailureAction != common.Warn {
+			continue
+		}
+
+		policyName := er.PolicyResponse.Policy.Name
+		if er.PolicyResponse.Policy.Namespace != "" {
+			policyName = er.PolicyResponse.Policy.Namespace + "/" + policyName
+		}
+
+		for _, rule := range er.PolicyResponse.Rules {
+			if rule.Status != response.RuleStatusFail && rule.Status != response.RuleStatusError {
+				continue
+			}
+
+			warning := fmt.Sprintf("policy %s.%s: %s", policyName, rule.Name, rule.Message)
+			if !seen[warning] {
+				seen[warning] = true
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+	return warnings
+}
+
 // getErrorMsg gets all failed engine response message
 func getErrorMsg(engineReponses []*response.EngineResponse) string {
 	var str []string
@@ -184,3 +255,12 @@ func excludeKyvernoResources(kind string) bool {
 		return false
 	}
 }
+
+// excludedUsername returns the username of the Kyverno service account if its requests are excluded
+// from the validate rules, or an empty string
+func excludedUsername() string {
+	if !config.ExcludeKyvernoServiceAccount {
+		return ""
+	}
+	return config.KyvernoUsername()
+}
@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/engine"
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"gotest.tools/assert"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newValidationResponse(policy, action string, status response.RuleStatus) *response.EngineResponse {
+	return &response.EngineResponse{
+		PolicyResponse: response.PolicyResponse{
+			Policy:                  response.PolicySpec{Name: policy},
+			Resource:                response.ResourceSpec{Kind: "Pod", Namespace: "default", Name: "nginx"},
+			ValidationFailureAction: action,
+			Rules: []response.RuleResponse{
+				{
+					Name:    "validate-image-tag",
+					Type:    "Validation",
+					Message: "using a mutable image tag is not allowed",
+					Status:  status,
+				},
+			},
+		},
+	}
+}
+
+func Test_toBlockResource(t *testing.T) {
+	testCases := []struct {
+		name      string
+		responses []*response.EngineResponse
+		block     bool
+	}{
+		{
+			name:      "audit failure is allowed",
+			responses: []*response.EngineResponse{newValidationResponse("audit-policy", common.Audit, response.RuleStatusFail)},
+			block:     false,
+		},
+		{
+			name:      "enforce failure is denied",
+			responses: []*response.EngineResponse{newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusFail)},
+			block:     true,
+		},
+		{
+			name:      "enforce pass is allowed",
+			responses: []*response.EngineResponse{newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusPass)},
+			block:     false,
+		},
+		{
+			name: "enforce failure is denied alongside audit failure",
+			responses: []*response.EngineResponse{
+				newValidationResponse("audit-policy", common.Audit, response.RuleStatusFail),
+				newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusFail),
+			},
+			block: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, toBlockResource(tc.responses, log.Log), tc.block)
+		})
+	}
+}
+
+func Test_toBlockResource_FailurePolicy(t *testing.T) {
+	newErrorResponse := func(failurePolicy string, errType error) *response.EngineResponse {
+		er := newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusError)
+		er.PolicyResponse.FailurePolicy = failurePolicy
+		er.PolicyResponse.Rules[0].Error = &engine.RuleError{Type: errType, Policy: "enforce-policy", Rule: "validate-image-tag", Err: errors.New("failed to load context")}
+		return er
+	}
+
+	testCases := []struct {
+		name      string
+		responses []*response.EngineResponse
+		block     bool
+	}{
+		{
+			name:      "evaluation error is ignored",
+			responses: []*response.EngineResponse{newErrorResponse(string(kyverno.Ignore), engine.ErrRuleEvaluation)},
+			block:     false,
+		},
+		{
+			name:      "pattern error is ignored",
+			responses: []*response.EngineResponse{newErrorResponse(string(kyverno.Ignore), engine.ErrPatternMatch)},
+			block:     false,
+		},
+		{
+			name:      "evaluation error is denied with the Fail failure policy",
+			responses: []*response.EngineResponse{newErrorResponse(string(kyverno.Fail), engine.ErrRuleEvaluation)},
+			block:     true,
+		},
+		{
+			name:      "evaluation error is denied without a failure policy",
+			responses: []*response.EngineResponse{newErrorResponse("", engine.ErrRuleEvaluation)},
+			block:     true,
+		},
+		{
+			name:      "patch error is denied",
+			responses: []*response.EngineResponse{newErrorResponse(string(kyverno.Ignore), engine.ErrPatchApply)},
+			block:     true,
+		},
+		{
+			name: "rule failure is denied with the Ignore failure policy",
+			responses: []*response.EngineResponse{func() *response.EngineResponse {
+				er := newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusFail)
+				er.PolicyResponse.FailurePolicy = string(kyverno.Ignore)
+				return er
+			}()},
+			block: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, toBlockResource(tc.responses, log.Log), tc.block)
+			assert.Equal(t, strings.Contains(getEnforceFailureErrorMsg(tc.responses), "enforce-policy"), tc.block)
+		})
+	}
+}
+
+func Test_getEnforceFailureErrorMsg(t *testing.T) {
+	responses := []*response.EngineResponse{
+		newValidationResponse("audit-policy", common.Audit, response.RuleStatusFail),
+		newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusFail),
+	}
+
+	msg := getEnforceFailureErrorMsg(responses)
+	assert.Assert(t, strings.Contains(msg, "resource Pod/default/nginx was blocked"))
+	assert.Assert(t, strings.Contains(msg, "enforce-policy"))
+	assert.Assert(t, strings.Contains(msg, "using a mutable image tag is not allowed"))
+	assert.Assert(t, !strings.Contains(msg, "audit-policy"))
+}
+
+func Test_getWarnings(t *testing.T) {
+	nsPolicy := newValidationResponse("warn-policy", common.Warn, response.RuleStatusFail)
+	nsPolicy.PolicyResponse.Policy.Namespace = "default"
+
+	responses := []*response.EngineResponse{
+		newValidationResponse("warn-policy", common.Warn, response.RuleStatusFail),
+		newValidationResponse("warn-policy", common.Warn, response.RuleStatusFail),
+		newValidationResponse("passing-warn-policy", common.Warn, response.RuleStatusPass),
+		newValidationResponse("audit-policy", common.Audit, response.RuleStatusFail),
+		newValidationResponse("enforce-policy", common.Enforce, response.RuleStatusFail),
+		nsPolicy,
+	}
+
+	assert.DeepEqual(t, getWarnings(responses), []string{
+		"policy warn-policy.validate-image-tag: using a mutable image tag is not allowed",
+		"policy default/warn-policy.validate-image-tag: using a mutable image tag is not allowed",
+	})
+
+	assert.Assert(t, getWarnings(responses[2:5]) == nil)
+}
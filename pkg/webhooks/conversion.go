@@ -0,0 +1,58 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// handleConversion converts the policies of a ConversionReview request to the desired API version
+func (ws *WebhookServer) handleConversion(rw http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	logger := ws.log.WithName("ConversionWebhook")
+
+	if r.Body == nil {
+		http.Error(rw, "empty body", http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(rw, "failed to read HTTP body", http.StatusBadRequest)
+		return
+	}
+
+	review := &apiextv1.ConversionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		logger.Info("failed to decode conversion review", "error", err)
+		http.Error(rw, "Can't decode body as ConversionReview", http.StatusBadRequest)
+		return
+	}
+
+	logger = logger.WithValues("uid", review.Request.UID, "desiredAPIVersion", review.Request.DesiredAPIVersion, "objects", len(review.Request.Objects))
+	review.Response = ws.converter.Review(review.Request)
+	review.Request = nil
+	if review.Response.Result.Status != metav1.StatusSuccess {
+		logger.Info("failed to convert policies", "reason", review.Response.Result.Message)
+	}
+
+	responseJSON, err := json.Marshal(review)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("Could not encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := rw.Write(responseJSON); err != nil {
+		http.Error(rw, fmt.Sprintf("could not write response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.V(4).Info("conversion review request processed", "time", time.Since(startTime).String())
+}
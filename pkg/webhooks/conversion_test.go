@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/conversion"
+	"gotest.tools/assert"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_handleConversion(t *testing.T) {
+	ws := &WebhookServer{converter: conversion.NewConverter(), log: logr.DiscardLogger{}}
+	policy := `{"apiVersion":"kyverno.io/v1","kind":"ClusterPolicy","metadata":{"name":"require-labels"},"spec":{"validationFailureAction":"audit"}}`
+	body := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"1","desiredAPIVersion":"kyverno.io/v1","objects":[` + policy + `]}}`
+
+	rw := httptest.NewRecorder()
+	ws.handleConversion(rw, httptest.NewRequest(http.MethodPost, config.ConversionWebhookServicePath, bytes.NewBufferString(body)))
+	assert.Equal(t, rw.Code, http.StatusOK)
+
+	review := &apiextv1.ConversionReview{}
+	assert.NilError(t, json.Unmarshal(rw.Body.Bytes(), review))
+	assert.Assert(t, review.Request == nil)
+	assert.Equal(t, string(review.Response.UID), "1")
+	assert.Equal(t, review.Response.Result.Status, metav1.StatusSuccess)
+	assert.Equal(t, len(review.Response.ConvertedObjects), 1)
+	assert.Equal(t, string(review.Response.ConvertedObjects[0].Raw), policy)
+}
+
+func Test_handleConversion_InvalidBody(t *testing.T) {
+	ws := &WebhookServer{converter: conversion.NewConverter(), log: logr.DiscardLogger{}}
+
+	rw := httptest.NewRecorder()
+	ws.handleConversion(rw, httptest.NewRequest(http.MethodPost, config.ConversionWebhookServicePath, bytes.NewBufferString(`{"kind":"ConversionReview"}`)))
+	assert.Equal(t, rw.Code, http.StatusBadRequest)
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/kyverno/kyverno/pkg/webhookconfig"
+)
+
+// debugState is the JSON document served by the debug endpoint
+type debugState struct {
+	Webhooks webhookconfig.DebugState `json:"webhooks"`
+
+	// PolicyCache lists the cached policies by kind and policy type
+	PolicyCache map[string]map[string][]string `json:"policyCache"`
+}
+
+// handleDebug serves the registered webhook configurations, the policy cache and the CA fingerprint.
+// Only the requests from localhost are served, e.g. through kubectl port-forward, as the webhook
+// port is reachable from the cluster network.
+func (ws *WebhookServer) handleDebug(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !isLoopback(r.RemoteAddr) {
+		ws.log.Info("debug request rejected, only the requests from localhost are served", "remoteAddr", r.RemoteAddr)
+		http.Error(w, "the debug endpoint only serves the requests from localhost", http.StatusForbidden)
+		return
+	}
+
+	registration, err := ws.webhookRegister.DebugState()
+	if err != nil {
+		ws.log.Error(err, "failed to build the debug state of the webhooks")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	state := debugState{Webhooks: registration, PolicyCache: ws.pCache.Dump()}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(raw); err != nil {
+		ws.log.Error(err, "failed to write the debug state")
+	}
+}
+
+// isLoopback returns true if the address of the remote peer is a loopback address
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/client/clientset/versioned/fake"
+	kyvernoinformer "github.com/kyverno/kyverno/pkg/client/informers/externalversions"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/policycache"
+	"github.com/kyverno/kyverno/pkg/webhookconfig"
+	"gotest.tools/assert"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_handleDebug(t *testing.T) {
+	factory := kyvernoinformer.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	pCache := policycache.NewPolicyCacheController(factory.Kyverno().V1().ClusterPolicies(), factory.Kyverno().V1().Policies(), log.Log).Cache
+
+	policy := newRequireLabelPolicy(t, "require-team-label", "enforce")
+	pCache.Add(policy)
+	nsPolicy := &kyverno.ClusterPolicy{Spec: policy.Spec}
+	nsPolicy.SetNamespace("default")
+	nsPolicy.SetName("require-team-label")
+	pCache.Add(nsPolicy)
+
+	ws := &WebhookServer{log: log.Log, pCache: pCache, webhookRegister: &webhookconfig.Register{}}
+
+	// the requests from the cluster network are rejected
+	w := httptest.NewRecorder()
+	ws.handleDebug(w, httptest.NewRequest("GET", config.DebugServicePath, nil))
+	assert.Equal(t, w.Code, http.StatusForbidden)
+
+	for _, remoteAddr := range []string{"127.0.0.1:41234", "[::1]:41234"} {
+		r := httptest.NewRequest("GET", config.DebugServicePath, nil)
+		r.RemoteAddr = remoteAddr
+		w = httptest.NewRecorder()
+		ws.handleDebug(w, r)
+		assert.Equal(t, w.Code, http.StatusOK, remoteAddr)
+		assert.Equal(t, w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+
+		var state struct {
+			Webhooks struct {
+				Registered            bool                     `json:"registered"`
+				CAFingerprint         string                   `json:"caFingerprint"`
+				WebhookConfigurations []map[string]interface{} `json:"webhookConfigurations"`
+			} `json:"webhooks"`
+			PolicyCache map[string]map[string][]string `json:"policyCache"`
+		}
+		assert.NilError(t, json.Unmarshal(w.Body.Bytes(), &state))
+
+		// the webhooks are not registered
+		assert.Assert(t, !state.Webhooks.Registered)
+		assert.Equal(t, state.Webhooks.CAFingerprint, "")
+		assert.Assert(t, state.Webhooks.WebhookConfigurations != nil)
+		assert.Equal(t, len(state.Webhooks.WebhookConfigurations), 0)
+
+		assert.DeepEqual(t, state.PolicyCache, map[string]map[string][]string{
+			"Pod": {"ValidateEnforce": {"require-team-label", "default/require-team-label"}},
+		})
+	}
+}
+
+func Test_isLoopback(t *testing.T) {
+	assert.Assert(t, isLoopback("127.0.0.1:8080"))
+	assert.Assert(t, isLoopback("[::1]:8080"))
+	assert.Assert(t, isLoopback("127.0.0.1"))
+	assert.Assert(t, !isLoopback("10.0.0.12:8080"))
+	assert.Assert(t, !isLoopback("localhost:8080"))
+	assert.Assert(t, !isLoopback(""))
+}
@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	admissionReviewDuration "github.com/kyverno/kyverno/pkg/metrics/admissionreviewduration"
+	"github.com/kyverno/kyverno/pkg/metrics/admissionreviewtimeouts"
+	"k8s.io/api/admission/v1beta1"
+)
+
+// DefaultEngineTimeout is the default deadline of the policy evaluation of an admission request,
+// it is lower than the default webhook timeout so that Kyverno responds before the API server gives up
+const DefaultEngineTimeout = 8 * time.Second
+
+// evaluateWithTimeout runs the policy evaluation within the engine timeout, it returns an error
+// if the deadline is exceeded. The context passed to evaluate is then cancelled: the engine skips
+// the remaining rules and the evaluation must not report its result, e.g. with events or policy
+// reports, the caller discards it. A zero timeout disables the deadline.
+func (ws *WebhookServer) evaluateWithTimeout(evaluate func(ctx context.Context)) error {
+	if ws.engineTimeout <= 0 {
+		evaluate(context.Background())
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ws.engineTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	var recovered interface{}
+	go func() {
+		defer close(done)
+		defer func() { recovered = recover() }()
+		evaluate(ctx)
+	}()
+
+	select {
+	case <-done:
+		// a panic of the evaluation is raised in the handler, as without the deadline
+		if recovered != nil {
+			panic(recovered)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("policy evaluation exceeded the deadline of %s", ws.engineTimeout)
+	}
+}
+
+// timeoutResponse returns the response of an admission request whose policy evaluation timed out,
+// the request is allowed if all the policies ignore failures and denied otherwise
+func (ws *WebhookServer) timeoutResponse(request *v1beta1.AdmissionRequest, policies []*kyverno.ClusterPolicy, webhookType admissionreviewtimeouts.WebhookType, err error, logger logr.Logger) *v1beta1.AdmissionResponse {
+	failurePolicy := timeoutFailurePolicy(policies)
+	logger.Error(err, "failed to evaluate the policies", "failurePolicy", failurePolicy)
+	go registerAdmissionReviewTimeoutsMetric(logger, *ws.promConfig, request, webhookType, failurePolicy)
+
+	if failurePolicy == kyverno.Ignore {
+		return successResponse(nil)
+	}
+	return failureResponse(err.Error())
+}
+
+// timeoutFailurePolicy returns Ignore if all the policies ignore failures, and Fail otherwise.
+// The warn policies are ignored, as they never deny a request.
+func timeoutFailurePolicy(policies []*kyverno.ClusterPolicy) kyverno.FailurePolicyType {
+	for _, policy := range policies {
+		if policy.Spec.ValidationFailureAction == common.Warn {
+			continue
+		}
+
+		if policy.Spec.FailurePolicy == nil || *policy.Spec.FailurePolicy != kyverno.Ignore {
+			return kyverno.Fail
+		}
+	}
+	return kyverno.Ignore
+}
+
+func registerAdmissionReviewTimeoutsMetric(logger logr.Logger, promConfig metrics.PromConfig, request *v1beta1.AdmissionRequest, webhookType admissionreviewtimeouts.WebhookType, failurePolicy kyverno.FailurePolicyType) {
+	resourceRequestOperationPromAlias, err := admissionReviewDuration.ParseResourceRequestOperation(string(request.Operation))
+	if err != nil {
+		logger.Error(err, "error occurred while registering kyverno_admission_review_timeouts_total metrics")
+	}
+	if err := admissionreviewtimeouts.ParsePromConfig(promConfig).ProcessTimeout(request.Kind.Kind, request.Namespace, resourceRequestOperationPromAlias, webhookType, failurePolicy); err != nil {
+		logger.Error(err, "error occurred while registering kyverno_admission_review_timeouts_total metrics")
+	}
+}
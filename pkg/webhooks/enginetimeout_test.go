@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/event"
+	"github.com/kyverno/kyverno/pkg/metrics/admissionreviewtimeouts"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTimeoutTestPolicy(t *testing.T, failurePolicy *kyverno.FailurePolicyType) *kyverno.ClusterPolicy {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+	policy.Spec.FailurePolicy = failurePolicy
+	return &policy
+}
+
+// eventRecorder records the events of the webhook
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []event.Info
+}
+
+func (r *eventRecorder) Add(infos ...event.Info) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, infos...)
+}
+
+func (r *eventRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func Test_evaluateWithTimeout_SlowRule(t *testing.T) {
+	ignore, fail := kyverno.Ignore, kyverno.Fail
+	testCases := []struct {
+		name          string
+		failurePolicy []*kyverno.FailurePolicyType
+		allowed       bool
+		expected      kyverno.FailurePolicyType
+	}{
+		{name: "default failure policy", failurePolicy: []*kyverno.FailurePolicyType{nil}, allowed: false, expected: kyverno.Fail},
+		{name: "fail", failurePolicy: []*kyverno.FailurePolicyType{&fail}, allowed: false, expected: kyverno.Fail},
+		{name: "ignore", failurePolicy: []*kyverno.FailurePolicyType{&ignore}, allowed: true, expected: kyverno.Ignore},
+		{name: "ignore and fail", failurePolicy: []*kyverno.FailurePolicyType{&ignore, &fail}, allowed: false, expected: kyverno.Fail},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ws := newMutationWebhookServer(t)
+			ws.engineTimeout = 50 * time.Millisecond
+			events := &eventRecorder{}
+			ws.eventGen = events
+
+			var policies []*kyverno.ClusterPolicy
+			for _, fp := range tc.failurePolicy {
+				policies = append(policies, newTimeoutTestPolicy(t, fp))
+			}
+
+			request := &v1beta1.AdmissionRequest{
+				UID:       "1",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace: "default",
+				Name:      "nginx",
+				Operation: v1beta1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"}}`)},
+			}
+
+			// the slow evaluation resumes after the deadline, with its context cancelled
+			var patches []byte
+			release, done := make(chan struct{}), make(chan struct{})
+			err := ws.evaluateWithTimeout(func(ctx context.Context) {
+				defer close(done)
+				<-release
+				patches, _ = mutateWithContext(t, ws, ctx, policies, v1beta1.Create, request.Object.Raw)
+			})
+			close(release)
+			<-done
+			assert.ErrorContains(t, err, "exceeded the deadline of 50ms")
+
+			// the rules are skipped, no patch is returned and no event is reported
+			assert.Assert(t, patches == nil)
+			assert.Equal(t, events.count(), 0)
+
+			resp := ws.timeoutResponse(request, policies, admissionreviewtimeouts.MutatingWebhook, err, logr.DiscardLogger{})
+			assert.Equal(t, resp.Allowed, tc.allowed)
+			assert.Assert(t, resp.Patch == nil)
+			if !tc.allowed {
+				assert.Assert(t, resp.Result != nil && resp.Result.Message == err.Error())
+			}
+
+			counter := ws.promConfig.Metrics.AdmissionReviewTimeouts.With(prom.Labels{
+				"resource_kind":              "Pod",
+				"resource_namespace":         "default",
+				"resource_request_operation": "create",
+				"webhook_type":               "mutating",
+				"failure_policy":             string(tc.expected),
+			})
+			deadline := time.Now().Add(time.Second)
+			for testutil.ToFloat64(counter) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			assert.Equal(t, testutil.ToFloat64(counter), float64(1))
+		})
+	}
+}
+
+func Test_evaluateWithTimeout(t *testing.T) {
+	ws := &WebhookServer{engineTimeout: time.Second}
+
+	evaluated := false
+	assert.NilError(t, ws.evaluateWithTimeout(func(ctx context.Context) {
+		// the context is only cancelled if the deadline is exceeded
+		assert.NilError(t, ctx.Err())
+		evaluated = true
+	}))
+	assert.Assert(t, evaluated)
+
+	// the deadline is disabled
+	ws.engineTimeout = 0
+	evaluated = false
+	assert.NilError(t, ws.evaluateWithTimeout(func(context.Context) {
+		time.Sleep(20 * time.Millisecond)
+		evaluated = true
+	}))
+	assert.Assert(t, evaluated)
+
+	// a panic of the evaluation is raised by the caller
+	ws.engineTimeout = time.Second
+	defer func() {
+		assert.Equal(t, recover(), "rule panicked")
+	}()
+	_ = ws.evaluateWithTimeout(func(context.Context) { panic("rule panicked") })
+	t.Fatal("expected the panic to be raised")
+}
+
+func Test_timeoutFailurePolicy(t *testing.T) {
+	ignore, fail := kyverno.Ignore, kyverno.Fail
+	assert.Equal(t, timeoutFailurePolicy(nil), kyverno.Ignore)
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{newTimeoutTestPolicy(t, &ignore)}), kyverno.Ignore)
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{newTimeoutTestPolicy(t, &ignore), newTimeoutTestPolicy(t, nil)}), kyverno.Fail)
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{newTimeoutTestPolicy(t, &fail)}), kyverno.Fail)
+
+	// the warn policies never deny a request
+	warn := newTimeoutTestPolicy(t, nil)
+	warn.Spec.ValidationFailureAction = common.Warn
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{warn}), kyverno.Ignore)
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{warn, newTimeoutTestPolicy(t, &ignore)}), kyverno.Ignore)
+	assert.Equal(t, timeoutFailurePolicy([]*kyverno.ClusterPolicy{warn, newTimeoutTestPolicy(t, nil)}), kyverno.Fail)
+}
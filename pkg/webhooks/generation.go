@@ -76,6 +76,7 @@ func (ws *WebhookServer) handleGenerate(
 			ResourceCache:       ws.resCache,
 			JSONContext:         ctx,
 			Client:              ws.client,
+			SubResource:         common.RequestSubresource(request.Resource.Resource, request.SubResource),
 		}
 
 		for _, policy := range policies {
@@ -104,6 +105,9 @@ func (ws *WebhookServer) handleGenerate(
 
 			// registering the kyverno_policy_execution_duration_seconds metric concurrently
 			go ws.registerPolicyExecutionDurationMetricGenerate(logger, string(request.Operation), *policy, *engineResponse)
+
+			// registering the kyverno_policy_outcomes_total metric concurrently
+			go registerPolicyOutcomesMetric(ws.promConfig, logger, *policy, *engineResponse)
 		}
 
 		// Adds Generate Request to a channel(queue size 1000) to generators
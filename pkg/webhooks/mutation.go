@@ -70,20 +70,42 @@ func (ws *WebhookServer) handleMutation(
 	if deletionTimeStamp != nil && request.Operation == v1beta1.Update {
 		return nil, nil
 	}
+
+	// skip the resources already mutated by the policies, e.g. when the webhook is re-invoked
+	var hash string
+	hashKey := ws.mutationHashKey()
+	if request.Operation == v1beta1.Create || request.Operation == v1beta1.Update {
+		hash = mutationHash(hashKey, request.Operation, request.Namespace, newR, policies)
+		if hash != "" && newR.GetAnnotations()[mutationHashAnnotation] == hash {
+			logger.V(4).Info("resource already mutated by the policies, skipping", "hash", hash)
+			return nil, nil
+		}
+	}
+
 	var patches [][]byte
 	var engineResponses []*response.EngineResponse
+	var mutationFailed bool
 
 	for _, policy := range policies {
 		if !policy.HasMutate() {
 			continue
 		}
 
+		if policyContext.Cancelled() {
+			break
+		}
+
 		logger.V(3).Info("applying policy mutate rules", "policy", policy.Name)
 		policyContext.Policy = *policy
 		engineResponse, policyPatches, err := ws.applyMutation(request, policyContext, logger)
+		if ws.statusUpdater != nil {
+			ws.statusUpdater.RecordMutation(engineResponse)
+		}
+
 		if err != nil {
 			// TODO report errors in engineResponse and record in metrics
 			logger.Error(err, "mutate error")
+			mutationFailed = true
 			continue
 		}
 
@@ -101,13 +123,35 @@ func (ws *WebhookServer) handleMutation(
 
 		// registering the kyverno_policy_execution_duration_seconds metric concurrently
 		go ws.registerPolicyExecutionDurationMetricMutate(logger, string(request.Operation), *policy, *engineResponse)
+
+		// registering the kyverno_policy_outcomes_total metric concurrently
+		go registerPolicyOutcomesMetric(ws.promConfig, logger, *policy, *engineResponse)
 	}
 
+	// the result of a cancelled evaluation is discarded, it is neither reported nor returned
+	if policyContext.Cancelled() {
+		logger.V(3).Info("the evaluation of the request was cancelled, skipping the mutation")
+		return nil, nil
+	}
+
+	// the policies applied patches, the other annotation patches do not mutate the resource
+	applied := len(patches) > 0
+
 	// generate annotations
-	if annPatches := generateAnnotationPatches(engineResponses, logger); annPatches != nil {
+	annPatches := generateAnnotationPatches(engineResponses, logger)
+	if annPatches != nil {
 		patches = append(patches, annPatches...)
 	}
 
+	// the hash of the mutated resource is set when a policy applied a patch and none of the policies
+	// failed, so that a resource whose mutation hash annotation was removed is mutated again
+	if hash != "" && applied && !mutationFailed && isResponseSuccessful(engineResponses) {
+		if hash = mutationHash(hashKey, request.Operation, request.Namespace, policyContext.NewResource, policies); hash != "" {
+			hasAnnotations := annPatches != nil || policyContext.NewResource.GetAnnotations() != nil
+			patches = append(patches, mutationHashPatch(hash, hasAnnotations))
+		}
+	}
+
 	// REPORTING EVENTS
 	// Scenario 1:
 	//   some/all policies failed to apply on the resource. a policy violation is generated.
@@ -131,6 +175,13 @@ func (ws *WebhookServer) handleMutation(
 		}
 	}()
 
+	// the policies did not change the resource, e.g. when the webhook is re-invoked with a resource
+	// it already mutated, so no modification is reported to the API server
+	if reflect.DeepEqual(newR.Object, policyContext.NewResource.Object) {
+		logger.V(4).Info("the policies do not change the resource, skipping the patches")
+		return nil, engineResponses
+	}
+
 	// patches holds all the successful patches, if no patch is created, it returns nil
 	return engineutils.JoinPatches(patches), engineResponses
 }
@@ -145,7 +196,7 @@ func (ws *WebhookServer) applyMutation(request *v1beta1.AdmissionRequest, policy
 	policyPatches := engineResponse.GetPatches()
 
 	if !engineResponse.IsSuccessful() && len(engineResponse.GetFailedRules()) > 0 {
-		return nil, nil, fmt.Errorf("failed to apply policy %s rules %v", policyContext.Policy.Name, engineResponse.GetFailedRules())
+		return engineResponse, nil, fmt.Errorf("failed to apply policy %s rules %v", policyContext.Policy.Name, engineResponse.GetFailedRules())
 	}
 
 	err := ws.openAPIController.ValidateResource(*engineResponse.PatchedResource.DeepCopy(), engineResponse.PatchedResource.GetAPIVersion(), engineResponse.PatchedResource.GetKind())
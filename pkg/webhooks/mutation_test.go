@@ -0,0 +1,307 @@
+package webhooks
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/event"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	"github.com/kyverno/kyverno/pkg/openapi"
+	tlsutils "github.com/kyverno/kyverno/pkg/tls"
+	"github.com/kyverno/kyverno/pkg/utils"
+	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type eventGeneratorStub struct{}
+
+func (eventGeneratorStub) Add(...event.Info) {}
+
+const addTeamLabelPolicy = `{
+	"apiVersion": "kyverno.io/v1",
+	"kind": "ClusterPolicy",
+	"metadata": {"name": "add-team-label"},
+	"spec": {
+		"rules": [
+			{
+				"name": "add-team-label",
+				"match": {"resources": {"kinds": ["Pod"]}},
+				"mutate": {"patchStrategicMerge": {"metadata": {"labels": {"+(team)": "platform"}}}}
+			}
+		]
+	}
+}`
+
+func newMutationWebhookServer(t *testing.T) *WebhookServer {
+	openAPIController, err := openapi.NewOpenAPIController()
+	assert.NilError(t, err)
+
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+
+	caCert, _, err := tlsutils.GenerateCACert(tlsutils.CertValidityDuration)
+	assert.NilError(t, err)
+	tlsPair, err := tlsutils.GenerateCertPem(caCert, tlsutils.CertificateProps{Service: "kyverno-svc", Namespace: "kyverno"}, "", tlsutils.CertValidityDuration)
+	assert.NilError(t, err)
+	certCache := tlsutils.NewCertificateCache()
+	assert.NilError(t, certCache.Update(tlsPair))
+
+	return &WebhookServer{
+		eventGen:          eventGeneratorStub{},
+		openAPIController: openAPIController,
+		promConfig:        promConfig,
+		certCache:         certCache,
+		log:               logr.DiscardLogger{},
+	}
+}
+
+// mutate runs the mutating webhook on the resource, it returns the patches and the patched resource
+func mutate(t *testing.T, ws *WebhookServer, policies []*kyverno.ClusterPolicy, operation v1beta1.Operation, resource []byte) ([]byte, []byte) {
+	return mutateWithContext(t, ws, nil, policies, operation, resource)
+}
+
+// mutateWithContext runs the mutating webhook on the resource with the context of the request evaluation
+func mutateWithContext(t *testing.T, ws *WebhookServer, requestContext gocontext.Context, policies []*kyverno.ClusterPolicy, operation v1beta1.Operation, resource []byte) ([]byte, []byte) {
+	return mutateInNamespace(t, ws, requestContext, "", policies, operation, resource)
+}
+
+// mutateInNamespace runs the mutating webhook on the resource of a request in the namespace
+func mutateInNamespace(t *testing.T, ws *WebhookServer, requestContext gocontext.Context, namespace string, policies []*kyverno.ClusterPolicy, operation v1beta1.Operation, resource []byte) ([]byte, []byte) {
+	request := &v1beta1.AdmissionRequest{
+		UID:       "1",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Name:      "nginx",
+		Namespace: namespace,
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: resource},
+	}
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddRequest(request))
+
+	newR, oldR, err := utils.ExtractResources(nil, request)
+	assert.NilError(t, err)
+
+	policyContext := &engine.PolicyContext{NewResource: newR, OldResource: oldR, JSONContext: ctx, RequestContext: requestContext}
+	patches, _ := ws.handleMutation(request, policyContext, policies)
+	if patches == nil {
+		return nil, resource
+	}
+
+	patch, err := jsonpatch.DecodePatch(patches)
+	assert.NilError(t, err)
+	patched, err := patch.Apply(resource)
+	assert.NilError(t, err)
+	return patches, patched
+}
+
+func Test_handleMutation_SkipsMutatedResource(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+	policies := []*kyverno.ClusterPolicy{&policy}
+	ws := newMutationWebhookServer(t)
+
+	resource := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`)
+	patches, mutated := mutate(t, ws, policies, v1beta1.Create, resource)
+	assert.Assert(t, patches != nil)
+
+	var pod map[string]interface{}
+	assert.NilError(t, json.Unmarshal(mutated, &pod))
+	metadata := pod["metadata"].(map[string]interface{})
+	assert.Equal(t, metadata["labels"].(map[string]interface{})["team"], "platform")
+	hash := metadata["annotations"].(map[string]interface{})[mutationHashAnnotation]
+	assert.Assert(t, hash != nil)
+
+	// a second identical admission, e.g. a reinvocation, produces no patch
+	patches, _ = mutate(t, ws, policies, v1beta1.Create, mutated)
+	assert.Assert(t, patches == nil)
+
+	// the resource is mutated again once the user removes the annotation
+	delete(metadata, "annotations")
+	delete(metadata["labels"].(map[string]interface{}), "team")
+	stripped, err := json.Marshal(pod)
+	assert.NilError(t, err)
+
+	patches, remutated := mutate(t, ws, policies, v1beta1.Update, stripped)
+	assert.Assert(t, patches != nil)
+	assert.NilError(t, json.Unmarshal(remutated, &pod))
+	metadata = pod["metadata"].(map[string]interface{})
+	assert.Equal(t, metadata["labels"].(map[string]interface{})["team"], "platform")
+	updateHash := metadata["annotations"].(map[string]interface{})[mutationHashAnnotation]
+	assert.Assert(t, updateHash != nil && updateHash != hash, "the hash of an update must differ from the hash of a create")
+
+	patches, _ = mutate(t, ws, policies, v1beta1.Update, remutated)
+	assert.Assert(t, patches == nil)
+}
+
+func Test_handleMutation_HashNotReplayedInOtherNamespace(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(`{
+		"apiVersion": "kyverno.io/v1",
+		"kind": "ClusterPolicy",
+		"metadata": {"name": "add-labels"},
+		"spec": {
+			"rules": [
+				{
+					"name": "add-team-label",
+					"match": {"resources": {"kinds": ["Pod"]}},
+					"mutate": {"patchStrategicMerge": {"metadata": {"labels": {"+(team)": "platform"}}}}
+				},
+				{
+					"name": "add-restricted-label",
+					"match": {"resources": {"kinds": ["Pod"], "namespaces": ["team-b"]}},
+					"mutate": {"patchStrategicMerge": {"metadata": {"labels": {"+(restricted)": "true"}}}}
+				}
+			]
+		}
+	}`), &policy))
+	policies := []*kyverno.ClusterPolicy{&policy}
+	ws := newMutationWebhookServer(t)
+	ws.nsLister = listerv1.NewNamespaceLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+
+	resource := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","namespace":"team-a"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`)
+	_, mutated := mutateInNamespace(t, ws, nil, "team-a", policies, v1beta1.Create, resource)
+
+	var pod map[string]interface{}
+	assert.NilError(t, json.Unmarshal(mutated, &pod))
+	labels := pod["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+	assert.Equal(t, labels["team"], "platform")
+	assert.Assert(t, labels["restricted"] == nil)
+
+	// the resource mutated in team-a is created with its hash in team-b, where the second rule applies
+	pod["metadata"].(map[string]interface{})["namespace"] = "team-b"
+	replayed, err := json.Marshal(pod)
+	assert.NilError(t, err)
+
+	patches, remutated := mutateInNamespace(t, ws, nil, "team-b", policies, v1beta1.Create, replayed)
+	assert.Assert(t, patches != nil)
+	assert.NilError(t, json.Unmarshal(remutated, &pod))
+	assert.Equal(t, pod["metadata"].(map[string]interface{})["labels"].(map[string]interface{})["restricted"], "true")
+}
+
+func Test_handleMutation_UnchangedResource(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+	policies := []*kyverno.ClusterPolicy{&policy}
+	ws := newMutationWebhookServer(t)
+
+	// the label is already set, the policy does not change the resource
+	resource := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","labels":{"team":"platform"}},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`)
+	patches, _ := mutate(t, ws, policies, v1beta1.Create, resource)
+	assert.Assert(t, patches == nil)
+}
+
+func Test_handleMutation_ChangedResourceIsMutated(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+	policies := []*kyverno.ClusterPolicy{&policy}
+	ws := newMutationWebhookServer(t)
+
+	resource := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`)
+	_, mutated := mutate(t, ws, policies, v1beta1.Create, resource)
+
+	// the hash no longer matches the resource when the user changes it
+	var pod map[string]interface{}
+	assert.NilError(t, json.Unmarshal(mutated, &pod))
+	pod["spec"].(map[string]interface{})["hostname"] = "web"
+	changed, err := json.Marshal(pod)
+	assert.NilError(t, err)
+
+	patches, _ := mutate(t, ws, policies, v1beta1.Update, changed)
+	assert.Assert(t, patches != nil)
+}
+
+func Test_mutationHash(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+
+	resource, err := utils.ConvertToUnstructured([]byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","resourceVersion":"1","annotations":{"policies.kyverno.io/mutation-hash":"abc"}},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`))
+	assert.NilError(t, err)
+	key := []byte("secret")
+	hash := mutationHash(key, v1beta1.Create, "default", *resource, []*kyverno.ClusterPolicy{&policy})
+	assert.Assert(t, hash != "")
+
+	// the hash cannot be computed without the key
+	assert.Equal(t, mutationHash(nil, v1beta1.Create, "default", *resource, []*kyverno.ClusterPolicy{&policy}), "")
+	assert.Assert(t, mutationHash([]byte("other"), v1beta1.Create, "default", *resource, []*kyverno.ClusterPolicy{&policy}) != hash)
+
+	// the server metadata and the Kyverno annotations are not part of the hash
+	resource.SetResourceVersion("2")
+	resource.SetUID("1234")
+	resource.SetAnnotations(nil)
+	assert.Equal(t, mutationHash(key, v1beta1.Create, "default", *resource, []*kyverno.ClusterPolicy{&policy}), hash)
+
+	// the hash depends on the operation, the namespace and the metadata set by the users and the policies
+	assert.Assert(t, mutationHash(key, v1beta1.Update, "default", *resource, []*kyverno.ClusterPolicy{&policy}) != hash)
+	assert.Assert(t, mutationHash(key, v1beta1.Create, "other", *resource, []*kyverno.ClusterPolicy{&policy}) != hash)
+
+	changes := []func(*unstructured.Unstructured){
+		func(r *unstructured.Unstructured) { r.SetLabels(map[string]string{"app": "nginx"}) },
+		func(r *unstructured.Unstructured) { r.SetNamespace("other") },
+		func(r *unstructured.Unstructured) { r.SetName("web") },
+		func(r *unstructured.Unstructured) { r.SetGenerateName("nginx-") },
+		func(r *unstructured.Unstructured) { r.SetFinalizers([]string{"kyverno.io/cleanup"}) },
+		func(r *unstructured.Unstructured) {
+			r.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "nginx", UID: "1"}})
+		},
+	}
+	for i, change := range changes {
+		changed := resource.DeepCopy()
+		change(changed)
+		assert.Assert(t, mutationHash(key, v1beta1.Create, "default", *changed, []*kyverno.ClusterPolicy{&policy}) != hash, "change %d", i)
+	}
+
+	// the namespace of the request is the namespace of a resource without one
+	namespaced := resource.DeepCopy()
+	namespaced.SetNamespace("default")
+	assert.Equal(t, mutationHash(key, v1beta1.Create, "default", *namespaced, []*kyverno.ClusterPolicy{&policy}), hash)
+
+	// a mutation depending on the request is never skipped
+	var userPolicy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(`{
+		"metadata": {"name": "add-owner"},
+		"spec": {
+			"rules": [
+				{
+					"name": "add-owner",
+					"match": {"resources": {"kinds": ["Pod"]}},
+					"mutate": {"patchStrategicMerge": {"metadata": {"labels": {"owner": "{{request.userInfo.username}}"}}}}
+				}
+			]
+		}
+	}`), &userPolicy))
+	assert.Equal(t, mutationHash(key, v1beta1.Create, "default", *resource, []*kyverno.ClusterPolicy{&policy, &userPolicy}), "")
+}
+
+func Test_handleMutation_ForgedHashIsIgnored(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+	policies := []*kyverno.ClusterPolicy{&policy}
+	ws := newMutationWebhookServer(t)
+
+	// a client sets the hash computed with another key to bypass the mutation
+	resource, err := utils.ConvertToUnstructured([]byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`))
+	assert.NilError(t, err)
+	resource.SetAnnotations(map[string]string{mutationHashAnnotation: mutationHash([]byte("guessed"), v1beta1.Create, "", *resource, policies)})
+	forged, err := resource.MarshalJSON()
+	assert.NilError(t, err)
+
+	patches, mutated := mutate(t, ws, policies, v1beta1.Create, forged)
+	assert.Assert(t, patches != nil)
+
+	var pod map[string]interface{}
+	assert.NilError(t, json.Unmarshal(mutated, &pod))
+	assert.Equal(t, pod["metadata"].(map[string]interface{})["labels"].(map[string]interface{})["team"], "platform")
+}
@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/engine/variables"
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// mutationHashAnnotation is the hash of a resource mutated by Kyverno and of the mutate policies applied to it
+	mutationHashAnnotation = "policies.kyverno.io/mutation-hash"
+)
+
+// regexRequestFilter matches the match and exclude filters on the request user or the namespace labels
+var regexRequestFilter = regexp.MustCompile(`"(roles|clusterRoles|subjects|namespaceSelector)":`)
+
+// serverMetadataFields are the metadata fields set by the API server
+var serverMetadataFields = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds", "managedFields", "selfLink"}
+
+// resourceVariablePrefixes are the prefixes of the variables resolved from the resource only
+var resourceVariablePrefixes = []string{"request.object", "element", "@"}
+
+type mutationHashInput struct {
+	Operation v1beta1.Operation      `json:"operation"`
+	Policies  []mutationHashPolicy   `json:"policies"`
+	Resource  map[string]interface{} `json:"resource"`
+}
+
+type mutationHashPolicy struct {
+	Namespace string             `json:"namespace,omitempty"`
+	Name      string             `json:"name"`
+	Spec      kyverno.PolicySpec `json:"spec"`
+}
+
+// mutationHash returns the HMAC of the request operation, of the resource in the request namespace and of the
+// mutate policies with the secret key of Kyverno. A resource whose annotation has the same hash was already
+// mutated by these policies and is left as is; the annotation is set by the clients, so the hash must not be
+// computable without the key, and must not match another operation or another resource, e.g. with the same
+// content in another namespace.
+// It returns an empty string if the key is empty, or if the mutation of the resource may depend on the
+// request, e.g. a rule loads a context, uses the request user info or the namespace labels, as a hash of
+// the resource and of the policies does not tell if the mutation would be the same.
+func mutationHash(key []byte, operation v1beta1.Operation, namespace string, resource unstructured.Unstructured, policies []*kyverno.ClusterPolicy) string {
+	if len(key) == 0 || resource.Object == nil {
+		return ""
+	}
+
+	input := mutationHashInput{Operation: operation, Resource: mutationHashResource(resource, namespace)}
+	for _, policy := range policies {
+		if !policy.HasMutate() {
+			continue
+		}
+
+		if !isResourceMutation(policy) {
+			return ""
+		}
+
+		input.Policies = append(input.Policies, mutationHashPolicy{Namespace: policy.Namespace, Name: policy.Name, Spec: policy.Spec})
+	}
+
+	if len(input.Policies) == 0 {
+		return ""
+	}
+
+	sort.Slice(input.Policies, func(i, j int) bool {
+		if input.Policies[i].Namespace != input.Policies[j].Namespace {
+			return input.Policies[i].Namespace < input.Policies[j].Namespace
+		}
+		return input.Policies[i].Name < input.Policies[j].Name
+	})
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// mutationHashKey returns the key of the mutation hashes, derived from the private key of the served TLS pair
+func (ws *WebhookServer) mutationHashKey() []byte {
+	if ws.certCache == nil {
+		return nil
+	}
+
+	return ws.certCache.HMACKey()
+}
+
+// mutationHashResource returns the content of the resource set by the users and the policies, in the namespace
+// of the request if the resource has none. The metadata set by the API server, the status and the annotations
+// of Kyverno are left out.
+func mutationHashResource(resource unstructured.Unstructured, namespace string) map[string]interface{} {
+	content := resource.DeepCopy().Object
+	delete(content, "status")
+
+	metadata, _, _ := unstructured.NestedMap(content, "metadata")
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+
+	for _, field := range serverMetadataFields {
+		delete(metadata, field)
+	}
+
+	if resource.GetNamespace() == "" && namespace != "" {
+		metadata["namespace"] = namespace
+	}
+
+	annotations := resource.GetAnnotations()
+	for _, key := range []string{mutationHashAnnotation, strings.ReplaceAll(policyAnnotation, "~1", "/"), strings.ReplaceAll(oldAnnotation, "~1", "/")} {
+		delete(annotations, key)
+	}
+
+	delete(metadata, "annotations")
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+
+	content["metadata"] = metadata
+
+	return content
+}
+
+// isResourceMutation returns true if the mutate rules of the policy only depend on the resource
+func isResourceMutation(policy *kyverno.ClusterPolicy) bool {
+	for _, rule := range policy.Spec.Rules {
+		if !rule.HasMutate() {
+			continue
+		}
+
+		if len(rule.Context) > 0 {
+			return false
+		}
+
+		filters, err := json.Marshal([]interface{}{rule.MatchResources, rule.ExcludeResources})
+		if err != nil || regexRequestFilter.Match(filters) {
+			return false
+		}
+
+		raw, err := json.Marshal(rule)
+		if err != nil {
+			return false
+		}
+
+		for _, v := range variables.RegexVariables.FindAllString(string(raw), -1) {
+			v = strings.TrimSpace(v[strings.Index(v, "{{")+2 : len(v)-2])
+			if !hasResourceVariablePrefix(v) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func hasResourceVariablePrefix(variable string) bool {
+	for _, prefix := range resourceVariablePrefixes {
+		if strings.HasPrefix(variable, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mutationHashPatch returns the patch setting the mutation hash annotation, hasAnnotations
+// tells if the annotations of the patched resource exist
+func mutationHashPatch(hash string, hasAnnotations bool) []byte {
+	var patch annresponse
+	if hasAnnotations {
+		patch = annresponse{
+			Op:    "add",
+			Path:  "/metadata/annotations/" + strings.ReplaceAll(mutationHashAnnotation, "/", "~1"),
+			Value: hash,
+		}
+	} else {
+		patch = annresponse{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: map[string]string{mutationHashAnnotation: hash},
+		}
+	}
+
+	patchBytes, _ := json.Marshal(patch)
+	return patchBytes
+}
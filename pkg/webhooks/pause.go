@@ -0,0 +1,16 @@
+package webhooks
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// enforcementPaused returns true if the enforcement is paused in the Kyverno ConfigMap. The admission requests
+// are then allowed without applying the policies, the webhooks stay registered so that the enforcement resumes instantly.
+func (ws *WebhookServer) enforcementPaused(logger logr.Logger) bool {
+	if ws.configHandler == nil || !ws.configHandler.IsEnforcementPaused() {
+		return false
+	}
+
+	logger.Info("enforcement is paused, admission request allowed without applying the policies")
+	return true
+}
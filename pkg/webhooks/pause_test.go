@@ -0,0 +1,93 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/client/clientset/versioned/fake"
+	kyvernoinformer "github.com/kyverno/kyverno/pkg/client/informers/externalversions"
+	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/policycache"
+	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pausableConfig is the dynamic configuration with a toggle of the enforcement
+type pausableConfig struct {
+	config.Interface
+	paused bool
+}
+
+func (c *pausableConfig) IsEnforcementPaused() bool {
+	return c.paused
+}
+
+func (c *pausableConfig) ToFilter(kind, namespace, name string) bool {
+	return false
+}
+
+func (c *pausableConfig) GetExcludeGroupRole() []string {
+	return nil
+}
+
+func newPausableWebhookServer(t *testing.T, cfg *pausableConfig, policies ...*kyverno.ClusterPolicy) *WebhookServer {
+	factory := kyvernoinformer.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	pCache := policycache.NewPolicyCacheController(factory.Kyverno().V1().ClusterPolicies(), factory.Kyverno().V1().Policies(), log.Log).Cache
+	for _, policy := range policies {
+		pCache.Add(policy)
+	}
+
+	ws := newMutationWebhookServer(t)
+	ws.pCache = pCache
+	ws.configHandler = cfg
+	ws.prGenerator = prGeneratorStub{}
+	return ws
+}
+
+func newPodRequest(operation v1beta1.Operation) *v1beta1.AdmissionRequest {
+	return &v1beta1.AdmissionRequest{
+		UID:       "1",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Name:      "nginx",
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx"},"spec":{"containers":[{"name":"nginx","image":"nginx"}]}}`)},
+	}
+}
+
+func Test_resourceValidation_EnforcementPaused(t *testing.T) {
+	cfg := &pausableConfig{paused: true}
+	ws := newPausableWebhookServer(t, cfg, newRequireLabelPolicy(t, "require-team-label", common.Enforce))
+
+	// the request violating the policy is allowed while the enforcement is paused
+	resp := ws.resourceValidation(newPodRequest(v1beta1.Create))
+	assert.Assert(t, resp.Allowed)
+
+	// the request is denied once the enforcement is resumed
+	cfg.paused = false
+	resp = ws.resourceValidation(newPodRequest(v1beta1.Create))
+	assert.Assert(t, !resp.Allowed)
+	assert.Equal(t, resp.Result.Status, "Failure")
+}
+
+func Test_resourceMutation_EnforcementPaused(t *testing.T) {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(addTeamLabelPolicy), &policy))
+
+	cfg := &pausableConfig{paused: true}
+	ws := newPausableWebhookServer(t, cfg, &policy)
+
+	// the resource is not mutated while the enforcement is paused
+	resp := ws.resourceMutation(newPodRequest(v1beta1.Create))
+	assert.Assert(t, resp.Allowed)
+	assert.Assert(t, resp.Patch == nil)
+
+	cfg.paused = false
+	resp = ws.resourceMutation(newPodRequest(v1beta1.Create))
+	assert.Assert(t, resp.Allowed)
+	assert.Assert(t, resp.Patch != nil)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/go-logr/logr"
 	kyvernov1alpha2 "github.com/kyverno/kyverno/api/kyverno/v1alpha2"
 	"github.com/kyverno/kyverno/pkg/engine/response"
+	engineutils "github.com/kyverno/kyverno/pkg/engine/utils"
 
 	"github.com/kyverno/kyverno/pkg/event"
 )
@@ -20,7 +21,7 @@ func generateEvents(engineResponses []*response.EngineResponse, blocked, onUpdat
 	//     - report failure event on resource
 	//   - Some/All policies succeeded
 	//     - report success event on policy
-	//     - report success event on resource
+	//     - report success event on resource, if mutation rules patched it
 
 	for _, er := range engineResponses {
 		if !er.IsSuccessful() {
@@ -84,7 +85,35 @@ func generateEvents(engineResponses []*response.EngineResponse, blocked, onUpdat
 				er.PolicyResponse.Resource.GetKey(),
 			)
 			events = append(events, e)
+
+			// Event on the mutated resource
+			if mutatedRules := getMutatedRules(er); len(mutatedRules) > 0 {
+				re := event.NewEvent(
+					log,
+					er.PolicyResponse.Resource.Kind,
+					er.PolicyResponse.Resource.APIVersion,
+					er.PolicyResponse.Resource.Namespace,
+					er.PolicyResponse.Resource.Name,
+					event.PolicyApplied.String(),
+					event.AdmissionController,
+					event.SResourcePolicyApply,
+					strings.Join(mutatedRules, ";"),
+					er.PolicyResponse.Policy.Name,
+				)
+				events = append(events, re)
+			}
 		}
 	}
 	return events
 }
+
+// getMutatedRules returns the names of the mutation rules that patched the resource
+func getMutatedRules(er *response.EngineResponse) []string {
+	var rules []string
+	for _, rule := range er.PolicyResponse.Rules {
+		if rule.Type == engineutils.Mutation.String() && rule.Status == response.RuleStatusPass && len(rule.Patches) > 0 {
+			rules = append(rules, rule.Name)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/kyverno/kyverno/pkg/engine/response"
+	"github.com/kyverno/kyverno/pkg/event"
+	"gotest.tools/assert"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newEventEngineResponse(rule response.RuleResponse) *response.EngineResponse {
+	return &response.EngineResponse{
+		PolicyResponse: response.PolicyResponse{
+			Policy:   response.PolicySpec{Name: "add-labels"},
+			Resource: response.ResourceSpec{Kind: "Pod", APIVersion: "v1", Namespace: "default", Name: "nginx"},
+			Rules:    []response.RuleResponse{rule},
+		},
+	}
+}
+
+func Test_generateEvents_Mutation(t *testing.T) {
+	er := newEventEngineResponse(response.RuleResponse{
+		Name:    "add-team-label",
+		Type:    "Mutation",
+		Status:  response.RuleStatusPass,
+		Patches: [][]byte{[]byte(`{"op":"add","path":"/metadata/labels/team","value":"blue"}`)},
+	})
+
+	events := generateEvents([]*response.EngineResponse{er}, false, false, log.Log)
+	assert.Equal(t, len(events), 2)
+
+	policyEvent := events[0]
+	assert.Equal(t, policyEvent.Kind, "ClusterPolicy")
+	assert.Equal(t, policyEvent.Reason, event.PolicyApplied.String())
+
+	resourceEvent := events[1]
+	assert.Equal(t, resourceEvent.Kind, "Pod")
+	assert.Equal(t, resourceEvent.Namespace, "default")
+	assert.Equal(t, resourceEvent.Name, "nginx")
+	assert.Equal(t, resourceEvent.Reason, event.PolicyApplied.String())
+	assert.Equal(t, resourceEvent.Source, event.AdmissionController)
+	assert.Equal(t, resourceEvent.Message, "Rule(s) 'add-team-label' of policy 'add-labels' successfully applied on the resource")
+}
+
+func Test_generateEvents_MutationWithoutPatches(t *testing.T) {
+	er := newEventEngineResponse(response.RuleResponse{
+		Name:   "add-team-label",
+		Type:   "Mutation",
+		Status: response.RuleStatusPass,
+	})
+
+	events := generateEvents([]*response.EngineResponse{er}, false, false, log.Log)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Kind, "ClusterPolicy")
+}
+
+func Test_generateEvents_Violation(t *testing.T) {
+	er := newEventEngineResponse(response.RuleResponse{
+		Name:    "require-team-label",
+		Type:    "Validation",
+		Status:  response.RuleStatusFail,
+		Message: "label team is required",
+	})
+
+	events := generateEvents([]*response.EngineResponse{er}, true, false, log.Log)
+	assert.Equal(t, len(events), 2)
+
+	resourceEvent := events[1]
+	assert.Equal(t, resourceEvent.Kind, "Pod")
+	assert.Equal(t, resourceEvent.Name, "nginx")
+	assert.Equal(t, resourceEvent.Reason, event.PolicyViolation.String())
+	assert.Equal(t, resourceEvent.Message, "Rule(s) 'require-team-label' of policy 'add-labels' failed to apply on the resource")
+}
@@ -17,6 +17,7 @@ import (
 	kyvernolister "github.com/kyverno/kyverno/pkg/client/listers/kyverno/v1"
 	"github.com/kyverno/kyverno/pkg/common"
 	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/conversion"
 	client "github.com/kyverno/kyverno/pkg/dclient"
 	"github.com/kyverno/kyverno/pkg/engine"
 	enginectx "github.com/kyverno/kyverno/pkg/engine/context"
@@ -26,9 +27,11 @@ import (
 	"github.com/kyverno/kyverno/pkg/metrics"
 	admissionRequests "github.com/kyverno/kyverno/pkg/metrics/admissionrequests"
 	admissionReviewDuration "github.com/kyverno/kyverno/pkg/metrics/admissionreviewduration"
+	"github.com/kyverno/kyverno/pkg/metrics/admissionreviewtimeouts"
 	"github.com/kyverno/kyverno/pkg/openapi"
 	"github.com/kyverno/kyverno/pkg/policycache"
 	"github.com/kyverno/kyverno/pkg/policyreport"
+	"github.com/kyverno/kyverno/pkg/policystatus"
 	"github.com/kyverno/kyverno/pkg/resourcecache"
 	tlsutils "github.com/kyverno/kyverno/pkg/tls"
 	"github.com/kyverno/kyverno/pkg/userinfo"
@@ -45,6 +48,9 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+// webhookCleanupTimeout is the deadline to drain the in-flight requests and remove the webhook configurations on shutdown
+const webhookCleanupTimeout = 30 * time.Second
+
 // WebhookServer contains configured TLS server with MutationWebhook.
 type WebhookServer struct {
 	server        *http.Server
@@ -96,6 +102,10 @@ type WebhookServer struct {
 	// webhook registration client
 	webhookRegister *webhookconfig.Register
 
+	// certCache holds the certificate served by the webhook server, it is refreshed
+	// when the TLS pair secret is rotated
+	certCache *tlsutils.CertificateCache
+
 	// helpers to validate against current loaded configuration
 	configHandler config.Interface
 
@@ -128,6 +138,21 @@ type WebhookServer struct {
 	grController *generate.Controller
 
 	promConfig *metrics.PromConfig
+
+	// statusUpdater records the last mutation of the policies in their status
+	statusUpdater *policystatus.Updater
+
+	// converter converts the policies between the API versions for the conversion webhook
+	converter *conversion.Converter
+
+	// engineTimeout is the deadline of the policy evaluation of the resource admission requests
+	engineTimeout time.Duration
+
+	// slowRuleThreshold is the processing time above which the rules of the resource admission requests are logged as slow
+	slowRuleThreshold time.Duration
+
+	// debugEndpoint serves the webhook registration and policy cache state at config.DebugServicePath
+	debugEndpoint bool
 }
 
 // NewWebhookServer creates new instance of WebhookServer accordingly to given configuration
@@ -135,7 +160,7 @@ type WebhookServer struct {
 func NewWebhookServer(
 	kyvernoClient *kyvernoclient.Clientset,
 	client *client.Client,
-	tlsPair *tlsutils.PemPair,
+	certCache *tlsutils.CertificateCache,
 	grInformer kyvernoinformer.GenerateRequestInformer,
 	pInformer kyvernoinformer.ClusterPolicyInformer,
 	rbInformer rbacinformer.RoleBindingInformer,
@@ -157,18 +182,22 @@ func NewWebhookServer(
 	resCache resourcecache.ResourceCache,
 	grc *generate.Controller,
 	promConfig *metrics.PromConfig,
+	statusUpdater *policystatus.Updater,
+	engineTimeout time.Duration,
+	slowRuleThreshold time.Duration,
+	debugEndpoint bool,
 ) (*WebhookServer, error) {
 
-	if tlsPair == nil {
+	if certCache == nil {
 		return nil, errors.New("NewWebhookServer is not initialized properly")
 	}
 
-	var tlsConfig tls.Config
-	pair, err := tls.X509KeyPair(tlsPair.Certificate, tlsPair.PrivateKey)
-	if err != nil {
+	if _, err := certCache.GetCertificate(nil); err != nil {
 		return nil, err
 	}
-	tlsConfig.Certificates = []tls.Certificate{pair}
+
+	// the certificate is read from the cache on each handshake to serve the rotated TLS pair
+	tlsConfig := tls.Config{GetCertificate: certCache.GetCertificate}
 
 	ws := &WebhookServer{
 		client:         client,
@@ -191,6 +220,7 @@ func NewWebhookServer(
 		eventGen:          eventGen,
 		pCache:            pCache,
 		webhookRegister:   webhookRegistrationClient,
+		certCache:         certCache,
 		configHandler:     configHandler,
 		cleanUp:           cleanUp,
 		webhookMonitor:    webhookMonitor,
@@ -202,6 +232,11 @@ func NewWebhookServer(
 		openAPIController: openAPIController,
 		resCache:          resCache,
 		promConfig:        promConfig,
+		statusUpdater:     statusUpdater,
+		converter:         conversion.NewConverter(),
+		engineTimeout:     engineTimeout,
+		slowRuleThreshold: slowRuleThreshold,
+		debugEndpoint:     debugEndpoint,
 	}
 
 	mux := httprouter.New()
@@ -210,6 +245,7 @@ func NewWebhookServer(
 	mux.HandlerFunc("POST", config.PolicyMutatingWebhookServicePath, ws.handlerFunc(ws.policyMutation, true))
 	mux.HandlerFunc("POST", config.PolicyValidatingWebhookServicePath, ws.handlerFunc(ws.policyValidation, true))
 	mux.HandlerFunc("POST", config.VerifyMutatingWebhookServicePath, ws.handlerFunc(ws.verifyHandler, false))
+	mux.HandlerFunc("POST", config.ConversionWebhookServicePath, ws.handleConversion)
 
 	// Handle Liveness responds to a Kubernetes Liveness probe
 	// Fail this request if Kubernetes should restart this instance
@@ -230,6 +266,24 @@ func NewWebhookServer(
 		w.WriteHeader(http.StatusOK)
 	})
 
+	// Handle Readyz responds to the Kubernetes Readiness probe, it reports if this instance can serve the
+	// admission requests, i.e. the serving certificate is valid and signed by the CA bundle set on the
+	// webhooks, or by the CA until the leader registers the webhooks once the pods are ready.
+	mux.HandlerFunc("GET", config.ReadyzServicePath, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		if err := ws.webhookRegister.CheckReady(ws.certCache.CertificatePEM()); err != nil {
+			ws.log.V(4).Info("webhook server is not ready", "reason", err.Error())
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Handle Debug serves the troubleshooting state to the requests from localhost, when enabled
+	if ws.debugEndpoint {
+		mux.HandlerFunc("GET", config.DebugServicePath, ws.handleDebug)
+	}
+
 	ws.server = &http.Server{
 		Addr:         ":9443", // Listen on port for HTTPS requests
 		TLSConfig:    &tlsConfig,
@@ -296,6 +350,10 @@ func (ws *WebhookServer) resourceMutation(request *v1beta1.AdmissionRequest) *v1
 		return successResponse(nil)
 	}
 
+	if ws.enforcementPaused(logger) {
+		return successResponse(nil)
+	}
+
 	logger.V(4).Info("received an admission request in mutating webhook")
 	requestTime := time.Now().Unix()
 	kind := request.Kind.Kind
@@ -319,13 +377,23 @@ func (ws *WebhookServer) resourceMutation(request *v1beta1.AdmissionRequest) *v1
 		ws.log.Error(err, "failed to patch images info to resource, policies that mutate images may be impacted")
 	}
 
-	mutatePatches := ws.applyMutatePolicies(request, policyContext, mutatePolicies, requestTime, logger)
+	var mutatePatches, imagePatches []byte
+	var imageErr error
+	if err := ws.evaluateWithTimeout(func(evalCtx context.Context) {
+		policyContext := policyContext.Copy()
+		policyContext.RequestContext = evalCtx
+		mutatePatches = ws.applyMutatePolicies(request, policyContext, mutatePolicies, requestTime, logger)
 
-	newRequest := patchRequest(mutatePatches, request, logger)
-	imagePatches, err := ws.applyImageVerifyPolicies(newRequest, policyContext, verifyImagesPolicies, logger)
-	if err != nil {
-		logger.Error(err, "image verification failed")
-		return failureResponse(err.Error())
+		newRequest := patchRequest(mutatePatches, request, logger)
+		imagePatches, imageErr = ws.applyImageVerifyPolicies(newRequest, policyContext, verifyImagesPolicies, logger)
+	}); err != nil {
+		policies := append(append([]*v1.ClusterPolicy{}, mutatePolicies...), verifyImagesPolicies...)
+		return ws.timeoutResponse(request, policies, admissionreviewtimeouts.MutatingWebhook, err, logger)
+	}
+
+	if imageErr != nil {
+		logger.Error(imageErr, "image verification failed")
+		return failureResponse(imageErr.Error())
 	}
 
 	var patches = append(mutatePatches, imagePatches...)
@@ -376,6 +444,9 @@ func (ws *WebhookServer) buildPolicyContext(request *v1beta1.AdmissionRequest, a
 		ResourceCache:       ws.resCache,
 		JSONContext:         ctx,
 		Client:              ws.client,
+		NamespaceLister:     ws.nsLister,
+		SubResource:         common.RequestSubresource(request.Resource.Resource, request.SubResource),
+		SlowRuleThreshold:   ws.slowRuleThreshold,
 	}
 
 	if request.Operation == v1beta1.Update {
@@ -413,6 +484,14 @@ func errorResponse(logger logr.Logger, err error, message string) *v1beta1.Admis
 	}
 }
 
+// withWarnings sets the warnings of the response, they are shown to the user by kubectl
+func withWarnings(r *v1beta1.AdmissionResponse, warnings []string) *v1beta1.AdmissionResponse {
+	if len(warnings) > 0 {
+		r.Warnings = warnings
+	}
+	return r
+}
+
 func failureResponse(message string) *v1beta1.AdmissionResponse {
 	return &v1beta1.AdmissionResponse{
 		Allowed: false,
@@ -475,6 +554,10 @@ func registerAdmissionRequestsMetricGenerate(logger logr.Logger, promConfig metr
 
 func (ws *WebhookServer) resourceValidation(request *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
 	logger := ws.log.WithName("ValidateWebhook").WithValues("uid", request.UID, "kind", request.Kind.Kind, "namespace", request.Namespace, "name", request.Name, "operation", request.Operation)
+	if ws.enforcementPaused(logger) {
+		return successResponse(nil)
+	}
+
 	if request.Operation == v1beta1.Delete {
 		ws.handleDelete(request)
 	}
@@ -541,6 +624,10 @@ func (ws *WebhookServer) resourceValidation(request *v1beta1.AdmissionRequest) *
 		ResourceCache:       ws.resCache,
 		JSONContext:         ctx,
 		Client:              ws.client,
+		NamespaceLister:     ws.nsLister,
+		SubResource:         common.RequestSubresource(request.Resource.Resource, request.SubResource),
+		SlowRuleThreshold:   ws.slowRuleThreshold,
+		ExcludedUsername:    excludedUsername(),
 	}
 
 	vh := &validationHandler{
@@ -549,19 +636,36 @@ func (ws *WebhookServer) resourceValidation(request *v1beta1.AdmissionRequest) *
 		prGenerator: ws.prGenerator,
 	}
 
-	ok, msg := vh.handleValidation(ws.promConfig, request, policies, policyContext, namespaceLabels, admissionRequestTimestamp)
+	var ok bool
+	var msg string
+	var warnings []string
+	if err := ws.evaluateWithTimeout(func(evalCtx context.Context) {
+		// the generate policies are applied with the context of the request once the validation completes
+		policyContext := policyContext.Copy()
+		policyContext.RequestContext = evalCtx
+		ok, msg, warnings = vh.handleValidation(ws.promConfig, request, policies, policyContext, namespaceLabels, admissionRequestTimestamp)
+	}); err != nil {
+		return ws.timeoutResponse(request, policies, admissionreviewtimeouts.ValidatingWebhook, err, logger)
+	}
+
 	if !ok {
 		logger.Info("admission request denied")
-		return failureResponse(msg)
+		return withWarnings(failureResponse(msg), warnings)
 	}
 
 	// push admission request to audit handler, this won't block the admission request
 	ws.auditHandler.Add(request.DeepCopy())
 
+	// CONNECT requests, e.g. for pods/exec, do not create or change a resource,
+	// there is nothing to generate from
+	if request.Operation == v1beta1.Connect {
+		return withWarnings(successResponse(nil), warnings)
+	}
+
 	// process generate policies
 	ws.applyGeneratePolicies(request, policyContext, generatePolicies, admissionRequestTimestamp, logger)
 
-	return successResponse(nil)
+	return withWarnings(successResponse(nil), warnings)
 }
 
 // RunAsync TLS server in separate thread and returns control immediately
@@ -582,16 +686,16 @@ func (ws *WebhookServer) RunAsync(stopCh <-chan struct{}) {
 
 }
 
-// Stop TLS server and returns control after the server is shut down
+// Stop drains the in-flight admission requests, removes the webhook configurations
+// and returns control after the server is shut down
 func (ws *WebhookServer) Stop(ctx context.Context) {
 	logger := ws.log
 
-	// remove the static webhook configurations
-	go ws.webhookRegister.Remove(ws.cleanUp)
+	// the drain and the removal of the webhook configurations share the cleanup deadline
+	ctx, cancel := context.WithTimeout(ctx, webhookCleanupTimeout)
+	defer cancel()
 
-	// shutdown http.Server with context timeout
-	err := ws.server.Shutdown(ctx)
-	if err != nil {
+	if err := ws.webhookRegister.DeregisterGraceful(ctx, ws.server, ws.cleanUp); err != nil {
 		// Error from closing listeners, or context timeout:
 		logger.Error(err, "shutting down server")
 		err = ws.server.Close()
@@ -198,6 +198,9 @@ func (h *auditHandler) process(request *v1beta1.AdmissionRequest) error {
 		ResourceCache:       h.resCache,
 		JSONContext:         ctx,
 		Client:              h.client,
+		NamespaceLister:     h.nsLister,
+		SubResource:         common.RequestSubresource(request.Resource.Resource, request.SubResource),
+		ExcludedUsername:    excludedUsername(),
 	}
 
 	vh := &validationHandler{
@@ -14,6 +14,7 @@ import (
 	admissionRequests "github.com/kyverno/kyverno/pkg/metrics/admissionrequests"
 	admissionReviewDuration "github.com/kyverno/kyverno/pkg/metrics/admissionreviewduration"
 	policyExecutionDuration "github.com/kyverno/kyverno/pkg/metrics/policyexecutionduration"
+	policyOutcomes "github.com/kyverno/kyverno/pkg/metrics/policyoutcomes"
 	policyResults "github.com/kyverno/kyverno/pkg/metrics/policyresults"
 	"github.com/kyverno/kyverno/pkg/policyreport"
 	v1beta1 "k8s.io/api/admission/v1beta1"
@@ -30,16 +31,17 @@ type validationHandler struct {
 // handleValidation handles validating webhook admission request
 // If there are no errors in validating rule we apply generation rules
 // patchedResource is the (resource + patches) after applying mutation rules
+// The warnings are the messages of the failed rules of the warn policies, see getWarnings
 func (v *validationHandler) handleValidation(
 	promConfig *metrics.PromConfig,
 	request *v1beta1.AdmissionRequest,
 	policies []*v1.ClusterPolicy,
 	policyContext *engine.PolicyContext,
 	namespaceLabels map[string]string,
-	admissionRequestTimestamp int64) (bool, string) {
+	admissionRequestTimestamp int64) (bool, string, []string) {
 
 	if len(policies) == 0 {
-		return true, ""
+		return true, "", nil
 	}
 
 	resourceName := getResourceName(request)
@@ -53,11 +55,15 @@ func (v *validationHandler) handleValidation(
 	}
 
 	if deletionTimeStamp != nil && request.Operation == v1beta1.Update {
-		return true, ""
+		return true, "", nil
 	}
 
 	var engineResponses []*response.EngineResponse
 	for _, policy := range policies {
+		if policyContext.Cancelled() {
+			break
+		}
+
 		logger.V(3).Info("evaluating policy", "policy", policy.Name)
 		policyContext.Policy = *policy
 		policyContext.NamespaceLabels = namespaceLabels
@@ -72,6 +78,8 @@ func (v *validationHandler) handleValidation(
 		go registerPolicyResultsMetricValidation(promConfig, logger, string(request.Operation), policyContext.Policy, *engineResponse)
 		// registering the kyverno_policy_execution_duration_seconds metric concurrently
 		go registerPolicyExecutionDurationMetricValidate(promConfig, logger, string(request.Operation), policyContext.Policy, *engineResponse)
+		// registering the kyverno_policy_outcomes_total metric concurrently
+		go registerPolicyOutcomesMetric(promConfig, logger, policyContext.Policy, *engineResponse)
 
 		engineResponses = append(engineResponses, engineResponse)
 		if !engineResponse.IsSuccessful() {
@@ -84,9 +92,16 @@ func (v *validationHandler) handleValidation(
 		}
 	}
 
+	// the result of a cancelled evaluation is discarded by the webhook, it is not reported
+	if policyContext.Cancelled() {
+		logger.V(3).Info("the evaluation of the request was cancelled, skipping the validation")
+		return true, "", nil
+	}
+
 	// If Validation fails then reject the request
 	// no violations will be created on "enforce"
 	blocked := toBlockResource(engineResponses, logger)
+	warnings := getWarnings(engineResponses)
 
 	// REPORTING EVENTS
 	// Scenario 1:
@@ -107,16 +122,19 @@ func (v *validationHandler) handleValidation(
 		go registerAdmissionReviewDurationMetricValidate(promConfig, logger, string(request.Operation), engineResponses, admissionReviewLatencyDuration)
 		//registering the kyverno_admission_requests_total metric concurrently
 		go registerAdmissionRequestsMetricValidate(promConfig, logger, string(request.Operation), engineResponses)
-		return false, getEnforceFailureErrorMsg(engineResponses)
+		return false, getEnforceFailureErrorMsg(engineResponses), warnings
 	}
 
 	if request.Operation == v1beta1.Delete {
 		v.prGenerator.Add(buildDeletionPrInfo(policyContext.OldResource))
-		return true, ""
+		return true, "", warnings
 	}
 
-	prInfos := policyreport.GeneratePRsFromEngineResponse(engineResponses, logger)
-	v.prGenerator.Add(prInfos...)
+	// the options of a CONNECT request are not a resource to report on
+	if request.Operation != v1beta1.Connect {
+		prInfos := policyreport.GeneratePRsFromEngineResponse(engineResponses, logger)
+		v.prGenerator.Add(prInfos...)
+	}
 
 	//registering the kyverno_admission_review_duration_seconds metric concurrently
 	admissionReviewLatencyDuration := int64(time.Since(time.Unix(admissionRequestTimestamp, 0)))
@@ -124,7 +142,7 @@ func (v *validationHandler) handleValidation(
 
 	//registering the kyverno_admission_requests_total metric concurrently
 	go registerAdmissionRequestsMetricValidate(promConfig, logger, string(request.Operation), engineResponses)
-	return true, ""
+	return true, "", warnings
 }
 
 func getResourceName(request *v1beta1.AdmissionRequest) string {
@@ -156,6 +174,12 @@ func registerPolicyExecutionDurationMetricValidate(promConfig *metrics.PromConfi
 	}
 }
 
+func registerPolicyOutcomesMetric(promConfig *metrics.PromConfig, logger logr.Logger, policy v1.ClusterPolicy, engineResponse response.EngineResponse) {
+	if err := policyOutcomes.ParsePromConfig(*promConfig).ProcessEngineResponse(policy, engineResponse); err != nil {
+		logger.Error(err, "error occurred while registering kyverno_policy_outcomes_total metrics for the above policy", "name", policy.Name)
+	}
+}
+
 func registerAdmissionReviewDurationMetricValidate(promConfig *metrics.PromConfig, logger logr.Logger, requestOperation string, engineResponses []*response.EngineResponse, admissionReviewLatencyDuration int64) {
 	resourceRequestOperationPromAlias, err := admissionReviewDuration.ParseResourceRequestOperation(requestOperation)
 	if err != nil {
@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kyverno "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/kyverno/kyverno/pkg/common"
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine"
+	"github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/engine/utils"
+	"github.com/kyverno/kyverno/pkg/metrics"
+	"github.com/kyverno/kyverno/pkg/policyreport"
+	"gotest.tools/assert"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type prGeneratorStub struct{}
+
+func (prGeneratorStub) Add(...policyreport.Info) {}
+
+const requireLabelPolicy = `{
+	"apiVersion": "kyverno.io/v1",
+	"kind": "ClusterPolicy",
+	"metadata": {"name": "require-team-label"},
+	"spec": {
+		"rules": [
+			{
+				"name": "require-team-label",
+				"match": {"resources": {"kinds": ["Pod"]}},
+				"validate": {
+					"message": "the team label of {{request.object.metadata.name}} will be required",
+					"pattern": {"metadata": {"labels": {"team": "?*"}}}
+				}
+			}
+		]
+	}
+}`
+
+func newRequireLabelPolicy(t *testing.T, name, action string) *kyverno.ClusterPolicy {
+	var policy kyverno.ClusterPolicy
+	assert.NilError(t, json.Unmarshal([]byte(requireLabelPolicy), &policy))
+	policy.SetName(name)
+	policy.Spec.ValidationFailureAction = action
+	return &policy
+}
+
+// validate runs the validating webhook on the resource
+func validate(t *testing.T, policies []*kyverno.ClusterPolicy, resource []byte) (bool, string, []string) {
+	request := &v1beta1.AdmissionRequest{
+		UID:       "1",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "default",
+		Name:      "nginx",
+		Operation: v1beta1.Create,
+		Object:    runtime.RawExtension{Raw: resource},
+	}
+
+	ctx := context.NewContext()
+	assert.NilError(t, ctx.AddRequest(request))
+
+	newR, oldR, err := utils.ExtractResources(nil, request)
+	assert.NilError(t, err)
+
+	promConfig, err := metrics.NewPromConfig(&config.MetricsConfigData{}, logr.DiscardLogger{})
+	assert.NilError(t, err)
+
+	vh := &validationHandler{log: logr.DiscardLogger{}, eventGen: eventGeneratorStub{}, prGenerator: prGeneratorStub{}}
+	policyContext := &engine.PolicyContext{NewResource: newR, OldResource: oldR, JSONContext: ctx}
+	return vh.handleValidation(promConfig, request, policies, policyContext, nil, 0)
+}
+
+func Test_handleValidation_Warn(t *testing.T) {
+	pod := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","namespace":"default"}}`)
+
+	// the request is allowed with a warning for each failed rule
+	ok, msg, warnings := validate(t, []*kyverno.ClusterPolicy{
+		newRequireLabelPolicy(t, "require-team-label", common.Warn),
+		newRequireLabelPolicy(t, "require-team-label-v2", common.Warn),
+		newRequireLabelPolicy(t, "audit-team-label", common.Audit),
+	}, pod)
+	assert.Assert(t, ok)
+	assert.Equal(t, msg, "")
+	assert.Equal(t, len(warnings), 2)
+	for i, policy := range []string{"require-team-label", "require-team-label-v2"} {
+		assert.Assert(t, strings.HasPrefix(warnings[i], "policy "+policy+".require-team-label: validation error: "), warnings[i])
+		assert.Assert(t, strings.Contains(warnings[i], "the team label of nginx will be required"), warnings[i])
+	}
+
+	// the warnings are returned with a blocked request
+	ok, _, warnings = validate(t, []*kyverno.ClusterPolicy{
+		newRequireLabelPolicy(t, "require-team-label", common.Warn),
+		newRequireLabelPolicy(t, "enforce-team-label", common.Enforce),
+	}, pod)
+	assert.Assert(t, !ok)
+	assert.Equal(t, len(warnings), 1)
+
+	// no warning when the rules pass
+	ok, _, warnings = validate(t, []*kyverno.ClusterPolicy{newRequireLabelPolicy(t, "require-team-label", common.Warn)},
+		[]byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"nginx","namespace":"default","labels":{"team":"platform"}}}`))
+	assert.Assert(t, ok)
+	assert.Equal(t, len(warnings), 0)
+}
+
+func Test_withWarnings(t *testing.T) {
+	resp := withWarnings(successResponse(nil), []string{"policy require-team-label.require-team-label: the team label will be required"})
+	assert.Assert(t, resp.Allowed)
+	assert.DeepEqual(t, resp.Warnings, []string{"policy require-team-label.require-team-label: the team label will be required"})
+
+	assert.Assert(t, withWarnings(successResponse(nil), nil).Warnings == nil)
+}
@@ -35,6 +35,11 @@ func (ws *WebhookServer) handleVerifyImages(request *v1beta1.AdmissionRequest,
 	var engineResponses []*response.EngineResponse
 	var patches [][]byte
 	for _, p := range policies {
+		if policyContext.Cancelled() {
+			logger.V(3).Info("the evaluation of the request was cancelled, skipping the image verification")
+			return true, "", nil
+		}
+
 		policyContext.Policy = *p
 		resp := engine.VerifyAndPatchImages(policyContext)
 		engineResponses = append(engineResponses, resp)